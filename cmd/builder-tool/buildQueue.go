@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/imagebuilder/client"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func cancelBuildSubcommand(args []string, logger log.DebugLogger) error {
+	if err := cancelBuild(args[0], logger); err != nil {
+		return fmt.Errorf("Error cancelling build: %s", err)
+	}
+	return nil
+}
+
+func cancelBuild(idString string, logger log.Logger) error {
+	id, err := strconv.ParseUint(idString, 10, 64)
+	if err != nil {
+		return err
+	}
+	return client.CancelBuild(getImaginatorClient(), id)
+}
+
+func listBuildQueueSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listBuildQueue(logger); err != nil {
+		return fmt.Errorf("Error listing build queue: %s", err)
+	}
+	return nil
+}
+
+func listBuildQueue(logger log.Logger) error {
+	builds, err := client.ListBuildQueue(getImaginatorClient())
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tSTREAM\tBRANCH\tSTATE\tREQUESTED BY\tWAITERS\tAGE")
+	currentTime := time.Now()
+	for _, build := range builds {
+		state := "queued"
+		age := currentTime.Sub(build.QueuedAt)
+		if build.Running {
+			state = "running"
+			age = currentTime.Sub(build.StartedAt)
+		}
+		requestedBy := build.Username
+		if !build.Interactive {
+			requestedBy = "(auto rebuild)"
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			build.Id, build.StreamName, build.GitBranch, state, requestedBy,
+			build.NumWaiters, format.Duration(age))
+	}
+	return writer.Flush()
+}
@@ -63,6 +63,8 @@ var subcommands = []commands.Command{
 		buildRawFromManifestSubcommand},
 	{"build-tree-from-manifest", "manifestDir", 1, 1,
 		buildTreeFromManifestSubcommand},
+	{"cancel-build", "id", 1, 1, cancelBuildSubcommand},
+	{"list-build-queue", "", 0, 0, listBuildQueueSubcommand},
 	{"process-manifest", "rootDir", 2, 2, processManifestSubcommand},
 }
 
@@ -31,9 +31,9 @@ var (
 	checkTopology = flag.Bool("checkTopology", false,
 		"If true, perform a one-time check, write to stdout and exit")
 	ipmiPasswordFile = flag.String("ipmiPasswordFile", "",
-		"Name of password file used to authenticate for IPMI requests")
+		"Name of password file used to authenticate for IPMI and Redfish requests")
 	ipmiUsername = flag.String("ipmiUsername", "",
-		"Name of user to authenticate as when making IPMI requests")
+		"Name of user to authenticate as when making IPMI and Redfish requests")
 	topologyCheckInterval = flag.Duration("topologyCheckInterval",
 		time.Minute, "Configuration check interval")
 	portNum = flag.Uint("portNum", constants.FleetManagerPortNumber,
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func checkTopologySubcommand(args []string, logger log.DebugLogger) error {
+	dirname := *topologyDir
+	if len(args) > 0 {
+		dirname = args[0]
+	}
+	if err := checkTopology(dirname, logger); err != nil {
+		return fmt.Errorf("Error checking topology: %s", err)
+	}
+	return nil
+}
+
+func checkTopology(dirname string, logger log.DebugLogger) error {
+	if dirname == "" {
+		return errors.New("no topology directory specified")
+	}
+	newTopology, err := topology.Load(dirname)
+	if err != nil {
+		return err
+	}
+	problems := newTopology.Check()
+	if *fleetManagerHostname != "" {
+		fleetManager := fmt.Sprintf("%s:%d",
+			*fleetManagerHostname, *fleetManagerPortNum)
+		client, err := srpc.DialHTTPWithDialer("tcp", fleetManager, rrDialer)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		vmAddresses, err := listVMsInLocation(client, "")
+		if err != nil {
+			return err
+		}
+		problems = append(problems,
+			newTopology.CheckVmAddresses(vmAddresses)...)
+		oldTopology, err := getTopology(client)
+		if err != nil {
+			return err
+		}
+		diffs := oldTopology.Diff(newTopology)
+		if len(diffs) < 1 {
+			fmt.Println("No changes from topology served by Fleet Manager")
+		} else {
+			fmt.Println("Changes from topology served by Fleet Manager:")
+			for _, diff := range diffs {
+				fmt.Println("  " + diff)
+			}
+		}
+	}
+	if len(problems) < 1 {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "Problems found:")
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, "  "+problem.Error())
+	}
+	return fmt.Errorf("%d problems found", len(problems))
+}
+
+func getTopology(client *srpc.Client) (*topology.Topology, error) {
+	conn, err := client.Call("FleetManager.GetTopology")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.Encode(fm_proto.GetTopologyRequest{}); err != nil {
+		return nil, err
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	var reply fm_proto.GetTopologyResponse
+	if err := conn.Decode(&reply); err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	var t topology.Topology
+	if err := conn.Decode(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func listVMsInLocation(client *srpc.Client, location string) ([]net.IP, error) {
+	conn, err := client.Call("FleetManager.ListVMsInLocation")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	request := fm_proto.ListVMsInLocationRequest{Location: location}
+	if err := conn.Encode(request); err != nil {
+		return nil, err
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	var addresses []net.IP
+	for {
+		var reply fm_proto.ListVMsInLocationResponse
+		if err := conn.Decode(&reply); err != nil {
+			return nil, err
+		}
+		if err := errors.New(reply.Error); err != nil {
+			return nil, err
+		}
+		if len(reply.IpAddresses) < 1 {
+			return addresses, nil
+		}
+		addresses = append(addresses, reply.IpAddresses...)
+	}
+}
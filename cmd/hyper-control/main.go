@@ -57,6 +57,8 @@ var (
 		"File containing network interfaces for show-network-configuration")
 	numAcknowledgementsToWaitFor = flag.Uint("numAcknowledgementsToWaitFor",
 		2, "Number of DHCP ACKs to wait for")
+	persistentBootDevice = flag.Bool("persistentBootDevice", false,
+		"If true, set-boot-device applies to all future boots")
 	randomSeedBytes = flag.Uint("randomSeedBytes", 0,
 		"Number of bytes of random seed data to inject into installing machine")
 	storageLayoutFilename = flag.String("storageLayoutFilename", "",
@@ -100,6 +102,8 @@ var subcommands = []commands.Command{
 	{"add-subnet", "ID IPgateway IPmask DNSserver...", 4, -1,
 		addSubnetSubcommand},
 	{"change-tags", "", 0, 0, changeTagsSubcommand},
+	{"check-topology", "[dirname]", 0, 1, checkTopologySubcommand},
+	{"force-power-off", "", 0, 0, forcePowerOffSubcommand},
 	{"get-machine-info", "hostname", 1, 1, getMachineInfoSubcommand},
 	{"get-updates", "", 0, 0, getUpdatesSubcommand},
 	{"installer-shell", "hostname", 1, 1, installerShellSubcommand},
@@ -110,6 +114,7 @@ var subcommands = []commands.Command{
 	{"netboot-machine", "MACaddr IPaddr [hostname]", 2, 3,
 		netbootMachineSubcommand},
 	{"netboot-vm", "", 0, 0, netbootVmSubcommand},
+	{"power-cycle", "", 0, 0, powerCycleSubcommand},
 	{"power-off", "", 0, 0, powerOffSubcommand},
 	{"power-on", "", 0, 0, powerOnSubcommand},
 	{"register-external-leases", "", 0, 0, registerExternalLeasesSubcommand},
@@ -119,6 +124,7 @@ var subcommands = []commands.Command{
 	{"remove-ip-address", "IPaddr", 1, 1, removeIpAddressSubcommand},
 	{"remove-mac-address", "MACaddr", 1, 1, removeMacAddressSubcommand},
 	{"rollout-image", "name", 1, 1, rolloutImageSubcommand},
+	{"set-boot-device", "device", 1, 1, setBootDeviceSubcommand},
 	{"show-network-configuration", "", 0, 0,
 		showNetworkConfigurationSubcommand},
 	{"update-network-configuration", "", 0, 0,
@@ -9,8 +9,45 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 )
 
+// controlMachinePower asks the Fleet Manager to perform a power control action
+// on the Hypervisor using its BMC.
+func controlMachinePower(
+	controlFunc func(client *srpc.Client, hostname string) error) error {
+	if *hypervisorHostname == "" {
+		return errors.New("unspecified Hypervisor")
+	}
+	if *fleetManagerHostname == "" {
+		return errors.New("unspecified Fleet Manager")
+	}
+	clientName := fmt.Sprintf("%s:%d", *fleetManagerHostname,
+		*fleetManagerPortNum)
+	client, err := srpc.DialHTTP("tcp", clientName, 0)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return controlFunc(client, *hypervisorHostname)
+}
+
+func forcePowerOffSubcommand(args []string, logger log.DebugLogger) error {
+	err := controlMachinePower(fmclient.PowerOffMachine)
+	if err != nil {
+		return fmt.Errorf("Error forcing power off: %s", err)
+	}
+	return nil
+}
+
+func powerCycleSubcommand(args []string, logger log.DebugLogger) error {
+	err := controlMachinePower(fmclient.PowerCycleMachine)
+	if err != nil {
+		return fmt.Errorf("Error power cycling: %s", err)
+	}
+	return nil
+}
+
 func powerOffSubcommand(args []string, logger log.DebugLogger) error {
 	err := powerOff(logger)
 	if err != nil {
@@ -46,6 +83,22 @@ func powerOnSubcommand(args []string, logger log.DebugLogger) error {
 }
 
 func powerOn(logger log.DebugLogger) error {
+	return controlMachinePower(fmclient.PowerOnMachine)
+}
+
+func setBootDeviceSubcommand(args []string, logger log.DebugLogger) error {
+	err := setBootDevice(args[0], logger)
+	if err != nil {
+		return fmt.Errorf("Error setting boot device: %s", err)
+	}
+	return nil
+}
+
+func setBootDevice(deviceName string, logger log.DebugLogger) error {
+	var bootDevice fm_proto.BootDevice
+	if err := bootDevice.UnmarshalText([]byte(deviceName)); err != nil {
+		return err
+	}
 	if *hypervisorHostname == "" {
 		return errors.New("unspecified Hypervisor")
 	}
@@ -59,5 +112,6 @@ func powerOn(logger log.DebugLogger) error {
 		return err
 	}
 	defer client.Close()
-	return fmclient.PowerOnMachine(client, *hypervisorHostname)
+	return fmclient.SetMachineBootDevice(client, *hypervisorHostname,
+		bootDevice, *persistentBootDevice)
 }
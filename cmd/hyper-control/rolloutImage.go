@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
@@ -492,7 +493,10 @@ func (h *hypervisorType) getLastImageName(cpuSharer *cpusharer.FifoCpuSharer) (
 	defer client.Put()
 	request := sub_proto.PollRequest{ShortPollOnly: true}
 	var reply sub_proto.PollResponse
-	if err := subclient.CallPoll(client, request, &reply); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = subclient.CallPollContext(ctx, client, request, &reply)
+	if err != nil {
 		client.Close()
 		if err != io.EOF {
 			return "", fmt.Errorf("error polling sub: %s", err)
@@ -30,6 +30,11 @@ const (
 var (
 	dhcpServerOnBridgesOnly = flag.Bool("dhcpServerOnBridgesOnly", false,
 		"If true, run the DHCP server on bridge interfaces only")
+	fleetManagerHostname = flag.String("fleetManagerHostname", "",
+		"Hostname of Fleet Manager to check quotas with (optional)")
+	fleetManagerPortNum = flag.Uint("fleetManagerPortNum",
+		constants.FleetManagerPortNumber,
+		"Port number of Fleet Manager")
 	imageServerHostname = flag.String("imageServerHostname", "localhost",
 		"Hostname of image server")
 	imageServerPortNum = flag.Uint("imageServerPortNum",
@@ -40,6 +45,8 @@ var (
 	objectCacheSize = flagutil.Size(10 << 30)
 	portNum         = flag.Uint("portNum", constants.HypervisorPortNumber,
 		"Port number to allocate and listen on for HTTP/RPC")
+	quotaFailOpen = flag.Bool("quotaFailOpen", false,
+		"If true, permit VM allocations if the Fleet Manager is unreachable")
 	showVGA  = flag.Bool("showVGA", false, "If true, show VGA console")
 	stateDir = flag.String("stateDir", "/var/lib/hypervisor",
 		"Name of state directory")
@@ -152,17 +159,24 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Cannot start tftpboot server: %s\n", err)
 	}
+	var fleetManagerAddress string
+	if *fleetManagerHostname != "" {
+		fleetManagerAddress = fmt.Sprintf("%s:%d",
+			*fleetManagerHostname, *fleetManagerPortNum)
+	}
 	managerObj, err := manager.New(manager.StartOptions{
-		BridgeMap:          bridgeMap,
-		DhcpServer:         dhcpServer,
-		ImageServerAddress: imageServerAddress,
-		Logger:             logger,
-		ObjectCacheBytes:   uint64(objectCacheSize),
-		ShowVgaConsole:     *showVGA,
-		StateDir:           *stateDir,
-		Username:           *username,
-		VlanIdToBridge:     vlanIdToBridge,
-		VolumeDirectories:  volumeDirectories,
+		BridgeMap:           bridgeMap,
+		DhcpServer:          dhcpServer,
+		FleetManagerAddress: fleetManagerAddress,
+		ImageServerAddress:  imageServerAddress,
+		Logger:              logger,
+		ObjectCacheBytes:    uint64(objectCacheSize),
+		QuotaFailOpen:       *quotaFailOpen,
+		ShowVgaConsole:      *showVGA,
+		StateDir:            *stateDir,
+		Username:            *username,
+		VlanIdToBridge:      vlanIdToBridge,
+		VolumeDirectories:   volumeDirectories,
 	})
 	if err != nil {
 		logger.Fatalf("Cannot start hypervisor: %s\n", err)
@@ -186,6 +200,9 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Cannot start metadata server: %s\n", err)
 	}
+	if _, err := setupserver.StartUnixServer(); err != nil {
+		logger.Fatalf("Unable to create Unix server: %s\n", err)
+	}
 	if err := httpd.StartServer(*portNum, managerObj, false); err != nil {
 		logger.Fatalf("Unable to create http server: %s\n", err)
 	}
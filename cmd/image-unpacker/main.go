@@ -64,6 +64,10 @@ func main() {
 	httpd.AddHtmlWriter(unpackerObj)
 	httpd.AddHtmlWriter(rpcHtmlWriter)
 	httpd.AddHtmlWriter(logger)
+	if _, err := setupserver.StartUnixServer(); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Unix server: %s\n", err)
+		os.Exit(1)
+	}
 	if err = httpd.StartServer(*portNum, unpackerObj, false); err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to create http server: %s\n", err)
 		os.Exit(1)
@@ -10,9 +10,11 @@ import (
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/constants"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+	"github.com/Cloud-Foundations/Dominator/lib/vulndb"
 	objectserverRpcd "github.com/Cloud-Foundations/Dominator/objectserver/rpcd"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder/units"
@@ -33,8 +35,17 @@ var (
 		"If true, run in insecure mode. This gives remote access to all")
 	portNum = flag.Uint("portNum", constants.ImageServerPortNumber,
 		"Port number to allocate and listen on for HTTP/RPC")
+	vulnerabilityFeed = flag.String("vulnerabilityFeed", "",
+		"Optional filename of OSV vulnerability feed to match images against")
+
+	vulnerabilityEcosystems flagutil.StringList
 )
 
+func init() {
+	flag.Var(&vulnerabilityEcosystems, "vulnerabilityEcosystems",
+		"Comma separated list of ecosystems (e.g. Debian,Ubuntu) to load from the vulnerability feed (required with -vulnerabilityFeed)")
+}
+
 type imageObjectServersType struct {
 	imdb   *scanner.ImageDataBase
 	objSrv *filesystem.ObjectServer
@@ -88,6 +99,14 @@ func main() {
 	httpd.AddHtmlWriter(imgSrvRpcHtmlWriter)
 	httpd.AddHtmlWriter(objSrvRpcHtmlWriter)
 	httpd.AddHtmlWriter(logger)
+	if *vulnerabilityFeed != "" {
+		if len(vulnerabilityEcosystems) < 1 {
+			logger.Fatalln(
+				"-vulnerabilityEcosystems required with -vulnerabilityFeed")
+		}
+		httpd.WatchVulnerabilityDatabase(vulndb.WatchFeed(*vulnerabilityFeed,
+			vulnerabilityEcosystems, logger))
+	}
 	if err = httpd.StartServer(*portNum, imdb, objSrv, false); err != nil {
 		logger.Fatalf("Unable to create http server: %s\n", err)
 	}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
@@ -25,6 +27,17 @@ func addImagefileSubcommand(args []string, logger log.DebugLogger) error {
 func addImagefile(imageSClient *srpc.Client,
 	objectClient *objectclient.ObjectClient,
 	name, imageFilename, filterFilename, triggersFilename string) error {
+	return addImageFromBuilder(imageSClient, objectClient, name,
+		filterFilename, triggersFilename,
+		func(filter *filter.Filter) (*filesystem.FileSystem, error) {
+			return buildImage(imageSClient, filter, imageFilename)
+		})
+}
+
+func addImageFromBuilder(imageSClient *srpc.Client,
+	objectClient *objectclient.ObjectClient,
+	name, filterFilename, triggersFilename string,
+	builder func(*filter.Filter) (*filesystem.FileSystem, error)) error {
 	imageExists, err := client.CheckImage(imageSClient, name)
 	if err != nil {
 		return errors.New("error checking for image existence: " + err.Error())
@@ -37,8 +50,7 @@ func addImagefile(imageSClient *srpc.Client,
 		triggersFilename); err != nil {
 		return err
 	}
-	newImage.FileSystem, err = buildImage(imageSClient, newImage.Filter,
-		imageFilename)
+	newImage.FileSystem, err = builder(newImage.Filter)
 	if err != nil {
 		return errors.New("error building image: " + err.Error())
 	}
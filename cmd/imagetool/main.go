@@ -59,6 +59,13 @@ var (
 		"If true, make raw image bootable by installing GRUB")
 	minFreeBytes = flag.Uint64("minFreeBytes", 4<<20,
 		"minimum number of free bytes in raw image")
+	ociArchitecture = flag.String("ociArchitecture", "",
+		"Architecture to record when exporting OCI images (default: local)")
+	provenanceInsecure = flag.Bool("provenanceInsecure", false,
+		"If true, do not verify the provenance certificate chain")
+	provenanceRootsFile = flag.String("provenanceRootsFile",
+		"/etc/ssl/CA.pem",
+		"File containing CA certificates to verify provenance")
 	releaseNotes = flag.String("releaseNotes", "",
 		"Filename or URL containing release notes")
 	requiredPaths = flagutil.StringToRuneMap(constants.RequiredPaths)
@@ -128,7 +135,11 @@ var subcommands = []commands.Command{
 	{"delunrefobj", "percentage bytes", 2, 2,
 		deleteUnreferencedObjectsSubcommand},
 	{"diff", diffArgs, 3, 3, diffSubcommand},
+	{"diff-sboms", "leftName rightName", 2, 2, diffSBOMsSubcommand},
 	{"estimate-usage", "     name", 1, 1, estimateImageUsageSubcommand},
+	{"export-oci", "         name destination", 2, 2, exportOciSubcommand},
+	{"export-sbom", "        name format [outfile]", 2, 3,
+		exportSBOMSubcommand},
 	{"find-latest-image", "  directory", 1, 1, findLatestImageSubcommand},
 	{"get", "                name directory", 2, 2, getImageSubcommand},
 	{"get-archive-data", "   name outfile", 2, 2,
@@ -136,6 +147,8 @@ var subcommands = []commands.Command{
 	{"get-file-in-image", "  name imageFile [outfile]", 2, 3,
 		getFileInImageSubcommand},
 	{"get-image-expiration", "name", 1, 1, getImageExpirationSubcommand},
+	{"import-oci", "name source filterfile triggerfile", 4, 4,
+		importOciSubcommand},
 	{"list", "", 0, 0, listImagesSubcommand},
 	{"listdirs", "", 0, 0, listDirectoriesSubcommand},
 	{"listunrefobj", "", 0, 0, listUnreferencedObjectsSubcommand},
@@ -149,6 +162,7 @@ var subcommands = []commands.Command{
 	{"showunrefobj", "", 0, 0, showUnreferencedObjectsSubcommand},
 	{"tar", "                name [file]", 1, 2, tarImageSubcommand},
 	{"test-download-speed", "name", 1, 1, testDownloadSpeedSubcommand},
+	{"verify-provenance", "  name", 1, 1, verifyProvenanceSubcommand},
 }
 
 var imageSrpcClient *srpc.Client
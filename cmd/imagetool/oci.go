@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/oci"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+func exportOciSubcommand(args []string, logger log.DebugLogger) error {
+	_, objectClient := getClients()
+	if err := exportOci(objectClient, args[0], args[1]); err != nil {
+		return fmt.Errorf("Error exporting image: %s", err)
+	}
+	return nil
+}
+
+// exportOci writes an OCI archive if destination ends with ".tar", else an
+// OCI image layout directory.
+func exportOci(objectClient *objectclient.ObjectClient, imageName,
+	destination string) error {
+	fs, objectsGetter, err := getImageForUnpack(objectClient, imageName)
+	if err != nil {
+		return err
+	}
+	params := oci.WriteParams{
+		Architecture: *ociArchitecture,
+		Name:         imageName,
+	}
+	if !strings.HasSuffix(destination, ".tar") {
+		if err := os.Mkdir(destination, fsutil.DirPerms); err != nil {
+			return err
+		}
+		return oci.WriteLayout(destination, fs, objectsGetter, params)
+	}
+	file, err := os.OpenFile(destination, os.O_CREATE|os.O_EXCL|os.O_WRONLY,
+		filePerms)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	err = oci.WriteArchive(writer, fs, objectsGetter, params)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destination)
+	}
+	return err
+}
+
+func importOciSubcommand(args []string, logger log.DebugLogger) error {
+	imageSClient, objectClient := getClients()
+	err := addImageFromBuilder(imageSClient, objectClient, args[0], args[2],
+		args[3],
+		func(filter *filter.Filter) (*filesystem.FileSystem, error) {
+			return buildImageFromOci(imageSClient, filter, args[1])
+		})
+	if err != nil {
+		return fmt.Errorf("Error importing image: \"%s\": %s", args[0], err)
+	}
+	return nil
+}
+
+// buildImageFromOci reads an OCI image layout directory, OCI archive or Docker
+// archive and uploads the objects.
+func buildImageFromOci(imageSClient *srpc.Client, filter *filter.Filter,
+	source string) (*filesystem.FileSystem, error) {
+	var h hasher
+	var err error
+	h.objQ, err = objectclient.NewObjectAdderQueue(imageSClient)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := decodeOci(source, &h, filter)
+	if err != nil {
+		h.objQ.Close()
+		return nil, err
+	}
+	if err := h.objQ.Close(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func decodeOci(source string, h *hasher, filter *filter.Filter) (
+	*filesystem.FileSystem, error) {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return oci.Decode(source, h, filter)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return oci.DecodeArchive(bufio.NewReader(file), h, filter)
+}
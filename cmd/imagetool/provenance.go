@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/provenance"
+)
+
+func verifyProvenanceSubcommand(args []string, logger log.DebugLogger) error {
+	if err := verifyProvenance(args[0]); err != nil {
+		return fmt.Errorf("Error verifying provenance: %s", err)
+	}
+	return nil
+}
+
+func loadProvenanceRoots() (*x509.CertPool, error) {
+	if *provenanceInsecure {
+		return nil, nil
+	}
+	if *provenanceRootsFile == "" {
+		return nil, errors.New("no -provenanceRootsFile specified")
+	}
+	data, err := ioutil.ReadFile(*provenanceRootsFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("unable to parse: %s", *provenanceRootsFile)
+	}
+	return roots, nil
+}
+
+func verifyProvenance(name string) error {
+	roots, err := loadProvenanceRoots()
+	if err != nil {
+		return err
+	}
+	imageSClient, objectClient := getClients()
+	img, err := getImage(imageSClient, name)
+	if err != nil {
+		return err
+	}
+	if img.Provenance == nil || img.Provenance.Object == nil {
+		return errors.New(name + ": no provenance")
+	}
+	_, reader, err := objectClient.GetObject(*img.Provenance.Object)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	attestation, err := provenance.Read(reader)
+	if err != nil {
+		return err
+	}
+	record, cert, err := attestation.Verify(roots)
+	if err != nil {
+		return err
+	}
+	fsDigest := provenance.DigestFileSystem(img.FileSystem)
+	if fsDigest != record.Subject.FileSystemDigest {
+		return fmt.Errorf(
+			"file-system digest: %s does not match provenance: %s",
+			fsDigest, record.Subject.FileSystemDigest)
+	}
+	if roots == nil {
+		fmt.Fprintln(os.Stderr,
+			"Warning: certificate chain not verified (-provenanceInsecure)")
+	}
+	fmt.Fprintf(os.Stderr, "Provenance signed by: %s\n", cert.Subject)
+	return json.WriteWithIndent(os.Stdout, "    ", record)
+}
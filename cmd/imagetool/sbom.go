@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+func diffSBOMsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := diffSBOMs(args[0], args[1]); err != nil {
+		return fmt.Errorf("Error diffing SBOMs: %s", err)
+	}
+	return nil
+}
+
+func diffSBOMs(leftName, rightName string) error {
+	imageSClient, objectClient := getClients()
+	left, err := getSBOM(imageSClient, objectClient, leftName)
+	if err != nil {
+		return err
+	}
+	right, err := getSBOM(imageSClient, objectClient, rightName)
+	if err != nil {
+		return err
+	}
+	return sbom.Diff(left, right).Write(os.Stdout)
+}
+
+func exportSBOMSubcommand(args []string, logger log.DebugLogger) error {
+	var outFileName string
+	if len(args) > 2 {
+		outFileName = args[2]
+	}
+	if err := exportSBOM(args[0], args[1], outFileName); err != nil {
+		return fmt.Errorf("Error exporting SBOM: %s", err)
+	}
+	return nil
+}
+
+func exportSBOM(imageName, format, outFileName string) error {
+	imageSClient, objectClient := getClients()
+	bom, err := getSBOM(imageSClient, objectClient, imageName)
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = os.Stdout
+	if outFileName != "" {
+		file, err := os.OpenFile(outFileName,
+			os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePerms)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	bufferedWriter := bufio.NewWriter(writer)
+	if err := bom.Write(bufferedWriter, format, imageName); err != nil {
+		return err
+	}
+	return bufferedWriter.Flush()
+}
+
+func getSBOM(imageSClient *srpc.Client,
+	objectClient *objectclient.ObjectClient, name string) (*sbom.SBOM, error) {
+	img, err := getImage(imageSClient, name)
+	if err != nil {
+		return nil, err
+	}
+	if img.SBOM == nil || img.SBOM.Object == nil {
+		return nil, errors.New(name + ": no SBOM")
+	}
+	_, reader, err := objectClient.GetObject(*img.SBOM.Object)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return sbom.Read(reader)
+}
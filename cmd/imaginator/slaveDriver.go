@@ -3,12 +3,14 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/slavedriver"
+	"github.com/Cloud-Foundations/Dominator/lib/slavedriver/namespace"
 	"github.com/Cloud-Foundations/Dominator/lib/slavedriver/smallstack"
 	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
@@ -19,6 +21,9 @@ type slaveDriverConfiguration struct {
 	ImageIdentifier   string
 	MemoryInMiB       uint64
 	MilliCPUs         uint
+	Command           []string // Namespace slaves only.
+	Subnet            string   // Namespace slaves only.
+	Type              string   // "namespace" or "smallstack" (default).
 }
 
 func createSlaveDriver(logger log.DebugLogger) (
@@ -31,16 +36,7 @@ func createSlaveDriver(logger log.DebugLogger) (
 	if err != nil {
 		return nil, err
 	}
-	slaveTrader, err := smallstack.NewSlaveTrader(hypervisor.CreateVmRequest{
-		DhcpTimeout:      time.Minute,
-		MinimumFreeBytes: 256 << 20,
-		SkipBootloader:   true,
-		VmInfo: hypervisor.VmInfo{
-			ImageName:   configuration.ImageIdentifier,
-			MemoryInMiB: configuration.MemoryInMiB,
-			MilliCPUs:   configuration.MilliCPUs,
-		},
-	}, logger)
+	slaveTrader, err := createSlaveTrader(configuration, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -58,3 +54,33 @@ func createSlaveDriver(logger log.DebugLogger) (
 	}
 	return slaveDriver, nil
 }
+
+func createSlaveTrader(configuration slaveDriverConfiguration,
+	logger log.DebugLogger) (slavedriver.SlaveTrader, error) {
+	switch configuration.Type {
+	case "namespace":
+		return namespace.NewSlaveTrader(namespace.SlaveTraderOptions{
+			Command:   configuration.Command,
+			ImageName: configuration.ImageIdentifier,
+			ImageServerAddress: fmt.Sprintf("%s:%d",
+				*imageServerHostname, *imageServerPortNum),
+			MemoryInMiB:    configuration.MemoryInMiB,
+			MilliCPUs:      configuration.MilliCPUs,
+			StateDirectory: filepath.Join(*stateDir, "namespace-slaves"),
+			Subnet:         configuration.Subnet,
+		}, logger)
+	case "", "smallstack":
+		return smallstack.NewSlaveTrader(hypervisor.CreateVmRequest{
+			DhcpTimeout:      time.Minute,
+			MinimumFreeBytes: 256 << 20,
+			SkipBootloader:   true,
+			VmInfo: hypervisor.VmInfo{
+				ImageName:   configuration.ImageIdentifier,
+				MemoryInMiB: configuration.MemoryInMiB,
+				MilliCPUs:   configuration.MilliCPUs,
+			},
+		}, logger)
+	default:
+		return nil, fmt.Errorf("unknown slave type: %s", configuration.Type)
+	}
+}
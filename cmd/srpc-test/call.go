@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+)
+
+func callSubcommand(args []string, logger log.DebugLogger) error {
+	var request []byte
+	if len(args) > 2 && args[2] != "-" {
+		request = []byte(args[2])
+	} else {
+		var err error
+		if request, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("Error reading request: %s", err)
+		}
+	}
+	if err := call(args[0], args[1], request, logger); err != nil {
+		return fmt.Errorf("Error calling %s: %s", args[1], err)
+	}
+	return nil
+}
+
+// call calls a request/reply method using the JSON coder, so that the request
+// and response types are not needed.
+func call(address, serviceMethod string, request []byte,
+	logger log.DebugLogger) error {
+	if !json.Valid(request) {
+		return fmt.Errorf("invalid JSON request: %s", request)
+	}
+	if err := setupserver.SetupTlsClientOnly(); err != nil {
+		if *permitInsecureMode {
+			logger.Debugln(0, err)
+		} else {
+			return err
+		}
+	}
+	client, err := srpc.DialJsonHTTP("tcp", address, nil, *timeout)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var response json.RawMessage
+	err = client.RequestReplyContext(ctx, serviceMethod,
+		json.RawMessage(request), &response)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	if err := json.Indent(&buffer, response, "", "    "); err != nil {
+		return err
+	}
+	buffer.WriteByte('\n')
+	_, err = buffer.WriteTo(os.Stdout)
+	return err
+}
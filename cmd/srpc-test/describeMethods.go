@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+func describeMethodsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := describeMethods(args[0], args[1:]); err != nil {
+		return fmt.Errorf("Error describing methods: %s", err)
+	}
+	return nil
+}
+
+// describeMethods writes the descriptions of the methods matching any of the
+// patterns (or all methods if there are no patterns).
+func describeMethods(address string, patterns []string) error {
+	descriptions, err := srpc.GetMethodDescriptions(address, *timeout)
+	if err != nil {
+		return err
+	}
+	var matchingDescriptions []srpc.MethodDescription
+	for _, description := range descriptions {
+		if matchesAny(description.Name, patterns) {
+			matchingDescriptions = append(matchingDescriptions, description)
+		}
+	}
+	if len(matchingDescriptions) < 1 {
+		return fmt.Errorf("no matching methods")
+	}
+	return json.WriteWithIndent(os.Stdout, "    ", matchingDescriptions)
+}
+
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) < 1 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
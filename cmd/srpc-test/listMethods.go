@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+func listMethodsSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listMethods(args[0]); err != nil {
+		return fmt.Errorf("Error listing methods: %s", err)
+	}
+	return nil
+}
+
+func listServicesSubcommand(args []string, logger log.DebugLogger) error {
+	if err := listServices(args[0]); err != nil {
+		return fmt.Errorf("Error listing services: %s", err)
+	}
+	return nil
+}
+
+func listMethods(address string) error {
+	descriptions, err := srpc.GetMethodDescriptions(address, *timeout)
+	if err != nil {
+		return err
+	}
+	for _, description := range descriptions {
+		fmt.Printf("%-40s %s\n", description.Name, description.Type)
+	}
+	return nil
+}
+
+func listServices(address string) error {
+	descriptions, err := srpc.GetMethodDescriptions(address, *timeout)
+	if err != nil {
+		return err
+	}
+	var lastService string
+	for _, description := range descriptions {
+		service := strings.SplitN(description.Name, ".", 2)[0]
+		if service != lastService {
+			fmt.Println(service)
+			lastService = service
+		}
+	}
+	return nil
+}
@@ -3,16 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
-	"net"
-	"net/http"
 	"os"
+	"time"
 
+	"github.com/Cloud-Foundations/Dominator/lib/flags/commands"
 	"github.com/Cloud-Foundations/Dominator/lib/flags/loadflags"
-	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/log/serverlogger"
-	"github.com/Cloud-Foundations/Dominator/lib/srpc"
-	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
-	"github.com/Cloud-Foundations/Dominator/proto/test"
 	"github.com/Cloud-Foundations/tricorder/go/tricorder"
 )
 
@@ -21,41 +17,47 @@ var (
 		"If true, run in insecure mode. This gives remote access to all")
 	portNum = flag.Uint("portNum", 12345,
 		"Port number to allocate and listen on for HTTP/RPC")
+	timeout = flag.Duration("timeout", time.Minute,
+		"Timeout for connecting to servers and for calls")
 )
 
-type serverType struct{}
+func printUsage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "Usage: srpc-test [flags...] [command [args...]]")
+	fmt.Fprintln(w, "If no command is given, the test server is run.")
+	fmt.Fprintln(w, "Common flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "Commands:")
+	commands.PrintCommands(w, subcommands)
+}
 
-func doMain(logger log.DebugLogger) error {
-	if err := setupserver.SetupTls(); err != nil {
-		if *permitInsecureMode {
-			logger.Println(err)
-		} else {
-			return err
-		}
-	}
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *portNum))
-	if err != nil {
-		return err
-	}
-	srpc.RegisterName("Test", &serverType{})
-	return http.Serve(listener, nil)
+var subcommands = []commands.Command{
+	{"call", "            address Service.Method [request]", 2, 3,
+		callSubcommand},
+	{"describe-methods", "address [Service.Method...]", 1, -1,
+		describeMethodsSubcommand},
+	{"list-methods", "    address", 1, 1, listMethodsSubcommand},
+	{"list-services", "   address", 1, 1, listServicesSubcommand},
 }
 
-func main() {
+func doMain() int {
 	if err := loadflags.LoadForDaemon("srpc-test"); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 1
 	}
+	flag.Usage = printUsage
 	flag.Parse()
 	tricorder.RegisterFlags()
 	logger := serverlogger.New("")
-	if err := doMain(logger); err != nil {
-		logger.Fatalln(err)
+	if flag.NArg() < 1 {
+		if err := serve(logger); err != nil {
+			logger.Fatalln(err)
+		}
+		return 0
 	}
+	return commands.RunCommands(subcommands, printUsage, logger)
 }
 
-func (t *serverType) RequestReply(conn *srpc.Conn, request test.EchoRequest,
-	response *test.EchoResponse) error {
-	*response = test.EchoResponse{Response: request.Request}
-	return nil
+func main() {
+	os.Exit(doMain())
 }
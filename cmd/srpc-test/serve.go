@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+type serverType struct{}
+
+func serve(logger log.DebugLogger) error {
+	if err := setupserver.SetupTls(); err != nil {
+		if *permitInsecureMode {
+			logger.Println(err)
+		} else {
+			return err
+		}
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *portNum))
+	if err != nil {
+		return err
+	}
+	srpc.RegisterName("Test", &serverType{})
+	return http.Serve(listener, nil)
+}
+
+func (t *serverType) RequestReply(conn *srpc.Conn, request test.EchoRequest,
+	response *test.EchoResponse) error {
+	*response = test.EchoResponse{Response: request.Request}
+	return nil
+}
@@ -399,6 +399,10 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Unable to create http server: %s\n", err)
 			os.Exit(1)
 		}
+		if _, err := setupserver.StartUnixServer(); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to create Unix server: %s\n", err)
+			os.Exit(1)
+		}
 		fsh.Update(nil)
 		sighupChannel := make(chan os.Signal, 1)
 		signal.Notify(sighupChannel, syscall.SIGHUP)
@@ -58,8 +58,9 @@ var (
 		"If true, perform a short poll which does not request image or object data")
 	showTimes = flag.Bool("showTimes", false,
 		"If true, show time taken for some operations")
-	subHostname = flag.String("subHostname", "localhost", "Hostname of sub")
-	subPortNum  = flag.Uint("subPortNum", constants.SubPortNumber,
+	subHostname = flag.String("subHostname", "localhost",
+		"Hostname of sub (or unix://path for a Unix socket)")
+	subPortNum = flag.Uint("subPortNum", constants.SubPortNumber,
 		"Port number of sub")
 	timeout = flag.Duration("timeout", 15*time.Minute,
 		"timeout for long operations")
@@ -89,7 +90,7 @@ func printUsage() {
 }
 
 func getSubClient(logger log.DebugLogger) *srpc.Client {
-	clientName := fmt.Sprintf("%s:%d", *subHostname, *subPortNum)
+	clientName := srpc.JoinHostPort(*subHostname, *subPortNum)
 	client, err := srpc.DialHTTP("tcp", clientName, *connectTimeout)
 	if err != nil {
 		logger.Fatalf("Error dialing %s: %s\n", clientName, err)
@@ -98,7 +99,7 @@ func getSubClient(logger log.DebugLogger) *srpc.Client {
 }
 
 func getSubClientRetry(logger log.DebugLogger) *srpc.Client {
-	clientName := fmt.Sprintf("%s:%d", *subHostname, *subPortNum)
+	clientName := srpc.JoinHostPort(*subHostname, *subPortNum)
 	var client *srpc.Client
 	var err error
 	for time.Now().Before(timeoutTime) {
@@ -15,7 +15,7 @@ import (
 
 var (
 	imageUnpackerHostname = flag.String("imageUnpackerHostname", "localhost",
-		"Hostname of image-unpacker server")
+		"Hostname of image-unpacker server (or unix://path for a Unix socket)")
 	imageUnpackerPortNum = flag.Uint("imageUnpackerPortNum",
 		constants.ImageUnpackerPortNumber,
 		"Port number of image-unpacker server")
@@ -68,8 +68,8 @@ func doMain() int {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	clientName := fmt.Sprintf("%s:%d",
-		*imageUnpackerHostname, *imageUnpackerPortNum)
+	clientName := srpc.JoinHostPort(*imageUnpackerHostname,
+		*imageUnpackerPortNum)
 	client, err := srpc.DialHTTP("tcp", clientName, 0)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error dialing\t%s\n", err)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
@@ -30,8 +31,8 @@ func changeVmMigrationState(vmHostname string, enable bool,
 	}
 	var hypervisor string
 	if *hypervisorHostname != "" {
-		hypervisor = fmt.Sprintf("%s:%d",
-			*hypervisorHostname, *hypervisorPortNum)
+		hypervisor = srpc.JoinHostPort(*hypervisorHostname,
+			*hypervisorPortNum)
 	} else {
 		hypervisor = fmt.Sprintf("localhost:%d", *hypervisorPortNum)
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
 	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
@@ -36,10 +38,11 @@ func createVmSubcommand(args []string, logger log.DebugLogger) error {
 	return nil
 }
 
-func callCreateVm(client *srpc.Client, request hyper_proto.CreateVmRequest,
-	reply *hyper_proto.CreateVmResponse, imageReader, userDataReader io.Reader,
-	imageSize, userDataSize int64, logger log.DebugLogger) error {
-	conn, err := client.Call("Hypervisor.CreateVm")
+func callCreateVm(ctx context.Context, client *srpc.Client,
+	request hyper_proto.CreateVmRequest, reply *hyper_proto.CreateVmResponse,
+	imageReader, userDataReader io.Reader, imageSize, userDataSize int64,
+	logger log.DebugLogger) error {
+	conn, err := client.CallContext(ctx, "Hypervisor.CreateVm")
 	if err != nil {
 		return fmt.Errorf("error calling Hypervisor.CreateVm: %s", err)
 	}
@@ -188,9 +191,14 @@ func createVmOnHypervisor(hypervisor string, logger log.DebugLogger) error {
 		return err
 	}
 	defer client.Close()
+	ctx, span := trace.StartSpan(context.Background(), "vm-control.createVm",
+		trace.SpanKindInternal)
+	span.SetAttribute("hypervisor", hypervisor)
 	var reply hyper_proto.CreateVmResponse
-	err = callCreateVm(client, request, &reply, imageReader, userDataReader,
-		int64(request.ImageDataSize), int64(request.UserDataSize), logger)
+	err = callCreateVm(ctx, client, request, &reply, imageReader,
+		userDataReader, int64(request.ImageDataSize),
+		int64(request.UserDataSize), logger)
+	span.End(err)
 	if err != nil {
 		return err
 	}
@@ -209,7 +217,7 @@ func createVmOnHypervisor(hypervisor string, logger log.DebugLogger) error {
 
 func getHypervisorAddress() (string, error) {
 	if *hypervisorHostname != "" {
-		return fmt.Sprintf("%s:%d", *hypervisorHostname, *hypervisorPortNum),
+		return srpc.JoinHostPort(*hypervisorHostname, *hypervisorPortNum),
 			nil
 	}
 	client, err := dialFleetManager(fmt.Sprintf("%s:%d",
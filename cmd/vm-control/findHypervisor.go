@@ -12,7 +12,7 @@ import (
 
 func findHypervisor(vmIpAddr net.IP) (string, error) {
 	if *hypervisorHostname != "" {
-		return fmt.Sprintf("%s:%d", *hypervisorHostname, *hypervisorPortNum),
+		return srpc.JoinHostPort(*hypervisorHostname, *hypervisorPortNum),
 			nil
 	} else if *fleetManagerHostname != "" {
 		cm := fmt.Sprintf("%s:%d", *fleetManagerHostname, *fleetManagerPortNum)
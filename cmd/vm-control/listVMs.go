@@ -5,6 +5,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/lib/errors"
 	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/verstr"
 	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
@@ -20,7 +21,7 @@ func listVMsSubcommand(args []string, logger log.DebugLogger) error {
 func listVMs(logger log.DebugLogger) error {
 	if *hypervisorHostname != "" {
 		return listVMsOnHypervisor(
-			fmt.Sprintf("%s:%d", *hypervisorHostname, *hypervisorPortNum),
+			srpc.JoinHostPort(*hypervisorHostname, *hypervisorPortNum),
 			logger)
 	}
 	if *fleetManagerHostname != "" {
@@ -16,6 +16,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/net/rrdialer"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc/setupclient"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
 	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
@@ -39,7 +40,7 @@ var (
 	forceIfNotStopped = flag.Bool("forceIfNotStopped", false,
 		"If true, snapshot or restore VM even if not stopped")
 	hypervisorHostname = flag.String("hypervisorHostname", "",
-		"Hostname of hypervisor")
+		"Hostname of hypervisor (or unix://path for a Unix socket)")
 	hypervisorPortNum = flag.Uint("hypervisorPortNum",
 		constants.HypervisorPortNumber, "Port number of hypervisor")
 	includeUnhealthy = flag.Bool("includeUnhealthy", false,
@@ -191,6 +192,7 @@ func doMain() int {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
+	defer trace.Flush()
 	var err error
 	rrDialer, err = rrdialer.New(&net.Dialer{Timeout: time.Second * 10}, "",
 		logger)
@@ -37,6 +37,7 @@ const (
 	statusMissingCertificate
 	statusBadCertificate
 	statusFailedToConnect
+	statusCircuitBreakerOpen
 	statusWaitingToPoll
 	statusPolling
 	statusPollDenied
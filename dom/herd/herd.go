@@ -26,8 +26,16 @@ var (
 		"If true, updates are disabled at startup")
 	pollSlotsPerCPU = flag.Uint("pollSlotsPerCPU", 100,
 		"Number of poll slots per CPU")
+	subCircuitBreakerThreshold = flag.Uint("subCircuitBreakerThreshold", 0,
+		"Consecutive sub connect failures before backing off. If zero, never")
 	subConnectTimeout = flag.Uint("subConnectTimeout", 15,
 		"Timeout in seconds for sub connections. If zero, OS timeout is used")
+	subHealthCheckInterval = flag.Duration("subHealthCheckInterval", 0,
+		"Interval between pings of idle sub connections. If zero, never")
+	subMaximumBackoff = flag.Duration("subMaximumBackoff", 5*time.Minute,
+		"Maximum time to back off from failing subs")
+	subPollTimeout = flag.Duration("subPollTimeout", 10*time.Minute,
+		"Timeout for sub polls. If zero, there is no timeout")
 )
 
 func newHerd(imageServerAddress string, objectServer objectserver.ObjectServer,
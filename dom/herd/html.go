@@ -50,6 +50,8 @@ func (herd *Herd) writeHtml(writer io.Writer) {
 	fmt.Fprintf(writer,
 		"Number of alive subs: <a href=\"showAliveSubs\">%d</a><br>\n",
 		numSubs)
+	numSubs = herd.countSelectedSubs(selectBackingOffSub)
+	fmt.Fprintf(writer, "Number of subs backing off: %d<br>\n", numSubs)
 	fmt.Fprint(writer, "Number of reachable subs in last: ")
 	herd.writeReachableSubsLink(writer, time.Minute, "1 min", "1m", true)
 	herd.writeReachableSubsLink(writer, time.Minute*10, "10 min", "10m", true)
@@ -134,12 +136,18 @@ func selectAliveSub(sub *Sub) bool {
 		return false
 	case statusFailedToConnect:
 		return false
+	case statusCircuitBreakerOpen:
+		return false
 	case statusFailedToPoll:
 		return false
 	}
 	return true
 }
 
+func selectBackingOffSub(sub *Sub) bool {
+	return sub.publishedStatus == statusCircuitBreakerOpen
+}
+
 func selectDeviantSub(sub *Sub) bool {
 	switch sub.publishedStatus {
 	case statusComputingUpdate:
@@ -179,6 +179,8 @@ func (herd *Herd) showSubHandler(w io.Writer, req *http.Request) {
 	showSince(w, timeNow, sub.lastSyncTime)
 	newRow(w, "Last connection duration", false)
 	showDuration(w, sub.lastConnectDuration, false)
+	newRow(w, "Connection health", false)
+	sub.showConnectionHealth(w)
 	newRow(w, "Last short poll duration", false)
 	showDuration(w, sub.lastShortPollDuration, !sub.lastPollWasFull)
 	newRow(w, "Last full poll duration", false)
@@ -219,6 +221,31 @@ func (sub *Sub) showBusy(writer io.Writer) {
 	}
 }
 
+func (sub *Sub) showConnectionHealth(writer io.Writer) {
+	sub.deletingFlagMutex.Lock()
+	clientResource := sub.clientResource
+	sub.deletingFlagMutex.Unlock()
+	if clientResource == nil {
+		fmt.Fprintln(writer, "    <td></td>")
+		return
+	}
+	status := clientResource.GetStatus()
+	fmt.Fprintf(writer, "    <td>circuit breaker %s",
+		status.CircuitBreakerState)
+	if status.ConsecutiveFailures > 0 {
+		fmt.Fprintf(writer, ", %d consecutive failures",
+			status.ConsecutiveFailures)
+	}
+	if status.CircuitBreakerState == srpc.CircuitBreakerOpen {
+		fmt.Fprintf(writer, ", retry in %s",
+			format.Duration(time.Until(status.RetryTime)))
+	}
+	if status.LastError != nil {
+		fmt.Fprintf(writer, ", last error: %s", status.LastError)
+	}
+	fmt.Fprintln(writer, "</td>")
+}
+
 func showSince(writer io.Writer, now time.Time, since time.Time) {
 	if now.IsZero() || since.IsZero() {
 		fmt.Fprintln(writer, "    <td></td>")
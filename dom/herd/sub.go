@@ -1,6 +1,7 @@
 package herd
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
 	"github.com/Cloud-Foundations/Dominator/lib/resourcepool"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
 	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
 	"github.com/Cloud-Foundations/Dominator/sub/client"
 )
@@ -106,7 +108,12 @@ func (sub *Sub) connectAndPoll() {
 		return
 	}
 	if sub.clientResource == nil {
-		sub.clientResource = srpc.NewClientResource("tcp", sub.address())
+		sub.clientResource = srpc.NewClientResourceWithOptions("tcp",
+			sub.address(), srpc.ClientResourceOptions{
+				FailureThreshold:    *subCircuitBreakerThreshold,
+				HealthCheckInterval: *subHealthCheckInterval,
+				MaximumBackoff:      *subMaximumBackoff,
+			})
 	}
 	sub.deletingFlagMutex.Unlock()
 	previousStatus := sub.status
@@ -127,6 +134,10 @@ func (sub *Sub) connectAndPoll() {
 		if err == resourcepool.ErrorResourceLimitExceeded {
 			return
 		}
+		if err == srpc.ErrorCircuitBreakerOpen {
+			sub.status = statusCircuitBreakerOpen
+			return
+		}
 		if err, ok := err.(*net.OpError); ok {
 			if _, ok := err.Err.(*net.DNSError); ok {
 				sub.status = statusDNSError
@@ -302,7 +313,17 @@ func (sub *Sub) poll(srpcClient *srpc.Client, previousStatus subStatus) {
 	}
 	logger := sub.herd.logger
 	sub.lastPollStartTime = time.Now()
-	if err := client.CallPoll(srpcClient, request, &reply); err != nil {
+	ctx := context.Background()
+	if *subPollTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *subPollTimeout)
+		defer cancel()
+	}
+	ctx, span := trace.StartSpan(ctx, "herd.poll", trace.SpanKindInternal)
+	span.SetAttribute("sub", sub.String())
+	err := client.CallPollContext(ctx, srpcClient, request, &reply)
+	span.End(err)
+	if err != nil {
 		srpcClient.Close()
 		if err == io.EOF {
 			return
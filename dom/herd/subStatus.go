@@ -24,6 +24,8 @@ func (status subStatus) string() string {
 		return "connect failed: bad certificate"
 	case statusFailedToConnect:
 		return "connect failed"
+	case statusCircuitBreakerOpen:
+		return "backing off"
 	case statusWaitingToPoll:
 		return "waiting to poll"
 	case statusPolling:
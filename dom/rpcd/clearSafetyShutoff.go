@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/dominator"
 )
@@ -8,10 +9,11 @@ import (
 func (t *rpcType) ClearSafetyShutoff(conn *srpc.Conn,
 	request dominator.ClearSafetyShutoffRequest,
 	reply *dominator.ClearSafetyShutoffResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	if conn.Username() == "" {
-		t.logger.Printf("ClearSafetyShutoff(%s)\n", request.Hostname)
+		logger.Printf("ClearSafetyShutoff(%s)\n", request.Hostname)
 	} else {
-		t.logger.Printf("ClearSafetyShutoff(%s): by %s\n",
+		logger.Printf("ClearSafetyShutoff(%s): by %s\n",
 			request.Hostname, conn.Username())
 	}
 	return t.herd.ClearSafetyShutoff(request.Hostname)
@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/dominator"
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
@@ -9,10 +10,11 @@ import (
 func (t *rpcType) ConfigureSubs(conn *srpc.Conn,
 	request dominator.ConfigureSubsRequest,
 	reply *dominator.ConfigureSubsResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	if conn.Username() == "" {
-		t.logger.Printf("ConfigureSubs()\n")
+		logger.Printf("ConfigureSubs()\n")
 	} else {
-		t.logger.Printf("ConfigureSubs(): by %s\n", conn.Username())
+		logger.Printf("ConfigureSubs(): by %s\n", conn.Username())
 	}
 	return t.herd.ConfigureSubs(sub.Configuration(request))
 }
@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/dominator"
 )
@@ -8,10 +9,11 @@ import (
 func (t *rpcType) DisableUpdates(conn *srpc.Conn,
 	request dominator.DisableUpdatesRequest,
 	reply *dominator.DisableUpdatesResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	if conn.Username() == "" {
-		t.logger.Printf("DisableUpdates(%s)\n", request.Reason)
+		logger.Printf("DisableUpdates(%s)\n", request.Reason)
 	} else {
-		t.logger.Printf("DisableUpdates(%s): by %s\n",
+		logger.Printf("DisableUpdates(%s): by %s\n",
 			request.Reason, conn.Username())
 	}
 	return t.herd.DisableUpdates(conn.Username(), request.Reason)
@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/dominator"
 )
@@ -8,10 +9,11 @@ import (
 func (t *rpcType) EnableUpdates(conn *srpc.Conn,
 	request dominator.EnableUpdatesRequest,
 	reply *dominator.EnableUpdatesResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	if conn.Username() == "" {
-		t.logger.Printf("EnableUpdates(%s)\n", request.Reason)
+		logger.Printf("EnableUpdates(%s)\n", request.Reason)
 	} else {
-		t.logger.Printf("EnableUpdates(%s): by %s\n",
+		logger.Printf("EnableUpdates(%s): by %s\n",
 			request.Reason, conn.Username())
 	}
 	return t.herd.EnableUpdates()
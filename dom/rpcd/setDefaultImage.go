@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/dominator"
 )
@@ -8,10 +9,11 @@ import (
 func (t *rpcType) SetDefaultImage(conn *srpc.Conn,
 	request dominator.SetDefaultImageRequest,
 	reply *dominator.SetDefaultImageResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	if conn.Username() == "" {
-		t.logger.Printf("SetDefaultImage(%s)\n", request.ImageName)
+		logger.Printf("SetDefaultImage(%s)\n", request.ImageName)
 	} else {
-		t.logger.Printf("SetDefaultImage(%s): by %s\n",
+		logger.Printf("SetDefaultImage(%s): by %s\n",
 			request.ImageName, conn.Username())
 	}
 	return t.herd.SetDefaultImage(request.ImageName)
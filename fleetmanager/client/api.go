@@ -2,8 +2,22 @@ package client
 
 import (
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 )
 
+func PowerCycleMachine(client *srpc.Client, hostname string) error {
+	return powerCycleMachine(client, hostname)
+}
+
+func PowerOffMachine(client *srpc.Client, hostname string) error {
+	return powerOffMachine(client, hostname)
+}
+
 func PowerOnMachine(client *srpc.Client, hostname string) error {
 	return powerOnMachine(client, hostname)
 }
+
+func SetMachineBootDevice(client *srpc.Client, hostname string,
+	bootDevice proto.BootDevice, persistent bool) error {
+	return setMachineBootDevice(client, hostname, bootDevice, persistent)
+}
@@ -6,6 +6,27 @@ import (
 	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 )
 
+func powerCycleMachine(client *srpc.Client, hostname string) error {
+	request := proto.PowerCycleMachineRequest{Hostname: hostname}
+	var reply proto.PowerCycleMachineResponse
+	err := client.RequestReply("FleetManager.PowerCycleMachine", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
+func powerOffMachine(client *srpc.Client, hostname string) error {
+	request := proto.PowerOffMachineRequest{Hostname: hostname}
+	var reply proto.PowerOffMachineResponse
+	err := client.RequestReply("FleetManager.PowerOffMachine", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
 func powerOnMachine(client *srpc.Client, hostname string) error {
 	request := proto.PowerOnMachineRequest{Hostname: hostname}
 	var reply proto.PowerOnMachineResponse
@@ -15,3 +36,19 @@ func powerOnMachine(client *srpc.Client, hostname string) error {
 	}
 	return errors.New(reply.Error)
 }
+
+func setMachineBootDevice(client *srpc.Client, hostname string,
+	bootDevice proto.BootDevice, persistent bool) error {
+	request := proto.SetMachineBootDeviceRequest{
+		BootDevice: bootDevice,
+		Hostname:   hostname,
+		Persistent: persistent,
+	}
+	var reply proto.SetMachineBootDeviceResponse
+	err := client.RequestReply("FleetManager.SetMachineBootDevice", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
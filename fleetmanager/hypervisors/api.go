@@ -32,6 +32,7 @@ type hypervisorType struct {
 	cachedSerialNumber string
 	conn               *srpc.Conn
 	deleteScheduled    bool
+	hardwareInventory  *hyper_proto.HardwareInventory
 	healthStatus       string
 	lastIpmiProbe      time.Time
 	localTags          tags.Tags
@@ -59,20 +60,25 @@ type locationType struct {
 }
 
 type Manager struct {
-	invertTable      [256]byte
-	ipmiPasswordFile string
-	ipmiUsername     string
-	logger           log.DebugLogger
-	storer           Storer
-	mutex            sync.RWMutex               // Protect everything below.
-	allocatingIPs    map[string]struct{}        // Key: VM IP address.
-	hypervisors      map[string]*hypervisorType // Key: hypervisor machine name.
-	locations        map[string]*locationType   // Key: location.
-	migratingIPs     map[string]struct{}        // Key: VM IP address.
-	notifiers        map[<-chan fm_proto.Update]*locationType
-	topology         *topology.Topology
-	subnets          map[string]*subnetType // Key: Gateway IP.
-	vms              map[string]*vmInfoType // Key: VM IP address.
+	invertTable        [256]byte
+	ipmiPasswordFile   string
+	ipmiUsername       string
+	logger             log.DebugLogger
+	storer             Storer
+	eventsReady        chan struct{}
+	eventMutex         sync.Mutex // Protect pendingEvents.
+	pendingEvents      []fm_proto.Event
+	mutex              sync.RWMutex               // Protect everything below.
+	allocatingIPs      map[string]struct{}        // Key: VM IP address.
+	hypervisors        map[string]*hypervisorType // Key: hypervisor machine name.
+	locations          map[string]*locationType   // Key: location.
+	migratingIPs       map[string]struct{}        // Key: VM IP address.
+	migratingVmSources map[string]string          // Key: VM IP address.
+	notifiers          map[<-chan fm_proto.Update]*locationType
+	topology           *topology.Topology
+	subnets            map[string]*subnetType        // Key: Gateway IP.
+	vmReservations     map[string]*vmReservationType // Key: VM IP address.
+	vms                map[string]*vmInfoType        // Key: VM IP address.
 }
 
 type probeStatus uint
@@ -89,7 +95,14 @@ type StartOptions struct {
 	Storer           Storer
 }
 
+type eventStorer interface {
+	CompactEvents(before time.Time) error
+	ReadEvents(startTime, endTime time.Time) ([]fm_proto.Event, error)
+	WriteEvent(event fm_proto.Event) error
+}
+
 type Storer interface {
+	eventStorer
 	ipStorer
 	serialStorer
 	tagsStorer
@@ -130,10 +143,23 @@ func (m *Manager) ChangeMachineTags(hostname string,
 	return m.changeMachineTags(hostname, authInfo, tgs)
 }
 
+// CheckVmAllocation returns an error if the requested resources would exceed
+// the quotas of the owners. If the VM IP address is specified, the resources
+// are reserved until an update for the VM is received.
+func (m *Manager) CheckVmAllocation(
+	request fm_proto.CheckVmAllocationRequest) error {
+	return m.checkVmAllocation(request)
+}
+
 func (m *Manager) CloseUpdateChannel(channel <-chan fm_proto.Update) {
 	m.closeUpdateChannel(channel)
 }
 
+func (m *Manager) GetHardwareInventory(hostname string) (
+	*hyper_proto.HardwareInventory, error) {
+	return m.getHardwareInventory(hostname)
+}
+
 func (m *Manager) GetHypervisorForVm(ipAddr net.IP) (string, error) {
 	return m.getHypervisorForVm(ipAddr)
 }
@@ -142,6 +168,11 @@ func (m *Manager) GetMachineInfo(hostname string) (fm_proto.Machine, error) {
 	return m.getMachineInfo(hostname)
 }
 
+func (m *Manager) GetOwnerUsage(
+	request fm_proto.GetOwnerUsageRequest) ([]fm_proto.OwnerUsage, error) {
+	return m.listOwnerUsage(request)
+}
+
 func (m *Manager) GetTopology() (*topology.Topology, error) {
 	return m.getTopology()
 }
@@ -151,6 +182,11 @@ func (m *Manager) ListHypervisorsInLocation(
 	return m.listHypervisorsInLocation(request)
 }
 
+func (m *Manager) ListEvents(request fm_proto.ListEventsRequest) (
+	[]fm_proto.Event, error) {
+	return m.listEvents(request)
+}
+
 func (m *Manager) ListLocations(dirname string) ([]string, error) {
 	return m.listLocations(dirname)
 }
@@ -167,11 +203,33 @@ func (m *Manager) MoveIpAddresses(hostname string, ipAddresses []net.IP) error {
 	return m.moveIpAddresses(hostname, ipAddresses)
 }
 
+// PowerCycleMachine power cycles the machine using its BMC. Any running VMs
+// are abruptly stopped.
+func (m *Manager) PowerCycleMachine(hostname string,
+	authInfo *srpc.AuthInformation) error {
+	return m.powerCycleMachine(hostname, authInfo)
+}
+
+// PowerOffMachine powers off the machine using its BMC, without shutting down
+// the operating system. Any running VMs are abruptly stopped.
+func (m *Manager) PowerOffMachine(hostname string,
+	authInfo *srpc.AuthInformation) error {
+	return m.powerOffMachine(hostname, authInfo)
+}
+
 func (m *Manager) PowerOnMachine(hostname string,
 	authInfo *srpc.AuthInformation) error {
 	return m.powerOnMachine(hostname, authInfo)
 }
 
+// SetMachineBootDevice sets the boot device for the machine using its BMC. If
+// persistent is false the setting applies to the next boot only.
+func (m *Manager) SetMachineBootDevice(hostname string,
+	authInfo *srpc.AuthInformation, bootDevice fm_proto.BootDevice,
+	persistent bool) error {
+	return m.setMachineBootDevice(hostname, authInfo, bootDevice, persistent)
+}
+
 func (m *Manager) WriteHtml(writer io.Writer) {
 	m.writeHtml(writer)
 }
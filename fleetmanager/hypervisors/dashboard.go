@@ -46,6 +46,11 @@ func (m *Manager) writeHtml(writer io.Writer) {
 	writeCountLinksHTJ(writer, "Number of VMs known",
 		"listVMs?", numVMs)
 	fmt.Fprintln(writer, `Hypervisor <a href="listLocations">locations</a><br>`)
+	fmt.Fprintln(writer,
+		`Hypervisor <a href="listHardwareInventory">hardware inventory</a><br>`)
+	fmt.Fprintln(writer,
+		`Resource <a href="listOwnerUsage">usage by owner</a><br>`)
+	fmt.Fprintln(writer, `Event <a href="listEvents">history</a><br>`)
 }
 
 func writeCountLinksHT(writer io.Writer, text, path string, count uint) {
@@ -0,0 +1,227 @@
+package hypervisors
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+var (
+	eventHistoryRetention = flag.Duration("eventHistoryRetention",
+		time.Hour*24*365, "Duration to keep event history for")
+)
+
+func makeVmEvent(eventType fm_proto.EventType, h *hypervisorType,
+	ipAddr string, vm *hyper_proto.VmInfo) fm_proto.Event {
+	return fm_proto.Event{
+		Hypervisor:  h.machine.Hostname,
+		IpAddress:   ipAddr,
+		OwnerGroups: vm.OwnerGroups,
+		OwnerUsers:  vm.OwnerUsers,
+		State:       vm.State,
+		Type:        eventType,
+	}
+}
+
+func testEventMatch(event fm_proto.Event,
+	request fm_proto.ListEventsRequest) bool {
+	if request.Hypervisor != "" && event.Hypervisor != request.Hypervisor &&
+		event.PreviousHypervisor != request.Hypervisor {
+		return false
+	}
+	if len(request.IpAddress) > 0 &&
+		event.IpAddress != request.IpAddress.String() {
+		return false
+	}
+	if request.OwnerGroup != "" {
+		found := false
+		for _, group := range event.OwnerGroups {
+			if group == request.OwnerGroup {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if request.OwnerUser != "" {
+		found := event.Username == request.OwnerUser
+		for _, user := range event.OwnerUsers {
+			if user == request.OwnerUser {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) compactEventsLoop() {
+	for ; ; time.Sleep(time.Hour) {
+		err := m.storer.CompactEvents(time.Now().Add(-*eventHistoryRetention))
+		if err != nil {
+			m.logger.Printf("error compacting event history: %s\n", err)
+		}
+	}
+}
+
+func (m *Manager) eventWriterLoop() {
+	for range m.eventsReady {
+		m.writePendingEvents()
+	}
+}
+
+func (m *Manager) listEvents(request fm_proto.ListEventsRequest) (
+	[]fm_proto.Event, error) {
+	events, err := m.storer.ReadEvents(request.StartTime, request.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	matchingEvents := make([]fm_proto.Event, 0, len(events))
+	for _, event := range events {
+		if testEventMatch(event, request) {
+			matchingEvents = append(matchingEvents, event)
+		}
+	}
+	if request.MaxEvents > 0 && uint(len(matchingEvents)) > request.MaxEvents {
+		matchingEvents = matchingEvents[uint(len(matchingEvents))-
+			request.MaxEvents:]
+	}
+	return matchingEvents, nil
+}
+
+func (m *Manager) listEventsHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	parsedQuery := url.ParseQuery(req.URL)
+	request := fm_proto.ListEventsRequest{
+		Hypervisor: parsedQuery.Table["hypervisor"],
+		OwnerGroup: parsedQuery.Table["ownerGroup"],
+		OwnerUser:  parsedQuery.Table["ownerUser"],
+	}
+	if vm := parsedQuery.Table["vm"]; vm != "" {
+		request.IpAddress = net.ParseIP(vm)
+		if request.IpAddress == nil {
+			fmt.Fprintf(writer, "invalid IP address: %s\n", vm)
+			return
+		}
+	}
+	if _, ok := parsedQuery.Table["last"]; ok {
+		if last, err := parsedQuery.Last(); err != nil {
+			fmt.Fprintln(writer, err)
+			return
+		} else {
+			request.StartTime = time.Now().Add(-last)
+		}
+	} else {
+		request.StartTime = time.Now().Add(-time.Hour * 24 * 7)
+	}
+	events, err := m.listEvents(request)
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeJson:
+		json.WriteWithIndent(writer, "   ", events)
+	case url.OutputTypeText:
+		for _, event := range events {
+			fmt.Fprintf(writer, "%s %s %s %s\n",
+				event.Time.Format(format.TimeFormatSeconds), event.Type,
+				event.Hypervisor, event.IpAddress)
+		}
+	case url.OutputTypeHtml:
+		fmt.Fprintf(writer, "<title>Event history</title>\n")
+		writer.WriteString(commonStyleSheet)
+		fmt.Fprintln(writer, "<body>")
+		fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+		fmt.Fprintln(writer, "  <tr>")
+		fmt.Fprintln(writer, "    <th>Time</th>")
+		fmt.Fprintln(writer, "    <th>Event</th>")
+		fmt.Fprintln(writer, "    <th>IP Addr</th>")
+		fmt.Fprintln(writer, "    <th>Hypervisor</th>")
+		fmt.Fprintln(writer, "    <th>Details</th>")
+		fmt.Fprintln(writer, "    <th>User</th>")
+		fmt.Fprintln(writer, "  </tr>")
+		for index := len(events) - 1; index >= 0; index-- { // Newest first.
+			writeEventRow(writer, events[index])
+		}
+		fmt.Fprintln(writer, "</table>")
+		fmt.Fprintln(writer, "</body>")
+	}
+}
+
+// recordEvent queues an event to be written by the event writer, so that it
+// is safe to call with m.mutex held.
+func (m *Manager) recordEvent(event fm_proto.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	m.eventMutex.Lock()
+	m.pendingEvents = append(m.pendingEvents, event)
+	m.eventMutex.Unlock()
+	select {
+	case m.eventsReady <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) writePendingEvents() {
+	m.eventMutex.Lock()
+	events := m.pendingEvents
+	m.pendingEvents = nil
+	m.eventMutex.Unlock()
+	for _, event := range events {
+		if err := m.storer.WriteEvent(event); err != nil {
+			m.logger.Printf("error recording event: %s\n", err)
+		}
+	}
+}
+
+func writeEventRow(writer *bufio.Writer, event fm_proto.Event) {
+	fmt.Fprintln(writer, "  <tr>")
+	fmt.Fprintf(writer, "    <td>%s</td>\n",
+		event.Time.Format(format.TimeFormatSeconds))
+	fmt.Fprintf(writer, "    <td>%s</td>\n", event.Type)
+	if event.IpAddress == "" {
+		fmt.Fprintln(writer, "    <td></td>")
+	} else {
+		fmt.Fprintf(writer,
+			"    <td><a href=\"listEvents?vm=%s&last=52w\">%s</a></td>\n",
+			event.IpAddress, event.IpAddress)
+	}
+	fmt.Fprintf(writer,
+		"    <td><a href=\"showHypervisor?%s\">%s</a></td>\n",
+		event.Hypervisor, event.Hypervisor)
+	switch event.Type {
+	case fm_proto.EventTypeVmMigrated:
+		if event.PreviousHypervisor == "" {
+			fmt.Fprintln(writer, "    <td></td>")
+		} else {
+			fmt.Fprintf(writer, "    <td>from: %s</td>\n",
+				event.PreviousHypervisor)
+		}
+	case fm_proto.EventTypeVmStateChanged:
+		fmt.Fprintf(writer, "    <td>%s</td>\n", event.State)
+	case fm_proto.EventTypeMachineTagsChanged:
+		fmt.Fprintf(writer, "    <td>%v</td>\n", event.Tags)
+	default:
+		fmt.Fprintln(writer, "    <td></td>")
+	}
+	fmt.Fprintf(writer, "    <td>%s</td>\n", event.Username)
+	fmt.Fprintln(writer, "  </tr>")
+}
@@ -3,9 +3,11 @@ package fsstorer
 import (
 	"net"
 	"sync"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
@@ -14,9 +16,10 @@ type IP [4]byte
 type Storer struct {
 	topDir          string
 	logger          log.DebugLogger
-	mutex           sync.RWMutex
-	hypervisorToIPs map[IP][]IP // Key: hypervisor IP address.
-	ipToHypervisor  map[IP]IP   // Key: IP address, value: hypervisor.
+	eventsMutex     sync.Mutex   // Protect event segment files.
+	mutex           sync.RWMutex // Protect everything below.
+	hypervisorToIPs map[IP][]IP  // Key: hypervisor IP address.
+	ipToHypervisor  map[IP]IP    // Key: IP address, value: hypervisor.
 }
 
 func New(topDir string, logger log.DebugLogger) (*Storer, error) {
@@ -45,6 +48,10 @@ func (s *Storer) CheckIpIsRegistered(addr net.IP) (bool, error) {
 	return s.checkIpIsRegistered(addr)
 }
 
+func (s *Storer) CompactEvents(before time.Time) error {
+	return s.compactEvents(before)
+}
+
 func (s *Storer) DeleteVm(hypervisor net.IP, ipAddr string) error {
 	return s.deleteVm(hypervisor, ipAddr)
 }
@@ -61,6 +68,11 @@ func (s *Storer) ListVMs(hypervisor net.IP) ([]string, error) {
 	return s.listVMs(hypervisor)
 }
 
+func (s *Storer) ReadEvents(startTime, endTime time.Time) (
+	[]fm_proto.Event, error) {
+	return s.readEvents(startTime, endTime)
+}
+
 func (s *Storer) ReadMachineSerialNumber(hypervisor net.IP) (string, error) {
 	return s.readMachineSerialNumber(hypervisor)
 }
@@ -83,6 +95,10 @@ func (s *Storer) UnregisterHypervisor(hypervisor net.IP) error {
 	return s.unregisterHypervisor(hypervisor)
 }
 
+func (s *Storer) WriteEvent(event fm_proto.Event) error {
+	return s.writeEvent(event)
+}
+
 func (s *Storer) WriteMachineSerialNumber(hypervisor net.IP,
 	serialNumber string) error {
 	return s.writeMachineSerialNumber(hypervisor, serialNumber)
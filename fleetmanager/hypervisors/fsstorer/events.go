@@ -0,0 +1,155 @@
+package fsstorer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+// Events are stored as JSON lines, with one segment file per (UTC) day. Old
+// history is compacted by removing whole segments.
+
+const (
+	eventsDirname          = "events"
+	eventsSegmentExtension = ".json"
+	eventsSegmentFormat    = "2006-01-02"
+)
+
+func getSegmentName(t time.Time) string {
+	return t.UTC().Format(eventsSegmentFormat) + eventsSegmentExtension
+}
+
+func parseSegmentName(name string) (time.Time, bool) {
+	if !strings.HasSuffix(name, eventsSegmentExtension) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(eventsSegmentFormat,
+		name[:len(name)-len(eventsSegmentExtension)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (s *Storer) compactEvents(before time.Time) error {
+	dirname := filepath.Join(s.topDir, eventsDirname)
+	s.eventsMutex.Lock()
+	defer s.eventsMutex.Unlock()
+	names, err := fsutil.ReadDirnames(dirname, true)
+	if err != nil {
+		return err
+	}
+	var numRemoved uint
+	for _, name := range names {
+		if startTime, ok := parseSegmentName(name); !ok {
+			continue
+		} else if !startTime.Add(24 * time.Hour).After(before) {
+			if err := os.Remove(filepath.Join(dirname, name)); err != nil {
+				return err
+			}
+			numRemoved++
+		}
+	}
+	if numRemoved > 0 {
+		s.logger.Debugf(0, "removed %d old event segments\n", numRemoved)
+	}
+	return nil
+}
+
+func (s *Storer) readEvents(startTime, endTime time.Time) (
+	[]proto.Event, error) {
+	// Only list the segments with the lock held, so that a slow read does not
+	// block writers. Partial writes are skipped when decoding and compacted
+	// segments are skipped.
+	dirname := filepath.Join(s.topDir, eventsDirname)
+	s.eventsMutex.Lock()
+	names, err := fsutil.ReadDirnames(dirname, true)
+	s.eventsMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var events []proto.Event
+	for _, name := range names {
+		segmentStart, ok := parseSegmentName(name)
+		if !ok {
+			continue
+		}
+		if !segmentStart.Add(24 * time.Hour).After(startTime) {
+			continue
+		}
+		if !endTime.IsZero() && segmentStart.After(endTime) {
+			continue
+		}
+		events, err = s.readEventSegment(filepath.Join(dirname, name),
+			startTime, endTime, events)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func (s *Storer) readEventSegment(filename string, startTime, endTime time.Time,
+	events []proto.Event) ([]proto.Event, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) { // Compacted.
+			return events, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var event proto.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Probably a partial write: skip it.
+			s.logger.Printf("error decoding event in: %s: %s\n", filename, err)
+			continue
+		}
+		if event.Time.Before(startTime) {
+			continue
+		}
+		if !endTime.IsZero() && event.Time.After(endTime) {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *Storer) writeEvent(event proto.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	dirname := filepath.Join(s.topDir, eventsDirname)
+	s.eventsMutex.Lock()
+	defer s.eventsMutex.Unlock()
+	if err := os.MkdirAll(dirname, dirPerms); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dirname, getSegmentName(event.Time)),
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerms)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
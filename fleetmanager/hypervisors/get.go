@@ -6,6 +6,7 @@ import (
 
 	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
 	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
 func (m *Manager) getLockedHypervisor(name string,
@@ -24,6 +25,16 @@ func (m *Manager) getLockedHypervisor(name string,
 	}
 }
 
+func (m *Manager) getHardwareInventory(hostname string) (
+	*hyper_proto.HardwareInventory, error) {
+	if hypervisor, err := m.getLockedHypervisor(hostname, false); err != nil {
+		return nil, err
+	} else {
+		defer hypervisor.mutex.RUnlock()
+		return hypervisor.hardwareInventory, nil
+	}
+}
+
 func (m *Manager) getHypervisorForVm(ipAddr net.IP) (string, error) {
 	addr := ipAddr.String()
 	m.mutex.RLock()
@@ -0,0 +1,173 @@
+package hypervisors
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+type inventoryEntry struct {
+	Hostname          string
+	Location          string
+	HardwareInventory *hyper_proto.HardwareInventory
+}
+
+// getSearchStrings returns the strings in the inventory which are matched
+// against search queries.
+func getSearchStrings(inventory *hyper_proto.HardwareInventory) []string {
+	searchStrings := []string{
+		inventory.BiosVendor,
+		inventory.BiosVersion,
+		inventory.CpuModel,
+		inventory.ProductName,
+		inventory.SystemVendor,
+	}
+	for _, disk := range inventory.Disks {
+		searchStrings = append(searchStrings, disk.Model)
+	}
+	for _, netInterface := range inventory.NetworkInterfaces {
+		searchStrings = append(searchStrings, netInterface.Driver,
+			netInterface.FirmwareVersion)
+	}
+	return searchStrings
+}
+
+func testInventoryMatch(inventory *hyper_proto.HardwareInventory,
+	search string) bool {
+	if search == "" {
+		return true
+	}
+	search = strings.ToLower(search)
+	for _, searchString := range getSearchStrings(inventory) {
+		if strings.Contains(strings.ToLower(searchString), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeInventoryRow(writer io.Writer, entry inventoryEntry) {
+	inventory := entry.HardwareInventory
+	var diskBytes uint64
+	for _, disk := range inventory.Disks {
+		diskBytes += disk.Size
+	}
+	speeds := make([]string, 0, len(inventory.NetworkInterfaces))
+	for _, netInterface := range inventory.NetworkInterfaces {
+		if netInterface.SpeedInMbps > 0 {
+			speeds = append(speeds,
+				fmt.Sprintf("%d Mb/s", netInterface.SpeedInMbps))
+		}
+	}
+	fmt.Fprintf(writer, "    <td><a href=\"showHypervisor?%s\">%s</a></td>\n",
+		entry.Hostname, entry.Hostname)
+	fmt.Fprintf(writer, "    <td>%s</td>\n", entry.Location)
+	fmt.Fprintf(writer, "    <td>%s %s</td>\n",
+		inventory.SystemVendor, inventory.ProductName)
+	fmt.Fprintf(writer, "    <td>%s</td>\n", inventory.CpuModel)
+	fmt.Fprintf(writer, "    <td>%d/%d/%d</td>\n",
+		inventory.NumSockets, inventory.NumCores, inventory.NumCPUs)
+	fmt.Fprintf(writer, "    <td>%s</td>\n",
+		format.FormatBytes(inventory.MemoryInMiB<<20))
+	fmt.Fprintf(writer, "    <td>%d (%s)</td>\n",
+		len(inventory.Disks), format.FormatBytes(diskBytes))
+	fmt.Fprintf(writer, "    <td>%s</td>\n", strings.Join(speeds, ", "))
+	fmt.Fprintf(writer, "    <td>%s</td>\n", inventory.BiosVersion)
+}
+
+func (m *Manager) listHardwareInventory(location,
+	search string) ([]inventoryEntry, error) {
+	hypervisors, err := m.listHypervisors(location, showAll, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(hypervisors)
+	entries := make([]inventoryEntry, 0, len(hypervisors))
+	for _, hypervisor := range hypervisors {
+		hypervisor.mutex.RLock()
+		inventory := hypervisor.hardwareInventory
+		hypervisor.mutex.RUnlock()
+		if inventory == nil || !testInventoryMatch(inventory, search) {
+			continue
+		}
+		entries = append(entries, inventoryEntry{
+			Hostname:          hypervisor.machine.Hostname,
+			Location:          hypervisor.location,
+			HardwareInventory: inventory,
+		})
+	}
+	return entries, nil
+}
+
+func (m *Manager) listHardwareInventoryHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	_, err := m.getTopology()
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	parsedQuery := url.ParseQuery(req.URL)
+	search := parsedQuery.Table["search"]
+	entries, err := m.listHardwareInventory(parsedQuery.Table["location"],
+		search)
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeJson:
+		json.WriteWithIndent(writer, "    ", entries)
+	case url.OutputTypeText:
+		for _, entry := range entries {
+			fmt.Fprintln(writer, entry.Hostname)
+		}
+	case url.OutputTypeHtml:
+		fmt.Fprintf(writer, "<title>Hypervisor hardware inventory</title>\n")
+		writer.WriteString(commonStyleSheet)
+		fmt.Fprintln(writer, "<body>")
+		fmt.Fprintln(writer, `<form action="listHardwareInventory">`)
+		fmt.Fprintf(writer,
+			"Search: <input type=\"text\" name=\"search\" value=\"%s\">\n",
+			html.EscapeString(search))
+		fmt.Fprintln(writer, `<input type="submit" value="Search">`)
+		fmt.Fprintln(writer, "</form>")
+		fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+		fmt.Fprintln(writer, "  <tr>")
+		fmt.Fprintln(writer, "    <th>Name</th>")
+		fmt.Fprintln(writer, "    <th>Location</th>")
+		fmt.Fprintln(writer, "    <th>Model</th>")
+		fmt.Fprintln(writer, "    <th>CPU</th>")
+		fmt.Fprintln(writer, "    <th>Sockets/Cores/Threads</th>")
+		fmt.Fprintln(writer, "    <th>RAM</th>")
+		fmt.Fprintln(writer, "    <th>Disks</th>")
+		fmt.Fprintln(writer, "    <th>NIC Speeds</th>")
+		fmt.Fprintln(writer, "    <th>BIOS Version</th>")
+		fmt.Fprintln(writer, "  </tr>")
+		lastRowHighlighted := true
+		for _, entry := range entries {
+			if lastRowHighlighted {
+				lastRowHighlighted = false
+				fmt.Fprintf(writer, "  <tr>\n")
+			} else {
+				lastRowHighlighted = true
+				fmt.Fprintf(writer, "  <tr style=\"%s\">\n",
+					rowStyles[rowStyleHighlight].html)
+			}
+			writeInventoryRow(writer, entry)
+			fmt.Fprintf(writer, "  </tr>\n")
+		}
+		fmt.Fprintln(writer, "</table>")
+		fmt.Fprintln(writer, "</body>")
+	}
+}
@@ -0,0 +1,76 @@
+package hypervisors
+
+import (
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func makeTestInventory(cpuModel, driver string) *hyper_proto.HardwareInventory {
+	return &hyper_proto.HardwareInventory{
+		CpuModel: cpuModel,
+		Disks:    []hyper_proto.Disk{{Model: "TestDisk", Size: 1 << 30}},
+		NetworkInterfaces: []hyper_proto.NetworkInterface{
+			{Driver: driver, FirmwareVersion: "1.0", SpeedInMbps: 10000},
+		},
+		ProductName:  "TestServer",
+		SystemVendor: "TestVendor",
+	}
+}
+
+func TestListHardwareInventory(t *testing.T) {
+	m := makeTestManager(t)
+	topo, err := topology.Load("testdata/topology")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.topology = topo
+	hyper0 := makeTestHypervisor(m, "hyper0", "10.0.0.10")
+	hyper0.location = "a"
+	hyper0.hardwareInventory = makeTestInventory("Intel Xeon", "ixgbe")
+	hyper1 := makeTestHypervisor(m, "hyper1", "10.0.0.11")
+	hyper1.location = "a" // No inventory reported yet.
+	hyper2 := makeTestHypervisor(m, "hyper2", "10.0.1.10")
+	hyper2.location = "b"
+	hyper2.hardwareInventory = makeTestInventory("AMD EPYC", "mlx5_core")
+	tests := []struct {
+		location string
+		search   string
+		expected []string
+	}{
+		{"", "", []string{"hyper0", "hyper2"}},
+		{"a", "", []string{"hyper0"}},
+		{"b", "", []string{"hyper2"}},
+		{"", "xeon", []string{"hyper0"}},
+		{"", "MLX5", []string{"hyper2"}},
+		{"", "testdisk", []string{"hyper0", "hyper2"}},
+		{"a", "epyc", nil},
+		{"", "missing", nil},
+	}
+	for _, test := range tests {
+		entries, err := m.listHardwareInventory(test.location, test.search)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var hostnames []string
+		for _, entry := range entries {
+			hostnames = append(hostnames, entry.Hostname)
+			if entry.HardwareInventory == nil {
+				t.Errorf("%s: no inventory", entry.Hostname)
+			}
+		}
+		if len(hostnames) != len(test.expected) {
+			t.Errorf("%q, %q: %v != %v",
+				test.location, test.search, hostnames, test.expected)
+			continue
+		}
+		for index, hostname := range hostnames {
+			if hostname != test.expected[index] {
+				t.Errorf("%q, %q: %v != %v",
+					test.location, test.search, hostnames, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/net/util"
+	"github.com/Cloud-Foundations/Dominator/lib/redfish"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 )
 
 const powerOff = "Power is off"
@@ -19,6 +21,52 @@ var (
 	wolConn *net.UDPConn
 )
 
+func getIpmiHostname(machine *fm_proto.Machine) string {
+	if len(machine.IPMI.HostIpAddress) > 0 {
+		return machine.IPMI.HostIpAddress.String()
+	}
+	return machine.IPMI.Hostname
+}
+
+// controlMachinePower performs a power control action using the BMC for the
+// machine. The resetType is used for Redfish BMCs and the ipmiAction is used
+// otherwise.
+func (m *Manager) controlMachinePower(hostname string,
+	authInfo *srpc.AuthInformation, resetType, ipmiAction string) error {
+	h, err := m.getLockedHypervisor(hostname, false)
+	if err != nil {
+		return err
+	}
+	defer h.mutex.RUnlock()
+	if err := h.checkAuth(authInfo); err != nil {
+		return err
+	}
+	ipmiHostname := getIpmiHostname(h.machine)
+	if ipmiHostname == "" {
+		return fmt.Errorf("no IPMI address for: %s", hostname)
+	}
+	if h.machine.ManagementType == fm_proto.ManagementTypeRedfish {
+		if client, err := m.makeRedfishClient(ipmiHostname); err != nil {
+			return err
+		} else {
+			return client.Reset(resetType)
+		}
+	}
+	return m.runIpmitool(ipmiHostname, "chassis", "power", ipmiAction)
+}
+
+func (m *Manager) powerCycleMachine(hostname string,
+	authInfo *srpc.AuthInformation) error {
+	return m.controlMachinePower(hostname, authInfo,
+		redfish.ResetTypePowerCycle, "cycle")
+}
+
+func (m *Manager) powerOffMachine(hostname string,
+	authInfo *srpc.AuthInformation) error {
+	return m.controlMachinePower(hostname, authInfo,
+		redfish.ResetTypeForceOff, "off")
+}
+
 func (m *Manager) powerOnMachine(hostname string,
 	authInfo *srpc.AuthInformation) error {
 	h, err := m.getLockedHypervisor(hostname, false)
@@ -29,27 +77,75 @@ func (m *Manager) powerOnMachine(hostname string,
 	if err := h.checkAuth(authInfo); err != nil {
 		return err
 	}
-	var ipmiHostname string
-	if len(h.machine.IPMI.HostIpAddress) > 0 {
-		ipmiHostname = h.machine.IPMI.HostIpAddress.String()
-	} else if h.machine.IPMI.Hostname != "" {
-		ipmiHostname = h.machine.IPMI.Hostname
-	} else if sentWakeOnLan, err := m.wakeOnLan(h); err != nil {
-		return err
-	} else if sentWakeOnLan {
-		return nil
-	} else {
+	ipmiHostname := getIpmiHostname(h.machine)
+	if ipmiHostname == "" {
+		if sentWakeOnLan, err := m.wakeOnLan(h); err != nil {
+			return err
+		} else if sentWakeOnLan {
+			return nil
+		}
 		return fmt.Errorf("no IPMI address for: %s", hostname)
 	}
-	cmd := exec.Command("ipmitool", "-f", m.ipmiPasswordFile,
-		"-H", ipmiHostname, "-I", "lanplus", "-U", m.ipmiUsername,
-		"chassis", "power", "on")
+	if h.machine.ManagementType == fm_proto.ManagementTypeRedfish {
+		if client, err := m.makeRedfishClient(ipmiHostname); err != nil {
+			return err
+		} else {
+			return client.Reset(redfish.ResetTypeOn)
+		}
+	}
+	return m.runIpmitool(ipmiHostname, "chassis", "power", "on")
+}
+
+func (m *Manager) runIpmitool(ipmiHostname string, args ...string) error {
+	cmd := exec.Command("ipmitool", append([]string{"-f", m.ipmiPasswordFile,
+		"-H", ipmiHostname, "-I", "lanplus", "-U", m.ipmiUsername},
+		args...)...)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("%s: %s", err, string(output))
 	}
 	return nil
 }
 
+func (m *Manager) setMachineBootDevice(hostname string,
+	authInfo *srpc.AuthInformation, bootDevice fm_proto.BootDevice,
+	persistent bool) error {
+	h, err := m.getLockedHypervisor(hostname, false)
+	if err != nil {
+		return err
+	}
+	defer h.mutex.RUnlock()
+	if err := h.checkAuth(authInfo); err != nil {
+		return err
+	}
+	ipmiHostname := getIpmiHostname(h.machine)
+	if ipmiHostname == "" {
+		return fmt.Errorf("no IPMI address for: %s", hostname)
+	}
+	if h.machine.ManagementType == fm_proto.ManagementTypeRedfish {
+		return m.setRedfishBootDevice(ipmiHostname, bootDevice, persistent)
+	}
+	var device string
+	switch bootDevice {
+	case fm_proto.BootDeviceNone:
+		device = "none"
+	case fm_proto.BootDevicePxe:
+		device = "pxe"
+	case fm_proto.BootDeviceDisk:
+		device = "disk"
+	case fm_proto.BootDeviceCdrom:
+		device = "cdrom"
+	case fm_proto.BootDeviceBiosSetup:
+		device = "bios"
+	default:
+		return fmt.Errorf("unsupported boot device: %s", bootDevice)
+	}
+	args := []string{"chassis", "bootdev", device}
+	if persistent {
+		args = append(args, "options=persistent")
+	}
+	return m.runIpmitool(ipmiHostname, args...)
+}
+
 func (m *Manager) wakeOnLan(h *hypervisorType) (bool, error) {
 	if len(h.machine.HostMacAddress) < 1 {
 		return false, nil
@@ -99,12 +195,8 @@ func (m *Manager) probeUnreachable(h *hypervisorType) probeStatus {
 	if m.ipmiPasswordFile == "" || m.ipmiUsername == "" {
 		return probeStatusUnreachable
 	}
-	var ipmiHostname string
-	if len(h.machine.IPMI.HostIpAddress) > 0 {
-		ipmiHostname = h.machine.IPMI.HostIpAddress.String()
-	} else if h.machine.IPMI.Hostname != "" {
-		ipmiHostname = h.machine.IPMI.Hostname
-	} else {
+	ipmiHostname := getIpmiHostname(h.machine)
+	if ipmiHostname == "" {
 		return probeStatusUnreachable
 	}
 	h.mutex.RLock()
@@ -115,10 +207,13 @@ func (m *Manager) probeUnreachable(h *hypervisorType) probeStatus {
 		time.Until(h.lastIpmiProbe.Add(mimimumProbeInterval)) > 0 {
 		return probeStatusOff
 	}
+	h.lastIpmiProbe = time.Now()
+	if h.machine.ManagementType == fm_proto.ManagementTypeRedfish {
+		return m.probeRedfish(ipmiHostname, previousProbeStatus)
+	}
 	cmd := exec.Command("ipmitool", "-f", m.ipmiPasswordFile,
 		"-H", ipmiHostname, "-I", "lanplus", "-U", m.ipmiUsername,
 		"chassis", "power", "status")
-	h.lastIpmiProbe = time.Now()
 	if output, err := cmd.Output(); err != nil {
 		if previousProbeStatus == probeStatusOff {
 			return probeStatusOff
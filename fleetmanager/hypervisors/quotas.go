@@ -0,0 +1,394 @@
+package hypervisors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// vmReservationTimeout is how long resources are reserved for a VM if no
+// update for the VM is received.
+const vmReservationTimeout = time.Hour
+
+type ownerType struct {
+	group string
+	user  string
+}
+
+// vmReservationType records resources which were permitted for a VM but which
+// are not yet reflected in the VM updates from its Hypervisor.
+type vmReservationType struct {
+	expires   time.Time
+	owners    []ownerType
+	resources fm_proto.Resources // NumVMs=1: new VM, else extra resources.
+}
+
+func addResources(left, right fm_proto.Resources) fm_proto.Resources {
+	return fm_proto.Resources{
+		IpAddresses: left.IpAddresses + right.IpAddresses,
+		MemoryInMiB: left.MemoryInMiB + right.MemoryInMiB,
+		MilliCPUs:   left.MilliCPUs + right.MilliCPUs,
+		NumVMs:      left.NumVMs + right.NumVMs,
+		VolumeBytes: left.VolumeBytes + right.VolumeBytes,
+	}
+}
+
+func maxResources(left, right fm_proto.Resources) fm_proto.Resources {
+	if right.IpAddresses > left.IpAddresses {
+		left.IpAddresses = right.IpAddresses
+	}
+	if right.MemoryInMiB > left.MemoryInMiB {
+		left.MemoryInMiB = right.MemoryInMiB
+	}
+	if right.MilliCPUs > left.MilliCPUs {
+		left.MilliCPUs = right.MilliCPUs
+	}
+	if right.NumVMs > left.NumVMs {
+		left.NumVMs = right.NumVMs
+	}
+	if right.VolumeBytes > left.VolumeBytes {
+		left.VolumeBytes = right.VolumeBytes
+	}
+	return left
+}
+
+func checkQuota(owner ownerType, quota *fm_proto.Quota,
+	usage, request fm_proto.Resources) error {
+	if quota == nil {
+		return nil
+	}
+	var name string
+	if owner.group != "" {
+		name = "OwnerGroup: " + owner.group
+	} else {
+		name = "OwnerUser: " + owner.user
+	}
+	total := addResources(usage, request)
+	limits := quota.Limits
+	if limits.IpAddresses > 0 && total.IpAddresses > limits.IpAddresses {
+		return fmt.Errorf("%s would exceed IP address quota: %d+%d > %d",
+			name, usage.IpAddresses, request.IpAddresses, limits.IpAddresses)
+	}
+	if limits.MemoryInMiB > 0 && total.MemoryInMiB > limits.MemoryInMiB {
+		return fmt.Errorf("%s would exceed memory quota: %s+%s > %s",
+			name, format.FormatBytes(usage.MemoryInMiB<<20),
+			format.FormatBytes(request.MemoryInMiB<<20),
+			format.FormatBytes(limits.MemoryInMiB<<20))
+	}
+	if limits.MilliCPUs > 0 && total.MilliCPUs > limits.MilliCPUs {
+		return fmt.Errorf("%s would exceed CPU quota: %g+%g > %g",
+			name, float64(usage.MilliCPUs)*1e-3,
+			float64(request.MilliCPUs)*1e-3, float64(limits.MilliCPUs)*1e-3)
+	}
+	if limits.VolumeBytes > 0 && total.VolumeBytes > limits.VolumeBytes {
+		return fmt.Errorf("%s would exceed volume quota: %s+%s > %s",
+			name, format.FormatBytes(usage.VolumeBytes),
+			format.FormatBytes(request.VolumeBytes),
+			format.FormatBytes(limits.VolumeBytes))
+	}
+	if limits.NumVMs > 0 && total.NumVMs > limits.NumVMs {
+		return fmt.Errorf("%s would exceed VM quota: %d+%d > %d",
+			name, usage.NumVMs, request.NumVMs, limits.NumVMs)
+	}
+	return nil
+}
+
+func getQuota(t *topology.Topology, owner ownerType) *fm_proto.Quota {
+	if owner.group != "" {
+		return t.GetGroupQuota(owner.group)
+	}
+	return t.GetUserQuota(owner.user)
+}
+
+func getVmResources(vm *hyper_proto.VmInfo) fm_proto.Resources {
+	resources := fm_proto.Resources{
+		IpAddresses: uint(len(vm.SecondaryAddresses)),
+		MemoryInMiB: vm.MemoryInMiB,
+		MilliCPUs:   vm.MilliCPUs,
+		NumVMs:      1,
+	}
+	if len(vm.Address.IpAddress) > 0 {
+		resources.IpAddresses++
+	}
+	for _, volume := range vm.Volumes {
+		resources.VolumeBytes += volume.Size
+	}
+	return resources
+}
+
+// getVmOwners returns the owners a VM is charged to: each of its owner groups
+// and its primary owner user.
+func getVmOwners(ownerGroups, ownerUsers []string) []ownerType {
+	owners := make([]ownerType, 0, len(ownerGroups)+1)
+	for _, group := range ownerGroups {
+		owners = append(owners, ownerType{group: group})
+	}
+	if len(ownerUsers) > 0 {
+		owners = append(owners, ownerType{user: ownerUsers[0]})
+	}
+	return owners
+}
+
+// checkVmAllocation checks the requested resources against the quotas of the
+// owners. If the request specifies the VM IP address and is permitted, the
+// resources are reserved until an update for the VM is received, so that
+// concurrent requests cannot together exceed a quota.
+func (m *Manager) checkVmAllocation(
+	request fm_proto.CheckVmAllocationRequest) error {
+	t, err := m.getTopology()
+	if err != nil {
+		return err
+	}
+	owners := getVmOwners(request.OwnerGroups, request.OwnerUsers)
+	var ipAddr string
+	if len(request.IpAddress) > 0 {
+		ipAddr = request.IpAddress.String()
+	}
+	now := time.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for ipAddr, reservation := range m.vmReservations {
+		if now.After(reservation.expires) {
+			delete(m.vmReservations, ipAddr)
+		}
+	}
+	var excludeIpAddr string
+	if request.Resources.NumVMs > 0 {
+		// Do not count a new VM (which may already have been reported) twice.
+		excludeIpAddr = ipAddr
+	}
+	usage := m.getOwnerUsageWithLock(now, excludeIpAddr)
+	for _, owner := range owners {
+		err := checkQuota(owner, getQuota(t, owner), usage[owner],
+			request.Resources)
+		if err != nil {
+			return err
+		}
+	}
+	if ipAddr != "" {
+		m.vmReservations[ipAddr] = &vmReservationType{
+			expires:   now.Add(vmReservationTimeout),
+			owners:    owners,
+			resources: request.Resources,
+		}
+	}
+	return nil
+}
+
+func (m *Manager) getOwnerUsage() map[ownerType]fm_proto.Resources {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.getOwnerUsageWithLock(time.Now(), "")
+}
+
+// getOwnerUsageWithLock returns the resources consumed by the VMs of each
+// owner, including reserved resources. The VM with IP address excludeIpAddr
+// (if specified) is not counted. It must be called with the lock held.
+func (m *Manager) getOwnerUsageWithLock(now time.Time,
+	excludeIpAddr string) map[ownerType]fm_proto.Resources {
+	usage := make(map[ownerType]fm_proto.Resources)
+	for ipAddr, vm := range m.vms {
+		if ipAddr == excludeIpAddr {
+			continue
+		}
+		resources := getVmResources(&vm.VmInfo)
+		if reservation := m.vmReservations[ipAddr]; reservation != nil &&
+			now.Before(reservation.expires) {
+			if reservation.resources.NumVMs > 0 { // Being created.
+				resources = maxResources(resources, reservation.resources)
+			} else {
+				resources = addResources(resources, reservation.resources)
+			}
+		}
+		for _, owner := range getVmOwners(vm.OwnerGroups, vm.OwnerUsers) {
+			usage[owner] = addResources(usage[owner], resources)
+		}
+	}
+	for ipAddr, reservation := range m.vmReservations {
+		if ipAddr == excludeIpAddr || now.After(reservation.expires) {
+			continue
+		}
+		if _, ok := m.vms[ipAddr]; ok {
+			continue // Counted above.
+		}
+		for _, owner := range reservation.owners {
+			usage[owner] = addResources(usage[owner], reservation.resources)
+		}
+	}
+	return usage
+}
+
+func (m *Manager) listOwnerUsage(
+	request fm_proto.GetOwnerUsageRequest) ([]fm_proto.OwnerUsage, error) {
+	t, err := m.getTopology()
+	if err != nil {
+		return nil, err
+	}
+	usage := m.getOwnerUsage()
+	for _, quota := range t.Quotas {
+		owner := ownerType{group: quota.OwnerGroup, user: quota.OwnerUser}
+		if _, ok := usage[owner]; !ok {
+			usage[owner] = fm_proto.Resources{}
+		}
+	}
+	owners := make([]fm_proto.OwnerUsage, 0, len(usage))
+	for owner, resources := range usage {
+		if request.OwnerGroup != "" && owner.group != request.OwnerGroup {
+			continue
+		}
+		if request.OwnerUser != "" && owner.user != request.OwnerUser {
+			continue
+		}
+		ownerUsage := fm_proto.OwnerUsage{
+			OwnerGroup: owner.group,
+			OwnerUser:  owner.user,
+			Usage:      resources,
+		}
+		if quota := getQuota(t, owner); quota != nil {
+			limits := quota.Limits
+			ownerUsage.Quota = &limits
+		}
+		owners = append(owners, ownerUsage)
+	}
+	sort.Slice(owners, func(left, right int) bool { // Groups come first.
+		leftGroup := owners[left].OwnerGroup
+		rightGroup := owners[right].OwnerGroup
+		if leftGroup == "" && rightGroup != "" {
+			return false
+		}
+		if leftGroup != "" && rightGroup == "" {
+			return true
+		}
+		if leftGroup != rightGroup {
+			return leftGroup < rightGroup
+		}
+		return owners[left].OwnerUser < owners[right].OwnerUser
+	})
+	return owners, nil
+}
+
+// releaseVmReservation releases the resources reserved for a VM once they are
+// reflected in the update for the VM (protoVm), or when the VM is deleted
+// (protoVm is nil). It must be called with the lock held.
+func (m *Manager) releaseVmReservation(ipAddr string,
+	protoVm *hyper_proto.VmInfo) {
+	reservation, ok := m.vmReservations[ipAddr]
+	if !ok {
+		return
+	}
+	if protoVm != nil && reservation.resources.NumVMs > 0 &&
+		protoVm.State == hyper_proto.StateStarting {
+		return // Volumes may not yet have been created.
+	}
+	delete(m.vmReservations, ipAddr)
+}
+
+func (m *Manager) listOwnerUsageHandler(w http.ResponseWriter,
+	req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	parsedQuery := url.ParseQuery(req.URL)
+	owners, err := m.listOwnerUsage(fm_proto.GetOwnerUsageRequest{
+		OwnerGroup: parsedQuery.Table["ownerGroup"],
+		OwnerUser:  parsedQuery.Table["ownerUser"],
+	})
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeJson:
+		json.WriteWithIndent(writer, "   ", owners)
+	case url.OutputTypeText:
+		for _, owner := range owners {
+			if owner.OwnerGroup != "" {
+				fmt.Fprintln(writer, "group:"+owner.OwnerGroup)
+			} else {
+				fmt.Fprintln(writer, "user:"+owner.OwnerUser)
+			}
+		}
+	case url.OutputTypeHtml:
+		fmt.Fprintf(writer, "<title>Resource usage by owner</title>\n")
+		writer.WriteString(commonStyleSheet)
+		fmt.Fprintln(writer, "<body>")
+		fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+		fmt.Fprintln(writer, "  <tr>")
+		fmt.Fprintln(writer, "    <th>Owner Group</th>")
+		fmt.Fprintln(writer, "    <th>Owner User</th>")
+		fmt.Fprintln(writer, "    <th>CPU</th>")
+		fmt.Fprintln(writer, "    <th>RAM</th>")
+		fmt.Fprintln(writer, "    <th>Storage</th>")
+		fmt.Fprintln(writer, "    <th>IP Addrs</th>")
+		fmt.Fprintln(writer, "    <th>VMs</th>")
+		fmt.Fprintln(writer, "  </tr>")
+		for _, owner := range owners {
+			writeOwnerUsageRow(writer, owner)
+		}
+		fmt.Fprintln(writer, "</table>")
+		fmt.Fprintln(writer, "</body>")
+	}
+}
+
+func writeOwnerUsageRow(writer io.Writer, owner fm_proto.OwnerUsage) {
+	var limits fm_proto.Resources
+	if owner.Quota != nil {
+		limits = *owner.Quota
+	}
+	usage := owner.Usage
+	fmt.Fprintln(writer, "  <tr>")
+	fmt.Fprintf(writer, "    <td>%s</td>\n", owner.OwnerGroup)
+	if owner.OwnerUser == "" {
+		fmt.Fprintln(writer, "    <td></td>")
+	} else {
+		fmt.Fprintf(writer,
+			"    <td><a href=\"listVMs?primaryOwner=%s\">%s</a></td>\n",
+			owner.OwnerUser, owner.OwnerUser)
+	}
+	writeUsageTableEntry(writer,
+		fmt.Sprintf("%g", float64(usage.MilliCPUs)*1e-3),
+		fmt.Sprintf("%g", float64(limits.MilliCPUs)*1e-3),
+		uint64(usage.MilliCPUs), uint64(limits.MilliCPUs))
+	writeUsageTableEntry(writer,
+		format.FormatBytes(usage.MemoryInMiB<<20),
+		format.FormatBytes(limits.MemoryInMiB<<20),
+		usage.MemoryInMiB, limits.MemoryInMiB)
+	writeUsageTableEntry(writer,
+		format.FormatBytes(usage.VolumeBytes),
+		format.FormatBytes(limits.VolumeBytes),
+		usage.VolumeBytes, limits.VolumeBytes)
+	writeUsageTableEntry(writer,
+		fmt.Sprintf("%d", usage.IpAddresses),
+		fmt.Sprintf("%d", limits.IpAddresses),
+		uint64(usage.IpAddresses), uint64(limits.IpAddresses))
+	writeUsageTableEntry(writer,
+		fmt.Sprintf("%d", usage.NumVMs),
+		fmt.Sprintf("%d", limits.NumVMs),
+		uint64(usage.NumVMs), uint64(limits.NumVMs))
+	fmt.Fprintln(writer, "  </tr>")
+}
+
+func writeUsageTableEntry(writer io.Writer, usageString, limitString string,
+	usage, limit uint64) {
+	if limit < 1 {
+		fmt.Fprintf(writer, "    <td>%s</td>\n", usageString)
+		return
+	}
+	var style string
+	if usage >= limit {
+		style = ` style="color:red"`
+	} else if usage*10 >= limit*9 {
+		style = ` style="color:#c00000"`
+	}
+	fmt.Fprintf(writer, "    <td%s>%s / %s</td>\n",
+		style, usageString, limitString)
+}
@@ -0,0 +1,286 @@
+package hypervisors
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/fleetmanager/topology"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const testQuotas = `[
+	{"OwnerGroup": "team", "Limits": {"MilliCPUs": 4000, "NumVMs": 3}},
+	{"OwnerUser": "user", "Limits": {"MemoryInMiB": 4096}}
+]`
+
+func loadTestQuotas(t *testing.T, m *Manager) {
+	topDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(topDir, "quotas.json"),
+		[]byte(testQuotas), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo, err := topology.Load(topDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.topology = topo
+}
+
+func makeTestQuotaVm(ipAddr string, state hyper_proto.State,
+	milliCPUs uint) *hyper_proto.VmInfo {
+	return &hyper_proto.VmInfo{
+		Address:     hyper_proto.Address{IpAddress: net.ParseIP(ipAddr)},
+		MemoryInMiB: 1024,
+		MilliCPUs:   milliCPUs,
+		OwnerGroups: []string{"team"},
+		OwnerUsers:  []string{"user", "other"},
+		State:       state,
+		Volumes:     []hyper_proto.Volume{{Size: 1 << 30}},
+	}
+}
+
+func sendTestVms(m *Manager, h *hypervisorType,
+	vms map[string]*hyper_proto.VmInfo) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.processVmUpdatesWithLock(h, vms)
+}
+
+func TestCheckQuota(t *testing.T) {
+	owner := ownerType{group: "team"}
+	quota := &fm_proto.Quota{
+		OwnerGroup: "team",
+		Limits: fm_proto.Resources{
+			IpAddresses: 10,
+			MemoryInMiB: 8192,
+			MilliCPUs:   4000,
+			NumVMs:      5,
+			VolumeBytes: 100 << 30,
+		},
+	}
+	usage := fm_proto.Resources{
+		IpAddresses: 8,
+		MemoryInMiB: 6144,
+		MilliCPUs:   3000,
+		NumVMs:      4,
+		VolumeBytes: 90 << 30,
+	}
+	tests := []struct {
+		name    string
+		request fm_proto.Resources
+		fail    bool
+	}{
+		{"IP below", fm_proto.Resources{IpAddresses: 1}, false},
+		{"IP at", fm_proto.Resources{IpAddresses: 2}, false},
+		{"IP above", fm_proto.Resources{IpAddresses: 3}, true},
+		{"memory below", fm_proto.Resources{MemoryInMiB: 1024}, false},
+		{"memory at", fm_proto.Resources{MemoryInMiB: 2048}, false},
+		{"memory above", fm_proto.Resources{MemoryInMiB: 2049}, true},
+		{"CPU below", fm_proto.Resources{MilliCPUs: 500}, false},
+		{"CPU at", fm_proto.Resources{MilliCPUs: 1000}, false},
+		{"CPU above", fm_proto.Resources{MilliCPUs: 1001}, true},
+		{"VMs at", fm_proto.Resources{NumVMs: 1}, false},
+		{"VMs above", fm_proto.Resources{NumVMs: 2}, true},
+		{"volume below", fm_proto.Resources{VolumeBytes: 1 << 30}, false},
+		{"volume at", fm_proto.Resources{VolumeBytes: 10 << 30}, false},
+		{"volume above", fm_proto.Resources{VolumeBytes: 10<<30 + 1}, true},
+	}
+	for _, test := range tests {
+		err := checkQuota(owner, quota, usage, test.request)
+		if test.fail && err == nil {
+			t.Errorf("%s: allocation permitted", test.name)
+		} else if !test.fail && err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		}
+		if err := checkQuota(owner, nil, usage, test.request); err != nil {
+			t.Errorf("%s: no quota: %s", test.name, err)
+		}
+	}
+	// Zero limits are unlimited.
+	err := checkQuota(owner, &fm_proto.Quota{OwnerGroup: "team"}, usage,
+		fm_proto.Resources{MilliCPUs: 1 << 20, NumVMs: 1 << 20})
+	if err != nil {
+		t.Errorf("zero limits: %s", err)
+	}
+}
+
+func TestCheckVmAllocationReservations(t *testing.T) {
+	m := makeTestManager(t)
+	loadTestQuotas(t, m)
+	h := makeTestHypervisor(m, "hyper", "10.0.0.1")
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{
+		"10.1.0.1": makeTestQuotaVm("10.1.0.1", hyper_proto.StateRunning,
+			2000),
+	})
+	request := fm_proto.CheckVmAllocationRequest{
+		IpAddress:   net.ParseIP("10.1.0.2"),
+		OwnerGroups: []string{"team"},
+		OwnerUsers:  []string{"user"},
+		Resources: fm_proto.Resources{
+			MemoryInMiB: 1024,
+			MilliCPUs:   1500,
+			NumVMs:      1,
+		},
+	}
+	if err := m.checkVmAllocation(request); err != nil {
+		t.Fatal(err)
+	}
+	// A concurrent request must see the reservation.
+	request.IpAddress = net.ParseIP("10.1.0.3")
+	if err := m.checkVmAllocation(request); err == nil {
+		t.Fatal("concurrent allocation exceeded quota")
+	}
+	// While the VM is being created, it is counted once.
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{
+		"10.1.0.2": makeTestQuotaVm("10.1.0.2", hyper_proto.StateStarting,
+			1500),
+	})
+	usage := m.getOwnerUsage()
+	if cpus := usage[ownerType{group: "team"}].MilliCPUs; cpus != 3500 {
+		t.Errorf("CPUs while starting: %d", cpus)
+	}
+	// Re-checking a new VM which was already reported does not count it twice.
+	request.IpAddress = net.ParseIP("10.1.0.2")
+	if err := m.checkVmAllocation(request); err != nil {
+		t.Errorf("re-check: %s", err)
+	}
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{
+		"10.1.0.2": makeTestQuotaVm("10.1.0.2", hyper_proto.StateRunning,
+			1000),
+	})
+	if _, ok := m.vmReservations["10.1.0.2"]; ok {
+		t.Error("reservation not released after VM started")
+	}
+	usage = m.getOwnerUsage()
+	if cpus := usage[ownerType{group: "team"}].MilliCPUs; cpus != 3000 {
+		t.Errorf("CPUs after start: %d", cpus)
+	}
+	// Growing a VM adds to its usage until the update arrives.
+	grow := fm_proto.CheckVmAllocationRequest{
+		IpAddress:   net.ParseIP("10.1.0.2"),
+		OwnerGroups: []string{"team"},
+		OwnerUsers:  []string{"user"},
+		Resources:   fm_proto.Resources{MilliCPUs: 1000},
+	}
+	if err := m.checkVmAllocation(grow); err != nil {
+		t.Fatal(err)
+	}
+	usage = m.getOwnerUsage()
+	if cpus := usage[ownerType{group: "team"}].MilliCPUs; cpus != 4000 {
+		t.Errorf("CPUs while growing: %d", cpus)
+	}
+	// Deleting the VM releases the reservation.
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{"10.1.0.2": nil})
+	if len(m.vmReservations) != 0 {
+		t.Errorf("reservations after delete: %d", len(m.vmReservations))
+	}
+	// Failed creates which are never reported expire.
+	request.IpAddress = net.ParseIP("10.1.0.4")
+	if err := m.checkVmAllocation(request); err != nil {
+		t.Fatal(err)
+	}
+	m.vmReservations["10.1.0.4"].expires = time.Now().Add(-time.Second)
+	request.IpAddress = net.ParseIP("10.1.0.5")
+	if err := m.checkVmAllocation(request); err != nil {
+		t.Errorf("expired reservation counted: %s", err)
+	}
+	if _, ok := m.vmReservations["10.1.0.4"]; ok {
+		t.Error("expired reservation not removed")
+	}
+}
+
+func TestGetVmOwners(t *testing.T) {
+	tests := []struct {
+		groups   []string
+		users    []string
+		expected []ownerType
+	}{
+		{nil, nil, []ownerType{}},
+		{nil, []string{"alice", "bob"}, []ownerType{{user: "alice"}}},
+		{[]string{"a", "b"}, nil, []ownerType{{group: "a"}, {group: "b"}}},
+		{[]string{"a"}, []string{"alice"},
+			[]ownerType{{group: "a"}, {user: "alice"}}},
+	}
+	for _, test := range tests {
+		owners := getVmOwners(test.groups, test.users)
+		if !reflect.DeepEqual(owners, test.expected) {
+			t.Errorf("%v, %v: %v != %v",
+				test.groups, test.users, owners, test.expected)
+		}
+	}
+}
+
+func TestListOwnerUsage(t *testing.T) {
+	m := makeTestManager(t)
+	loadTestQuotas(t, m)
+	h := makeTestHypervisor(m, "hyper", "10.0.0.1")
+	vm1 := makeTestQuotaVm("10.1.0.1", hyper_proto.StateRunning, 1000)
+	vm1.SecondaryAddresses = []hyper_proto.Address{{}}
+	vm2 := makeTestQuotaVm("10.1.0.2", hyper_proto.StateStopped, 500)
+	vm2.OwnerGroups = nil
+	vm2.OwnerUsers = []string{"other"}
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{
+		"10.1.0.1": vm1,
+		"10.1.0.2": vm2,
+	})
+	owners, err := m.listOwnerUsage(fm_proto.GetOwnerUsageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmUsage := fm_proto.Resources{
+		IpAddresses: 2,
+		MemoryInMiB: 1024,
+		MilliCPUs:   1000,
+		NumVMs:      1,
+		VolumeBytes: 1 << 30,
+	}
+	expected := []fm_proto.OwnerUsage{
+		{
+			OwnerGroup: "team",
+			Quota:      &fm_proto.Resources{MilliCPUs: 4000, NumVMs: 3},
+			Usage:      vmUsage,
+		},
+		{
+			OwnerUser: "other",
+			Usage: fm_proto.Resources{
+				IpAddresses: 1,
+				MemoryInMiB: 1024,
+				MilliCPUs:   500,
+				NumVMs:      1,
+				VolumeBytes: 1 << 30,
+			},
+		},
+		{
+			OwnerUser: "user",
+			Quota:     &fm_proto.Resources{MemoryInMiB: 4096},
+			Usage:     vmUsage,
+		},
+	}
+	if !reflect.DeepEqual(owners, expected) {
+		t.Errorf("usage:\n%+v\nexpected:\n%+v", owners, expected)
+	}
+	owners, err = m.listOwnerUsage(
+		fm_proto.GetOwnerUsageRequest{OwnerUser: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || owners[0].OwnerUser != "other" {
+		t.Errorf("filtered usage: %+v", owners)
+	}
+	// Owners with a quota but no VMs are listed.
+	sendTestVms(m, h, map[string]*hyper_proto.VmInfo{"10.1.0.1": nil})
+	owners, err = m.listOwnerUsage(
+		fm_proto.GetOwnerUsageRequest{OwnerGroup: "team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || owners[0].Usage != (fm_proto.Resources{}) {
+		t.Errorf("unused quota: %+v", owners)
+	}
+}
@@ -0,0 +1,134 @@
+package hypervisors
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/redfish"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+var bootDeviceToRedfishTarget = map[fm_proto.BootDevice]string{
+	fm_proto.BootDeviceNone:      redfish.BootTargetNone,
+	fm_proto.BootDevicePxe:       redfish.BootTargetPxe,
+	fm_proto.BootDeviceDisk:      redfish.BootTargetHdd,
+	fm_proto.BootDeviceCdrom:     redfish.BootTargetCd,
+	fm_proto.BootDeviceBiosSetup: redfish.BootTargetBiosSetup,
+}
+
+// makeRedfishClient makes a Redfish client for the BMC. The IPMI username and
+// password file are used to authenticate.
+func (m *Manager) makeRedfishClient(
+	ipmiHostname string) (*redfish.Client, error) {
+	if m.ipmiPasswordFile == "" || m.ipmiUsername == "" {
+		return nil, errors.New("no IPMI credentials configured")
+	}
+	data, err := ioutil.ReadFile(m.ipmiPasswordFile)
+	if err != nil {
+		return nil, err
+	}
+	password := strings.SplitN(string(data), "\n", 2)[0]
+	return redfish.New(redfish.Params{
+		Address:  ipmiHostname,
+		Password: strings.TrimSpace(password),
+		Username: m.ipmiUsername,
+	}), nil
+}
+
+func (m *Manager) probeRedfish(ipmiHostname string,
+	previousProbeStatus probeStatus) probeStatus {
+	client, err := m.makeRedfishClient(ipmiHostname)
+	if err != nil {
+		return probeStatusUnreachable
+	}
+	if powerState, err := client.GetPowerState(); err != nil {
+		if previousProbeStatus == probeStatusOff {
+			return probeStatusOff
+		}
+		return probeStatusUnreachable
+	} else if powerState == redfish.PowerStateOff {
+		return probeStatusOff
+	}
+	return probeStatusUnreachable
+}
+
+func (m *Manager) setRedfishBootDevice(ipmiHostname string,
+	bootDevice fm_proto.BootDevice, persistent bool) error {
+	target, ok := bootDeviceToRedfishTarget[bootDevice]
+	if !ok {
+		return fmt.Errorf("unsupported boot device: %s", bootDevice)
+	}
+	client, err := m.makeRedfishClient(ipmiHostname)
+	if err != nil {
+		return err
+	}
+	return client.SetBootOverride(target, persistent)
+}
+
+func (m *Manager) showBmcHealthHandler(w http.ResponseWriter,
+	req *http.Request) {
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var hostname string
+	for name := range parsedQuery.Flags {
+		hostname = name
+	}
+	h, err := m.getLockedHypervisor(hostname, false)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	machine := h.machine
+	h.mutex.RUnlock()
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	if machine.ManagementType != fm_proto.ManagementTypeRedfish {
+		fmt.Fprintf(writer, "BMC for: %s does not support Redfish\n", hostname)
+		return
+	}
+	client, err := m.makeRedfishClient(getIpmiHostname(machine))
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	health, err := client.GetHealth()
+	if err != nil {
+		fmt.Fprintln(writer, err)
+		return
+	}
+	if parsedQuery.OutputType() == url.OutputTypeJson {
+		json.WriteWithIndent(writer, "    ", health)
+		return
+	}
+	fmt.Fprintf(writer, "<title>BMC health for %s</title>\n", hostname)
+	writer.WriteString(commonStyleSheet)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintf(writer, "Power state: %s<br>\n", health.PowerState)
+	fmt.Fprintf(writer, "Health: %s<br>\n", health.Health)
+	fmt.Fprintln(writer, `<table border="1">`)
+	fmt.Fprintln(writer, "  <tr>")
+	fmt.Fprintln(writer, "    <th>Sensor</th>")
+	fmt.Fprintln(writer, "    <th>Reading</th>")
+	fmt.Fprintln(writer, "    <th>Health</th>")
+	fmt.Fprintln(writer, "  </tr>")
+	for _, sensor := range health.Sensors {
+		fmt.Fprintln(writer, "  <tr>")
+		fmt.Fprintf(writer, "    <td>%s</td>\n", sensor.Name)
+		fmt.Fprintf(writer, "    <td>%g %s</td>\n", sensor.Reading, sensor.Units)
+		fmt.Fprintf(writer, "    <td>%s</td>\n", sensor.Health)
+		fmt.Fprintln(writer, "  </tr>")
+	}
+	fmt.Fprintln(writer, "</table>")
+	fmt.Fprintln(writer, "</body>")
+}
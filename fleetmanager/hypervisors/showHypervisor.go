@@ -12,6 +12,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/url"
 	"github.com/Cloud-Foundations/Dominator/lib/verstr"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 )
 
 func (m *Manager) showHypervisorHandler(w http.ResponseWriter,
@@ -52,6 +53,12 @@ func (m *Manager) showHypervisorHandler(w http.ResponseWriter,
 	fmt.Fprintln(writer, `<pre style="background-color: #eee; border: 1px solid #999; display: block; float: left;">`)
 	json.WriteWithIndent(writer, "    ", h.machine)
 	fmt.Fprintln(writer, `</pre><p style="clear: both;">`)
+	if h.hardwareInventory != nil {
+		fmt.Fprintln(writer, "Hardware inventory:<br>")
+		fmt.Fprintln(writer, `<pre style="background-color: #eee; border: 1px solid #999; display: block; float: left;">`)
+		json.WriteWithIndent(writer, "    ", h.hardwareInventory)
+		fmt.Fprintln(writer, `</pre><p style="clear: both;">`)
+	}
 	subnets, err := topology.GetSubnetsForMachine(hostname)
 	if err != nil {
 		fmt.Fprintf(writer, "%s<br>\n", err)
@@ -84,6 +91,13 @@ func (m *Manager) showHypervisorHandler(w http.ResponseWriter,
 	fmt.Fprintf(writer,
 		"Number of VMs known: %d (<a href=\"http://%s:%d/listVMs\">live view</a>)<br>\n",
 		len(h.vms), hostname, constants.HypervisorPortNumber)
+	fmt.Fprintf(writer,
+		"<a href=\"listEvents?hypervisor=%s&last=4w\">Event history</a><br>\n",
+		hostname)
+	if h.machine.ManagementType == fm_proto.ManagementTypeRedfish {
+		fmt.Fprintf(writer,
+			"<a href=\"showBmcHealth?%s\">BMC health</a><br>\n", hostname)
+	}
 	fmt.Fprintln(writer, "<br>")
 	m.showVMsForHypervisor(writer, h)
 	fmt.Fprintln(writer, "<br>")
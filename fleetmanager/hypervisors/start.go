@@ -18,21 +18,31 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 		file.Close()
 	}
 	manager := &Manager{
-		ipmiUsername:     startOptions.IpmiUsername,
-		ipmiPasswordFile: startOptions.IpmiPasswordFile,
-		logger:           startOptions.Logger,
-		storer:           startOptions.Storer,
-		allocatingIPs:    make(map[string]struct{}),
-		hypervisors:      make(map[string]*hypervisorType),
-		migratingIPs:     make(map[string]struct{}),
-		subnets:          make(map[string]*subnetType),
-		vms:              make(map[string]*vmInfoType),
+		ipmiUsername:       startOptions.IpmiUsername,
+		ipmiPasswordFile:   startOptions.IpmiPasswordFile,
+		logger:             startOptions.Logger,
+		storer:             startOptions.Storer,
+		eventsReady:        make(chan struct{}, 1),
+		allocatingIPs:      make(map[string]struct{}),
+		hypervisors:        make(map[string]*hypervisorType),
+		migratingIPs:       make(map[string]struct{}),
+		migratingVmSources: make(map[string]string),
+		subnets:            make(map[string]*subnetType),
+		vmReservations:     make(map[string]*vmReservationType),
+		vms:                make(map[string]*vmInfoType),
 	}
 	manager.initInvertTable()
+	html.HandleFunc("/listEvents", manager.listEventsHandler)
+	html.HandleFunc("/listHardwareInventory",
+		manager.listHardwareInventoryHandler)
 	html.HandleFunc("/listHypervisors", manager.listHypervisorsHandler)
 	html.HandleFunc("/listLocations", manager.listLocationsHandler)
+	html.HandleFunc("/listOwnerUsage", manager.listOwnerUsageHandler)
 	html.HandleFunc("/listVMs", manager.listVMsHandler)
+	html.HandleFunc("/showBmcHealth", manager.showBmcHealthHandler)
 	html.HandleFunc("/showHypervisor", manager.showHypervisorHandler)
 	go manager.notifierLoop()
+	go manager.compactEventsLoop()
+	go manager.eventWriterLoop()
 	return manager, nil
 }
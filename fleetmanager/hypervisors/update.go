@@ -173,6 +173,12 @@ func (m *Manager) changeMachineTags(hostname string,
 		}
 		location := h.location
 		h.mutex.Unlock()
+		m.recordEvent(fm_proto.Event{
+			Hypervisor: hostname,
+			Tags:       tgs,
+			Type:       fm_proto.EventTypeMachineTagsChanged,
+			Username:   authInfo.Username,
+		})
 		m.sendUpdate(location, update)
 		return nil
 	}
@@ -601,6 +607,9 @@ func (m *Manager) processHypervisorUpdate(h *hypervisorType,
 	if update.HaveSerialNumber && update.SerialNumber != "" {
 		h.serialNumber = update.SerialNumber
 	}
+	if update.HardwareInventory != nil {
+		h.hardwareInventory = update.HardwareInventory
+	}
 	h.mutex.Unlock()
 	if !firstUpdate && update.HealthStatus != oldHealthStatus {
 		h.logger.Printf("health status changed from: \"%s\" to: \"%s\"\n",
@@ -711,6 +720,23 @@ func (m *Manager) processSubnetsUpdates(h *hypervisorType,
 		len(request.Add), len(request.Change), len(request.Delete))
 }
 
+// getMigrationSource returns the hostname of the hypervisor that the VM is
+// migrating from, or "" if unknown. The lock must be held.
+func (m *Manager) getMigrationSource(h *hypervisorType, ipAddr string) string {
+	if source := m.migratingVmSources[ipAddr]; source != "" {
+		return source
+	}
+	for _, hypervisor := range m.hypervisors {
+		if hypervisor == h {
+			continue
+		}
+		if _, ok := hypervisor.migratingVms[ipAddr]; ok {
+			return hypervisor.machine.Hostname
+		}
+	}
+	return ""
+}
+
 func (m *Manager) processVmUpdates(h *hypervisorType,
 	updateVMs map[string]*hyper_proto.VmInfo) {
 	for ipAddr, vm := range updateVMs {
@@ -728,10 +754,13 @@ func (m *Manager) processVmUpdatesWithLock(h *hypervisorType,
 	update := fm_proto.Update{ChangedVMs: make(map[string]*hyper_proto.VmInfo)}
 	vmsToDelete := make(map[string]struct{})
 	for ipAddr, protoVm := range updateVMs {
+		m.releaseVmReservation(ipAddr, protoVm)
 		if protoVm == nil {
 			if _, ok := h.migratingVms[ipAddr]; !ok {
 				vmsToDelete[ipAddr] = struct{}{}
 			} else {
+				// Keep the migration source: the VM may appear on the
+				// destination (or back on the source) later.
 				delete(h.migratingVms, ipAddr)
 				delete(m.migratingIPs, ipAddr)
 				h.logger.Debugf(0, "forgot migrating VM: %s\n", ipAddr)
@@ -739,11 +768,20 @@ func (m *Manager) processVmUpdatesWithLock(h *hypervisorType,
 		} else {
 			if protoVm.State == hyper_proto.StateMigrating {
 				if _, ok := h.vms[ipAddr]; ok {
+					// The source marks the VM as migrating first.
 					vmsToDelete[ipAddr] = struct{}{}
+					m.migratingVmSources[ipAddr] = h.machine.Hostname
+				} else if vm, ok := m.vms[ipAddr]; ok && vm.hypervisor != h {
+					m.migratingVmSources[ipAddr] =
+						vm.hypervisor.machine.Hostname
 				}
 				h.migratingVms[ipAddr] = &vmInfoType{ipAddr, *protoVm, h}
 				m.migratingIPs[ipAddr] = struct{}{}
 			} else if vm, ok := h.vms[ipAddr]; ok {
+				if vm.State != protoVm.State {
+					m.recordEvent(makeVmEvent(fm_proto.EventTypeVmStateChanged,
+						h, ipAddr, protoVm))
+				}
 				if !vm.VmInfo.Equal(protoVm) {
 					err := m.storer.WriteVm(h.machine.HostIpAddress, ipAddr,
 						*protoVm)
@@ -757,10 +795,24 @@ func (m *Manager) processVmUpdatesWithLock(h *hypervisorType,
 				vm.VmInfo = *protoVm
 				update.ChangedVMs[ipAddr] = protoVm
 			} else {
+				event := makeVmEvent(fm_proto.EventTypeVmCreated, h, ipAddr,
+					protoVm)
 				if _, ok := h.migratingVms[ipAddr]; ok {
+					source := m.getMigrationSource(h, ipAddr)
 					delete(h.migratingVms, ipAddr)
 					delete(m.migratingIPs, ipAddr)
+					delete(m.migratingVmSources, ipAddr)
+					if source == h.machine.Hostname { // Migration abandoned.
+						event.Type = fm_proto.EventTypeVmStateChanged
+					} else {
+						event.Type = fm_proto.EventTypeVmMigrated
+						event.PreviousHypervisor = source
+					}
+				} else if vm, ok := m.vms[ipAddr]; ok && vm.hypervisor != h {
+					event.Type = fm_proto.EventTypeVmMigrated
+					event.PreviousHypervisor = vm.hypervisor.machine.Hostname
 				}
+				m.recordEvent(event)
 				vm := &vmInfoType{ipAddr, *protoVm, h}
 				h.vms[ipAddr] = vm
 				m.vms[ipAddr] = vm
@@ -776,6 +828,13 @@ func (m *Manager) processVmUpdatesWithLock(h *hypervisorType,
 		}
 	}
 	for ipAddr := range vmsToDelete {
+		// Do not record VMs leaving as part of a migration as destroyed.
+		if vm, ok := h.vms[ipAddr]; ok {
+			if _, ok := m.migratingIPs[ipAddr]; !ok {
+				m.recordEvent(makeVmEvent(fm_proto.EventTypeVmDestroyed, h,
+					ipAddr, &vm.VmInfo))
+			}
+		}
 		delete(h.vms, ipAddr)
 		delete(m.vms, ipAddr)
 		err := m.storer.DeleteVm(h.machine.HostIpAddress, ipAddr)
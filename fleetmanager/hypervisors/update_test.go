@@ -0,0 +1,124 @@
+package hypervisors
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/fleetmanager/hypervisors/fsstorer"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	hyper_proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const testVmIpAddr = "10.1.0.10"
+
+func makeTestHypervisor(m *Manager, hostname, ipAddr string) *hypervisorType {
+	h := &hypervisorType{
+		logger: m.logger,
+		machine: &fm_proto.Machine{
+			Hostname:      hostname,
+			HostIpAddress: net.ParseIP(ipAddr),
+		},
+		migratingVms: make(map[string]*vmInfoType),
+		vms:          make(map[string]*vmInfoType),
+	}
+	m.hypervisors[hostname] = h
+	return h
+}
+
+func makeTestManager(t *testing.T) *Manager {
+	logger := testlogger.New(t)
+	storer, err := fsstorer.New(t.TempDir(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Manager{
+		logger:             logger,
+		storer:             storer,
+		hypervisors:        make(map[string]*hypervisorType),
+		migratingIPs:       make(map[string]struct{}),
+		migratingVmSources: make(map[string]string),
+		vmReservations:     make(map[string]*vmReservationType),
+		vms:                make(map[string]*vmInfoType),
+	}
+}
+
+func makeTestVm(state hyper_proto.State) *hyper_proto.VmInfo {
+	return &hyper_proto.VmInfo{
+		OwnerUsers: []string{"user"},
+		State:      state,
+		Volumes:    []hyper_proto.Volume{{Size: 1 << 30}},
+	}
+}
+
+func readTestEvents(t *testing.T, m *Manager) []fm_proto.Event {
+	m.writePendingEvents()
+	events, err := m.storer.ReadEvents(time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func sendTestVm(m *Manager, h *hypervisorType, vm *hyper_proto.VmInfo) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.processVmUpdatesWithLock(h,
+		map[string]*hyper_proto.VmInfo{testVmIpAddr: vm})
+}
+
+func TestVmMigrationEvents(t *testing.T) {
+	m := makeTestManager(t)
+	source := makeTestHypervisor(m, "source", "10.0.0.1")
+	destination := makeTestHypervisor(m, "destination", "10.0.0.2")
+	sendTestVm(m, source, makeTestVm(hyper_proto.StateStopped))
+	// Events are written by the event writer, not while holding the lock.
+	events, err := m.storer.ReadEvents(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 0 {
+		t.Errorf("events written with lock held: %v", events)
+	}
+	// The source marks the VM as migrating, then the destination creates it,
+	// then the source destroys its copy and the destination commits.
+	sendTestVm(m, source, makeTestVm(hyper_proto.StateMigrating))
+	sendTestVm(m, destination, makeTestVm(hyper_proto.StateMigrating))
+	sendTestVm(m, source, nil)
+	sendTestVm(m, destination, makeTestVm(hyper_proto.StateStopped))
+	events = readTestEvents(t, m)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got: %v", events)
+	}
+	if events[0].Type != fm_proto.EventTypeVmCreated ||
+		events[0].Hypervisor != "source" || events[0].Username != "" {
+		t.Errorf("bad create event: %+v", events[0])
+	}
+	if events[1].Type != fm_proto.EventTypeVmMigrated ||
+		events[1].Hypervisor != "destination" ||
+		events[1].PreviousHypervisor != "source" {
+		t.Errorf("bad migrate event: %+v", events[1])
+	}
+	if len(m.migratingVmSources) > 0 {
+		t.Errorf("migration source not forgotten: %v", m.migratingVmSources)
+	}
+}
+
+func TestVmMigrationAbandoned(t *testing.T) {
+	m := makeTestManager(t)
+	source := makeTestHypervisor(m, "source", "10.0.0.1")
+	destination := makeTestHypervisor(m, "destination", "10.0.0.2")
+	sendTestVm(m, source, makeTestVm(hyper_proto.StateStopped))
+	sendTestVm(m, source, makeTestVm(hyper_proto.StateMigrating))
+	sendTestVm(m, destination, makeTestVm(hyper_proto.StateMigrating))
+	sendTestVm(m, destination, nil)
+	sendTestVm(m, source, makeTestVm(hyper_proto.StateStopped))
+	events := readTestEvents(t, m)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got: %v", events)
+	}
+	if events[1].Type != fm_proto.EventTypeVmStateChanged ||
+		events[1].Hypervisor != "source" {
+		t.Errorf("bad event: %+v", events[1])
+	}
+}
@@ -29,20 +29,29 @@ func Setup(hypervisorsManager *hypervisors.Manager, logger log.DebugLogger) (
 		PerUserMethodLimiter: serverutil.NewPerUserMethodLimiter(
 			map[string]uint{
 				"GetMachineInfo": 1,
+				"GetTopology":    1,
 				"GetUpdates":     1,
+				"ListEvents":     1,
 			}),
 	}
 	srpc.RegisterNameWithOptions("FleetManager", srpcObj,
 		srpc.ReceiverOptions{
 			PublicMethods: []string{
 				"ChangeMachineTags",
+				"CheckVmAllocation",
 				"GetHypervisorForVM",
 				"GetMachineInfo",
+				"GetOwnerUsage",
+				"GetTopology",
 				"GetUpdates",
+				"ListEvents",
 				"ListHypervisorLocations",
 				"ListHypervisorsInLocation",
 				"ListVMsInLocation",
+				"PowerCycleMachine",
+				"PowerOffMachine",
 				"PowerOnMachine",
+				"SetMachineBootDevice",
 			}})
 	return (*htmlWriter)(srpcObj), nil
 }
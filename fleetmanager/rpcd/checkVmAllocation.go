@@ -0,0 +1,15 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) CheckVmAllocation(conn *srpc.Conn,
+	request fleetmanager.CheckVmAllocationRequest,
+	reply *fleetmanager.CheckVmAllocationResponse) error {
+	*reply = fleetmanager.CheckVmAllocationResponse{
+		errors.ErrorToString(t.hypervisorsManager.CheckVmAllocation(request))}
+	return nil
+}
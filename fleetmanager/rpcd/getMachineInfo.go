@@ -41,9 +41,14 @@ func (t *srpcType) getMachineInfo(hostname string) (
 	for _, tSubnet := range tSubnets {
 		subnets = append(subnets, &tSubnet.Subnet)
 	}
+	inventory, err := t.hypervisorsManager.GetHardwareInventory(hostname)
+	if err != nil {
+		return fm_proto.GetMachineInfoResponse{}, err
+	}
 	return fm_proto.GetMachineInfoResponse{
-		Location: location,
-		Machine:  machine,
-		Subnets:  subnets,
+		HardwareInventory: inventory,
+		Location:          location,
+		Machine:           machine,
+		Subnets:           subnets,
 	}, nil
 }
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) GetOwnerUsage(conn *srpc.Conn,
+	request fleetmanager.GetOwnerUsageRequest,
+	reply *fleetmanager.GetOwnerUsageResponse) error {
+	owners, err := t.hypervisorsManager.GetOwnerUsage(request)
+	*reply = fleetmanager.GetOwnerUsageResponse{
+		Error:  errors.ErrorToString(err),
+		Owners: owners,
+	}
+	return nil
+}
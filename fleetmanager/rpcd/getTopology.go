@@ -0,0 +1,23 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) GetTopology(conn *srpc.Conn) error {
+	var request proto.GetTopologyRequest
+	if err := conn.Decode(&request); err != nil {
+		return err
+	}
+	topology, err := t.hypervisorsManager.GetTopology()
+	response := proto.GetTopologyResponse{Error: errors.ErrorToString(err)}
+	if err := conn.Encode(response); err != nil {
+		return err
+	}
+	if err != nil {
+		return nil
+	}
+	return conn.Encode(topology)
+}
@@ -0,0 +1,18 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) ListEvents(conn *srpc.Conn,
+	request fleetmanager.ListEventsRequest,
+	reply *fleetmanager.ListEventsResponse) error {
+	events, err := t.hypervisorsManager.ListEvents(request)
+	*reply = fleetmanager.ListEventsResponse{
+		Error:  errors.ErrorToString(err),
+		Events: events,
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) PowerCycleMachine(conn *srpc.Conn,
+	request fleetmanager.PowerCycleMachineRequest,
+	reply *fleetmanager.PowerCycleMachineResponse) error {
+	*reply = fleetmanager.PowerCycleMachineResponse{
+		errors.ErrorToString(t.hypervisorsManager.PowerCycleMachine(
+			request.Hostname, conn.GetAuthInformation()))}
+	return nil
+}
@@ -0,0 +1,17 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+)
+
+func (t *srpcType) SetMachineBootDevice(conn *srpc.Conn,
+	request fleetmanager.SetMachineBootDeviceRequest,
+	reply *fleetmanager.SetMachineBootDeviceResponse) error {
+	*reply = fleetmanager.SetMachineBootDeviceResponse{
+		errors.ErrorToString(t.hypervisorsManager.SetMachineBootDevice(
+			request.Hostname, conn.GetAuthInformation(), request.BootDevice,
+			request.Persistent))}
+	return nil
+}
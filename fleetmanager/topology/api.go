@@ -55,8 +55,11 @@ func (subnet *Subnet) Shrink() {
 
 type Topology struct {
 	Root            *Directory
-	machineParents  map[string]*Directory // Key: machine name.
-	reservedIpAddrs map[string]struct{}   // Key: IP address.
+	Quotas          []*fm_proto.Quota          `json:",omitempty"`
+	groupQuotas     map[string]*fm_proto.Quota // Key: owner group.
+	machineParents  map[string]*Directory      // Key: machine name.
+	reservedIpAddrs map[string]struct{}        // Key: IP address.
+	userQuotas      map[string]*fm_proto.Quota // Key: owner user.
 }
 
 func Load(topologyDir string) (*Topology, error) {
@@ -70,6 +73,12 @@ func Watch(topologyRepository, localRepositoryDir, topologyDir string,
 		checkInterval, logger)
 }
 
+// Check performs consistency checks which are not performed by Load, such as
+// checking for overlapping subnets. A list of problems found is returned.
+func (t *Topology) Check() []error {
+	return t.check()
+}
+
 func (t *Topology) CheckIfIpIsReserved(ipAddr string) bool {
 	_, ok := t.reservedIpAddrs[ipAddr]
 	return ok
@@ -80,10 +89,26 @@ func (t *Topology) CheckIfMachineHasSubnet(name, subnetId string) (
 	return t.checkIfMachineHasSubnet(name, subnetId)
 }
 
+// CheckVmAddresses checks if the specified VM IP addresses conflict with
+// reserved IP addresses or machine IP addresses in the topology.
+func (t *Topology) CheckVmAddresses(ipAddrs []net.IP) []error {
+	return t.checkVmAddresses(ipAddrs)
+}
+
+// Diff returns a list of human-readable differences between the topology and
+// newTopology. The topologies need not have been loaded with Load.
+func (t *Topology) Diff(newTopology *Topology) []string {
+	return t.diff(newTopology)
+}
+
 func (t *Topology) FindDirectory(dirname string) (*Directory, error) {
 	return t.findDirectory(dirname)
 }
 
+func (t *Topology) GetGroupQuota(ownerGroup string) *fm_proto.Quota {
+	return t.groupQuotas[ownerGroup]
+}
+
 func (t *Topology) GetLocationOfMachine(name string) (string, error) {
 	return t.getLocationOfMachine(name)
 }
@@ -96,6 +121,10 @@ func (t *Topology) GetSubnetsForMachine(name string) ([]*Subnet, error) {
 	return t.getSubnetsForMachine(name)
 }
 
+func (t *Topology) GetUserQuota(ownerUser string) *fm_proto.Quota {
+	return t.userQuotas[ownerUser]
+}
+
 func (t *Topology) ListMachines(dirname string) ([]*fm_proto.Machine, error) {
 	return t.listMachines(dirname)
 }
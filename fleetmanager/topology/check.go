@@ -1,7 +1,9 @@
 package topology
 
 import (
+	"bytes"
 	"fmt"
+	"net"
 )
 
 func (t *Topology) checkIfMachineHasSubnet(name, subnetId string) (
@@ -20,3 +22,143 @@ func (t *Topology) checkIfMachineHasSubnet(name, subnetId string) (
 		return false, nil
 	}
 }
+
+type subnetInfo struct {
+	network *net.IPNet
+	path    string
+	subnet  *Subnet
+}
+
+func compareIPs(left, right net.IP) int {
+	if ip := left.To4(); ip != nil {
+		left = ip
+	}
+	if ip := right.To4(); ip != nil {
+		right = ip
+	}
+	return bytes.Compare(left, right)
+}
+
+func makeSubnetInfo(path string, subnet *Subnet) subnetInfo {
+	mask := net.IPMask(subnet.IpMask)
+	if ip := subnet.IpGateway.To4(); ip != nil && len(mask) == 16 {
+		mask = mask[12:]
+	}
+	return subnetInfo{
+		network: &net.IPNet{IP: subnet.IpGateway.Mask(mask), Mask: mask},
+		path:    path,
+		subnet:  subnet,
+	}
+}
+
+func (t *Topology) check() []error {
+	var errs []error
+	var subnets []subnetInfo
+	t.Walk(func(directory *Directory) error {
+		for _, subnet := range directory.Subnets {
+			subnets = append(subnets, makeSubnetInfo(directory.path, subnet))
+		}
+		return nil
+	})
+	for index, left := range subnets {
+		errs = append(errs, left.check()...)
+		for _, right := range subnets[index+1:] {
+			if left.network.Contains(right.network.IP) ||
+				right.network.Contains(left.network.IP) {
+				errs = append(errs, fmt.Errorf(
+					"subnet: %s (%s) overlaps subnet: %s (%s)",
+					left.subnet.Id, left.network, right.subnet.Id,
+					right.network))
+			}
+		}
+	}
+	t.Walk(func(directory *Directory) error {
+		for _, machine := range directory.Machines {
+			ipAddr := machine.HostIpAddress
+			if len(ipAddr) < 1 {
+				continue
+			}
+			for _, subnet := range subnets {
+				if subnet.checkIfIpIsAuto(ipAddr) {
+					errs = append(errs, fmt.Errorf(
+						"machine: %s IP address: %s may be allocated to VMs in subnet: %s",
+						machine.Hostname, ipAddr, subnet.subnet.Id))
+				}
+			}
+		}
+		return nil
+	})
+	return errs
+}
+
+func (t *Topology) checkVmAddresses(ipAddrs []net.IP) []error {
+	machineAddrs := make(map[string]string)
+	t.Walk(func(directory *Directory) error {
+		for _, machine := range directory.Machines {
+			if len(machine.HostIpAddress) > 0 {
+				machineAddrs[machine.HostIpAddress.String()] = machine.Hostname
+			}
+		}
+		return nil
+	})
+	var errs []error
+	for _, ipAddr := range ipAddrs {
+		ipString := ipAddr.String()
+		if t.CheckIfIpIsReserved(ipString) {
+			errs = append(errs,
+				fmt.Errorf("VM IP address: %s is reserved", ipString))
+		}
+		if hostname, ok := machineAddrs[ipString]; ok {
+			errs = append(errs,
+				fmt.Errorf("VM IP address: %s is used by machine: %s",
+					ipString, hostname))
+		}
+	}
+	return errs
+}
+
+func (s subnetInfo) check() []error {
+	var errs []error
+	for _, ipAddr := range []net.IP{s.subnet.FirstAutoIP, s.subnet.LastAutoIP} {
+		if len(ipAddr) > 0 && !s.network.Contains(ipAddr) {
+			errs = append(errs, fmt.Errorf(
+				"subnet: %s automatic IP address: %s is outside %s",
+				s.subnet.Id, ipAddr, s.network))
+		}
+	}
+	if len(s.subnet.FirstAutoIP) > 0 && len(s.subnet.LastAutoIP) > 0 &&
+		compareIPs(s.subnet.FirstAutoIP, s.subnet.LastAutoIP) > 0 {
+		errs = append(errs, fmt.Errorf(
+			"subnet: %s FirstAutoIP: %s is after LastAutoIP: %s",
+			s.subnet.Id, s.subnet.FirstAutoIP, s.subnet.LastAutoIP))
+	}
+	for _, ipAddr := range s.subnet.ReservedIPs {
+		if !s.network.Contains(ipAddr) {
+			errs = append(errs, fmt.Errorf(
+				"subnet: %s reserved IP address: %s is outside %s",
+				s.subnet.Id, ipAddr, s.network))
+		}
+	}
+	return errs
+}
+
+// checkIfIpIsAuto returns true if the IP address may be automatically
+// allocated to a VM.
+func (s subnetInfo) checkIfIpIsAuto(ipAddr net.IP) bool {
+	if !s.subnet.Manage || !s.network.Contains(ipAddr) {
+		return false
+	}
+	if ipAddr.Equal(s.subnet.IpGateway) ||
+		s.subnet.CheckIfIpIsReserved(ipAddr.String()) {
+		return false
+	}
+	if len(s.subnet.FirstAutoIP) > 0 &&
+		compareIPs(ipAddr, s.subnet.FirstAutoIP) < 0 {
+		return false
+	}
+	if len(s.subnet.LastAutoIP) > 0 &&
+		compareIPs(ipAddr, s.subnet.LastAutoIP) > 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package topology
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testSubnetA = `{"Id": "a", "IpGateway": "10.0.0.1",
+		"IpMask": "255.255.255.0", "Manage": true,
+		"FirstAutoIP": "10.0.0.100", "LastAutoIP": "10.0.0.200",
+		"ReservedIPs": ["10.0.0.150"]}`
+	testSubnetB = `{"Id": "b", "IpGateway": "10.0.1.1",
+		"IpMask": "255.255.255.0"}`
+)
+
+func loadTestTopology(t *testing.T, files map[string]string) *Topology {
+	topDir := t.TempDir()
+	for name, data := range files {
+		filename := filepath.Join(topDir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	topology, err := Load(topDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return topology
+}
+
+func testErrors(t *testing.T, name string, errs []error, expected []string) {
+	if len(errs) != len(expected) {
+		t.Errorf("%s: expected %d errors, got: %v",
+			name, len(expected), errs)
+		return
+	}
+	for index, err := range errs {
+		if !strings.Contains(err.Error(), expected[index]) {
+			t.Errorf("%s: error: \"%s\" does not contain: \"%s\"",
+				name, err, expected[index])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{
+			name: "consistent",
+			files: map[string]string{
+				"subnets.json":    "[" + testSubnetA + "]",
+				"a/subnets.json":  "[" + testSubnetB + "]",
+				"a/machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.0.150"}, {"Hostname": "m1", "HostIpAddress": "10.0.0.10"}]`,
+			},
+		},
+		{
+			name: "overlapping subnets",
+			files: map[string]string{
+				"subnets.json": "[" + testSubnetB + "]",
+				"a/subnets.json": `[{"Id": "big", "IpGateway": "10.0.0.1",
+					"IpMask": "255.255.0.0"}]`,
+			},
+			expected: []string{"subnet: b (10.0.1.0/24) overlaps subnet: big"},
+		},
+		{
+			name: "automatic IPs outside subnet",
+			files: map[string]string{
+				"subnets.json": `[{"Id": "a", "IpGateway": "10.0.0.1",
+					"IpMask": "255.255.255.0",
+					"FirstAutoIP": "10.0.1.10", "LastAutoIP": "10.0.1.20"}]`,
+			},
+			expected: []string{
+				"automatic IP address: 10.0.1.10 is outside",
+				"automatic IP address: 10.0.1.20 is outside",
+			},
+		},
+		{
+			name: "automatic IPs reversed",
+			files: map[string]string{
+				"subnets.json": `[{"Id": "a", "IpGateway": "10.0.0.1",
+					"IpMask": "255.255.255.0",
+					"FirstAutoIP": "10.0.0.20", "LastAutoIP": "10.0.0.10"}]`,
+			},
+			expected: []string{"FirstAutoIP: 10.0.0.20 is after LastAutoIP"},
+		},
+		{
+			name: "reserved IP outside subnet",
+			files: map[string]string{
+				"subnets.json": `[{"Id": "a", "IpGateway": "10.0.0.1",
+					"IpMask": "255.255.255.0", "ReservedIPs": ["10.0.2.1"]}]`,
+			},
+			expected: []string{"reserved IP address: 10.0.2.1 is outside"},
+		},
+		{
+			name: "machine in automatic range",
+			files: map[string]string{
+				"subnets.json":  "[" + testSubnetA + "]",
+				"machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.0.120"}]`,
+			},
+			expected: []string{"machine: m0 IP address: 10.0.0.120 may be"},
+		},
+		{
+			name: "machine in unmanaged subnet",
+			files: map[string]string{
+				"subnets.json":  "[" + testSubnetB + "]",
+				"machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.1.120"}]`,
+			},
+		},
+	}
+	for _, test := range tests {
+		topology := loadTestTopology(t, test.files)
+		testErrors(t, test.name, topology.Check(), test.expected)
+	}
+}
+
+func TestCheckVmAddresses(t *testing.T) {
+	topology := loadTestTopology(t, map[string]string{
+		"subnets.json":  "[" + testSubnetA + "]",
+		"machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.0.10"}]`,
+	})
+	tests := []struct {
+		name     string
+		ipAddr   string
+		expected []string
+	}{
+		{"free", "10.0.0.120", nil},
+		{"reserved", "10.0.0.150", []string{"10.0.0.150 is reserved"}},
+		{"machine", "10.0.0.10", []string{"is used by machine: m0"}},
+	}
+	for _, test := range tests {
+		errs := topology.CheckVmAddresses(
+			[]net.IP{net.ParseIP(test.ipAddr).To4()})
+		testErrors(t, test.name, errs, test.expected)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	left := loadTestTopology(t, map[string]string{
+		"subnets.json":    "[" + testSubnetA + "]",
+		"a/tags.json":     `{"Rack": "1"}`,
+		"a/machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.0.10"}, {"Hostname": "m1", "HostIpAddress": "10.0.0.11"}]`,
+		"b/machines.json": `[{"Hostname": "m2", "HostIpAddress": "10.0.0.12"}]`,
+		"quotas.json":     `[{"OwnerGroup": "team"}]`,
+	})
+	right := loadTestTopology(t, map[string]string{
+		"subnets.json": `[{"Id": "a", "IpGateway": "10.0.0.1",
+			"IpMask": "255.255.255.0", "Manage": true}]`,
+		"a/tags.json":     `{"Rack": "2"}`,
+		"a/machines.json": `[{"Hostname": "m0", "HostIpAddress": "10.0.0.10", "OwnerUsers": ["user"]}]`,
+		"c/machines.json": `[{"Hostname": "m2", "HostIpAddress": "10.0.0.12"}, {"Hostname": "m3", "HostIpAddress": "10.0.0.13"}]`,
+		"quotas.json":     `[{"OwnerUser": "user"}]`,
+	})
+	expected := []string{
+		"changed tags for directory: a",
+		"removed directory: b",
+		"added directory: c",
+		"changed machine: m0: OwnerUsers, Tags", // Tags inherited.
+		"removed machine: m1 from: a",
+		"moved machine: m2 from: b to: c",
+		"added machine: m3 in: c",
+		"changed subnet: a in: : FirstAutoIP, LastAutoIP, ReservedIPs",
+		"removed quota for OwnerGroup: team",
+		"added quota for OwnerUser: user",
+	}
+	diffs := left.Diff(right)
+	if strings.Join(diffs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s",
+			strings.Join(expected, "\n"), strings.Join(diffs, "\n"))
+	}
+	if diffs := left.Diff(left); len(diffs) > 0 {
+		t.Errorf("differences with self: %v", diffs)
+	}
+}
@@ -11,6 +11,14 @@ func (left *Topology) equal(right *Topology) bool {
 	if len(left.machineParents) != len(right.machineParents) {
 		return false
 	}
+	if len(left.Quotas) != len(right.Quotas) {
+		return false
+	}
+	for index, leftQuota := range left.Quotas {
+		if *leftQuota != *right.Quotas[index] {
+			return false
+		}
+	}
 	return left.Root.equal(right.Root)
 }
 
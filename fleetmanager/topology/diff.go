@@ -0,0 +1,249 @@
+package topology
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	"github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+type flatTopology struct {
+	directories map[string]*Directory      // Key: path.
+	machines    map[string]flatMachine     // Key: hostname.
+	quotas      map[string]*fm_proto.Quota // Key: owner.
+	subnets     map[string]flatSubnet      // Key: path:subnet ID.
+}
+
+type flatMachine struct {
+	machine *fm_proto.Machine
+	path    string
+}
+
+type flatSubnet struct {
+	path   string
+	subnet *Subnet
+}
+
+func diffMachines(left, right *fm_proto.Machine) []string {
+	var fields []string
+	if left.GatewaySubnetId != right.GatewaySubnetId {
+		fields = append(fields, "GatewaySubnetId")
+	}
+	if !left.NetworkEntry.Equal(&right.NetworkEntry) {
+		fields = append(fields, "NetworkEntry")
+	}
+	if !left.IPMI.Equal(&right.IPMI) {
+		fields = append(fields, "IPMI")
+	}
+	if left.ManagementType != right.ManagementType {
+		fields = append(fields, "ManagementType")
+	}
+	if !stringListsEqual(left.OwnerGroups, right.OwnerGroups) {
+		fields = append(fields, "OwnerGroups")
+	}
+	if !stringListsEqual(left.OwnerUsers, right.OwnerUsers) {
+		fields = append(fields, "OwnerUsers")
+	}
+	changedSecondaries := len(left.SecondaryNetworkEntries) !=
+		len(right.SecondaryNetworkEntries)
+	if !changedSecondaries {
+		for index, entry := range left.SecondaryNetworkEntries {
+			if !entry.Equal(&right.SecondaryNetworkEntries[index]) {
+				changedSecondaries = true
+				break
+			}
+		}
+	}
+	if changedSecondaries {
+		fields = append(fields, "SecondaryNetworkEntries")
+	}
+	if !left.Tags.Equal(right.Tags) {
+		fields = append(fields, "Tags")
+	}
+	return fields
+}
+
+func diffSubnets(left, right *Subnet) []string {
+	var fields []string
+	if !left.Subnet.Equal(&right.Subnet) {
+		fields = append(fields, "Subnet")
+	}
+	if !ipsEqual(left.FirstAutoIP, right.FirstAutoIP) {
+		fields = append(fields, "FirstAutoIP")
+	}
+	if !ipsEqual(left.LastAutoIP, right.LastAutoIP) {
+		fields = append(fields, "LastAutoIP")
+	}
+	if !hypervisor.IpListsEqual(left.ReservedIPs, right.ReservedIPs) {
+		fields = append(fields, "ReservedIPs")
+	}
+	return fields
+}
+
+func flatten(t *Topology) *flatTopology {
+	flat := &flatTopology{
+		directories: make(map[string]*Directory),
+		machines:    make(map[string]flatMachine),
+		quotas:      make(map[string]*fm_proto.Quota),
+		subnets:     make(map[string]flatSubnet),
+	}
+	if t == nil {
+		return flat
+	}
+	if t.Root != nil {
+		flat.addDirectory(t.Root, "")
+	}
+	for _, quota := range t.Quotas {
+		if quota.OwnerGroup != "" {
+			flat.quotas["OwnerGroup: "+quota.OwnerGroup] = quota
+		} else {
+			flat.quotas["OwnerUser: "+quota.OwnerUser] = quota
+		}
+	}
+	return flat
+}
+
+func ipsEqual(left, right net.IP) bool {
+	if len(left) < 1 || len(right) < 1 {
+		return len(left) == len(right)
+	}
+	return left.Equal(right)
+}
+
+func sortedKeys(keys map[string]struct{}) []string {
+	list := make([]string, 0, len(keys))
+	for key := range keys {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func stringListsEqual(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for index, leftString := range left {
+		if leftString != right[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// The path is computed rather than using directory.path so that topologies
+// which were decoded (rather than loaded) may be compared.
+func (flat *flatTopology) addDirectory(directory *Directory, path string) {
+	flat.directories[path] = directory
+	for _, machine := range directory.Machines {
+		flat.machines[machine.Hostname] = flatMachine{machine, path}
+	}
+	for _, subnet := range directory.Subnets {
+		flat.subnets[path+":"+subnet.Id] = flatSubnet{path, subnet}
+	}
+	for _, subdir := range directory.Directories {
+		flat.addDirectory(subdir, filepath.Join(path, subdir.Name))
+	}
+}
+
+func (left *Topology) diff(right *Topology) []string {
+	leftFlat := flatten(left)
+	rightFlat := flatten(right)
+	var diffs []string
+	allKeys := make(map[string]struct{})
+	for path := range leftFlat.directories {
+		allKeys[path] = struct{}{}
+	}
+	for path := range rightFlat.directories {
+		allKeys[path] = struct{}{}
+	}
+	for _, path := range sortedKeys(allKeys) {
+		leftDir, inLeft := leftFlat.directories[path]
+		rightDir, inRight := rightFlat.directories[path]
+		if !inLeft {
+			diffs = append(diffs, "added directory: "+path)
+		} else if !inRight {
+			diffs = append(diffs, "removed directory: "+path)
+		} else if !leftDir.Tags.Equal(rightDir.Tags) {
+			diffs = append(diffs, "changed tags for directory: "+path)
+		}
+	}
+	allKeys = make(map[string]struct{})
+	for hostname := range leftFlat.machines {
+		allKeys[hostname] = struct{}{}
+	}
+	for hostname := range rightFlat.machines {
+		allKeys[hostname] = struct{}{}
+	}
+	for _, hostname := range sortedKeys(allKeys) {
+		leftMachine, inLeft := leftFlat.machines[hostname]
+		rightMachine, inRight := rightFlat.machines[hostname]
+		if !inLeft {
+			diffs = append(diffs, fmt.Sprintf("added machine: %s in: %s",
+				hostname, rightMachine.path))
+			continue
+		}
+		if !inRight {
+			diffs = append(diffs, fmt.Sprintf("removed machine: %s from: %s",
+				hostname, leftMachine.path))
+			continue
+		}
+		if leftMachine.path != rightMachine.path {
+			diffs = append(diffs, fmt.Sprintf("moved machine: %s from: %s to: %s",
+				hostname, leftMachine.path, rightMachine.path))
+		}
+		fields := diffMachines(leftMachine.machine, rightMachine.machine)
+		if len(fields) > 0 {
+			diffs = append(diffs, fmt.Sprintf("changed machine: %s: %s",
+				hostname, strings.Join(fields, ", ")))
+		}
+	}
+	allKeys = make(map[string]struct{})
+	for key := range leftFlat.subnets {
+		allKeys[key] = struct{}{}
+	}
+	for key := range rightFlat.subnets {
+		allKeys[key] = struct{}{}
+	}
+	for _, key := range sortedKeys(allKeys) {
+		leftSubnet, inLeft := leftFlat.subnets[key]
+		rightSubnet, inRight := rightFlat.subnets[key]
+		if !inLeft {
+			diffs = append(diffs, fmt.Sprintf("added subnet: %s in: %s",
+				rightSubnet.subnet.Id, rightSubnet.path))
+		} else if !inRight {
+			diffs = append(diffs, fmt.Sprintf("removed subnet: %s from: %s",
+				leftSubnet.subnet.Id, leftSubnet.path))
+		} else {
+			fields := diffSubnets(leftSubnet.subnet, rightSubnet.subnet)
+			if len(fields) > 0 {
+				diffs = append(diffs, fmt.Sprintf("changed subnet: %s in: %s: %s",
+					leftSubnet.subnet.Id, leftSubnet.path,
+					strings.Join(fields, ", ")))
+			}
+		}
+	}
+	allKeys = make(map[string]struct{})
+	for owner := range leftFlat.quotas {
+		allKeys[owner] = struct{}{}
+	}
+	for owner := range rightFlat.quotas {
+		allKeys[owner] = struct{}{}
+	}
+	for _, owner := range sortedKeys(allKeys) {
+		leftQuota, inLeft := leftFlat.quotas[owner]
+		rightQuota, inRight := rightFlat.quotas[owner]
+		if !inLeft {
+			diffs = append(diffs, "added quota for "+owner)
+		} else if !inRight {
+			diffs = append(diffs, "removed quota for "+owner)
+		} else if leftQuota.Limits != rightQuota.Limits {
+			diffs = append(diffs, "changed quota for "+owner)
+		}
+	}
+	return diffs
+}
@@ -1,6 +1,7 @@
 package topology
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -44,8 +45,10 @@ func cloneSet(set map[string]struct{}) map[string]struct{} {
 
 func load(topologyDir string) (*Topology, error) {
 	topology := &Topology{
+		groupQuotas:     make(map[string]*proto.Quota),
 		machineParents:  make(map[string]*Directory),
 		reservedIpAddrs: make(map[string]struct{}),
+		userQuotas:      make(map[string]*proto.Quota),
 	}
 	directory, err := topology.readDirectory(topologyDir, "",
 		newInheritingState(),
@@ -59,6 +62,10 @@ func load(topologyDir string) (*Topology, error) {
 		return nil, err
 	}
 	topology.Root = directory
+	err = topology.loadQuotas(filepath.Join(topologyDir, "quotas.json"))
+	if err != nil {
+		return nil, err
+	}
 	return topology, nil
 }
 
@@ -239,6 +246,38 @@ func (state *inheritingState) copy() *inheritingState {
 	}
 }
 
+func (t *Topology) loadQuotas(filename string) error {
+	if err := json.ReadFromFile(filename, &t.Quotas); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading: %s: %s", filename, err)
+	}
+	for _, quota := range t.Quotas {
+		if quota.OwnerGroup != "" && quota.OwnerUser != "" {
+			return fmt.Errorf(
+				"cannot specify OwnerGroup(%s) and OwnerUser(%s) together",
+				quota.OwnerGroup, quota.OwnerUser)
+		}
+		if quota.OwnerGroup != "" {
+			if _, ok := t.groupQuotas[quota.OwnerGroup]; ok {
+				return fmt.Errorf("duplicate quota for OwnerGroup: %s",
+					quota.OwnerGroup)
+			}
+			t.groupQuotas[quota.OwnerGroup] = quota
+		} else if quota.OwnerUser != "" {
+			if _, ok := t.userQuotas[quota.OwnerUser]; ok {
+				return fmt.Errorf("duplicate quota for OwnerUser: %s",
+					quota.OwnerUser)
+			}
+			t.userQuotas[quota.OwnerUser] = quota
+		} else {
+			return errors.New("quota has no OwnerGroup or OwnerUser")
+		}
+	}
+	return nil
+}
+
 func (t *Topology) loadSubnets(directory *Directory, dirpath string,
 	subnetIds map[string]struct{}) error {
 	if err := directory.loadSubnets(dirpath, subnetIds); err != nil {
@@ -32,6 +32,7 @@ type DhcpServer interface {
 
 type Manager struct {
 	StartOptions
+	hardwareInventory *proto.HardwareInventory
 	rootCookie        []byte
 	memTotalInMiB     uint64
 	numCPU            int
@@ -50,16 +51,18 @@ type Manager struct {
 }
 
 type StartOptions struct {
-	BridgeMap          map[string]net.Interface // Key: interface name.
-	DhcpServer         DhcpServer
-	ImageServerAddress string
-	Logger             log.DebugLogger
-	ObjectCacheBytes   uint64
-	ShowVgaConsole     bool
-	StateDir           string
-	Username           string
-	VlanIdToBridge     map[uint]string // Key: VLAN ID, value: bridge interface.
-	VolumeDirectories  []string
+	BridgeMap           map[string]net.Interface // Key: interface name.
+	DhcpServer          DhcpServer
+	FleetManagerAddress string // If set, check quotas with the Fleet Manager.
+	ImageServerAddress  string
+	Logger              log.DebugLogger
+	ObjectCacheBytes    uint64
+	QuotaFailOpen       bool // If true, allow when Fleet Manager unreachable.
+	ShowVgaConsole      bool
+	StateDir            string
+	Username            string
+	VlanIdToBridge      map[uint]string // Key: VLAN ID, value: bridge interface.
+	VolumeDirectories   []string
 }
 
 type vmInfoType struct {
@@ -61,6 +61,12 @@ func (m *Manager) writeHtml(writer io.Writer) {
 	if m.serialNumber != "" {
 		fmt.Fprintf(writer, "Serial number: \"%s\"<br>\n", m.serialNumber)
 	}
+	if inventory := m.hardwareInventory; inventory.CpuModel != "" {
+		fmt.Fprintf(writer,
+			"CPU: %s (%d sockets, %d cores, %d threads)<br>\n",
+			inventory.CpuModel, inventory.NumSockets, inventory.NumCores,
+			inventory.NumCPUs)
+	}
 	fmt.Fprintf(writer,
 		"Number of subnets: <a href=\"listSubnets\">%d</a><br>\n", numSubnets)
 	fmt.Fprintf(writer, "Volume directories: %s<br>\n",
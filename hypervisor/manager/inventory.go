@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const (
+	cpuInfoFile   = "proc/cpuinfo"
+	dmiDirectory  = "sys/class/dmi/id"
+	sysBlockDir   = "sys/block"
+	sysNetworkDir = "sys/class/net"
+
+	ethtoolGetDriverInfo = 0x00000003 // ETHTOOL_GDRVINFO.
+	siocEthtool          = 0x8946     // SIOCETHTOOL.
+)
+
+type ethtoolDriverInfo struct {
+	cmd             uint32
+	driver          [32]byte
+	version         [32]byte
+	firmwareVersion [32]byte
+	busInfo         [32]byte
+	eromVersion     [32]byte
+	reserved2       [12]byte
+	nPrivFlags      uint32
+	nStats          uint32
+	testInfoLength  uint32
+	eedumpLength    uint32
+	regdumpLength   uint32
+}
+
+type interfaceRequest struct {
+	name [16]byte
+	data unsafe.Pointer
+	_    [16]byte // Pad to sizeof(struct ifreq).
+}
+
+func cString(buffer []byte) string {
+	if index := bytes.IndexByte(buffer, 0); index >= 0 {
+		buffer = buffer[:index]
+	}
+	return string(buffer)
+}
+
+func readCpuInfo(rootDir string, inventory *proto.HardwareInventory) {
+	file, err := os.Open(filepath.Join(rootDir, cpuInfoFile))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	cores := make(map[string]struct{})
+	sockets := make(map[string]struct{})
+	var physicalId string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		splitLine := strings.SplitN(scanner.Text(), ":", 2)
+		if len(splitLine) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(splitLine[1])
+		switch strings.TrimSpace(splitLine[0]) {
+		case "core id":
+			cores[physicalId+":"+value] = struct{}{}
+		case "model name":
+			if inventory.CpuModel == "" {
+				inventory.CpuModel = value
+			}
+		case "physical id":
+			physicalId = value
+			sockets[value] = struct{}{}
+		case "processor":
+			inventory.NumCPUs++
+		}
+	}
+	inventory.NumCores = uint(len(cores))
+	inventory.NumSockets = uint(len(sockets))
+}
+
+func readDisks(rootDir string) []proto.Disk {
+	blockDir := filepath.Join(rootDir, sysBlockDir)
+	names, err := fsutil.ReadDirnames(blockDir, false)
+	if err != nil {
+		return nil
+	}
+	var disks []proto.Disk
+	for _, name := range names {
+		dirname := filepath.Join(blockDir, name)
+		// Skip virtual devices such as loop, ram and device mapper devices.
+		if _, err := os.Stat(filepath.Join(dirname, "device")); err != nil {
+			continue
+		}
+		disk := proto.Disk{
+			Model: readSysFile(filepath.Join(dirname, "device", "model")),
+			Name:  name,
+		}
+		if sectors, err := strconv.ParseUint(
+			readSysFile(filepath.Join(dirname, "size")), 10, 64); err == nil {
+			disk.Size = sectors << 9
+		}
+		disk.Rotational = readSysFile(
+			filepath.Join(dirname, "queue", "rotational")) == "1"
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+func readDmiFile(rootDir, name string) string {
+	return readSysFile(filepath.Join(rootDir, dmiDirectory, name))
+}
+
+// readDriverInfo uses the SIOCETHTOOL ioctl to read the driver name and
+// firmware version for a network interface.
+func readDriverInfo(name string) (string, string, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return "", "", err
+	}
+	defer syscall.Close(fd)
+	driverInfo := ethtoolDriverInfo{cmd: ethtoolGetDriverInfo}
+	request := interfaceRequest{
+		data: unsafe.Pointer(&driverInfo),
+	}
+	copy(request.name[:len(request.name)-1], name)
+	err = wsyscall.Ioctl(fd, siocEthtool, uintptr(unsafe.Pointer(&request)))
+	if err != nil {
+		return "", "", err
+	}
+	return cString(driverInfo.driver[:]),
+		cString(driverInfo.firmwareVersion[:]), nil
+}
+
+// readHardwareInventory reads the inventory from the /proc and /sys trees
+// under rootDir.
+func readHardwareInventory(rootDir string,
+	memTotalInMiB uint64) *proto.HardwareInventory {
+	inventory := &proto.HardwareInventory{
+		BiosDate:          readDmiFile(rootDir, "bios_date"),
+		BiosVendor:        readDmiFile(rootDir, "bios_vendor"),
+		BiosVersion:       readDmiFile(rootDir, "bios_version"),
+		Disks:             readDisks(rootDir),
+		MemoryInMiB:       memTotalInMiB,
+		NetworkInterfaces: readNetworkInterfaces(rootDir),
+		ProductName:       readDmiFile(rootDir, "product_name"),
+		SystemVendor:      readDmiFile(rootDir, "sys_vendor"),
+	}
+	readCpuInfo(rootDir, inventory)
+	return inventory
+}
+
+func readNetworkInterfaces(rootDir string) []proto.NetworkInterface {
+	networkDir := filepath.Join(rootDir, sysNetworkDir)
+	names, err := fsutil.ReadDirnames(networkDir, false)
+	if err != nil {
+		return nil
+	}
+	var interfaces []proto.NetworkInterface
+	for _, name := range names {
+		dirname := filepath.Join(networkDir, name)
+		// Skip virtual interfaces such as bridges, VLANs and taps.
+		if _, err := os.Stat(filepath.Join(dirname, "device")); err != nil {
+			continue
+		}
+		netInterface := proto.NetworkInterface{
+			MacAddress: readSysFile(filepath.Join(dirname, "address")),
+			Name:       name,
+		}
+		// The speed is -1 or unreadable if the link is down.
+		if speed, err := strconv.ParseUint(
+			readSysFile(filepath.Join(dirname, "speed")), 10, 32); err == nil {
+			netInterface.SpeedInMbps = uint(speed)
+		}
+		driver, firmwareVersion, err := readDriverInfo(name)
+		if err == nil {
+			netInterface.Driver = driver
+			netInterface.FirmwareVersion = firmwareVersion
+		}
+		interfaces = append(interfaces, netInterface)
+	}
+	return interfaces
+}
+
+func readSysFile(filename string) string {
+	if data, err := ioutil.ReadFile(filename); err != nil {
+		return ""
+	} else {
+		return strings.TrimSpace(string(data))
+	}
+}
@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+const testInventoryDir = "testdata/inventory"
+
+func TestReadCpuInfo(t *testing.T) {
+	var inventory proto.HardwareInventory
+	readCpuInfo(testInventoryDir, &inventory)
+	expected := proto.HardwareInventory{
+		CpuModel:   "Test CPU @ 2.00GHz",
+		NumCores:   2,
+		NumCPUs:    4,
+		NumSockets: 2,
+	}
+	if !reflect.DeepEqual(inventory, expected) {
+		t.Errorf("%+v != %+v", inventory, expected)
+	}
+	inventory = proto.HardwareInventory{}
+	readCpuInfo(t.TempDir(), &inventory)
+	if !reflect.DeepEqual(inventory, proto.HardwareInventory{}) {
+		t.Errorf("missing cpuinfo: %+v", inventory)
+	}
+}
+
+func TestReadDisks(t *testing.T) {
+	disks := readDisks(testInventoryDir)
+	sort.Slice(disks, func(i, j int) bool {
+		return disks[i].Name < disks[j].Name
+	})
+	expected := []proto.Disk{
+		{Model: "TestDisk NVMe", Name: "nvme0n1", Size: 2 << 30},
+		{Model: "TestDisk HDD", Name: "sda", Rotational: true, Size: 1 << 30},
+	}
+	if !reflect.DeepEqual(disks, expected) {
+		t.Errorf("%+v != %+v", disks, expected)
+	}
+	if disks := readDisks(t.TempDir()); len(disks) != 0 {
+		t.Errorf("empty tree: %+v", disks)
+	}
+}
+
+func TestReadHardwareInventory(t *testing.T) {
+	inventory := readHardwareInventory(testInventoryDir, 4096)
+	if inventory.BiosDate != "01/02/2024" ||
+		inventory.BiosVendor != "TestBios" ||
+		inventory.BiosVersion != "1.2.3" ||
+		inventory.ProductName != "TestServer" ||
+		inventory.SystemVendor != "TestVendor" {
+		t.Errorf("bad DMI data: %+v", inventory)
+	}
+	if inventory.MemoryInMiB != 4096 {
+		t.Errorf("memory: %d", inventory.MemoryInMiB)
+	}
+	if len(inventory.Disks) != 2 || len(inventory.NetworkInterfaces) != 2 ||
+		inventory.NumCPUs != 4 {
+		t.Errorf("incomplete inventory: %+v", inventory)
+	}
+}
+
+func TestReadNetworkInterfaces(t *testing.T) {
+	interfaces := readNetworkInterfaces(testInventoryDir)
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].Name < interfaces[j].Name
+	})
+	// The driver information comes from the kernel and is not available for
+	// the fixture interfaces.
+	expected := []proto.NetworkInterface{
+		{
+			MacAddress:  "02:00:00:00:00:01",
+			Name:        "testeth0",
+			SpeedInMbps: 10000,
+		},
+		{
+			MacAddress: "02:00:00:00:00:02",
+			Name:       "testeth1",
+		},
+	}
+	if !reflect.DeepEqual(interfaces, expected) {
+		t.Errorf("%+v != %+v", interfaces, expected)
+	}
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+// quotaError is returned when the Fleet Manager refuses an allocation.
+type quotaError struct {
+	error
+}
+
+// getRequestedResources estimates the resources a new VM will consume. The
+// size of a root volume created from an image is not yet known, so only the
+// requested minimum free space is counted for it.
+func getRequestedResources(
+	request proto.CreateVmRequest) fm_proto.Resources {
+	resources := fm_proto.Resources{
+		IpAddresses: uint(1 + len(request.SecondarySubnetIDs)),
+		MemoryInMiB: request.MemoryInMiB,
+		MilliCPUs:   request.MilliCPUs,
+		NumVMs:      1,
+		VolumeBytes: request.ImageDataSize + request.MinimumFreeBytes,
+	}
+	for _, volume := range request.SecondaryVolumes {
+		resources.VolumeBytes += volume.Size
+	}
+	return resources
+}
+
+// checkQuota asks the Fleet Manager (if configured) whether the owners may
+// consume the specified resources for the VM with IP address ipAddr. If the
+// Fleet Manager cannot be reached the allocation is refused, unless
+// QuotaFailOpen is set.
+func (m *Manager) checkQuota(ownerGroups, ownerUsers []string, ipAddr net.IP,
+	resources fm_proto.Resources) error {
+	if m.FleetManagerAddress == "" {
+		return nil
+	}
+	err := m.requestQuotaCheck(fm_proto.CheckVmAllocationRequest{
+		IpAddress:   ipAddr,
+		OwnerGroups: ownerGroups,
+		OwnerUsers:  ownerUsers,
+		Resources:   resources,
+	})
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(quotaError); ok {
+		return err
+	}
+	if m.QuotaFailOpen {
+		m.Logger.Printf("error checking quota, permitting allocation: %s\n",
+			err)
+		return nil
+	}
+	return fmt.Errorf("error checking quota: %s", err)
+}
+
+// requestQuotaCheck calls the Fleet Manager. If the allocation is refused, a
+// quotaError is returned.
+func (m *Manager) requestQuotaCheck(
+	request fm_proto.CheckVmAllocationRequest) error {
+	client, err := srpc.DialHTTP("tcp", m.FleetManagerAddress,
+		time.Second*15)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var reply fm_proto.CheckVmAllocationResponse
+	err = client.RequestReply("FleetManager.CheckVmAllocation", request,
+		&reply)
+	if err != nil {
+		return err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return quotaError{err}
+	}
+	return nil
+}
+
+func (vm *vmInfoType) checkQuota(resources fm_proto.Resources) error {
+	return vm.manager.checkQuota(vm.OwnerGroups, vm.OwnerUsers,
+		vm.Address.IpAddress, resources)
+}
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
+	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
+)
+
+func TestCheckQuotaUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close() // Connections will be refused.
+	resources := fm_proto.Resources{MilliCPUs: 1000, NumVMs: 1}
+	m := &Manager{StartOptions: StartOptions{Logger: testlogger.New(t)}}
+	if err := m.checkQuota(nil, []string{"user"}, nil, resources); err != nil {
+		t.Errorf("no Fleet Manager: %s", err)
+	}
+	m.FleetManagerAddress = address
+	if err := m.checkQuota(nil, []string{"user"}, nil, resources); err == nil {
+		t.Error("allocation permitted with unreachable Fleet Manager")
+	}
+	m.QuotaFailOpen = true
+	if err := m.checkQuota(nil, []string{"user"}, nil, resources); err != nil {
+		t.Errorf("fail open: %s", err)
+	}
+}
+
+func TestGetRequestedResources(t *testing.T) {
+	request := proto.CreateVmRequest{
+		ImageDataSize:    1 << 20,
+		MinimumFreeBytes: 1 << 30,
+		SecondaryVolumes: []proto.Volume{{Size: 2 << 30}, {Size: 3 << 30}},
+	}
+	request.MemoryInMiB = 2048
+	request.MilliCPUs = 1500
+	request.SecondarySubnetIDs = []string{"a", "b"}
+	resources := getRequestedResources(request)
+	expected := fm_proto.Resources{
+		IpAddresses: 3,
+		MemoryInMiB: 2048,
+		MilliCPUs:   1500,
+		NumVMs:      1,
+		VolumeBytes: 6<<30 + 1<<20,
+	}
+	if resources != expected {
+		t.Errorf("%+v != %+v", resources, expected)
+	}
+}
@@ -41,6 +41,7 @@ func newManager(startOptions StartOptions) (*Manager, error) {
 	}
 	manager := &Manager{
 		StartOptions:      startOptions,
+		hardwareInventory: readHardwareInventory("/", memInfo.Total>>20),
 		rootCookie:        rootCookie,
 		memTotalInMiB:     memInfo.Total >> 20,
 		notifiers:         make(map[<-chan proto.Update]chan<- proto.Update),
@@ -37,16 +37,17 @@ func (m *Manager) makeUpdateChannel() <-chan proto.Update {
 	}
 	// Initial update: give everything.
 	channel <- proto.Update{
-		HaveAddressPool:  true,
-		AddressPool:      m.addressPool.Registered,
-		NumFreeAddresses: numFreeAddresses,
-		HealthStatus:     m.healthStatus,
-		HaveSerialNumber: true,
-		SerialNumber:     m.serialNumber,
-		HaveSubnets:      true,
-		Subnets:          subnets,
-		HaveVMs:          true,
-		VMs:              vms,
+		HaveAddressPool:   true,
+		AddressPool:       m.addressPool.Registered,
+		NumFreeAddresses:  numFreeAddresses,
+		HealthStatus:      m.healthStatus,
+		HardwareInventory: m.hardwareInventory,
+		HaveSerialNumber:  true,
+		SerialNumber:      m.serialNumber,
+		HaveSubnets:       true,
+		Subnets:           subnets,
+		HaveVMs:           true,
+		VMs:               vms,
 	}
 	return channel
 }
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/json"
 	"github.com/Cloud-Foundations/Dominator/lib/log/debuglogger"
 	"github.com/Cloud-Foundations/Dominator/lib/log/prefixlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/mbr"
 	libnet "github.com/Cloud-Foundations/Dominator/lib/net"
 	"github.com/Cloud-Foundations/Dominator/lib/objectcache"
@@ -39,6 +41,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 	"github.com/Cloud-Foundations/Dominator/lib/verstr"
 	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+	fm_proto "github.com/Cloud-Foundations/Dominator/proto/fleetmanager"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 	subproto "github.com/Cloud-Foundations/Dominator/proto/sub"
 	sublib "github.com/Cloud-Foundations/Dominator/sub/lib"
@@ -222,8 +225,14 @@ func (m *Manager) addVmVolumes(ipAddr net.IP, authInfo *srpc.AuthInformation,
 		return errors.New("VM is not stopped")
 	}
 	volumes := make([]proto.Volume, 0, len(volumeSizes))
+	var totalSize uint64
 	for _, size := range volumeSizes {
 		volumes = append(volumes, proto.Volume{Size: size})
+		totalSize += size
+	}
+	err = vm.checkQuota(fm_proto.Resources{VolumeBytes: totalSize})
+	if err != nil {
+		return err
 	}
 	volumeDirectories, err := vm.manager.getVolumeDirectories(0, volumes,
 		vm.SpreadVolumes)
@@ -451,6 +460,18 @@ func (m *Manager) changeVmSize(ipAddr net.IP, authInfo *srpc.AuthInformation,
 	if vm.State != proto.StateStopped {
 		return errors.New("VM is not stopped")
 	}
+	var extraResources fm_proto.Resources
+	if memoryInMiB > vm.MemoryInMiB {
+		extraResources.MemoryInMiB = memoryInMiB - vm.MemoryInMiB
+	}
+	if milliCPUs > vm.MilliCPUs {
+		extraResources.MilliCPUs = milliCPUs - vm.MilliCPUs
+	}
+	if extraResources.MemoryInMiB > 0 || extraResources.MilliCPUs > 0 {
+		if err := vm.checkQuota(extraResources); err != nil {
+			return err
+		}
+	}
 	changed := false
 	if memoryInMiB > 0 {
 		if memoryInMiB < vm.MemoryInMiB {
@@ -741,7 +762,8 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		return conn.Flush()
 	}
 
-	m.Logger.Debugf(1, "CreateVm(%s) starting\n", conn.Username())
+	logger := tracelogger.New(conn.Context(), m.Logger)
+	logger.Debugf(1, "CreateVm(%s) starting\n", conn.Username())
 	var request proto.CreateVmRequest
 	if err := conn.Decode(&request); err != nil {
 		return err
@@ -762,6 +784,16 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 	defer func() {
 		vm.cleanup() // Evaluate vm at return time, not defer time.
 	}()
+	// The quota is checked once the IP address is known, so that the Fleet
+	// Manager can reserve the resources for the VM.
+	err = m.checkQuota(request.OwnerGroups, ownerUsers, vm.Address.IpAddress,
+		getRequestedResources(request))
+	if err != nil {
+		if err := maybeDrainAll(conn, request); err != nil {
+			return err
+		}
+		return sendError(conn, err)
+	}
 	memoryError := tryAllocateMemory(request.MemoryInMiB)
 	vm.OwnerUsers = ownerUsers
 	vm.ownerUsers = make(map[string]struct{}, len(ownerUsers))
@@ -781,8 +813,8 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		if err := sendUpdate(conn, "getting image"); err != nil {
 			return err
 		}
-		client, img, imageName, err := m.getImage(request.ImageName,
-			request.ImageTimeout)
+		client, img, imageName, err := m.getImage(conn.Context(),
+			request.ImageName, request.ImageTimeout)
 		if err != nil {
 			return sendError(conn, err)
 		}
@@ -811,7 +843,7 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		if err != nil {
 			return sendError(conn, err)
 		}
-		m.Logger.Debugln(1, "finished writing volume")
+		logger.Debugln(1, "finished writing volume")
 		if fi, err := os.Stat(vm.VolumeLocations[0].Filename); err != nil {
 			return sendError(conn, err)
 		} else {
@@ -908,7 +940,7 @@ func (m *Manager) createVm(conn *srpc.Conn) error {
 		return err
 	}
 	vm = nil // Cancel cleanup.
-	m.Logger.Debugln(1, "CreateVm() finished")
+	logger.Debugln(1, "CreateVm() finished")
 	return nil
 }
 
@@ -1044,8 +1076,8 @@ func (m *Manager) exportLocalVm(authInfo *srpc.AuthInformation,
 	return &vmInfo, nil
 }
 
-func (m *Manager) getImage(searchName string, imageTimeout time.Duration) (
-	*srpc.Client, *image.Image, string, error) {
+func (m *Manager) getImage(ctx context.Context, searchName string,
+	imageTimeout time.Duration) (*srpc.Client, *image.Image, string, error) {
 	client, err := srpc.DialHTTP("tcp", m.ImageServerAddress, 0)
 	if err != nil {
 		return nil, nil, "",
@@ -1069,7 +1101,7 @@ func (m *Manager) getImage(searchName string, imageTimeout time.Duration) (
 			return nil, nil, "",
 				errors.New("no images in directory: " + searchName)
 		}
-		img, err := imclient.GetImage(client, imageName)
+		img, err := imclient.GetImageContext(ctx, client, imageName)
 		if err != nil {
 			return nil, nil, "", err
 		}
@@ -1077,7 +1109,8 @@ func (m *Manager) getImage(searchName string, imageTimeout time.Duration) (
 		doClose = false
 		return client, img, imageName, nil
 	}
-	img, err := imclient.GetImageWithTimeout(client, searchName, imageTimeout)
+	img, err := imclient.GetImageWithTimeoutContext(ctx, client, searchName,
+		imageTimeout)
 	if err != nil {
 		return nil, nil, "", err
 	}
@@ -1790,7 +1823,7 @@ func (m *Manager) notifyVmMetadataRequest(ipAddr net.IP, path string) {
 
 func (m *Manager) patchVmImage(conn *srpc.Conn,
 	request proto.PatchVmImageRequest) error {
-	client, img, imageName, err := m.getImage(request.ImageName,
+	client, img, imageName, err := m.getImage(conn.Context(), request.ImageName,
 		request.ImageTimeout)
 	if err != nil {
 		return nil
@@ -2071,8 +2104,8 @@ func (m *Manager) replaceVmImage(conn *srpc.Conn,
 		if err := sendUpdate(conn, "getting image"); err != nil {
 			return err
 		}
-		client, img, imageName, err := m.getImage(request.ImageName,
-			request.ImageTimeout)
+		client, img, imageName, err := m.getImage(conn.Context(),
+			request.ImageName, request.ImageTimeout)
 		if err != nil {
 			return sendError(conn, err)
 		}
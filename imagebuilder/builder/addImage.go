@@ -2,13 +2,18 @@ package builder
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	imageclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
@@ -21,6 +26,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/testreport"
 	"github.com/Cloud-Foundations/Dominator/lib/triggers"
 	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
@@ -38,6 +44,7 @@ type testResultType struct {
 	duration time.Duration
 	err      error
 	prog     string
+	timedOut bool
 }
 
 func (h *hasher) Hash(reader io.Reader, length uint64) (
@@ -150,7 +157,8 @@ func listPackages(rootDir string) ([]image.Package, error) {
 func packImage(client *srpc.Client, request proto.BuildImageRequest,
 	dirname string, scanFilter *filter.Filter,
 	computedFilesList []util.ComputedFile, imageFilter *filter.Filter,
-	trig *triggers.Triggers, buildLog buildLogger) (*image.Image, error) {
+	trig *triggers.Triggers, testPolicy *testPolicyType,
+	buildLog buildLogger) (*image.Image, error) {
 	packages, err := listPackages(dirname)
 	if err != nil {
 		return nil, fmt.Errorf("error listing packages: %s", err)
@@ -179,9 +187,11 @@ func packImage(client *srpc.Client, request proto.BuildImageRequest,
 		fmt.Fprintf(buildLog, "Copied mtimes in %s\n",
 			format.Duration(time.Since(patchStartTime)))
 	}
-	if err := runTests(dirname, buildLog); err != nil {
+	report, err := runTests(dirname, request.StreamName, testPolicy, buildLog)
+	if err != nil {
 		return nil, err
 	}
+	bom := makeSBOM(dirname, packages, fs, request.StreamName, buildLog)
 	objClient := objectclient.AttachObjectClient(client)
 	// Make a copy of the build log because AddObject() drains the buffer.
 	logReader := bytes.NewBuffer(buildLog.Bytes())
@@ -190,15 +200,40 @@ func packImage(client *srpc.Client, request proto.BuildImageRequest,
 	if err != nil {
 		return nil, err
 	}
+	bomBuffer := new(bytes.Buffer)
+	if err := json.NewEncoder(bomBuffer).Encode(bom); err != nil {
+		return nil, err
+	}
+	bomHashVal, _, err := objClient.AddObject(bomBuffer,
+		uint64(bomBuffer.Len()), nil)
+	if err != nil {
+		return nil, err
+	}
+	var testReport *image.Annotation
+	if report != nil {
+		reportBuffer := new(bytes.Buffer)
+		if err := json.NewEncoder(reportBuffer).Encode(report); err != nil {
+			return nil, err
+		}
+		reportHashVal, _, err := objClient.AddObject(reportBuffer,
+			uint64(reportBuffer.Len()), nil)
+		if err != nil {
+			return nil, err
+		}
+		testReport = &image.Annotation{Object: &reportHashVal}
+	}
 	if err := objClient.Close(); err != nil {
 		return nil, err
 	}
 	img := &image.Image{
-		BuildLog:   &image.Annotation{Object: &hashVal},
-		FileSystem: fs,
-		Filter:     imageFilter,
-		Triggers:   trig,
-		Packages:   packages,
+		BuildLog:    &image.Annotation{Object: &hashVal},
+		FileSystem:  fs,
+		Filter:      imageFilter,
+		Triggers:    trig,
+		Packages:    packages,
+		SBOM:        &image.Annotation{Object: &bomHashVal},
+		TestReport:  testReport,
+		TestsFailed: report != nil && report.Failed,
 	}
 	if err := img.Verify(); err != nil {
 		return nil, err
@@ -206,7 +241,20 @@ func packImage(client *srpc.Client, request proto.BuildImageRequest,
 	return img, nil
 }
 
-func runTests(rootDir string, buildLog buildLogger) error {
+func getExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
+
+func runTests(rootDir string, streamName string, policy *testPolicyType,
+	buildLog buildLogger) (*testreport.Report, error) {
 	var testProgrammes []string
 	err := filepath.Walk(filepath.Join(rootDir, "tests"),
 		func(path string, fi os.FileInfo, err error) error {
@@ -217,46 +265,77 @@ func runTests(rootDir string, buildLog buildLogger) error {
 			return nil
 		})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(testProgrammes) < 1 {
-		return nil
+		return nil, nil
 	}
 	fmt.Fprintf(buildLog, "Running %d tests\n", len(testProgrammes))
 	results := make(chan testResultType, 1)
 	for _, prog := range testProgrammes {
 		go func(prog string) {
-			results <- runTest(rootDir, prog)
+			results <- runTest(rootDir, prog, policy.getTimeout())
 		}(prog)
 	}
+	report := &testreport.Report{
+		CreatedOn:  time.Now(),
+		StreamName: streamName,
+		Tests:      make([]testreport.Result, 0, len(testProgrammes)),
+	}
 	numFailures := 0
 	for range testProgrammes {
 		result := <-results
-		io.Copy(buildLog, &result)
+		output, _ := ioutil.ReadAll(&result)
+		buildLog.Write(output)
+		testResult := testreport.Result{
+			Duration:   result.duration,
+			ExitStatus: getExitStatus(result.err),
+			Name:       strings.TrimPrefix(result.prog, "/tests/"),
+			Output:     string(output),
+			TimedOut:   result.timedOut,
+		}
 		if result.err != nil {
+			testResult.Error = result.err.Error()
 			fmt.Fprintf(buildLog, "error running: %s: %s\n",
 				result.prog, result.err)
-			numFailures++
+			if policy.isAllowedFailure(testResult.Name) {
+				testResult.Flaky = true
+				fmt.Fprintf(buildLog, "%s is allowed to fail\n", result.prog)
+			} else {
+				numFailures++
+			}
 		} else {
+			testResult.Passed = true
 			fmt.Fprintf(buildLog, "%s passed in %s\n",
 				result.prog, format.Duration(result.duration))
 		}
+		report.Tests = append(report.Tests, testResult)
 		fmt.Fprintln(buildLog)
 	}
+	sort.Slice(report.Tests, func(left, right int) bool {
+		return report.Tests[left].Name < report.Tests[right].Name
+	})
+	fmt.Fprintf(buildLog, "Tests: %s\n", report.Summarise())
 	if numFailures > 0 {
-		return fmt.Errorf("%d tests failed", numFailures)
+		report.Failed = true
+		if policy == nil || !policy.MarkFailures {
+			return nil, fmt.Errorf("%d tests failed", numFailures)
+		}
+		fmt.Fprintf(buildLog,
+			"%d tests failed, image will be marked as failing tests\n",
+			numFailures)
 	}
-	return nil
+	return report, nil
 }
 
-func runTest(rootDir, prog string) testResultType {
+func runTest(rootDir, prog string, timeout time.Duration) testResultType {
 	startTime := time.Now()
 	result := testResultType{
 		buffer: make(chan byte, 4096),
 		prog:   prog,
 	}
 	errChannel := make(chan error, 1)
-	timer := time.NewTimer(time.Second * 10)
+	timer := time.NewTimer(timeout)
 	go func() {
 		errChannel <- runInTarget(nil, &result, rootDir, nil, packagerPathname,
 			"run", prog)
@@ -265,11 +344,32 @@ func runTest(rootDir, prog string) testResultType {
 	case result.err = <-errChannel:
 		result.duration = time.Since(startTime)
 	case <-timer.C:
+		result.duration = time.Since(startTime)
 		result.err = errorTestTimedOut
+		result.timedOut = true
 	}
 	return result
 }
 
+func (policy *testPolicyType) getTimeout() time.Duration {
+	if policy == nil || policy.Timeout < 1 {
+		return time.Second * 10
+	}
+	return time.Second * time.Duration(policy.Timeout)
+}
+
+func (policy *testPolicyType) isAllowedFailure(name string) bool {
+	if policy == nil {
+		return false
+	}
+	for _, pattern := range policy.AllowedFailures {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *testResultType) Read(p []byte) (int, error) {
 	for count := 0; count < len(p); count++ {
 		select {
@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTestPolicyAllowedFailures(t *testing.T) {
+	var nilPolicy *testPolicyType
+	if nilPolicy.isAllowedFailure("network") {
+		t.Error("failure allowed without a policy")
+	}
+	policy := &testPolicyType{
+		AllowedFailures: []string{"network", "flaky-*", "["},
+	}
+	tests := []struct {
+		name    string
+		allowed bool
+	}{
+		{"network", true},
+		{"network-slow", false},
+		{"flaky-dns", true},
+		{"services", false},
+		{"subdir/flaky-dns", false},
+	}
+	for _, test := range tests {
+		if allowed := policy.isAllowedFailure(test.name); allowed !=
+			test.allowed {
+			t.Errorf("%s: allowed: %t", test.name, allowed)
+		}
+	}
+}
+
+func TestTestPolicyTimeout(t *testing.T) {
+	var nilPolicy *testPolicyType
+	if timeout := nilPolicy.getTimeout(); timeout != 10*time.Second {
+		t.Errorf("default timeout: %s", timeout)
+	}
+	if timeout := (&testPolicyType{}).getTimeout(); timeout !=
+		10*time.Second {
+		t.Errorf("default timeout: %s", timeout)
+	}
+	policy := &testPolicyType{Timeout: 120}
+	if timeout := policy.getTimeout(); timeout != 2*time.Minute {
+		t.Errorf("timeout: %s", timeout)
+	}
+}
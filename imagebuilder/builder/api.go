@@ -2,7 +2,9 @@ package builder
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"net/http"
 	"sync"
 	"time"
 
@@ -12,10 +14,15 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/log"
 	"github.com/Cloud-Foundations/Dominator/lib/slavedriver"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/testreport"
 	"github.com/Cloud-Foundations/Dominator/lib/triggers"
 	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
 
+// ErrWebhookUnauthorised is returned by ProcessWebhook if the webhook request
+// does not have a valid signature or token.
+var ErrWebhookUnauthorised = errors.New("webhook not authorised")
+
 type buildLogger interface {
 	Bytes() []byte
 	io.Writer
@@ -43,20 +50,28 @@ type bootstrapStream struct {
 }
 
 type buildResultType struct {
-	imageName  string
-	startTime  time.Time
-	finishTime time.Time
-	buildLog   []byte
-	error      error
+	imageName   string
+	startTime   time.Time
+	finishTime  time.Time
+	buildLog    []byte
+	error       error
+	testSummary *testreport.Summary
 }
 
 type masterConfigurationType struct {
 	BindMounts                []string                    `json:",omitempty"`
 	BootstrapStreams          map[string]*bootstrapStream `json:",omitempty"`
+	BuildCacheMaxAge          uint                        `json:",omitempty"`
+	BuildCacheMaxBytes        uint64                      `json:",omitempty"`
 	ImageStreamsCheckInterval uint                        `json:",omitempty"`
 	ImageStreamsToAutoRebuild []string                    `json:",omitempty"`
 	ImageStreamsUrl           string                      `json:",omitempty"`
+	ManifestCheckInterval     uint                        `json:",omitempty"`
+	MaxConcurrentBuilds       uint                        `json:",omitempty"`
+	MaxConcurrentGroupBuilds  map[string]uint             `json:",omitempty"`
 	PackagerTypes             map[string]packagerType     `json:",omitempty"`
+	WebhookImageExpiration    uint                        `json:",omitempty"`
+	WebhookSecret             string                      `json:",omitempty"`
 }
 
 type manifestConfigType struct {
@@ -73,8 +88,10 @@ type imageStreamType struct {
 	builder           *Builder
 	name              string
 	BuilderGroups     []string
+	GitBranch         string `json:",omitempty"`
 	ManifestUrl       string
 	ManifestDirectory string
+	TestPolicy        *testPolicyType `json:",omitempty"`
 }
 
 type imageStreamsConfigurationType struct {
@@ -89,23 +106,34 @@ type listCommandType struct {
 }
 
 type packagerType struct {
-	CleanCommand   argList
-	InstallCommand argList
-	ListCommand    listCommandType
-	RemoveCommand  argList
-	UpdateCommand  argList
-	UpgradeCommand argList
-	Verbatim       []string
+	CleanCommand       argList
+	InstallCommand     argList
+	ListCommand        listCommandType
+	ListDetailsCommand argList
+	ListFilesCommand   argList
+	RemoveCommand      argList
+	UpdateCommand      argList
+	UpgradeCommand     argList
+	Verbatim           []string
 }
 
 type sourceImageInfoType struct {
-	computedFiles []util.ComputedFile
-	filter        *filter.Filter
-	triggers      *triggers.Triggers
+	computedFiles    []util.ComputedFile
+	fileSystemDigest string
+	filter           *filter.Filter
+	imageName        string
+	triggers         *triggers.Triggers
+}
+
+type testPolicyType struct {
+	AllowedFailures []string `json:",omitempty"` // Patterns for flaky tests.
+	MarkFailures    bool     `json:",omitempty"` // Upload despite failures.
+	Timeout         uint     `json:",omitempty"` // Seconds. Default: 10.
 }
 
 type Builder struct {
 	bindMounts                []string
+	buildCache                *buildCacheType
 	stateDir                  string
 	imageServerAddress        string
 	logger                    log.Logger
@@ -114,12 +142,29 @@ type Builder struct {
 	bootstrapStreams          map[string]*bootstrapStream
 	imageStreams              map[string]*imageStreamType
 	imageStreamsToAutoRebuild []string
+	imageRebuildInterval      time.Duration
 	slaveDriver               *slavedriver.SlaveDriver
+	dependencyLock            sync.RWMutex
+	dependencyGraph           *dependencyGraphType
+	manifestCheckInterval     time.Duration
+	manifestInfo              map[string]manifestInfoType // Key: stream name.
+	rebuildQueue              map[string]struct{}         // Key: stream name.
+	rebuildQueueWakeup        chan struct{}
+	queueLock                 sync.Mutex
+	buildQueue                []*queuedBuildType // Not yet running.
+	maxConcurrentBuilds       uint
+	maxConcurrentGroupBuilds  map[string]uint // Key: builder group.
+	nextBuildId               uint64
+	numRunningBuilds          uint
+	numRunningGroupBuilds     map[string]uint             // Key: builder group.
+	runningBuilds             map[uint64]*queuedBuildType // Key: build ID.
 	buildResultsLock          sync.RWMutex
 	currentBuildLogs          map[string]*bytes.Buffer   // Key: stream name.
 	lastBuildResults          map[string]buildResultType // Key: stream name.
 	packagerTypes             map[string]packagerType
 	variables                 map[string]string
+	webhookImageExpiration    time.Duration
+	webhookSecret             string
 }
 
 func Load(confUrl, variablesFile, stateDir, imageServerAddress string,
@@ -135,6 +180,12 @@ func (b *Builder) BuildImage(request proto.BuildImageRequest,
 	return b.buildImage(request, authInfo, logWriter)
 }
 
+// CancelBuild cancels a queued or running build. Running builds are stopped by
+// killing their processes or their slave.
+func (b *Builder) CancelBuild(id uint64, authInfo *srpc.AuthInformation) error {
+	return b.cancelBuild(id, authInfo)
+}
+
 func (b *Builder) GetCurrentBuildLog(streamName string) ([]byte, error) {
 	return b.getCurrentBuildLog(streamName)
 }
@@ -143,6 +194,19 @@ func (b *Builder) GetLatestBuildLog(streamName string) ([]byte, error) {
 	return b.getLatestBuildLog(streamName)
 }
 
+func (b *Builder) ListBuildQueue() []proto.QueuedBuild {
+	return b.listBuildQueue()
+}
+
+// ProcessWebhook authenticates and processes a webhook request from a Git
+// hosting service. For push events, builds are queued for the image streams
+// with a manifest in the pushed repository, using the pushed branch. The
+// names of the streams are returned.
+func (b *Builder) ProcessWebhook(header http.Header, body []byte) (
+	[]string, error) {
+	return b.processWebhook(header, body)
+}
+
 func (b *Builder) ShowImageStream(writer io.Writer, streamName string) {
 	b.showImageStream(writer, streamName)
 }
@@ -181,7 +245,7 @@ func ProcessManifest(manifestDir, rootDir string, bindMounts []string,
 
 func UnpackImageAndProcessManifest(client *srpc.Client, manifestDir string,
 	rootDir string, bindMounts []string, buildLog io.Writer) error {
-	_, err := unpackImageAndProcessManifest(client, manifestDir, rootDir,
+	_, err := unpackImageAndProcessManifest(client, nil, manifestDir, rootDir,
 		bindMounts, true, nil, buildLog)
 	return err
 }
@@ -88,7 +88,8 @@ func (stream *bootstrapStream) build(b *Builder, client *srpc.Client,
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdout = buildLog
 	cmd.Stderr = buildLog
-	if err := cmd.Run(); err != nil {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := runCancellableCommand(cmd, buildLog); err != nil {
 		return nil, err
 	} else {
 		packager := b.packagerTypes[stream.PackagerType]
@@ -105,7 +106,7 @@ func (stream *bootstrapStream) build(b *Builder, client *srpc.Client,
 			return nil, err
 		}
 		return packImage(client, request, rootDir,
-			stream.Filter, nil, stream.imageFilter, stream.imageTriggers,
+			stream.Filter, nil, stream.imageFilter, stream.imageTriggers, nil,
 			buildLog)
 	}
 }
@@ -136,6 +137,8 @@ func (packager *packagerType) writePackageInstallerContents(writer io.Writer) {
 	fmt.Fprintln(writer, `[ "$cmd" = "copy-in" ] && exec cat > "$1"`)
 	writePackagerCommand(writer, "install", packager.InstallCommand)
 	writePackagerCommand(writer, "list", packager.ListCommand.ArgList)
+	writePackagerCommand(writer, "list-details", packager.ListDetailsCommand)
+	writePackagerCommand(writer, "list-files", packager.ListFilesCommand)
 	writePackagerCommand(writer, "remove", packager.RemoveCommand)
 	fmt.Fprintln(writer, `[ "$cmd" = "run" ] && exec "$@"`)
 	multiplier := packager.ListCommand.SizeMultiplier
@@ -197,7 +200,7 @@ func runInTarget(input io.Reader, output io.Writer, rootDir string,
 		Setsid:     true,
 		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID,
 	}
-	return cmd.Run()
+	return runCancellableCommand(cmd, output)
 }
 
 func runInTargetWithBindMounts(input io.Reader, output io.Writer,
@@ -11,12 +11,15 @@ import (
 	buildclient "github.com/Cloud-Foundations/Dominator/imagebuilder/client"
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/testreport"
 	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
 
 const errNoSourceImage = "no source image: "
 const errTooOldSourceImage = "too old source image: "
+const minimumImageExpiration = time.Minute * 15
 
 type dualBuildLogger struct {
 	buffer *bytes.Buffer
@@ -52,6 +55,26 @@ func needSourceImage(err error) (bool, string) {
 	return false, ""
 }
 
+func getTestSummary(client *srpc.Client,
+	img *image.Image) *testreport.Summary {
+	if img == nil || img.TestReport == nil || img.TestReport.Object == nil {
+		return nil
+	}
+	objClient := objectclient.AttachObjectClient(client)
+	defer objClient.Close()
+	_, reader, err := objClient.GetObject(*img.TestReport.Object)
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	report, err := testreport.Read(reader)
+	if err != nil {
+		return nil
+	}
+	summary := report.Summarise()
+	return &summary
+}
+
 func (bl *dualBuildLogger) Bytes() []byte {
 	return bl.buffer.Bytes()
 }
@@ -60,45 +83,14 @@ func (bl *dualBuildLogger) Write(p []byte) (int, error) {
 	return bl.writer.Write(p)
 }
 
-func (b *Builder) rebuildImages(minInterval time.Duration) {
-	if minInterval < 1 {
-		return
-	}
-	var sleepUntil time.Time
-	for ; ; time.Sleep(time.Until(sleepUntil)) {
-		sleepUntil = time.Now().Add(minInterval)
-		client, err := srpc.DialHTTP("tcp", b.imageServerAddress, 0)
-		if err != nil {
-			b.logger.Printf("%s: %s\n", b.imageServerAddress, err)
-			continue
-		}
-		for _, streamName := range b.listStreamsToAutoRebuild() {
-			_, _, err := b.build(client, proto.BuildImageRequest{
-				StreamName: streamName,
-				ExpiresIn:  minInterval * 2,
-			},
-				nil, nil)
-			if err != nil {
-				b.logger.Printf("Error building image: %s: %s\n",
-					streamName, err)
-			}
-		}
-		client.Close()
-	}
-}
-
 func (b *Builder) buildImage(request proto.BuildImageRequest,
 	authInfo *srpc.AuthInformation,
 	logWriter io.Writer) (*image.Image, string, error) {
-	if request.ExpiresIn < time.Minute*15 {
+	if request.ExpiresIn < minimumImageExpiration {
 		return nil, "", errors.New("minimum expiration time is 15 minutes")
 	}
-	client, err := srpc.DialHTTP("tcp", b.imageServerAddress, 0)
-	if err != nil {
-		return nil, "", err
-	}
-	defer client.Close()
-	img, name, err := b.build(client, request, authInfo, logWriter)
+	img, name, err := b.queueAndBuild(request, authInfo, logWriter,
+		buildPriorityInteractive)
 	if request.ReturnImage {
 		return img, "", err
 	}
@@ -128,15 +120,19 @@ func (b *Builder) build(client *srpc.Client, request proto.BuildImageRequest,
 			buffer: buildLogBuffer,
 			writer: io.MultiWriter(buildLogBuffer, logWriter),
 		}
+		if registrar, ok := logWriter.(cancelRegistrar); ok {
+			buildLog = &cancellableBuildLogger{buildLog, registrar}
+		}
 	}
 	img, name, err := b.buildWithLogger(builder, client, request, authInfo,
 		startTime, buildLog)
 	finishTime := time.Now()
+	testSummary := getTestSummary(client, img)
 	b.buildResultsLock.Lock()
 	defer b.buildResultsLock.Unlock()
 	delete(b.currentBuildLogs, request.StreamName)
 	b.lastBuildResults[request.StreamName] = buildResultType{
-		name, startTime, finishTime, buildLog.Bytes(), err}
+		name, startTime, finishTime, buildLog.Bytes(), err, testSummary}
 	if err == nil {
 		b.logger.Printf("Built image for stream: %s in %s\n",
 			request.StreamName, format.Duration(finishTime.Sub(startTime)))
@@ -243,6 +239,16 @@ func (b *Builder) buildOnSlave(client *srpc.Client,
 			slave.Destroy()
 		}
 	}()
+	if registrar, ok := buildLog.(cancelRegistrar); ok {
+		unregister, err := registrar.registerCanceller(func() {
+			slave.GetClient().Close()
+		})
+		if err != nil {
+			keepSlave = true
+			return nil, err
+		}
+		defer unregister()
+	}
 	if authInfo == nil {
 		b.logger.Printf("Auto building image on %s for stream: %s\n",
 			slave, request.StreamName)
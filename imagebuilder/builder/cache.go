@@ -0,0 +1,372 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+const buildCacheInfoFile = "info.json"
+
+type buildCacheType struct {
+	directory  string
+	maxAge     time.Duration
+	maxBytes   uint64
+	logger     log.Logger
+	lock       sync.Mutex
+	entries    map[string]*buildCacheEntryType // Key: stage key.
+	totalBytes uint64
+}
+
+type buildCacheEntryType struct {
+	key      string
+	info     buildCacheInfoType
+	lastUsed time.Time
+	numUsers uint
+}
+
+type buildCacheInfoType struct {
+	CreatedOn time.Time
+	Size      uint64
+	Stage     string
+}
+
+type buildStageType struct {
+	inputs []string // Manifest files and directories used by the stage.
+	name   string
+	run    func() error
+}
+
+func computeTreeSize(dirname string) (uint64, error) {
+	var size uint64
+	inodesSeen := make(map[uint64]struct{})
+	err := filepath.Walk(dirname,
+		func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+				if _, ok := inodesSeen[stat.Ino]; ok {
+					return nil
+				}
+				inodesSeen[stat.Ino] = struct{}{}
+				size += uint64(stat.Blocks) * 512
+			} else {
+				size += uint64(fi.Size())
+			}
+			return nil
+		})
+	return size, err
+}
+
+func hashManifestInput(hasher hash.Hash, manifestDir, name string) error {
+	topDir := filepath.Join(manifestDir, name)
+	return filepath.Walk(topDir,
+		func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if path == topDir && os.IsNotExist(err) {
+					fmt.Fprintf(hasher, "missing: %s\n", name)
+					return nil
+				}
+				return err
+			}
+			fmt.Fprintf(hasher, "%s %o\n", path[len(manifestDir):], fi.Mode())
+			switch {
+			case fi.Mode()&os.ModeSymlink != 0:
+				target, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(hasher, target)
+			case fi.Mode().IsRegular():
+				file, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				if _, err := io.Copy(hasher, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+}
+
+// makeBuildStageKeys computes a key for each stage from the key of the
+// previous stage (or the source image name and environment for the first
+// stage) and the inputs of the stage.
+func makeBuildStageKeys(sourceImageName string, manifestDir string,
+	bindMounts []string, envGetter environmentGetter,
+	stages []buildStageType) ([]string, error) {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "source: %s\n", sourceImageName)
+	if envGetter != nil {
+		environment := envGetter.getenv()
+		names := make([]string, 0, len(environment))
+		for name := range environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(hasher, "env: %s=%s\n", name, environment[name])
+		}
+	}
+	for _, bindMount := range bindMounts {
+		fmt.Fprintf(hasher, "bind: %s\n", bindMount)
+	}
+	previousKey := fmt.Sprintf("%x", hasher.Sum(nil))
+	keys := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		hasher := sha256.New()
+		fmt.Fprintf(hasher, "previous: %s\nstage: %s\n", previousKey,
+			stage.name)
+		for _, input := range stage.inputs {
+			if err := hashManifestInput(hasher, manifestDir, input); err != nil {
+				return nil, err
+			}
+		}
+		previousKey = fmt.Sprintf("%x", hasher.Sum(nil))
+		keys = append(keys, previousKey)
+	}
+	return keys, nil
+}
+
+func newBuildCache(directory string, maxBytes uint64, maxAge time.Duration,
+	logger log.Logger) (*buildCacheType, error) {
+	if err := os.MkdirAll(directory, fsutil.DirPerms); err != nil {
+		return nil, err
+	}
+	cache := &buildCacheType{
+		directory: directory,
+		maxAge:    maxAge,
+		maxBytes:  maxBytes,
+		logger:    logger,
+		entries:   make(map[string]*buildCacheEntryType),
+	}
+	names, err := fsutil.ReadDirnames(directory, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		dirname := filepath.Join(directory, name)
+		if strings.Contains(name, ".") { // Incomplete entry.
+			os.RemoveAll(dirname)
+			continue
+		}
+		fi, err := os.Stat(dirname)
+		if err != nil {
+			return nil, err
+		}
+		var info buildCacheInfoType
+		err = json.ReadFromFile(filepath.Join(dirname, buildCacheInfoFile),
+			&info)
+		if err != nil {
+			logger.Printf("removing bad build cache entry: %s: %s\n", name, err)
+			os.RemoveAll(dirname)
+			continue
+		}
+		cache.entries[name] = &buildCacheEntryType{
+			key:      name,
+			info:     info,
+			lastUsed: fi.ModTime(),
+		}
+		cache.totalBytes += info.Size
+	}
+	cache.lock.Lock()
+	cache.evict()
+	cache.lock.Unlock()
+	logger.Printf("Loaded build cache with %d entries using %s\n",
+		len(cache.entries), format.FormatBytes(cache.totalBytes))
+	return cache, nil
+}
+
+// evict removes expired entries and then the least recently used entries
+// until the cache is within its budget. Entries which are in use are not
+// removed. The lock must be held.
+func (cache *buildCacheType) evict() {
+	entries := make([]*buildCacheEntryType, 0, len(cache.entries))
+	for _, entry := range cache.entries {
+		if entry.numUsers > 0 {
+			continue
+		}
+		if cache.maxAge > 0 && time.Since(entry.info.CreatedOn) > cache.maxAge {
+			cache.remove(entry)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(left, right int) bool {
+		return entries[left].lastUsed.Before(entries[right].lastUsed)
+	})
+	for _, entry := range entries {
+		if cache.totalBytes <= cache.maxBytes {
+			break
+		}
+		cache.remove(entry)
+	}
+}
+
+// get returns the entry for key if present, marking it as in use. The entry
+// must be released with put.
+func (cache *buildCacheType) get(key string) *buildCacheEntryType {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	entry := cache.entries[key]
+	if entry == nil {
+		return nil
+	}
+	if cache.maxAge > 0 && time.Since(entry.info.CreatedOn) > cache.maxAge {
+		if entry.numUsers < 1 {
+			cache.remove(entry)
+		}
+		return nil
+	}
+	entry.numUsers++
+	entry.lastUsed = time.Now()
+	dirname := filepath.Join(cache.directory, key)
+	os.Chtimes(dirname, entry.lastUsed, entry.lastUsed)
+	return entry
+}
+
+func (cache *buildCacheType) put(entry *buildCacheEntryType) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	entry.numUsers--
+	cache.evict()
+}
+
+// remove deletes an entry. The lock must be held.
+func (cache *buildCacheType) remove(entry *buildCacheEntryType) {
+	delete(cache.entries, entry.key)
+	cache.totalBytes -= entry.info.Size
+	err := os.RemoveAll(filepath.Join(cache.directory, entry.key))
+	if err != nil {
+		cache.logger.Printf("error removing build cache entry: %s: %s\n",
+			entry.key, err)
+	}
+}
+
+// restore replaces the contents of rootDir with the tree saved for key. It
+// returns false if there is no entry for key.
+func (cache *buildCacheType) restore(key, stageName, rootDir string,
+	buildLog io.Writer) (bool, error) {
+	entry := cache.get(key)
+	if entry == nil {
+		return false, nil
+	}
+	defer cache.put(entry)
+	startTime := time.Now()
+	if err := os.RemoveAll(rootDir); err != nil {
+		return false, err
+	}
+	err := runCommand(buildLog, "", "cp", "-a",
+		filepath.Join(cache.directory, key, "root"), rootDir)
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(buildLog,
+		"Build cache hit for stage: %s (built %s ago), restored in %s\n",
+		stageName, format.Duration(time.Since(entry.info.CreatedOn)),
+		format.Duration(time.Since(startTime)))
+	return true, nil
+}
+
+// save copies rootDir into the cache. Any directories listed in
+// directoriesToSkip (temporary mount points) are not included in the copy.
+func (cache *buildCacheType) save(key, stageName, rootDir string,
+	directoriesToSkip []string, buildLog io.Writer) error {
+	cache.lock.Lock()
+	_, ok := cache.entries[key]
+	cache.lock.Unlock()
+	if ok {
+		return nil
+	}
+	startTime := time.Now()
+	tmpDir, err := ioutil.TempDir(cache.directory, key+".")
+	if err != nil {
+		return err
+	}
+	doCleanup := true
+	defer func() {
+		if doCleanup {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+	copyRoot := filepath.Join(tmpDir, "root")
+	if err := runCommand(buildLog, "", "cp", "-a", rootDir, copyRoot); err != nil {
+		return err
+	}
+	for index := len(directoriesToSkip) - 1; index >= 0; index-- {
+		relativePath, err := filepath.Rel(rootDir, directoriesToSkip[index])
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(filepath.Join(copyRoot, relativePath)); err != nil {
+			return err
+		}
+	}
+	size, err := computeTreeSize(copyRoot)
+	if err != nil {
+		return err
+	}
+	if size > cache.maxBytes {
+		fmt.Fprintf(buildLog,
+			"Not caching stage: %s, size: %s exceeds build cache size\n",
+			stageName, format.FormatBytes(size))
+		return nil
+	}
+	info := buildCacheInfoType{
+		CreatedOn: time.Now(),
+		Size:      size,
+		Stage:     stageName,
+	}
+	err = json.WriteToFile(filepath.Join(tmpDir, buildCacheInfoFile),
+		fsutil.PublicFilePerms, "    ", info)
+	if err != nil {
+		return err
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if _, ok := cache.entries[key]; ok {
+		return nil // Another build saved the same stage.
+	}
+	if err := os.Rename(tmpDir, filepath.Join(cache.directory, key)); err != nil {
+		return err
+	}
+	doCleanup = false
+	cache.entries[key] = &buildCacheEntryType{
+		key:      key,
+		info:     info,
+		lastUsed: info.CreatedOn,
+	}
+	cache.totalBytes += size
+	cache.evict()
+	fmt.Fprintf(buildLog, "Saved stage: %s in build cache (%s) in %s\n",
+		stageName, format.FormatBytes(size),
+		format.Duration(time.Since(startTime)))
+	return nil
+}
+
+func (cache *buildCacheType) writeHtml(writer io.Writer) {
+	cache.lock.Lock()
+	numEntries := len(cache.entries)
+	totalBytes := cache.totalBytes
+	cache.lock.Unlock()
+	fmt.Fprintf(writer, "Build cache: %d entries using %s of %s<br>\n",
+		numEntries, format.FormatBytes(totalBytes),
+		format.FormatBytes(cache.maxBytes))
+}
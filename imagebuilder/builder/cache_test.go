@@ -0,0 +1,204 @@
+package builder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+type testEnvironment map[string]string
+
+var testBuildStages = []buildStageType{
+	{name: "packages", inputs: []string{"package-list"}},
+	{name: "files", inputs: []string{"files"}},
+	{name: "scripts", inputs: []string{"scripts"}},
+}
+
+func (env testEnvironment) getenv() map[string]string {
+	return env
+}
+
+func makeTestCacheEntry(t *testing.T, directory, key string, size uint64,
+	createdOn, lastUsed time.Time) {
+	dirname := filepath.Join(directory, key)
+	if err := os.MkdirAll(filepath.Join(dirname, "root"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := json.WriteToFile(filepath.Join(dirname, buildCacheInfoFile), 0644,
+		"    ", buildCacheInfoType{CreatedOn: createdOn, Size: size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dirname, lastUsed, lastUsed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeTestKeys(t *testing.T, sourceImage, manifestDir string,
+	env testEnvironment) []string {
+	keys, err := makeBuildStageKeys(sourceImage, manifestDir, nil, env,
+		testBuildStages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(testBuildStages) {
+		t.Fatalf("expected %d keys, got: %d", len(testBuildStages), len(keys))
+	}
+	return keys
+}
+
+func writeTestFile(t *testing.T, filename, data string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildCacheEviction(t *testing.T) {
+	directory := t.TempDir()
+	now := time.Now()
+	makeTestCacheEntry(t, directory, "expired", 1, now.Add(-48*time.Hour),
+		now)
+	makeTestCacheEntry(t, directory, "old", 100, now, now.Add(-3*time.Hour))
+	makeTestCacheEntry(t, directory, "middle", 100, now,
+		now.Add(-2*time.Hour))
+	makeTestCacheEntry(t, directory, "new", 100, now, now.Add(-time.Hour))
+	if err := os.Mkdir(filepath.Join(directory, "new.123"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := newBuildCache(directory, 250, 24*time.Hour,
+		testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"expired", "old", "new.123"} {
+		if _, err := os.Stat(filepath.Join(directory, key)); err == nil {
+			t.Errorf("%s not removed", key)
+		}
+	}
+	if len(cache.entries) != 2 || cache.totalBytes != 200 {
+		t.Fatalf("entries: %d, totalBytes: %d",
+			len(cache.entries), cache.totalBytes)
+	}
+	// Using "middle" makes "new" the least recently used entry. Entries which
+	// are in use are not evicted.
+	entry := cache.get("middle")
+	if entry == nil {
+		t.Fatal("middle missing")
+	}
+	cache.lock.Lock()
+	cache.maxBytes = 50
+	cache.evict()
+	cache.lock.Unlock()
+	if cache.get("new") != nil {
+		t.Error("new not evicted")
+	}
+	if _, ok := cache.entries["middle"]; !ok {
+		t.Fatal("in use entry evicted")
+	}
+	cache.put(entry)
+	if len(cache.entries) != 0 || cache.totalBytes != 0 {
+		t.Errorf("entries: %d, totalBytes: %d after release",
+			len(cache.entries), cache.totalBytes)
+	}
+}
+
+func TestBuildCacheSaveRestore(t *testing.T) {
+	cache, err := newBuildCache(t.TempDir(), 1<<30, 0, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootDir := filepath.Join(t.TempDir(), "root")
+	writeTestFile(t, filepath.Join(rootDir, "etc", "hostname"), "builder")
+	mountPoint := filepath.Join(rootDir, "proc")
+	if err := os.Mkdir(mountPoint, 0755); err != nil {
+		t.Fatal(err)
+	}
+	buildLog := &bytes.Buffer{}
+	if found, err := cache.restore("key", "files", rootDir,
+		buildLog); err != nil {
+		t.Fatal(err)
+	} else if found {
+		t.Fatal("restored missing entry")
+	}
+	err = cache.save("key", "files", rootDir, []string{mountPoint}, buildLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(rootDir); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := cache.restore("key", "files", rootDir,
+		buildLog); err != nil {
+		t.Fatal(err)
+	} else if !found {
+		t.Fatal("entry not restored")
+	}
+	data, err := os.ReadFile(filepath.Join(rootDir, "etc", "hostname"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "builder" {
+		t.Errorf("restored: %s", string(data))
+	}
+	if _, err := os.Stat(mountPoint); err == nil {
+		t.Error("skipped directory restored")
+	}
+}
+
+func TestBuildStageKeys(t *testing.T) {
+	manifestDir := t.TempDir()
+	writeTestFile(t, filepath.Join(manifestDir, "package-list"), "bash\n")
+	writeTestFile(t, filepath.Join(manifestDir, "files", "etc", "motd"), "hi")
+	env := testEnvironment{"PROXY": "proxy:3128"}
+	keys := makeTestKeys(t, "base/image:1", manifestDir, env)
+	if keys[0] == keys[1] || keys[1] == keys[2] {
+		t.Fatalf("duplicate stage keys: %v", keys)
+	}
+	if newKeys := makeTestKeys(t, "base/image:1", manifestDir,
+		env); !reflect.DeepEqual(newKeys, keys) {
+		t.Fatalf("unstable keys: %v != %v", newKeys, keys)
+	}
+	tests := []struct {
+		name         string
+		change       func() (string, testEnvironment)
+		numUnchanged int
+	}{
+		{"source image", func() (string, testEnvironment) {
+			return "base/image:2", env
+		}, 0},
+		{"environment", func() (string, testEnvironment) {
+			return "base/image:1", testEnvironment{"PROXY": "proxy:8080"}
+		}, 0},
+		{"input file", func() (string, testEnvironment) {
+			writeTestFile(t, filepath.Join(manifestDir, "files", "etc", "motd"),
+				"hello")
+			return "base/image:1", env
+		}, 1},
+		{"added input", func() (string, testEnvironment) {
+			writeTestFile(t, filepath.Join(manifestDir, "scripts", "10-run"),
+				"true")
+			return "base/image:1", env
+		}, 2},
+	}
+	for _, test := range tests {
+		sourceImage, newEnv := test.change()
+		newKeys := makeTestKeys(t, sourceImage, manifestDir, newEnv)
+		for index := range keys {
+			if changed := newKeys[index] != keys[index]; changed !=
+				(index >= test.numUnchanged) {
+				t.Errorf("%s: stage: %s changed: %t",
+					test.name, testBuildStages[index].name, changed)
+			}
+		}
+		keys = makeTestKeys(t, "base/image:1", manifestDir, env)
+	}
+}
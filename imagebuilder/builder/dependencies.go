@@ -0,0 +1,446 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+type dependencyGraphType struct {
+	buildOrder   []string            // Topologically sorted stream names.
+	cycles       [][]string          // Upstream first.
+	dependents   map[string][]string // Key: source stream name.
+	lastUpdate   time.Time
+	sourceImages map[string]string   // Key: stream name.
+	unbuildable  map[string]struct{} // Streams in or downstream of cycles.
+}
+
+type manifestInfoType struct {
+	commitId    string
+	sourceImage string
+}
+
+// computeDependencyGraph computes the dependency graph for the specified
+// streams from their source images. Streams with a source image which is not
+// one of the specified streams (such as bootstrap streams, which have no source
+// image) are the roots of the graph.
+func computeDependencyGraph(
+	sourceImages map[string]string) *dependencyGraphType {
+	graph := &dependencyGraphType{
+		dependents:   make(map[string][]string),
+		lastUpdate:   time.Now(),
+		sourceImages: sourceImages,
+		unbuildable:  make(map[string]struct{}),
+	}
+	streamNames := make([]string, 0, len(sourceImages))
+	for streamName := range sourceImages {
+		streamNames = append(streamNames, streamName)
+	}
+	sort.Strings(streamNames)
+	var ready []string
+	for _, streamName := range streamNames {
+		sourceImage := sourceImages[streamName]
+		if sourceImage == "" {
+			ready = append(ready, streamName)
+			continue
+		}
+		graph.dependents[sourceImage] = append(graph.dependents[sourceImage],
+			streamName)
+		if _, ok := sourceImages[sourceImage]; !ok {
+			ready = append(ready, streamName)
+		}
+	}
+	// Each stream has at most one source, so a breadth-first walk from the
+	// roots visits each stream which is not part of (or downstream of) a cycle
+	// exactly once, after its source.
+	visited := make(map[string]struct{}, len(sourceImages))
+	for len(ready) > 0 {
+		streamName := ready[0]
+		ready = ready[1:]
+		graph.buildOrder = append(graph.buildOrder, streamName)
+		visited[streamName] = struct{}{}
+		ready = append(ready, graph.dependents[streamName]...)
+	}
+	inCycle := make(map[string]struct{})
+	for _, streamName := range streamNames {
+		if _, ok := visited[streamName]; ok {
+			continue
+		}
+		graph.unbuildable[streamName] = struct{}{}
+		// Follow the chain of source images until it loops back on itself.
+		positions := make(map[string]int)
+		var chain []string
+		for name := streamName; ; name = sourceImages[name] {
+			if _, ok := inCycle[name]; ok {
+				break
+			}
+			if start, ok := positions[name]; ok {
+				cycle := make([]string, 0, len(chain)-start)
+				for index := len(chain) - 1; index >= start; index-- {
+					cycle = append(cycle, chain[index])
+					inCycle[chain[index]] = struct{}{}
+				}
+				graph.cycles = append(graph.cycles, cycle)
+				break
+			}
+			positions[name] = len(chain)
+			chain = append(chain, name)
+		}
+	}
+	return graph
+}
+
+func getGitCommitId(repositoryUrl, branch string) (string, error) {
+	output, err := exec.Command("git", "ls-remote", repositoryUrl,
+		"refs/heads/"+branch).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("error running git ls-remote: %s: %s",
+				err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 1 {
+		return "", errors.New("branch: " + branch + " not found")
+	}
+	return fields[0], nil
+}
+
+func readSourceImage(manifestDirectory string,
+	envGetter environmentGetter) (string, error) {
+	manifestBytes, err := ioutil.ReadFile(
+		filepath.Join(manifestDirectory, "manifest"))
+	if err != nil {
+		return "", err
+	}
+	var manifest manifestConfigType
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", err
+	}
+	return os.Expand(manifest.SourceImage, func(name string) string {
+		return envGetter.getenv()[name]
+	}), nil
+}
+
+func (b *Builder) getDependencyGraph() *dependencyGraphType {
+	b.dependencyLock.RLock()
+	defer b.dependencyLock.RUnlock()
+	return b.dependencyGraph
+}
+
+func (b *Builder) listStaleStreams(maxAge time.Duration) []string {
+	graph := b.getDependencyGraph()
+	if graph == nil {
+		return nil
+	}
+	var staleStreams []string
+	b.buildResultsLock.RLock()
+	defer b.buildResultsLock.RUnlock()
+	for _, streamName := range graph.buildOrder {
+		if _, ok := b.currentBuildLogs[streamName]; ok {
+			continue
+		}
+		result, ok := b.lastBuildResults[streamName]
+		if !ok || time.Since(result.startTime) >= maxAge {
+			staleStreams = append(staleStreams, streamName)
+		}
+	}
+	return staleStreams
+}
+
+// queueRebuilds adds the specified streams to the rebuild queue. Streams which
+// are not automatically rebuilt or which are part of a dependency cycle are
+// ignored.
+func (b *Builder) queueRebuilds(streamNames []string, reason string) {
+	if len(streamNames) < 1 {
+		return
+	}
+	b.dependencyLock.Lock()
+	defer b.dependencyLock.Unlock()
+	graph := b.dependencyGraph
+	if graph == nil {
+		return
+	}
+	numQueued := 0
+	for _, streamName := range streamNames {
+		if _, ok := graph.sourceImages[streamName]; !ok {
+			continue
+		}
+		if _, ok := graph.unbuildable[streamName]; ok {
+			b.logger.Printf(
+				"Not rebuilding stream: %s which depends on a cycle\n",
+				streamName)
+			continue
+		}
+		if _, ok := b.rebuildQueue[streamName]; ok {
+			continue
+		}
+		b.rebuildQueue[streamName] = struct{}{}
+		b.logger.Printf("Queued rebuild of stream: %s (%s)\n",
+			streamName, reason)
+		numQueued++
+	}
+	if numQueued > 0 {
+		select {
+		case b.rebuildQueueWakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// getNextRebuild removes and returns the first queued stream in build order.
+func (b *Builder) getNextRebuild() string {
+	b.dependencyLock.Lock()
+	defer b.dependencyLock.Unlock()
+	if len(b.rebuildQueue) < 1 {
+		return ""
+	}
+	for _, streamName := range b.dependencyGraph.buildOrder {
+		if _, ok := b.rebuildQueue[streamName]; ok {
+			delete(b.rebuildQueue, streamName)
+			return streamName
+		}
+	}
+	// Whatever remains is no longer buildable with the current graph.
+	b.rebuildQueue = make(map[string]struct{})
+	return ""
+}
+
+func (b *Builder) rebuildImages(minInterval time.Duration) {
+	if minInterval < 1 {
+		return
+	}
+	checkInterval := b.manifestCheckInterval
+	if checkInterval > minInterval {
+		checkInterval = minInterval
+	}
+	go b.rebuildQueueLoop(minInterval)
+	go b.watchImageServerLoop()
+	var sleepUntil time.Time
+	for ; ; time.Sleep(time.Until(sleepUntil)) {
+		sleepUntil = time.Now().Add(checkInterval)
+		b.queueRebuilds(b.updateDependencyGraph(), "manifest changed")
+		b.queueRebuilds(b.listStaleStreams(minInterval), "periodic rebuild")
+	}
+}
+
+func (b *Builder) rebuildQueueLoop(minInterval time.Duration) {
+	for range b.rebuildQueueWakeup {
+		for {
+			streamName := b.getNextRebuild()
+			if streamName == "" {
+				break
+			}
+			_, _, err := b.queueAndBuild(proto.BuildImageRequest{
+				StreamName: streamName,
+				ExpiresIn:  minInterval * 2,
+			},
+				nil, nil, buildPriorityAuto)
+			if err != nil {
+				b.logger.Printf("Error building image: %s: %s\n",
+					streamName, err)
+			}
+		}
+	}
+}
+
+// updateDependencyGraph recomputes the dependency graph for the bootstrap
+// streams and the streams to automatically rebuild. It returns the names of
+// the streams whose manifest has changed since the previous call.
+func (b *Builder) updateDependencyGraph() []string {
+	sourceImages := make(map[string]string)
+	for _, streamName := range b.listBootstrapStreamNames() {
+		sourceImages[streamName] = ""
+	}
+	var changedStreams []string
+	for _, streamName := range b.listStreamsToAutoRebuild() {
+		if _, ok := sourceImages[streamName]; ok {
+			continue
+		}
+		stream := b.getNormalStream(streamName)
+		if stream == nil {
+			continue
+		}
+		oldInfo, haveOldInfo := b.manifestInfo[streamName]
+		info, err := stream.getManifestInfo(b, oldInfo)
+		if err != nil {
+			b.logger.Printf("Error getting manifest for stream: %s: %s\n",
+				streamName, err)
+			if haveOldInfo {
+				sourceImages[streamName] = oldInfo.sourceImage
+			}
+			continue
+		}
+		if haveOldInfo && info.commitId != oldInfo.commitId {
+			b.logger.Printf("Manifest for stream: %s changed: %s -> %s\n",
+				streamName, oldInfo.commitId, info.commitId)
+			changedStreams = append(changedStreams, streamName)
+		}
+		b.manifestInfo[streamName] = info
+		sourceImages[streamName] = info.sourceImage
+	}
+	graph := computeDependencyGraph(sourceImages)
+	for _, cycle := range graph.cycles {
+		b.logger.Printf("Dependency cycle between streams: %s\n",
+			strings.Join(cycle, " -> "))
+	}
+	b.dependencyLock.Lock()
+	b.dependencyGraph = graph
+	b.dependencyLock.Unlock()
+	return changedStreams
+}
+
+func (b *Builder) watchImageServer() error {
+	client, err := srpc.DialHTTP("tcp", b.imageServerAddress, 0)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	conn, err := client.Call("ImageServer.GetImageUpdates")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	initialListReceived := false
+	for {
+		var imageUpdate imageserver.ImageUpdate
+		if err := conn.Decode(&imageUpdate); err != nil {
+			return err
+		}
+		if imageUpdate.Operation != imageserver.OperationAddImage {
+			continue
+		}
+		if imageUpdate.Name == "" {
+			initialListReceived = true
+			continue
+		}
+		if !initialListReceived {
+			continue
+		}
+		streamName := path.Dir(imageUpdate.Name)
+		if graph := b.getDependencyGraph(); graph != nil {
+			b.queueRebuilds(graph.dependents[streamName],
+				"new source image: "+imageUpdate.Name)
+		}
+	}
+}
+
+func (b *Builder) watchImageServerLoop() {
+	for ; ; time.Sleep(time.Second * 15) {
+		if err := b.watchImageServer(); err != nil {
+			if err == io.EOF {
+				b.logger.Printf("Connection to: %s closed\n",
+					b.imageServerAddress)
+			} else {
+				b.logger.Printf("Error watching for new images: %s: %s\n",
+					b.imageServerAddress, err)
+			}
+		}
+	}
+}
+
+func (b *Builder) writeDependencyGraphHtml(writer io.Writer) {
+	graph := b.getDependencyGraph()
+	if graph == nil {
+		return
+	}
+	b.dependencyLock.RLock()
+	queued := make(map[string]struct{}, len(b.rebuildQueue))
+	for streamName := range b.rebuildQueue {
+		queued[streamName] = struct{}{}
+	}
+	b.dependencyLock.RUnlock()
+	fmt.Fprintf(writer,
+		"Automatic rebuild dependencies (computed %s ago):<br>\n",
+		format.Duration(time.Since(graph.lastUpdate)))
+	fmt.Fprintf(writer, "<pre style=\"%s\">\n", codeStyle)
+	var writeStream func(streamName string, depth int)
+	writeStream = func(streamName string, depth int) {
+		fmt.Fprintf(writer, "%s<a href=\"showImageStream?%s\">%s</a>",
+			strings.Repeat("  ", depth), streamName, streamName)
+		if _, ok := queued[streamName]; ok {
+			fmt.Fprint(writer, " (queued)")
+		}
+		fmt.Fprintln(writer)
+		for _, dependent := range graph.dependents[streamName] {
+			writeStream(dependent, depth+1)
+		}
+	}
+	var externalSources []string
+	for _, streamName := range graph.buildOrder {
+		sourceImage := graph.sourceImages[streamName]
+		if sourceImage == "" {
+			writeStream(streamName, 0)
+		} else if _, ok := graph.sourceImages[sourceImage]; !ok {
+			externalSources = append(externalSources, sourceImage)
+		}
+	}
+	sort.Strings(externalSources)
+	for index, sourceImage := range externalSources {
+		if index > 0 && sourceImage == externalSources[index-1] {
+			continue
+		}
+		fmt.Fprintf(writer, "<a href=\"showImageStream?%s\">%s</a>\n",
+			sourceImage, sourceImage)
+		for _, dependent := range graph.dependents[sourceImage] {
+			writeStream(dependent, 1)
+		}
+	}
+	fmt.Fprintln(writer, "</pre><p style=\"clear: both;\">")
+	for _, cycle := range graph.cycles {
+		fmt.Fprintf(writer,
+			"<font color=\"red\">Dependency cycle (not rebuilt): %s</font><br>\n",
+			strings.Join(cycle, " -> "))
+	}
+}
+
+func (stream *imageStreamType) getManifestInfo(b *Builder,
+	oldInfo manifestInfoType) (manifestInfoType, error) {
+	variableFunc := b.getVariableFunc(stream.getenv(), nil)
+	manifestUrl := os.Expand(stream.ManifestUrl, variableFunc)
+	if parsedUrl, err := url.Parse(manifestUrl); err == nil {
+		if parsedUrl.Scheme == "dir" {
+			sourceImage, err := readSourceImage(filepath.Join(parsedUrl.Path,
+				os.Expand(stream.ManifestDirectory, variableFunc)), stream)
+			if err != nil {
+				return manifestInfoType{}, err
+			}
+			return manifestInfoType{sourceImage: sourceImage}, nil
+		}
+	}
+	gitBranch := stream.getGitBranch()
+	commitId, err := getGitCommitId(manifestUrl, gitBranch)
+	if err != nil {
+		return manifestInfoType{}, err
+	}
+	if commitId == oldInfo.commitId {
+		return oldInfo, nil
+	}
+	manifestDirectory, _, err := stream.getManifest(b, stream.name, gitBranch,
+		"", nil, ioutil.Discard)
+	if err != nil {
+		return manifestInfoType{}, err
+	}
+	defer os.RemoveAll(manifestDirectory)
+	sourceImage, err := readSourceImage(manifestDirectory, stream)
+	if err != nil {
+		return manifestInfoType{}, err
+	}
+	return manifestInfoType{commitId: commitId, sourceImage: sourceImage}, nil
+}
@@ -0,0 +1,84 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeDependencyGraphBuildOrder(t *testing.T) {
+	sourceImages := map[string]string{
+		"app":              "base",
+		"base":             "bootstrap/debian",
+		"bootstrap/debian": "",
+		"external":         "other/image", // Not a managed stream.
+		"web":              "base",
+	}
+	graph := computeDependencyGraph(sourceImages)
+	expectedOrder := []string{"bootstrap/debian", "external", "base", "app",
+		"web"}
+	if !reflect.DeepEqual(graph.buildOrder, expectedOrder) {
+		t.Errorf("build order: %v != %v", graph.buildOrder, expectedOrder)
+	}
+	// Every stream must be built after its source.
+	positions := make(map[string]int, len(graph.buildOrder))
+	for index, streamName := range graph.buildOrder {
+		positions[streamName] = index
+	}
+	for streamName, sourceImage := range sourceImages {
+		if sourcePosition, ok := positions[sourceImage]; ok &&
+			sourcePosition > positions[streamName] {
+			t.Errorf("%s built before its source: %s", streamName, sourceImage)
+		}
+	}
+	if len(graph.cycles) != 0 || len(graph.unbuildable) != 0 {
+		t.Errorf("unexpected cycles: %v, unbuildable: %v",
+			graph.cycles, graph.unbuildable)
+	}
+	expectedDependents := map[string][]string{
+		"base":             {"app", "web"},
+		"bootstrap/debian": {"base"},
+		"other/image":      {"external"},
+	}
+	if !reflect.DeepEqual(graph.dependents, expectedDependents) {
+		t.Errorf("dependents: %v != %v", graph.dependents, expectedDependents)
+	}
+}
+
+func TestComputeDependencyGraphCycles(t *testing.T) {
+	graph := computeDependencyGraph(map[string]string{
+		"a":    "b",
+		"b":    "a",
+		"c":    "a", // Downstream of a cycle.
+		"d":    "",
+		"e":    "d",
+		"self": "self",
+	})
+	if expected := []string{"d", "e"}; !reflect.DeepEqual(graph.buildOrder,
+		expected) {
+		t.Errorf("build order: %v != %v", graph.buildOrder, expected)
+	}
+	expectedCycles := [][]string{{"b", "a"}, {"self"}}
+	if !reflect.DeepEqual(graph.cycles, expectedCycles) {
+		t.Errorf("cycles: %v != %v", graph.cycles, expectedCycles)
+	}
+	expectedUnbuildable := map[string]struct{}{
+		"a":    {},
+		"b":    {},
+		"c":    {},
+		"self": {},
+	}
+	if !reflect.DeepEqual(graph.unbuildable, expectedUnbuildable) {
+		t.Errorf("unbuildable: %v != %v",
+			graph.unbuildable, expectedUnbuildable)
+	}
+}
+
+func TestGetGitBranch(t *testing.T) {
+	if branch := (&imageStreamType{}).getGitBranch(); branch != "master" {
+		t.Errorf("default branch: %s", branch)
+	}
+	stream := &imageStreamType{GitBranch: "release"}
+	if branch := stream.getGitBranch(); branch != "release" {
+		t.Errorf("configured branch: %s", branch)
+	}
+}
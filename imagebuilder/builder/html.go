@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/format"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
 )
 
 const codeStyle = `background-color: #eee; border: 1px solid #999; display: block; float: left;`
@@ -88,6 +90,10 @@ func (b *Builder) writeHtml(writer io.Writer) {
 	fmt.Fprintf(writer,
 		"Number of image streams: <a href=\"showImageStreams\">%d</a><p>\n",
 		b.getNumNormalStreams())
+	if b.buildCache != nil {
+		b.buildCache.writeHtml(writer)
+	}
+	b.writeDependencyGraphHtml(writer)
 	currentBuilds := make([]string, 0)
 	goodBuilds := make(map[string]buildResultType)
 	failedBuilds := make(map[string]buildResultType)
@@ -117,6 +123,7 @@ func (b *Builder) writeHtml(writer io.Writer) {
 		}
 		fmt.Fprintln(writer, "</table><br>")
 	}
+	b.writeBuildQueueHtml(writer, currentTime)
 	if len(failedBuilds) > 0 {
 		streamNames := make([]string, 0, len(failedBuilds))
 		for streamName := range failedBuilds {
@@ -149,15 +156,27 @@ func (b *Builder) writeHtml(writer io.Writer) {
 		fmt.Fprintln(writer, "Successful image builds:<br>")
 		fmt.Fprintln(writer, `<table border="1">`)
 		tw, _ := html.NewTableWriter(writer, true, "Image Stream", "Name",
-			"Build log", "Duration", "Age")
+			"Build log", "Tests", "Duration", "Age")
 		for _, streamName := range streamNames {
 			result := goodBuilds[streamName]
-			tw.WriteRow("", "",
+			var tests string
+			if result.testSummary != nil {
+				tests = fmt.Sprintf(
+					"<a href=\"http://%s/listTestReport?%s\">%s</a>",
+					b.imageServerAddress, result.imageName,
+					result.testSummary)
+			}
+			background := ""
+			if result.testSummary != nil && result.testSummary.NumFailed > 0 {
+				background = "yellow"
+			}
+			tw.WriteRow("", background,
 				streamName,
 				fmt.Sprintf("<a href=\"http://%s/showImage?%s\">%s</a>",
 					b.imageServerAddress, result.imageName, result.imageName),
 				fmt.Sprintf("<a href=\"showLastBuildLog?%s\">log</a>",
 					streamName),
+				tests,
 				format.Duration(result.finishTime.Sub(result.startTime)),
 				fmt.Sprintf("%s ago",
 					format.Duration(currentTime.Sub(result.finishTime))),
@@ -167,6 +186,38 @@ func (b *Builder) writeHtml(writer io.Writer) {
 	}
 }
 
+func (b *Builder) writeBuildQueueHtml(writer io.Writer, currentTime time.Time) {
+	var queuedBuilds []proto.QueuedBuild
+	for _, build := range b.listBuildQueue() {
+		if !build.Running {
+			queuedBuilds = append(queuedBuilds, build)
+		}
+	}
+	if len(queuedBuilds) < 1 {
+		return
+	}
+	fmt.Fprintln(writer, "Queued image builds:<br>")
+	fmt.Fprintln(writer, `<table border="1">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"ID", "Image Stream", "Branch", "Requested By", "Waiters", "Queued")
+	for _, build := range queuedBuilds {
+		requestedBy := build.Username
+		if !build.Interactive {
+			requestedBy = "(auto rebuild)"
+		}
+		tw.WriteRow("", "",
+			strconv.FormatUint(build.Id, 10),
+			build.StreamName,
+			build.GitBranch,
+			requestedBy,
+			strconv.FormatUint(uint64(build.NumWaiters), 10),
+			fmt.Sprintf("%s ago",
+				format.Duration(currentTime.Sub(build.QueuedAt))),
+		)
+	}
+	fmt.Fprintln(writer, "</table><br>")
+}
+
 func (stream *imageStreamType) WriteHtml(writer io.Writer) {
 	if len(stream.BuilderGroups) > 0 {
 		fmt.Fprintf(writer, "BuilderGroups: %s<br>\n",
@@ -176,9 +227,20 @@ func (stream *imageStreamType) WriteHtml(writer io.Writer) {
 		stream.ManifestUrl)
 	fmt.Fprintf(writer, "Manifest Directory: <code>%s</code><br>\n",
 		stream.ManifestDirectory)
+	if policy := stream.TestPolicy; policy != nil {
+		fmt.Fprintf(writer, "Test timeout: %s<br>\n",
+			format.Duration(policy.getTimeout()))
+		if len(policy.AllowedFailures) > 0 {
+			fmt.Fprintf(writer, "Tests allowed to fail: <code>%s</code><br>\n",
+				strings.Join(policy.AllowedFailures, " "))
+		}
+		if policy.MarkFailures {
+			fmt.Fprintln(writer, "Images which fail tests are uploaded<br>")
+		}
+	}
 	buildLog := new(bytes.Buffer)
 	manifestDirectory, gitInfo, err := stream.getManifest(stream.builder,
-		stream.name, "", nil, buildLog)
+		stream.name, "", "", nil, buildLog)
 	if err != nil {
 		fmt.Fprintf(writer, "<b>%s</b><br>\n", err)
 		return
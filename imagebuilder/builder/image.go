@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
@@ -20,6 +21,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
 	"github.com/Cloud-Foundations/Dominator/lib/image"
 	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/provenance"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/lib/triggers"
 	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
@@ -34,13 +36,14 @@ func (stream *imageStreamType) build(b *Builder, client *srpc.Client,
 	request proto.BuildImageRequest, buildLog buildLogger) (
 	*image.Image, error) {
 	manifestDirectory, gitInfo, err := stream.getManifest(b, request.StreamName,
-		request.GitBranch, request.Variables, buildLog)
+		request.GitBranch, request.GitCommitId, request.Variables, buildLog)
 	if err != nil {
 		return nil, err
 	}
 	defer os.RemoveAll(manifestDirectory)
-	img, err := buildImageFromManifest(client, manifestDirectory, request,
-		b.bindMounts, stream, gitInfo, buildLog)
+	img, err := buildImageFromManifest(client, b.buildCache,
+		manifestDirectory, stream.getManifestLocation(), request,
+		b.bindMounts, stream, gitInfo, stream.TestPolicy, buildLog)
 	if err != nil {
 		return nil, err
 	}
@@ -56,11 +59,8 @@ func (stream *imageStreamType) getenv() map[string]string {
 }
 
 func (stream *imageStreamType) getManifest(b *Builder, streamName string,
-	gitBranch string, variables map[string]string,
+	gitBranch, gitCommitId string, variables map[string]string,
 	buildLog io.Writer) (string, *gitInfoType, error) {
-	if gitBranch == "" {
-		gitBranch = "master"
-	}
 	variableFunc := b.getVariableFunc(stream.getenv(), variables)
 	manifestRoot, err := makeTempDirectory("",
 		strings.Replace(streamName, "/", "_", -1)+".manifest")
@@ -81,10 +81,15 @@ func (stream *imageStreamType) getManifest(b *Builder, streamName string,
 				return "", nil, fmt.Errorf("missing leading slash: %s",
 					parsedUrl.Path)
 			}
-			if gitBranch != "master" {
+			if gitBranch != "" && gitBranch != "master" {
 				return "", nil,
 					fmt.Errorf("branch: %s is not master", gitBranch)
 			}
+			if gitCommitId != "" {
+				return "", nil,
+					fmt.Errorf("cannot build commit: %s from directory",
+						gitCommitId)
+			}
 			sourceTree := filepath.Join(parsedUrl.Path, manifestDirectory)
 			fmt.Fprintf(buildLog, "Copying manifest tree: %s\n", sourceTree)
 			if err := fsutil.CopyTree(manifestRoot, sourceTree); err != nil {
@@ -94,6 +99,9 @@ func (stream *imageStreamType) getManifest(b *Builder, streamName string,
 			return manifestRoot, nil, nil
 		}
 	}
+	if gitBranch == "" {
+		gitBranch = stream.getGitBranch()
+	}
 	fmt.Fprintf(buildLog, "Cloning repository: %s branch: %s\n",
 		stream.ManifestUrl, gitBranch)
 	err = runCommand(buildLog, "", "git", "init", manifestRoot)
@@ -132,6 +140,21 @@ func (stream *imageStreamType) getManifest(b *Builder, streamName string,
 			return "", nil, err
 		}
 	}
+	if gitCommitId != "" {
+		// The commit is usually the tip of the branch, which was just pulled.
+		if !gitHasCommit(manifestRoot, gitCommitId) {
+			err = runCommand(buildLog, manifestRoot, "git", "fetch",
+				"--depth=1", "origin", gitCommitId)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		err = runCommand(buildLog, manifestRoot, "git", "reset", "--hard",
+			gitCommitId)
+		if err != nil {
+			return "", nil, err
+		}
+	}
 	loadTime := time.Since(startTime)
 	repoSize, err := getTreeSize(manifestRoot)
 	if err != nil {
@@ -197,6 +220,24 @@ func (stream *imageStreamType) getManifest(b *Builder, streamName string,
 	return manifestRoot, gitInfo, nil
 }
 
+// getGitBranch returns the branch to build from: the configured branch for the
+// stream, else master.
+func (stream *imageStreamType) getGitBranch() string {
+	if stream.GitBranch != "" {
+		return stream.GitBranch
+	}
+	return "master"
+}
+
+// getManifestLocation returns the unexpanded URL and directory of the
+// manifest, suitable for recording in build provenance.
+func (stream *imageStreamType) getManifestLocation() string {
+	if stream.ManifestDirectory == "" {
+		return stream.ManifestUrl
+	}
+	return stream.ManifestUrl + "#" + stream.ManifestDirectory
+}
+
 func getTreeSize(dirname string) (uint64, error) {
 	var size uint64
 	err := filepath.Walk(dirname,
@@ -226,18 +267,27 @@ func listDirectory(directoryName string) ([]string, error) {
 	return filenames, nil
 }
 
+// gitHasCommit returns true if the commit is present in the repository.
+func gitHasCommit(repositoryDirectory, commitId string) bool {
+	cmd := exec.Command("git", "cat-file", "-e", commitId+"^{commit}")
+	cmd.Dir = repositoryDirectory
+	return cmd.Run() == nil
+}
+
 func runCommand(buildLog io.Writer, cwd string, args ...string) error {
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = cwd
 	cmd.Stdout = buildLog
 	cmd.Stderr = buildLog
-	return cmd.Run()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return runCancellableCommand(cmd, buildLog)
 }
 
-func buildImageFromManifest(client *srpc.Client, manifestDir string,
-	request proto.BuildImageRequest, bindMounts []string,
-	envGetter environmentGetter, gitInfo *gitInfoType,
-	buildLog buildLogger) (*image.Image, error) {
+func buildImageFromManifest(client *srpc.Client, cache *buildCacheType,
+	manifestDir, manifestUrl string, request proto.BuildImageRequest,
+	bindMounts []string, envGetter environmentGetter, gitInfo *gitInfoType,
+	testPolicy *testPolicyType, buildLog buildLogger) (*image.Image, error) {
+	startTime := time.Now()
 	// First load all the various manifest files (fail early on error).
 	computedFilesList, addComputedFiles, err := loadComputedFiles(manifestDir)
 	if err != nil {
@@ -258,8 +308,8 @@ func buildImageFromManifest(client *srpc.Client, manifestDir string,
 	}
 	defer os.RemoveAll(rootDir)
 	fmt.Fprintf(buildLog, "Created image working directory: %s\n", rootDir)
-	manifest, err := unpackImageAndProcessManifest(client, manifestDir,
-		rootDir, bindMounts, false, envGetter, buildLog)
+	manifest, err := unpackImageAndProcessManifest(client, cache,
+		manifestDir, rootDir, bindMounts, false, envGetter, buildLog)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +342,7 @@ func buildImageFromManifest(client *srpc.Client, manifestDir string,
 		imageTriggers = mergeableTriggers.ExportTriggers()
 	}
 	img, err := packImage(client, request, rootDir, manifest.filter,
-		computedFilesList, imageFilter, imageTriggers, buildLog)
+		computedFilesList, imageFilter, imageTriggers, testPolicy, buildLog)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +350,11 @@ func buildImageFromManifest(client *srpc.Client, manifestDir string,
 		img.BuildBranch = gitInfo.branch
 		img.BuildCommitId = gitInfo.commitId
 	}
+	err = attachProvenance(client, img, request, manifestDir, manifestUrl,
+		gitInfo, manifest.sourceImageInfo, startTime, buildLog)
+	if err != nil {
+		return nil, fmt.Errorf("error attaching provenance: %s", err)
+	}
 	return img, nil
 }
 
@@ -307,8 +362,8 @@ func buildImageFromManifestAndUpload(client *srpc.Client, manifestDir string,
 	request proto.BuildImageRequest, bindMounts []string,
 	envGetter environmentGetter,
 	buildLog buildLogger) (*image.Image, string, error) {
-	img, err := buildImageFromManifest(client, manifestDir, request, bindMounts,
-		envGetter, nil, buildLog)
+	img, err := buildImageFromManifest(client, nil, manifestDir, "", request,
+		bindMounts, envGetter, nil, nil, buildLog)
 	if err != nil {
 		return nil, "", err
 	}
@@ -326,7 +381,7 @@ func buildTreeFromManifest(client *srpc.Client, manifestDir string,
 	if err != nil {
 		return "", err
 	}
-	_, err = unpackImageAndProcessManifest(client, manifestDir, rootDir,
+	_, err = unpackImageAndProcessManifest(client, nil, manifestDir, rootDir,
 		bindMounts, true, envGetter, buildLog)
 	if err != nil {
 		os.RemoveAll(rootDir)
@@ -426,30 +481,42 @@ func loadTriggers(manifestDir string) (*triggers.Triggers, bool, error) {
 	}
 }
 
-func unpackImage(client *srpc.Client, streamName string,
-	maxSourceAge, expiresIn time.Duration, rootDir string,
-	buildLog io.Writer) (*sourceImageInfoType, error) {
+func getSourceImage(client *srpc.Client, streamName string,
+	maxSourceAge time.Duration, buildLog io.Writer) (
+	string, *image.Image, error) {
 	imageName, sourceImage, err := getLatestImage(client, streamName, buildLog)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	if sourceImage == nil {
-		return nil, errors.New(errNoSourceImage + streamName)
+		return "", nil, errors.New(errNoSourceImage + streamName)
 	}
 	if maxSourceAge > 0 && time.Since(sourceImage.CreatedOn) > maxSourceAge {
-		return nil, errors.New(errNoSourceImage + streamName)
+		return "", nil, errors.New(errNoSourceImage + streamName)
+	}
+	return imageName, sourceImage, nil
+}
+
+func makeSourceImageInfo(imageName string,
+	sourceImage *image.Image) *sourceImageInfoType {
+	return &sourceImageInfoType{
+		computedFiles:    listComputedFiles(sourceImage.FileSystem),
+		fileSystemDigest: provenance.DigestFileSystem(sourceImage.FileSystem),
+		filter:           sourceImage.Filter,
+		imageName:        imageName,
+		triggers:         sourceImage.Triggers,
 	}
+}
+
+func unpackSourceImage(client *srpc.Client, imageName string,
+	sourceImage *image.Image, rootDir string, buildLog io.Writer) error {
 	objClient := objectclient.AttachObjectClient(client)
 	defer objClient.Close()
-	err = util.Unpack(sourceImage.FileSystem, objClient, rootDir,
+	err := util.Unpack(sourceImage.FileSystem, objClient, rootDir,
 		stdlog.New(buildLog, "", 0))
 	if err != nil {
-		return nil, err
+		return err
 	}
 	fmt.Fprintf(buildLog, "Source image: %s\n", imageName)
-	return &sourceImageInfoType{
-		listComputedFiles(sourceImage.FileSystem),
-		sourceImage.Filter,
-		sourceImage.Triggers,
-	}, nil
+	return nil
 }
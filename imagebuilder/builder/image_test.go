@@ -0,0 +1,39 @@
+package builder
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func runTestGit(t *testing.T, dirname string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test",
+		"-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dirname
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestGitHasCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dirname := t.TempDir()
+	runTestGit(t, dirname, "init", "-q")
+	runTestGit(t, dirname, "commit", "-q", "--allow-empty", "-m", "first")
+	commitId := runTestGit(t, dirname, "rev-parse", "HEAD")
+	if !gitHasCommit(dirname, commitId) {
+		t.Errorf("commit: %s not found", commitId)
+	}
+	if gitHasCommit(dirname, zeroCommitId) {
+		t.Error("missing commit found")
+	}
+	// Trees and blobs are not commits.
+	treeId := runTestGit(t, dirname, "rev-parse", "HEAD^{tree}")
+	if gitHasCommit(dirname, treeId) {
+		t.Errorf("tree: %s found as a commit", treeId)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -60,19 +61,56 @@ func load(confUrl, variablesFile, stateDir, imageServerAddress string,
 	if variables == nil {
 		variables = make(map[string]string)
 	}
+	manifestCheckInterval := 5 * time.Minute
+	if masterConfiguration.ManifestCheckInterval > 0 {
+		manifestCheckInterval = time.Second * time.Duration(
+			masterConfiguration.ManifestCheckInterval)
+	}
+	var buildCache *buildCacheType
+	if masterConfiguration.BuildCacheMaxBytes > 0 {
+		maxAge := 24 * time.Hour
+		if masterConfiguration.BuildCacheMaxAge > 0 {
+			maxAge = time.Second * time.Duration(
+				masterConfiguration.BuildCacheMaxAge)
+		}
+		buildCache, err = newBuildCache(filepath.Join(stateDir, "build-cache"),
+			masterConfiguration.BuildCacheMaxBytes, maxAge, logger)
+		if err != nil {
+			return nil, fmt.Errorf("error loading build cache: %s", err)
+		}
+	}
+	webhookImageExpiration, err := getWebhookImageExpiration(
+		masterConfiguration.WebhookImageExpiration)
+	if err != nil {
+		return nil, err
+	}
+	webhookSecret := os.Expand(masterConfiguration.WebhookSecret,
+		func(name string) string { return variables[name] })
 	b := &Builder{
 		bindMounts:                masterConfiguration.BindMounts,
+		buildCache:                buildCache,
 		stateDir:                  stateDir,
 		imageServerAddress:        imageServerAddress,
 		logger:                    logger,
 		imageStreamsUrl:           masterConfiguration.ImageStreamsUrl,
 		bootstrapStreams:          masterConfiguration.BootstrapStreams,
 		imageStreamsToAutoRebuild: imageStreamsToAutoRebuild,
+		imageRebuildInterval:      imageRebuildInterval,
 		slaveDriver:               slaveDriver,
+		manifestCheckInterval:     manifestCheckInterval,
+		manifestInfo:              make(map[string]manifestInfoType),
+		rebuildQueue:              make(map[string]struct{}),
+		rebuildQueueWakeup:        make(chan struct{}, 1),
+		maxConcurrentBuilds:       masterConfiguration.MaxConcurrentBuilds,
+		maxConcurrentGroupBuilds:  masterConfiguration.MaxConcurrentGroupBuilds,
+		numRunningGroupBuilds:     make(map[string]uint),
+		runningBuilds:             make(map[uint64]*queuedBuildType),
 		currentBuildLogs:          make(map[string]*bytes.Buffer),
 		lastBuildResults:          make(map[string]buildResultType),
 		packagerTypes:             masterConfiguration.PackagerTypes,
 		variables:                 variables,
+		webhookImageExpiration:    webhookImageExpiration,
+		webhookSecret:             webhookSecret,
 	}
 	for name, stream := range b.bootstrapStreams {
 		stream.builder = b
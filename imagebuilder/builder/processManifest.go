@@ -88,9 +88,10 @@ func makeMountPoints(rootDir string, bindMounts []string,
 	return retval, nil
 }
 
-func unpackImageAndProcessManifest(client *srpc.Client, manifestDir string,
-	rootDir string, bindMounts []string, applyFilter bool,
-	envGetter environmentGetter, buildLog io.Writer) (manifestType, error) {
+func unpackImageAndProcessManifest(client *srpc.Client,
+	cache *buildCacheType, manifestDir string, rootDir string,
+	bindMounts []string, applyFilter bool, envGetter environmentGetter,
+	buildLog io.Writer) (manifestType, error) {
 	manifestFile := filepath.Join(manifestDir, "manifest")
 	var manifestConfig manifestConfigType
 	if err := json.ReadFromFile(manifestFile, &manifestConfig); err != nil {
@@ -103,14 +104,23 @@ func unpackImageAndProcessManifest(client *srpc.Client, manifestDir string,
 			return envGetter.getenv()[name]
 		})
 	}
-	sourceImageInfo, err := unpackImage(client, sourceImageName,
-		0, 0, rootDir, buildLog)
+	imageName, sourceImage, err := getSourceImage(client, sourceImageName, 0,
+		buildLog)
 	if err != nil {
 		return manifestType{},
 			errors.New("error unpacking image: " + err.Error())
 	}
+	unpacker := func() error {
+		err := unpackSourceImage(client, imageName, sourceImage, rootDir,
+			buildLog)
+		if err != nil {
+			return errors.New("error unpacking image: " + err.Error())
+		}
+		return nil
+	}
 	startTime := time.Now()
-	err = processManifest(manifestDir, rootDir, bindMounts, envGetter, buildLog)
+	err = processManifestWithCache(cache, imageName, unpacker, manifestDir,
+		rootDir, bindMounts, envGetter, buildLog)
 	if err != nil {
 		return manifestType{},
 			errors.New("error processing manifest: " + err.Error())
@@ -123,33 +133,42 @@ func unpackImageAndProcessManifest(client *srpc.Client, manifestDir string,
 	}
 	fmt.Fprintf(buildLog, "Processed manifest in %s\n",
 		format.Duration(time.Since(startTime)))
-	return manifestType{manifestConfig.Filter, sourceImageInfo}, nil
+	return manifestType{manifestConfig.Filter,
+		makeSourceImageInfo(imageName, sourceImage)}, nil
 }
 
 func processManifest(manifestDir, rootDir string, bindMounts []string,
 	envGetter environmentGetter, buildLog io.Writer) error {
-	// Copy in system /etc/resolv.conf
-	file, err := os.Open("/etc/resolv.conf")
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-	err = runInTarget(file, buildLog, rootDir, envGetter, packagerPathname,
-		"copy-in", "/etc/resolv.conf")
-	if err != nil {
-		return fmt.Errorf("error copying in /etc/resolv.conf: %s", err)
-	}
-	if err := copyFiles(manifestDir, "files", rootDir, buildLog); err != nil {
-		return err
-	}
+	return processManifestWithCache(nil, "", nil, manifestDir, rootDir,
+		bindMounts, envGetter, buildLog)
+}
+
+// processManifestWithCache processes the manifest in stages. If cache is not
+// nil, the tree resulting from the longest sequence of stages with unchanged
+// inputs is restored from the cache rather than running unpacker and those
+// stages, and the tree is saved to the cache after each stage which is run.
+func processManifestWithCache(cache *buildCacheType, sourceImageName string,
+	unpacker func() error, manifestDir, rootDir string, bindMounts []string,
+	envGetter environmentGetter, buildLog io.Writer) error {
 	for index, bindMount := range bindMounts {
 		bindMounts[index] = filepath.Clean(bindMount)
 	}
-	directoriesToDelete, err := makeMountPoints(rootDir, bindMounts, buildLog)
-	if err != nil {
-		return err
+	var directoriesToDelete []string
+	defer func() { deleteDirectories(directoriesToDelete) }()
+	mountPointsMade := false
+	makeMounts := func() error {
+		if mountPointsMade {
+			return nil
+		}
+		var err error
+		directoriesToDelete, err = makeMountPoints(rootDir, bindMounts,
+			buildLog)
+		if err != nil {
+			return err
+		}
+		mountPointsMade = true
+		return nil
 	}
-	defer deleteDirectories(directoriesToDelete)
 	packageList, err := fsutil.LoadLines(filepath.Join(manifestDir,
 		"package-list"))
 	if err != nil {
@@ -157,29 +176,120 @@ func processManifest(manifestDir, rootDir string, bindMounts []string,
 			return err
 		}
 	}
-	if len(packageList) > 0 {
-		err := updatePackageDatabase(rootDir, bindMounts, envGetter, buildLog)
+	stages := []buildStageType{
+		{
+			inputs: []string{"files"},
+			name:   "files",
+			run: func() error {
+				return copyFiles(manifestDir, "files", rootDir, buildLog)
+			},
+		},
+		{
+			inputs: []string{"package-list", "pre-install-scripts"},
+			name:   "packages",
+			run: func() error {
+				if err := makeMounts(); err != nil {
+					return err
+				}
+				if len(packageList) > 0 {
+					err := updatePackageDatabase(rootDir, bindMounts, envGetter,
+						buildLog)
+					if err != nil {
+						return err
+					}
+				}
+				err := runScripts(manifestDir, "pre-install-scripts", rootDir,
+					bindMounts, envGetter, buildLog)
+				if err != nil {
+					return err
+				}
+				err = installPackages(packageList, rootDir, bindMounts,
+					envGetter, buildLog)
+				if err != nil {
+					return errors.New("error installing packages: " +
+						err.Error())
+				}
+				return nil
+			},
+		},
+		{
+			inputs: []string{"post-install-files", "scripts"},
+			name:   "scripts",
+			run: func() error {
+				if err := makeMounts(); err != nil {
+					return err
+				}
+				err := copyFiles(manifestDir, "post-install-files", rootDir,
+					buildLog)
+				if err != nil {
+					return err
+				}
+				return runScripts(manifestDir, "scripts", rootDir, bindMounts,
+					envGetter, buildLog)
+			},
+		},
+	}
+	var stageKeys []string
+	if cache != nil {
+		stageKeys, err = makeBuildStageKeys(sourceImageName, manifestDir,
+			bindMounts, envGetter, stages)
 		if err != nil {
 			return err
 		}
 	}
-	err = runScripts(manifestDir, "pre-install-scripts", rootDir, bindMounts,
-		envGetter, buildLog)
-	if err != nil {
-		return err
+	firstStage := 0
+	for index := len(stageKeys) - 1; index >= 0; index-- {
+		restored, err := cache.restore(stageKeys[index], stages[index].name,
+			rootDir, buildLog)
+		if err != nil {
+			fmt.Fprintf(buildLog,
+				"Error restoring stage: %s from build cache: %s\n",
+				stages[index].name, err)
+			if err := os.RemoveAll(rootDir); err != nil {
+				return err
+			}
+			if err := os.Mkdir(rootDir, dirPerms); err != nil {
+				return err
+			}
+			break
+		}
+		if restored {
+			firstStage = index + 1
+			break
+		}
 	}
-	err = installPackages(packageList, rootDir, bindMounts, envGetter, buildLog)
-	if err != nil {
-		return errors.New("error installing packages: " + err.Error())
+	if firstStage < 1 && unpacker != nil {
+		if err := unpacker(); err != nil {
+			return err
+		}
 	}
-	err = copyFiles(manifestDir, "post-install-files", rootDir, buildLog)
+	// Copy in system /etc/resolv.conf
+	file, err := os.Open("/etc/resolv.conf")
 	if err != nil {
 		return err
 	}
-	err = runScripts(manifestDir, "scripts", rootDir, bindMounts, envGetter,
-		buildLog)
+	defer file.Close()
+	err = runInTarget(file, buildLog, rootDir, envGetter, packagerPathname,
+		"copy-in", "/etc/resolv.conf")
 	if err != nil {
-		return err
+		return fmt.Errorf("error copying in /etc/resolv.conf: %s", err)
+	}
+	for index, stage := range stages {
+		if index < firstStage {
+			continue
+		}
+		if err := stage.run(); err != nil {
+			return err
+		}
+		if index < len(stageKeys) {
+			err := cache.save(stageKeys[index], stage.name, rootDir,
+				directoriesToDelete, buildLog)
+			if err != nil {
+				fmt.Fprintf(buildLog,
+					"Error saving stage: %s to build cache: %s\n",
+					stage.name, err)
+			}
+		}
 	}
 	if err := cleanPackages(rootDir, buildLog); err != nil {
 		return err
@@ -191,7 +301,9 @@ func processManifest(manifestDir, rootDir string, bindMounts []string,
 	if err != nil {
 		return err
 	}
-	return deleteDirectories(directoriesToDelete)
+	err = deleteDirectories(directoriesToDelete)
+	directoriesToDelete = nil
+	return err
 }
 
 func copyFiles(manifestDir, dirname, rootDir string, buildLog io.Writer) error {
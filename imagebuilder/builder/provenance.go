@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/provenance"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+// attachProvenance will create, sign and upload a provenance record for img
+// and attach it to img. If there is no certificate to sign with, no record is
+// attached.
+func attachProvenance(client *srpc.Client, img *image.Image,
+	request proto.BuildImageRequest, manifestDir, manifestUrl string,
+	gitInfo *gitInfoType, sourceImageInfo *sourceImageInfoType,
+	startTime time.Time, buildLog io.Writer) error {
+	cert := srpc.GetClientCertificate()
+	if cert == nil {
+		fmt.Fprintln(buildLog, "No certificate: not signing build provenance")
+		return nil
+	}
+	manifestDigest, err := provenance.DigestTree(manifestDir)
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	manifestMaterial := provenance.Material{
+		Digest: manifestDigest,
+		Name:   "manifest",
+		Uri:    manifestUrl,
+	}
+	var gitBranch string
+	if gitInfo != nil {
+		gitBranch = gitInfo.branch
+		manifestMaterial.CommitId = gitInfo.commitId
+	}
+	record := &provenance.Provenance{
+		BuildFinishedOn: time.Now(),
+		BuildStartedOn:  startTime,
+		Builder: provenance.Builder{
+			Hostname: hostname,
+			Id:       provenance.BuilderId,
+		},
+		Materials: []provenance.Material{
+			manifestMaterial,
+			{
+				Digest: sourceImageInfo.fileSystemDigest,
+				Name:   "source-image",
+				Uri:    sourceImageInfo.imageName,
+			},
+		},
+		Parameters: provenance.Parameters{
+			GitBranch:       gitBranch,
+			StreamName:      request.StreamName,
+			VariableDigests: provenance.DigestVariables(request.Variables),
+		},
+		Subject: provenance.Subject{
+			FileSystemDigest: provenance.DigestFileSystem(img.FileSystem),
+			StreamName:       request.StreamName,
+		},
+	}
+	attestation, err := provenance.Sign(record, cert)
+	if err != nil {
+		return err
+	}
+	buffer := new(bytes.Buffer)
+	if err := attestation.Write(buffer); err != nil {
+		return err
+	}
+	objClient := objectclient.AttachObjectClient(client)
+	defer objClient.Close()
+	hashVal, _, err := objClient.AddObject(buffer, uint64(buffer.Len()), nil)
+	if err != nil {
+		return err
+	}
+	img.Provenance = &image.Annotation{Object: &hashVal}
+	fmt.Fprintln(buildLog, "Attached signed build provenance")
+	return nil
+}
@@ -0,0 +1,452 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+const (
+	buildPriorityAuto = iota
+	buildPriorityInteractive
+)
+
+var (
+	errBuildCancelled   = errors.New("build cancelled")
+	errLogWriterTooSlow = errors.New("build log requester too slow")
+)
+
+// A cancelRegistrar is implemented by the logs of builds which may be
+// cancelled. The cancel function is called if the build is cancelled. The
+// returned function must be called when cancellation is no longer needed.
+type cancelRegistrar interface {
+	registerCanceller(cancel func()) (func(), error)
+}
+
+type cancellableBuildLogger struct {
+	buildLogger
+	registrar cancelRegistrar
+}
+
+// A logForwarderType forwards build log data to a requester in the
+// background, so that a slow requester does not block the build.
+type logForwarderType struct {
+	data   chan []byte
+	done   chan struct{}
+	writer io.Writer
+}
+
+type queuedBuildType struct {
+	authInfo      *srpc.AuthInformation
+	builderGroups []string
+	dedupKey      string
+	done          chan struct{} // Closed when the build has finished.
+	id            uint64
+	queuedAt      time.Time
+	request       proto.BuildImageRequest
+	started       chan struct{} // Closed when started or cancelled.
+	// The following are protected by Builder.queueLock.
+	err        error
+	image      *image.Image
+	imageName  string
+	numWaiters uint
+	priority   uint
+	running    bool
+	startedAt  time.Time
+	// The following are protected by mutex.
+	mutex           sync.Mutex
+	cancelled       bool
+	cancellers      map[uint64]func()
+	logWriters      []io.Writer
+	nextCancellerId uint64
+}
+
+func newLogForwarder(writer io.Writer) *logForwarderType {
+	forwarder := &logForwarderType{
+		data:   make(chan []byte, 1024),
+		done:   make(chan struct{}),
+		writer: writer,
+	}
+	go forwarder.loop()
+	return forwarder
+}
+
+func makeDedupKey(request proto.BuildImageRequest) string {
+	request.StreamBuildLog = false
+	return fmt.Sprintf("%+v", request)
+}
+
+// runCancellableCommand runs a command which must be the leader of its own
+// process group. If output is the log for a build which is cancelled, the
+// process group is killed.
+func runCancellableCommand(cmd *exec.Cmd, output io.Writer) error {
+	registrar, ok := output.(cancelRegistrar)
+	if !ok {
+		return cmd.Run()
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	killProcessGroup := func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	unregister, err := registrar.registerCanceller(killProcessGroup)
+	if err != nil {
+		killProcessGroup()
+		cmd.Wait()
+		return err
+	}
+	defer unregister()
+	return cmd.Wait()
+}
+
+func (bl *cancellableBuildLogger) registerCanceller(cancel func()) (
+	func(), error) {
+	return bl.registrar.registerCanceller(cancel)
+}
+
+func (b *Builder) cancelBuild(id uint64,
+	authInfo *srpc.AuthInformation) error {
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+	qb := b.runningBuilds[id]
+	queueIndex := -1
+	if qb == nil {
+		for index, queuedBuild := range b.buildQueue {
+			if queuedBuild.id == id {
+				qb = queuedBuild
+				queueIndex = index
+				break
+			}
+		}
+	}
+	if qb == nil {
+		return fmt.Errorf("unknown build: %d", id)
+	}
+	if err := qb.checkCancelPermission(authInfo); err != nil {
+		return err
+	}
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+	if qb.cancelled {
+		return nil
+	}
+	qb.cancelled = true
+	if authInfo == nil {
+		b.logger.Printf("Cancelled build: %d for stream: %s\n",
+			id, qb.request.StreamName)
+	} else {
+		b.logger.Printf("%s cancelled build: %d for stream: %s\n",
+			authInfo.Username, id, qb.request.StreamName)
+	}
+	if queueIndex >= 0 {
+		b.buildQueue = append(b.buildQueue[:queueIndex],
+			b.buildQueue[queueIndex+1:]...)
+		close(qb.started)
+		return nil
+	}
+	for _, cancel := range qb.cancellers {
+		cancel()
+	}
+	return nil
+}
+
+// canStartBuild returns true if starting the build will not exceed the global
+// or per builder group concurrency limits. The queueLock must be held.
+func (b *Builder) canStartBuild(qb *queuedBuildType) bool {
+	if b.maxConcurrentBuilds > 0 &&
+		b.numRunningBuilds >= b.maxConcurrentBuilds {
+		return false
+	}
+	for _, group := range qb.builderGroups {
+		limit, ok := b.maxConcurrentGroupBuilds[group]
+		if ok && b.numRunningGroupBuilds[group] >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Builder) finishBuild(qb *queuedBuildType, img *image.Image,
+	name string, err error) {
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+	qb.mutex.Lock()
+	if qb.cancelled {
+		img = nil
+		name = ""
+		err = errBuildCancelled
+	}
+	qb.mutex.Unlock()
+	qb.image = img
+	qb.imageName = name
+	qb.err = err
+	if qb.running {
+		qb.running = false
+		delete(b.runningBuilds, qb.id)
+		b.numRunningBuilds--
+		for _, group := range qb.builderGroups {
+			b.numRunningGroupBuilds[group]--
+		}
+	}
+	close(qb.done)
+	b.startBuilds()
+}
+
+func (b *Builder) listBuildQueue() []proto.QueuedBuild {
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+	builds := make([]proto.QueuedBuild, 0,
+		len(b.runningBuilds)+len(b.buildQueue))
+	for _, qb := range b.runningBuilds {
+		builds = append(builds, qb.export())
+	}
+	sort.Slice(builds, func(left, right int) bool {
+		return builds[left].Id < builds[right].Id
+	})
+	for _, qb := range b.buildQueue {
+		builds = append(builds, qb.export())
+	}
+	return builds
+}
+
+// queueAndBuild queues a build and waits for it to complete. If an equivalent
+// build is already queued, the result of that build is returned instead. The
+// build log is written to logWriter (if not nil) before returning.
+func (b *Builder) queueAndBuild(request proto.BuildImageRequest,
+	authInfo *srpc.AuthInformation, logWriter io.Writer,
+	priority uint) (*image.Image, string, error) {
+	builder := b.getImageBuilderWithReload(request.StreamName)
+	if builder == nil {
+		return nil, "", errors.New("unknown stream: " + request.StreamName)
+	}
+	if err := checkPermission(builder, request, authInfo); err != nil {
+		return nil, "", err
+	}
+	if logWriter != nil {
+		forwarder := newLogForwarder(logWriter)
+		logWriter = forwarder
+		defer forwarder.close()
+	}
+	qb, isNew := b.queueBuild(builder, request, authInfo, logWriter, priority)
+	defer qb.removeLogWriter(logWriter) // Before the forwarder is closed.
+	if isNew {
+		<-qb.started
+		var img *image.Image
+		var name string
+		qb.mutex.Lock()
+		err := errBuildCancelled
+		cancelled := qb.cancelled
+		qb.mutex.Unlock()
+		if !cancelled {
+			var client *srpc.Client
+			client, err = srpc.DialHTTP("tcp", b.imageServerAddress, 0)
+			if err == nil {
+				img, name, err = b.build(client, request, authInfo, qb)
+				client.Close()
+			}
+		}
+		b.finishBuild(qb, img, name, err)
+	}
+	<-qb.done
+	return qb.image, qb.imageName, qb.err
+}
+
+// queueBuild adds a build to the queue, unless there is already an equivalent
+// build (with the same request) in the queue. For automatic builds, running
+// builds are also equivalent. The queued build is returned and true if it is a
+// new build.
+func (b *Builder) queueBuild(builder imageBuilder,
+	request proto.BuildImageRequest, authInfo *srpc.AuthInformation,
+	logWriter io.Writer, priority uint) (*queuedBuildType, bool) {
+	dedupKey := makeDedupKey(request)
+	b.queueLock.Lock()
+	defer b.queueLock.Unlock()
+	var existing *queuedBuildType
+	for _, qb := range b.buildQueue {
+		if qb.dedupKey == dedupKey {
+			existing = qb
+			break
+		}
+	}
+	if existing == nil && priority == buildPriorityAuto {
+		for _, qb := range b.runningBuilds {
+			if qb.dedupKey == dedupKey {
+				existing = qb
+				break
+			}
+		}
+	}
+	if existing != nil {
+		existing.numWaiters++
+		if priority > existing.priority {
+			existing.priority = priority
+		}
+		if logWriter != nil {
+			existing.mutex.Lock()
+			existing.logWriters = append(existing.logWriters, logWriter)
+			existing.mutex.Unlock()
+		}
+		return existing, false
+	}
+	b.nextBuildId++
+	qb := &queuedBuildType{
+		authInfo:   authInfo,
+		dedupKey:   dedupKey,
+		done:       make(chan struct{}),
+		id:         b.nextBuildId,
+		priority:   priority,
+		queuedAt:   time.Now(),
+		request:    request,
+		started:    make(chan struct{}),
+		cancellers: make(map[uint64]func()),
+	}
+	if stream, ok := builder.(*imageStreamType); ok {
+		qb.builderGroups = stream.BuilderGroups
+	}
+	if logWriter != nil {
+		qb.logWriters = []io.Writer{logWriter}
+	}
+	b.buildQueue = append(b.buildQueue, qb)
+	b.startBuilds()
+	return qb, true
+}
+
+// startBuilds starts as many queued builds as the concurrency limits permit,
+// in order of priority. The queueLock must be held.
+func (b *Builder) startBuilds() {
+	sort.SliceStable(b.buildQueue, func(left, right int) bool {
+		return b.buildQueue[left].priority > b.buildQueue[right].priority
+	})
+	remaining := make([]*queuedBuildType, 0, len(b.buildQueue))
+	for _, qb := range b.buildQueue {
+		if !b.canStartBuild(qb) {
+			remaining = append(remaining, qb)
+			continue
+		}
+		qb.running = true
+		qb.startedAt = time.Now()
+		b.runningBuilds[qb.id] = qb
+		b.numRunningBuilds++
+		for _, group := range qb.builderGroups {
+			b.numRunningGroupBuilds[group]++
+		}
+		close(qb.started)
+	}
+	b.buildQueue = remaining
+}
+
+func (qb *queuedBuildType) checkCancelPermission(
+	authInfo *srpc.AuthInformation) error {
+	if authInfo == nil || authInfo.HaveMethodAccess {
+		return nil
+	}
+	if qb.authInfo != nil && qb.authInfo.Username == authInfo.Username {
+		return nil
+	}
+	for _, group := range qb.builderGroups {
+		if _, ok := authInfo.GroupList[group]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("no permission to cancel build: %d", qb.id)
+}
+
+// export must be called with the queueLock held.
+func (qb *queuedBuildType) export() proto.QueuedBuild {
+	build := proto.QueuedBuild{
+		GitBranch:   qb.request.GitBranch,
+		Id:          qb.id,
+		Interactive: qb.authInfo != nil,
+		NumWaiters:  qb.numWaiters,
+		QueuedAt:    qb.queuedAt,
+		Running:     qb.running,
+		StartedAt:   qb.startedAt,
+		StreamName:  qb.request.StreamName,
+	}
+	if qb.authInfo != nil {
+		build.Username = qb.authInfo.Username
+	}
+	return build
+}
+
+func (forwarder *logForwarderType) close() {
+	close(forwarder.data)
+	<-forwarder.done
+}
+
+func (forwarder *logForwarderType) loop() {
+	defer close(forwarder.done)
+	var err error
+	for p := range forwarder.data {
+		if err == nil {
+			_, err = forwarder.writer.Write(p)
+		}
+	}
+}
+
+// Write queues a copy of the data to be written. It does not block: if too
+// much data are queued an error is returned.
+func (forwarder *logForwarderType) Write(p []byte) (int, error) {
+	select {
+	case forwarder.data <- append([]byte(nil), p...):
+		return len(p), nil
+	default:
+		return 0, errLogWriterTooSlow
+	}
+}
+
+func (qb *queuedBuildType) registerCanceller(cancel func()) (func(), error) {
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+	if qb.cancelled {
+		return nil, errBuildCancelled
+	}
+	qb.nextCancellerId++
+	id := qb.nextCancellerId
+	qb.cancellers[id] = cancel
+	return func() {
+		qb.mutex.Lock()
+		delete(qb.cancellers, id)
+		qb.mutex.Unlock()
+	}, nil
+}
+
+func (qb *queuedBuildType) removeLogWriter(logWriter io.Writer) {
+	if logWriter == nil {
+		return
+	}
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+	for index, writer := range qb.logWriters {
+		if writer == logWriter {
+			qb.logWriters[index] = nil
+		}
+	}
+}
+
+// Write sends build log data to all the requesters of the build. The writers
+// are log forwarders, which do not block. Requesters which fail or fall too far
+// behind are dropped, rather than failing the build for the others.
+func (qb *queuedBuildType) Write(p []byte) (int, error) {
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+	for index, writer := range qb.logWriters {
+		if writer == nil {
+			continue
+		}
+		if _, err := writer.Write(p); err != nil {
+			qb.logWriters[index] = nil
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,289 @@
+package builder
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func isBuildStarted(qb *queuedBuildType) bool {
+	select {
+	case <-qb.started:
+		return true
+	default:
+		return false
+	}
+}
+
+func listQueuedStreams(b *Builder) []string {
+	streamNames := make([]string, 0, len(b.buildQueue))
+	for _, qb := range b.buildQueue {
+		streamNames = append(streamNames, qb.request.StreamName)
+	}
+	return streamNames
+}
+
+func makeTestQueueBuilder(t *testing.T, maxConcurrentBuilds uint,
+	maxConcurrentGroupBuilds map[string]uint) *Builder {
+	return &Builder{
+		logger:                   testlogger.New(t),
+		maxConcurrentBuilds:      maxConcurrentBuilds,
+		maxConcurrentGroupBuilds: maxConcurrentGroupBuilds,
+		numRunningGroupBuilds:    make(map[string]uint),
+		runningBuilds:            make(map[uint64]*queuedBuildType),
+	}
+}
+
+func queueTestBuild(b *Builder, request proto.BuildImageRequest,
+	builderGroups []string, priority uint) (*queuedBuildType, bool) {
+	return b.queueBuild(&imageStreamType{BuilderGroups: builderGroups},
+		request, nil, nil, priority)
+}
+
+func testStreamNames(t *testing.T, name string, streamNames,
+	expected []string) {
+	if !reflect.DeepEqual(streamNames, expected) {
+		t.Errorf("%s: %v != %v", name, streamNames, expected)
+	}
+}
+
+func TestCancelBuild(t *testing.T) {
+	b := makeTestQueueBuilder(t, 1, nil)
+	owner := &srpc.AuthInformation{Username: "owner"}
+	running, _ := b.queueBuild(&imageStreamType{},
+		proto.BuildImageRequest{StreamName: "running"}, owner, nil,
+		buildPriorityInteractive)
+	queued, _ := b.queueBuild(&imageStreamType{},
+		proto.BuildImageRequest{StreamName: "queued"}, owner, nil,
+		buildPriorityInteractive)
+	if err := b.cancelBuild(queued.id+1, nil); err == nil {
+		t.Error("unknown build cancelled")
+	}
+	other := &srpc.AuthInformation{Username: "other"}
+	if err := b.cancelBuild(queued.id, other); err == nil {
+		t.Error("build cancelled by another user")
+	}
+	// A queued build is removed from the queue and its requester woken.
+	if err := b.cancelBuild(queued.id, owner); err != nil {
+		t.Fatal(err)
+	}
+	if !queued.cancelled || !isBuildStarted(queued) || queued.running {
+		t.Errorf("queued build not cancelled: %+v", queued)
+	}
+	if len(b.buildQueue) != 0 {
+		t.Errorf("cancelled build still queued: %v", listQueuedStreams(b))
+	}
+	// A running build has its commands killed.
+	var numCancels int
+	unregister, err := running.registerCanceller(func() { numCancels++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregister()
+	admin := &srpc.AuthInformation{HaveMethodAccess: true, Username: "admin"}
+	if err := b.cancelBuild(running.id, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.cancelBuild(running.id, admin); err != nil {
+		t.Errorf("second cancel: %s", err)
+	}
+	if !running.cancelled || numCancels != 1 {
+		t.Errorf("running build cancelled: %v, cancels: %d",
+			running.cancelled, numCancels)
+	}
+	_, err = running.registerCanceller(func() {})
+	if err != errBuildCancelled {
+		t.Errorf("registered canceller after cancel: %v", err)
+	}
+	b.finishBuild(running, nil, "name", nil)
+	if running.err != errBuildCancelled || running.imageName != "" {
+		t.Errorf("cancelled build result: %q, %v",
+			running.imageName, running.err)
+	}
+	if b.numRunningBuilds != 0 || len(b.runningBuilds) != 0 {
+		t.Errorf("cancelled build still running: %d", b.numRunningBuilds)
+	}
+}
+
+func TestCanStartBuild(t *testing.T) {
+	b := makeTestQueueBuilder(t, 3, map[string]uint{"gpu": 1, "large": 2})
+	tests := []struct {
+		name         string
+		numRunning   uint
+		groupRunning map[string]uint
+		groups       []string
+		expected     bool
+	}{
+		{"idle", 0, nil, nil, true},
+		{"below global", 2, nil, nil, true},
+		{"at global", 3, nil, nil, false},
+		{"at global with group", 3, nil, []string{"large"}, false},
+		{"below group", 1, map[string]uint{"large": 1},
+			[]string{"large"}, true},
+		{"at group", 1, map[string]uint{"gpu": 1}, []string{"gpu"}, false},
+		{"other group full", 1, map[string]uint{"gpu": 1},
+			[]string{"large"}, true},
+		{"one of groups full", 2, map[string]uint{"gpu": 1},
+			[]string{"large", "gpu"}, false},
+		{"unlimited group", 2, map[string]uint{"small": 10},
+			[]string{"small"}, true},
+	}
+	for _, test := range tests {
+		b.numRunningBuilds = test.numRunning
+		b.numRunningGroupBuilds = test.groupRunning
+		qb := &queuedBuildType{builderGroups: test.groups}
+		if result := b.canStartBuild(qb); result != test.expected {
+			t.Errorf("%s: %v != %v", test.name, result, test.expected)
+		}
+	}
+	b.maxConcurrentBuilds = 0
+	b.numRunningBuilds = 100
+	b.numRunningGroupBuilds = nil
+	if !b.canStartBuild(&queuedBuildType{}) {
+		t.Error("unlimited builds not permitted")
+	}
+}
+
+func TestQueueBuildDedup(t *testing.T) {
+	b := makeTestQueueBuilder(t, 1, nil)
+	request := proto.BuildImageRequest{StreamName: "stream"}
+	running, isNew := queueTestBuild(b, request, nil, buildPriorityAuto)
+	if !isNew || !isBuildStarted(running) {
+		t.Fatal("first build not started")
+	}
+	// Automatic builds share running builds.
+	qb, isNew := queueTestBuild(b, request, nil, buildPriorityAuto)
+	if isNew || qb != running || running.numWaiters != 1 {
+		t.Errorf("automatic build not shared: new: %v, waiters: %d",
+			isNew, running.numWaiters)
+	}
+	// Requested builds may have a newer manifest, so are not shared with
+	// running builds.
+	queued, isNew := queueTestBuild(b, request, nil, buildPriorityInteractive)
+	if !isNew || queued == running || isBuildStarted(queued) {
+		t.Fatal("requested build shared with running build")
+	}
+	// Queued builds are shared, whatever the priority. Whether the build log
+	// is streamed does not matter.
+	logWriter := &bytes.Buffer{}
+	streamRequest := request
+	streamRequest.StreamBuildLog = true
+	qb, isNew = b.queueBuild(&imageStreamType{}, streamRequest, nil,
+		logWriter, buildPriorityAuto)
+	if isNew || qb != queued {
+		t.Error("queued build not shared with automatic build")
+	}
+	qb, isNew = queueTestBuild(b, request, nil, buildPriorityInteractive)
+	if isNew || qb != queued {
+		t.Error("queued build not shared with requested build")
+	}
+	if queued.numWaiters != 2 || len(queued.logWriters) != 1 ||
+		queued.priority != buildPriorityInteractive {
+		t.Errorf("waiters: %d, log writers: %d, priority: %d",
+			queued.numWaiters, len(queued.logWriters), queued.priority)
+	}
+	// Different requests are not shared.
+	branchRequest := request
+	branchRequest.GitBranch = "other"
+	if _, isNew := queueTestBuild(b, branchRequest, nil,
+		buildPriorityAuto); !isNew {
+		t.Error("build for other branch shared")
+	}
+	testStreamNames(t, "queue", listQueuedStreams(b),
+		[]string{"stream", "stream"})
+	builds := b.listBuildQueue()
+	if len(builds) != 3 || !builds[0].Running || builds[0].NumWaiters != 1 ||
+		builds[1].NumWaiters != 2 {
+		t.Errorf("build queue: %+v", builds)
+	}
+}
+
+func TestQueueBuildRaisesPriority(t *testing.T) {
+	b := makeTestQueueBuilder(t, 1, nil)
+	running, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "a"},
+		nil, buildPriorityAuto)
+	first, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "b"},
+		nil, buildPriorityAuto)
+	second, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "c"},
+		nil, buildPriorityAuto)
+	// A request for a queued automatic build moves it ahead.
+	queueTestBuild(b, proto.BuildImageRequest{StreamName: "c"}, nil,
+		buildPriorityInteractive)
+	b.finishBuild(running, nil, "", nil)
+	if !isBuildStarted(second) || isBuildStarted(first) {
+		t.Error("requested build did not start first")
+	}
+}
+
+func TestStartBuildsGroupLimits(t *testing.T) {
+	b := makeTestQueueBuilder(t, 3, map[string]uint{"gpu": 1})
+	gpu0, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "gpu0"},
+		[]string{"gpu"}, buildPriorityAuto)
+	gpu1, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "gpu1"},
+		[]string{"gpu"}, buildPriorityAuto)
+	// A build blocked by its group limit does not block other builds.
+	cpu0, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "cpu0"},
+		nil, buildPriorityAuto)
+	cpu1, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "cpu1"},
+		nil, buildPriorityAuto)
+	cpu2, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "cpu2"},
+		nil, buildPriorityAuto)
+	if !isBuildStarted(gpu0) || isBuildStarted(gpu1) ||
+		!isBuildStarted(cpu0) || !isBuildStarted(cpu1) ||
+		isBuildStarted(cpu2) {
+		t.Fatalf("running: %d, queued: %v",
+			b.numRunningBuilds, listQueuedStreams(b))
+	}
+	if b.numRunningGroupBuilds["gpu"] != 1 {
+		t.Errorf("running GPU builds: %d", b.numRunningGroupBuilds["gpu"])
+	}
+	// Finishing a build starts the first queued build which may run.
+	b.finishBuild(cpu0, nil, "", nil)
+	if isBuildStarted(gpu1) || !isBuildStarted(cpu2) {
+		t.Errorf("after CPU build: queued: %v", listQueuedStreams(b))
+	}
+	b.finishBuild(gpu0, nil, "", nil)
+	if !isBuildStarted(gpu1) || len(b.buildQueue) != 0 {
+		t.Errorf("after GPU build: queued: %v", listQueuedStreams(b))
+	}
+	if b.numRunningBuilds != 3 || b.numRunningGroupBuilds["gpu"] != 1 {
+		t.Errorf("running: %d, running GPU builds: %d",
+			b.numRunningBuilds, b.numRunningGroupBuilds["gpu"])
+	}
+}
+
+func TestStartBuildsPriority(t *testing.T) {
+	b := makeTestQueueBuilder(t, 1, nil)
+	running, _ := queueTestBuild(b, proto.BuildImageRequest{StreamName: "r"},
+		nil, buildPriorityAuto)
+	for _, build := range []struct {
+		streamName string
+		priority   uint
+	}{
+		{"auto0", buildPriorityAuto},
+		{"auto1", buildPriorityAuto},
+		{"user0", buildPriorityInteractive},
+		{"auto2", buildPriorityAuto},
+		{"user1", buildPriorityInteractive},
+	} {
+		queueTestBuild(b, proto.BuildImageRequest{StreamName: build.streamName},
+			nil, build.priority)
+	}
+	// Higher priority first, otherwise first in, first out.
+	expected := []string{"user0", "user1", "auto0", "auto1", "auto2"}
+	testStreamNames(t, "queue", listQueuedStreams(b), expected)
+	var started []string
+	for qb := running; qb != nil; {
+		b.finishBuild(qb, nil, "", nil)
+		qb = nil
+		for _, runningBuild := range b.runningBuilds {
+			qb = runningBuild
+			started = append(started, qb.request.StreamName)
+		}
+	}
+	testStreamNames(t, "start order", started, expected)
+}
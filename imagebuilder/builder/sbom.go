@@ -0,0 +1,228 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+)
+
+const maxSymlinksToFollow = 40
+
+type packageDetailsType struct {
+	licenses string
+	origin   string
+}
+
+// getDebianLicenses returns the licenses listed in the machine-readable Debian
+// copyright file for a package, separated by commas, or the empty string if the
+// file does not exist or is not machine-readable.
+func getDebianLicenses(rootDir, packageName string) string {
+	if index := strings.IndexByte(packageName, ':'); index >= 0 {
+		packageName = packageName[:index] // Strip the architecture.
+	}
+	file, err := os.Open(filepath.Join(rootDir, "usr", "share", "doc",
+		packageName, "copyright"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	return parseDebianLicenses(file)
+}
+
+// listPackageDetails returns the origin and licenses of packages, keyed by
+// package name. Each line of output from the packager contains the name,
+// origin and licenses of a package, separated by tabs. If the packager does not
+// list the licenses, they are read from the Debian copyright file, if present.
+func listPackageDetails(rootDir string) (
+	map[string]packageDetailsType, error) {
+	output := new(bytes.Buffer)
+	err := runInTarget(nil, output, rootDir, nil, packagerPathname,
+		"list-details")
+	if err != nil {
+		return nil, err
+	}
+	details := make(map[string]packageDetailsType)
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 1 || fields[0] == "" {
+			continue
+		}
+		var pkgDetails packageDetailsType
+		if len(fields) > 1 && fields[1] != "(none)" {
+			pkgDetails.origin = fields[1]
+		}
+		if len(fields) > 2 && fields[2] != "(none)" {
+			pkgDetails.licenses = fields[2]
+		}
+		if pkgDetails.licenses == "" {
+			pkgDetails.licenses = getDebianLicenses(rootDir, fields[0])
+		}
+		details[fields[0]] = pkgDetails
+	}
+	return details, scanner.Err()
+}
+
+// listPackageFiles returns the set of pathnames owned by packages. Directory
+// symlinks in the pathnames (such as /bin -> usr/bin) are resolved.
+func listPackageFiles(rootDir string) (map[string]struct{}, error) {
+	output := new(bytes.Buffer)
+	err := runInTarget(nil, output, rootDir, nil, packagerPathname,
+		"list-files")
+	if err != nil {
+		return nil, err
+	}
+	resolvedDirectories := make(map[string]string)
+	files := make(map[string]struct{})
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		pathname := filepath.Clean(scanner.Text())
+		if !filepath.IsAbs(pathname) {
+			continue
+		}
+		dirname, filename := filepath.Split(pathname)
+		dirname = filepath.Clean(dirname)
+		resolved, ok := resolvedDirectories[dirname]
+		if !ok {
+			resolved = resolveDirectory(rootDir, dirname)
+			resolvedDirectories[dirname] = resolved
+		}
+		files[filepath.Join(resolved, filename)] = struct{}{}
+	}
+	return files, scanner.Err()
+}
+
+// makeSBOM makes the Software Bill Of Materials for the image. Failure to get
+// package details or package files from the packager (older images may not
+// support these commands) is logged and the SBOM is made without them.
+func makeSBOM(rootDir string, packages []image.Package,
+	fs *filesystem.FileSystem, streamName string,
+	buildLog io.Writer) *sbom.SBOM {
+	startTime := time.Now()
+	details, err := listPackageDetails(rootDir)
+	if err != nil {
+		fmt.Fprintf(buildLog, "Error listing package details: %s\n", err)
+	}
+	ownedFiles, err := listPackageFiles(rootDir)
+	if err != nil {
+		fmt.Fprintf(buildLog, "Error listing package files: %s\n", err)
+	}
+	bom := &sbom.SBOM{
+		CreatedOn:  time.Now(),
+		Packages:   make([]sbom.Package, 0, len(packages)),
+		StreamName: streamName,
+	}
+	for _, pkg := range packages {
+		pkgDetails := details[pkg.Name]
+		bom.Packages = append(bom.Packages, sbom.Package{
+			Licenses: pkgDetails.licenses,
+			Name:     pkg.Name,
+			Origin:   pkgDetails.origin,
+			Size:     pkg.Size,
+			Version:  pkg.Version,
+		})
+	}
+	// If the packager does not list package files, it is not possible to
+	// determine which files are unowned.
+	if len(ownedFiles) > 0 {
+		fs.ForEachFile(
+			func(name string, inodeNumber uint64,
+				inode filesystem.GenericInode) error {
+				if inode, ok := inode.(*filesystem.RegularInode); ok {
+					if _, ok := ownedFiles[name]; !ok {
+						bom.UnownedFiles = append(bom.UnownedFiles, sbom.File{
+							Hash: inode.Hash,
+							Path: name,
+							Size: inode.Size,
+						})
+					}
+				}
+				return nil
+			})
+	}
+	sort.Slice(bom.UnownedFiles, func(left, right int) bool {
+		return bom.UnownedFiles[left].Path < bom.UnownedFiles[right].Path
+	})
+	fmt.Fprintf(buildLog,
+		"Made SBOM with %d packages and %d unowned files in %s\n",
+		len(bom.Packages), len(bom.UnownedFiles),
+		format.Duration(time.Since(startTime)))
+	return bom
+}
+
+// parseDebianLicenses returns the licenses listed in the "License" fields of a
+// machine-readable (DEP-5) Debian copyright file, separated by commas. Only the
+// first line of each field (the license short name) is used.
+func parseDebianLicenses(reader io.Reader) string {
+	var licenses []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 0; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if lineNumber == 0 && !strings.HasPrefix(line, "Format:") {
+			return "" // Not machine-readable.
+		}
+		if !strings.HasPrefix(line, "License:") {
+			continue
+		}
+		license := strings.TrimSpace(strings.TrimPrefix(line, "License:"))
+		if license == "" {
+			continue
+		}
+		if _, ok := seen[license]; !ok {
+			seen[license] = struct{}{}
+			licenses = append(licenses, license)
+		}
+	}
+	return strings.Join(licenses, ", ")
+}
+
+// resolveDirectory resolves symlinks in dirname, treating rootDir as the root
+// of the file-system. If a symlink cannot be resolved, the partially resolved
+// pathname is returned.
+func resolveDirectory(rootDir, dirname string) string {
+	resolved := "/"
+	remaining := strings.Split(strings.TrimPrefix(dirname, "/"), "/")
+	numSymlinks := 0
+	for len(remaining) > 0 {
+		component := remaining[0]
+		remaining = remaining[1:]
+		if component == "" || component == "." {
+			continue
+		}
+		if component == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, component)
+		fi, err := os.Lstat(filepath.Join(rootDir, next))
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if numSymlinks++; numSymlinks > maxSymlinksToFollow {
+			return filepath.Join(append([]string{next}, remaining...)...)
+		}
+		target, err := os.Readlink(filepath.Join(rootDir, next))
+		if err != nil {
+			resolved = next
+			continue
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return resolved
+}
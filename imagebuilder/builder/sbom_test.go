@@ -0,0 +1,48 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDebianLicenses(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name: "machine-readable",
+			text: `Format: http://dep.debian.net/deps/dep5
+Upstream-Name: example
+
+Files: *
+Copyright: 2000 Someone
+License: GPL-2+
+
+Files: lib/*
+License: LGPL-2.1+
+
+Files: debian/*
+License: GPL-2+
+
+License: GPL-2+
+ This program is free software.
+`,
+			expected: "GPL-2+, LGPL-2.1+",
+		},
+		{
+			name:     "free-form",
+			text:     "This package was debianized by Someone.\nLicense: GPL\n",
+			expected: "",
+		},
+		{name: "empty"},
+	}
+	for _, test := range tests {
+		licenses := parseDebianLicenses(strings.NewReader(test.text))
+		if licenses != test.expected {
+			t.Errorf("%s: expected: \"%s\", got: \"%s\"",
+				test.name, test.expected, licenses)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package builder
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	zeroCommitId    = "0000000000000000000000000000000000000000"
+)
+
+// pushEventType contains the fields common to the push event payloads from
+// GitHub, GitLab and Gitea.
+type pushEventType struct {
+	After      string
+	Project    *webhookRepositoryType // GitLab.
+	Ref        string
+	Repository *webhookRepositoryType
+}
+
+type webhookRepositoryType struct {
+	CloneUrl   string `json:"clone_url"`
+	GitHttpUrl string `json:"git_http_url"`
+	GitSshUrl  string `json:"git_ssh_url"`
+	HtmlUrl    string `json:"html_url"`
+	SshUrl     string `json:"ssh_url"`
+	Url        string `json:"url"`
+	WebUrl     string `json:"web_url"`
+}
+
+func checkHmac(secret string, body []byte, signature string) error {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrWebhookUnauthorised
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrWebhookUnauthorised
+	}
+	return nil
+}
+
+// getWebhookEvent returns the type of event from the headers sent by the Git
+// hosting service. GitLab push events are mapped to "push".
+func getWebhookEvent(header http.Header) string {
+	if event := header.Get("X-Gitlab-Event"); event != "" {
+		if event == "Push Hook" {
+			return "push"
+		}
+		return event
+	}
+	if event := header.Get("X-Gitea-Event"); event != "" {
+		return event
+	}
+	return header.Get("X-GitHub-Event")
+}
+
+// normaliseRepositoryUrl converts a repository URL (including scp-like SSH
+// URLs) into host/path form, so that HTTPS and SSH URLs for the same
+// repository are equal.
+func normaliseRepositoryUrl(rawUrl string) string {
+	rawUrl = strings.TrimSpace(rawUrl)
+	if rawUrl == "" {
+		return ""
+	}
+	if !strings.Contains(rawUrl, "://") {
+		if index := strings.Index(rawUrl, ":"); index > 0 {
+			rawUrl = "ssh://" + rawUrl[:index] + "/" + rawUrl[index+1:]
+		}
+	}
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil || parsedUrl.Host == "" {
+		return ""
+	}
+	path := strings.TrimSuffix(strings.Trim(parsedUrl.Path, "/"), ".git")
+	return strings.ToLower(parsedUrl.Hostname()) + "/" + path
+}
+
+func (repo *webhookRepositoryType) addUrls(urls map[string]struct{}) {
+	if repo == nil {
+		return
+	}
+	for _, rawUrl := range []string{repo.CloneUrl, repo.GitHttpUrl,
+		repo.GitSshUrl, repo.HtmlUrl, repo.SshUrl, repo.Url, repo.WebUrl} {
+		if normalised := normaliseRepositoryUrl(rawUrl); normalised != "" {
+			urls[normalised] = struct{}{}
+		}
+	}
+}
+
+func (b *Builder) checkWebhookSignature(header http.Header,
+	body []byte) error {
+	if b.webhookSecret == "" {
+		return errors.New("webhooks not enabled")
+	}
+	if signature := header.Get("X-Hub-Signature-256"); signature != "" {
+		return checkHmac(b.webhookSecret, body,
+			strings.TrimPrefix(signature, "sha256="))
+	}
+	if signature := header.Get("X-Gitea-Signature"); signature != "" {
+		return checkHmac(b.webhookSecret, body, signature)
+	}
+	if token := header.Get("X-Gitlab-Token"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(token),
+			[]byte(b.webhookSecret)) == 1 {
+			return nil
+		}
+	}
+	return ErrWebhookUnauthorised
+}
+
+// getWebhookImageExpiration returns the expiration time for images built for
+// webhooks, given the configured value in seconds (0 for the default).
+func getWebhookImageExpiration(seconds uint) (time.Duration, error) {
+	if seconds < 1 {
+		return 24 * time.Hour, nil
+	}
+	expiration := time.Second * time.Duration(seconds)
+	if expiration < minimumImageExpiration {
+		return 0, fmt.Errorf(
+			"WebhookImageExpiration: %s is below the minimum: %s",
+			expiration, minimumImageExpiration)
+	}
+	return expiration, nil
+}
+
+// listStreamsForPush returns the names of the image streams with a manifest in
+// one of the specified repositories which are built from gitBranch.
+func (b *Builder) listStreamsForPush(repoUrls map[string]struct{},
+	gitBranch string) []string {
+	var streamNames []string
+	b.streamsLock.RLock()
+	for _, stream := range b.imageStreams {
+		if stream.getGitBranch() != gitBranch {
+			continue
+		}
+		manifestUrl := os.Expand(stream.ManifestUrl,
+			b.getVariableFunc(stream.getenv(), nil))
+		if _, ok := repoUrls[normaliseRepositoryUrl(manifestUrl)]; ok {
+			streamNames = append(streamNames, stream.name)
+		}
+	}
+	b.streamsLock.RUnlock()
+	sort.Strings(streamNames)
+	return streamNames
+}
+
+func (b *Builder) processWebhook(header http.Header, body []byte) (
+	[]string, error) {
+	if err := b.checkWebhookSignature(header, body); err != nil {
+		return nil, err
+	}
+	if event := getWebhookEvent(header); event != "push" {
+		return nil, nil // Ignore pings and other events.
+	}
+	var event pushEventType
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(event.Ref, branchRefPrefix) {
+		return nil, nil // Ignore tags.
+	}
+	if event.After == "" || event.After == zeroCommitId {
+		return nil, nil // Ignore deleted branches.
+	}
+	gitBranch := event.Ref[len(branchRefPrefix):]
+	repoUrls := make(map[string]struct{})
+	event.Project.addUrls(repoUrls)
+	event.Repository.addUrls(repoUrls)
+	streamNames := b.listStreamsForPush(repoUrls, gitBranch)
+	if len(streamNames) < 1 {
+		return nil, nil
+	}
+	b.logger.Printf("Push to branch: %s commit: %s, queueing builds for: %s\n",
+		gitBranch, event.After, strings.Join(streamNames, ", "))
+	for _, streamName := range streamNames {
+		go b.webhookBuild(streamName, gitBranch, event.After)
+	}
+	return streamNames, nil
+}
+
+func (b *Builder) webhookBuild(streamName, gitBranch, commitId string) {
+	_, _, err := b.queueAndBuild(proto.BuildImageRequest{
+		ExpiresIn:   b.webhookImageExpiration,
+		GitBranch:   gitBranch,
+		GitCommitId: commitId,
+		StreamName:  streamName,
+	},
+		nil, nil, buildPriorityInteractive)
+	if err != nil {
+		b.logger.Printf("Error building image: %s: %s\n", streamName, err)
+	}
+}
@@ -0,0 +1,85 @@
+package builder
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestCheckHmac(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/master"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	if err := checkHmac("secret", body, signature); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+	}{
+		{"wrong secret", "other", body, signature},
+		{"modified body", "secret", []byte(`{"ref":"refs/heads/evil"}`),
+			signature},
+		{"truncated signature", "secret", body, signature[:32]},
+		{"bad hex", "secret", body, "sha256=" + signature},
+		{"empty signature", "secret", body, ""},
+	}
+	for _, test := range tests {
+		err := checkHmac(test.secret, test.body, test.signature)
+		if err != ErrWebhookUnauthorised {
+			t.Errorf("%s: error: %v", test.name, err)
+		}
+	}
+}
+
+func TestGetWebhookImageExpiration(t *testing.T) {
+	tests := []struct {
+		seconds  uint
+		expected time.Duration
+		fail     bool
+	}{
+		{0, 24 * time.Hour, false},
+		{60, 0, true},
+		{899, 0, true},
+		{900, 15 * time.Minute, false},
+		{7200, 2 * time.Hour, false},
+	}
+	for _, test := range tests {
+		expiration, err := getWebhookImageExpiration(test.seconds)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%d: no error", test.seconds)
+			}
+		} else if err != nil {
+			t.Errorf("%d: %s", test.seconds, err)
+		} else if expiration != test.expected {
+			t.Errorf("%d: %s != %s", test.seconds, expiration, test.expected)
+		}
+	}
+}
+
+func TestNormaliseRepositoryUrl(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "",
+		"not a url":                            "",
+		"/local/path":                          "",
+		"https://GitHub.com/org/repo.git":      "github.com/org/repo",
+		"https://github.com/org/repo":          "github.com/org/repo",
+		"https://github.com/org/repo/":         "github.com/org/repo",
+		"https://user@github.com:443/org/repo": "github.com/org/repo",
+		"git@github.com:org/repo.git":          "github.com/org/repo",
+		"ssh://git@github.com/org/repo.git":    "github.com/org/repo",
+		" git@gitlab.com:group/sub/repo\n":     "gitlab.com/group/sub/repo",
+	}
+	for rawUrl, expected := range tests {
+		if normalised := normaliseRepositoryUrl(rawUrl); normalised !=
+			expected {
+			t.Errorf("%q: %q != %q", rawUrl, normalised, expected)
+		}
+	}
+}
@@ -11,3 +11,11 @@ func BuildImage(client *srpc.Client, request proto.BuildImageRequest,
 	response *proto.BuildImageResponse, logWriter io.Writer) error {
 	return buildImage(client, request, response, logWriter)
 }
+
+func CancelBuild(client *srpc.Client, id uint64) error {
+	return cancelBuild(client, id)
+}
+
+func ListBuildQueue(client *srpc.Client) ([]proto.QueuedBuild, error) {
+	return listBuildQueue(client)
+}
@@ -45,3 +45,26 @@ func buildImage(client *srpc.Client, request proto.BuildImageRequest,
 		}
 	}
 }
+
+func cancelBuild(client *srpc.Client, id uint64) error {
+	request := proto.CancelBuildRequest{Id: id}
+	var reply proto.CancelBuildResponse
+	err := client.RequestReply("Imaginator.CancelBuild", request, &reply)
+	if err != nil {
+		return err
+	}
+	return errors.New(reply.Error)
+}
+
+func listBuildQueue(client *srpc.Client) ([]proto.QueuedBuild, error) {
+	var request proto.ListBuildQueueRequest
+	var reply proto.ListBuildQueueResponse
+	err := client.RequestReply("Imaginator.ListBuildQueue", request, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := errors.New(reply.Error); err != nil {
+		return nil, err
+	}
+	return reply.Builds, nil
+}
@@ -32,6 +32,7 @@ func StartServer(portNum uint, builderObj *builder.Builder,
 	html.HandleFunc("/showImageStream", myState.showImageStreamHandler)
 	html.HandleFunc("/showImageStreams", myState.showImageStreamsHandler)
 	html.HandleFunc("/showLastBuildLog", myState.showLastBuildLogHandler)
+	http.HandleFunc("/webhook", myState.webhookHandler)
 	if daemon {
 		go http.Serve(listener, nil)
 	} else {
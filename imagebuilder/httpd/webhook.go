@@ -0,0 +1,37 @@
+package httpd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Cloud-Foundations/Dominator/imagebuilder/builder"
+)
+
+const maxWebhookPayloadSize = 25 << 20
+
+func (s state) webhookHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body,
+		maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	streamNames, err := s.builder.ProcessWebhook(req.Header, body)
+	if err == builder.ErrWebhookUnauthorised {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, streamName := range streamNames {
+		fmt.Fprintf(w, "Queued build for stream: %s\n", streamName)
+	}
+}
@@ -34,6 +34,8 @@ func Setup(builder *builder.Builder, logger log.Logger) (*htmlWriter, error) {
 		srpc.ReceiverOptions{
 			PublicMethods: []string{
 				"BuildImage",
+				"CancelBuild",
+				"ListBuildQueue",
 			}})
 	return (*htmlWriter)(srpcObj), nil
 }
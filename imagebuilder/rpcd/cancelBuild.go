@@ -0,0 +1,16 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/errors"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func (t *srpcType) CancelBuild(conn *srpc.Conn,
+	request proto.CancelBuildRequest,
+	reply *proto.CancelBuildResponse) error {
+	*reply = proto.CancelBuildResponse{
+		errors.ErrorToString(t.builder.CancelBuild(request.Id,
+			conn.GetAuthInformation()))}
+	return nil
+}
@@ -0,0 +1,13 @@
+package rpcd
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	proto "github.com/Cloud-Foundations/Dominator/proto/imaginator"
+)
+
+func (t *srpcType) ListBuildQueue(conn *srpc.Conn,
+	request proto.ListBuildQueueRequest,
+	reply *proto.ListBuildQueueResponse) error {
+	*reply = proto.ListBuildQueueResponse{Builds: t.builder.ListBuildQueue()}
+	return nil
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
@@ -48,7 +49,12 @@ func FindLatestImage(client *srpc.Client, dirname string,
 }
 
 func GetImage(client *srpc.Client, name string) (*image.Image, error) {
-	return getImage(client, name, 0)
+	return getImage(context.Background(), client, name, 0)
+}
+
+func GetImageContext(ctx context.Context, client *srpc.Client, name string) (
+	*image.Image, error) {
+	return getImage(ctx, client, name, 0)
 }
 
 func GetImageExpiration(client *srpc.Client, name string) (time.Time, error) {
@@ -57,7 +63,12 @@ func GetImageExpiration(client *srpc.Client, name string) (time.Time, error) {
 
 func GetImageWithTimeout(client *srpc.Client, name string,
 	timeout time.Duration) (*image.Image, error) {
-	return getImage(client, name, timeout)
+	return getImage(context.Background(), client, name, timeout)
+}
+
+func GetImageWithTimeoutContext(ctx context.Context, client *srpc.Client,
+	name string, timeout time.Duration) (*image.Image, error) {
+	return getImage(ctx, client, name, timeout)
 }
 
 func ListDirectories(client *srpc.Client) ([]image.Directory, error) {
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/image"
@@ -8,11 +9,12 @@ import (
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
 
-func getImage(client *srpc.Client, name string, timeout time.Duration) (
-	*image.Image, error) {
+func getImage(ctx context.Context, client *srpc.Client, name string,
+	timeout time.Duration) (*image.Image, error) {
 	request := imageserver.GetImageRequest{ImageName: name, Timeout: timeout}
 	var reply imageserver.GetImageResponse
-	err := client.RequestReply("ImageServer.GetImage", request, &reply)
+	err := client.RequestReplyContext(ctx, "ImageServer.GetImage", request,
+		&reply)
 	if err != nil {
 		return nil, err
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/Cloud-Foundations/Dominator/imageserver/scanner"
 	"github.com/Cloud-Foundations/Dominator/lib/html"
 	"github.com/Cloud-Foundations/Dominator/lib/objectserver/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/vulndb"
 )
 
 type HtmlWriter interface {
@@ -35,10 +36,17 @@ func StartServer(portNum uint, imdb *scanner.ImageDataBase,
 	html.HandleFunc("/listDirectories", myState.listDirectoriesHandler)
 	html.HandleFunc("/listFilter", myState.listFilterHandler)
 	html.HandleFunc("/listImage", myState.listImageHandler)
+	html.HandleFunc("/listAdvisories", myState.listAdvisoriesHandler)
 	html.HandleFunc("/listImages", myState.listImagesHandler)
 	html.HandleFunc("/listPackages", myState.listPackagesHandler)
+	html.HandleFunc("/listProvenance", myState.listProvenanceHandler)
 	html.HandleFunc("/listReleaseNotes", myState.listReleaseNotesHandler)
+	html.HandleFunc("/listSBOM", myState.listSBOMHandler)
+	html.HandleFunc("/listTestReport", myState.listTestReportHandler)
 	html.HandleFunc("/listTriggers", myState.listTriggersHandler)
+	html.HandleFunc("/listVulnerabilities",
+		myState.listVulnerabilitiesHandler)
+	html.HandleFunc("/showAdvisory", myState.showAdvisoryHandler)
 	html.HandleFunc("/showImage", myState.showImageHandler)
 	if daemon {
 		go http.Serve(listener, nil)
@@ -51,3 +59,9 @@ func StartServer(portNum uint, imdb *scanner.ImageDataBase,
 func AddHtmlWriter(htmlWriter HtmlWriter) {
 	htmlWriters = append(htmlWriters, htmlWriter)
 }
+
+// WatchVulnerabilityDatabase will use the vulnerability databases sent over
+// the databases channel to report which images have vulnerable packages.
+func WatchVulnerabilityDatabase(databases <-chan *vulndb.Database) {
+	watchVulnerabilityDatabase(databases)
+}
@@ -41,8 +41,14 @@ func (s state) listImagesHandler(w http.ResponseWriter, req *http.Request) {
 	fmt.Fprintln(writer, "</body>")
 }
 
+// writeImage writes a row for an image. Images which failed tests are shown in
+// red.
 func writeImage(tw *html.TableWriter, name string, image *image.Image) {
-	tw.WriteRow("", "",
+	var foreground string
+	if image.TestsFailed {
+		foreground = "red"
+	}
+	tw.WriteRow(foreground, "",
 		fmt.Sprintf("<a href=\"showImage?%s\">%s</a>", name, name),
 		fmt.Sprintf("<a href=\"listImage?%s\">%s</a>",
 			name, format.FormatBytes(image.FileSystem.TotalDataBytes)),
@@ -0,0 +1,33 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+)
+
+func (s state) listProvenanceHandler(w http.ResponseWriter, req *http.Request) {
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	imageName := req.URL.RawQuery
+	fmt.Fprintf(writer, "<title>image %s</title>\n", imageName)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	image := s.imageDataBase.GetImage(imageName)
+	if image == nil {
+		fmt.Fprintf(writer, "Image: %s UNKNOWN!\n", imageName)
+		return
+	}
+	if image.Provenance == nil {
+		fmt.Fprintf(writer, "No provenance for image: %s\n", imageName)
+		return
+	}
+	if image.Provenance.Object == nil {
+		fmt.Fprintf(writer, "No provenance data for image: %s\n", imageName)
+		return
+	}
+	fmt.Fprintf(writer, "Provenance for image: %s<br>\n", imageName)
+	fmt.Fprintln(writer, "</h3>")
+	listObject(writer, s.objectServer, image.Provenance.Object)
+	fmt.Fprintln(writer, "</body>")
+}
@@ -0,0 +1,138 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+	libhtml "github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/testreport"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+)
+
+func (s state) getTestReport(img *image.Image) (*testreport.Report, error) {
+	if img.TestReport == nil || img.TestReport.Object == nil {
+		return nil, nil
+	}
+	_, reader, err := s.objectServer.GetObject(*img.TestReport.Object)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return testreport.Read(reader)
+}
+
+func (s state) listTestReportHandler(w http.ResponseWriter,
+	req *http.Request) {
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var imageName string
+	for name := range parsedQuery.Flags {
+		imageName = name
+	}
+	img := s.imageDataBase.GetImage(imageName)
+	if img == nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	report, err := s.getTestReport(img)
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(w, err)
+		return
+	}
+	if report == nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "No test report for image: %s\n", imageName)
+		return
+	}
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeText:
+		for _, result := range report.Tests {
+			status := "passed"
+			if !result.Passed {
+				status = "failed"
+				if result.Flaky {
+					status = "flaky"
+				}
+			}
+			fmt.Fprintln(writer, result.Name, status, result.ExitStatus,
+				result.Duration)
+		}
+		return
+	case url.OutputTypeJson:
+		if err := json.WriteWithIndent(writer, "    ", report); err != nil {
+			fmt.Fprintln(writer, err)
+		}
+		return
+	}
+	fmt.Fprintf(writer, "<title>image %s test report</title>\n", imageName)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "Test report for image: %s", imageName)
+	fmt.Fprintf(writer,
+		" <a href=\"listTestReport?%s&output=text\">text</a>", imageName)
+	fmt.Fprintf(writer,
+		" <a href=\"listTestReport?%s&output=json\">json</a>", imageName)
+	fmt.Fprintln(writer, "</h3>")
+	fmt.Fprintf(writer, "Summary: %s<br>\n", report.Summarise())
+	if report.Failed {
+		fmt.Fprintln(writer,
+			"<font color=\"red\">Image failed tests</font><br>")
+	}
+	fmt.Fprintln(writer, "<p>")
+	fmt.Fprintln(writer, `<table border="1">`)
+	tw, _ := libhtml.NewTableWriter(writer, true,
+		"Test", "Status", "Exit Status", "Duration")
+	for _, result := range report.Tests {
+		status := "passed"
+		background := ""
+		if !result.Passed {
+			status = "FAILED"
+			background = "#ffb0b0"
+			if result.Flaky {
+				status = "failed (allowed)"
+				background = "yellow"
+			}
+			if result.TimedOut {
+				status += ": timed out"
+			}
+		}
+		tw.WriteRow("", background,
+			fmt.Sprintf("<a href=\"#%s\">%s</a>", result.Name, result.Name),
+			status,
+			strconv.Itoa(result.ExitStatus),
+			format.Duration(result.Duration),
+		)
+	}
+	fmt.Fprintln(writer, "</table>")
+	for _, result := range report.Tests {
+		if result.Output == "" && result.Error == "" {
+			continue
+		}
+		fmt.Fprintf(writer, "<h4 id=\"%s\">%s</h4>\n",
+			result.Name, result.Name)
+		if result.Error != "" {
+			fmt.Fprintf(writer, "Error: %s<br>\n",
+				html.EscapeString(result.Error))
+		}
+		fmt.Fprintln(writer, "<pre>")
+		fmt.Fprint(writer, html.EscapeString(result.Output))
+		fmt.Fprintln(writer, "</pre>")
+	}
+	fmt.Fprintln(writer, "</body>")
+}
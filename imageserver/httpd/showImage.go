@@ -62,6 +62,22 @@ func (s state) showImageHandler(w http.ResponseWriter, req *http.Request) {
 		"listReleaseNotes")
 	showAnnotation(writer, image.BuildLog, imageName, "Build log",
 		"listBuildLog")
+	showAnnotation(writer, image.SBOM, imageName, "Software Bill Of Materials",
+		"listSBOM")
+	showAnnotation(writer, image.Provenance, imageName, "Build provenance",
+		"listProvenance")
+	if report, err := s.getTestReport(image); err != nil {
+		fmt.Fprintf(writer, "Error reading test report: %s<br>\n", err)
+	} else if report != nil {
+		fmt.Fprintf(writer, "Tests: <a href=\"listTestReport?%s\">%s</a>",
+			imageName, report.Summarise())
+		if report.Failed || image.TestsFailed {
+			fmt.Fprint(writer, " <font color=\"red\">(FAILED)</font>")
+		}
+		fmt.Fprintln(writer, "<br>")
+	} else if image.TestsFailed {
+		fmt.Fprintln(writer, "Tests: <font color=\"red\">FAILED</font><br>")
+	}
 	if image.CreatedBy != "" {
 		fmt.Fprintf(writer, "Created by: %s\n<br>", image.CreatedBy)
 	}
@@ -74,6 +90,11 @@ func (s state) showImageHandler(w http.ResponseWriter, req *http.Request) {
 		fmt.Fprintf(writer,
 			"Packages: <a href=\"listPackages?%s\">%d</a><br>\n",
 			imageName, len(image.Packages))
+		if db := getVulnerabilityDatabase(); db != nil {
+			fmt.Fprintf(writer,
+				"Vulnerabilities: <a href=\"listVulnerabilities?%s\">%d</a><br>\n",
+				imageName, len(s.matchImage(db, image)))
+		}
 	}
 	fmt.Fprintln(writer, "</body>")
 }
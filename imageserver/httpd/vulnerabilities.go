@@ -0,0 +1,366 @@
+package httpd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/html"
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+	"github.com/Cloud-Foundations/Dominator/lib/url"
+	"github.com/Cloud-Foundations/Dominator/lib/verstr"
+	"github.com/Cloud-Foundations/Dominator/lib/vulndb"
+)
+
+type affectedImage struct {
+	Image string
+	vulndb.Match
+}
+
+type advisorySummary struct {
+	AdvisoryId        string
+	NumAffectedImages uint
+	Summary           string `json:",omitempty"`
+}
+
+type vulnerabilityHtmlWriter struct{}
+
+var (
+	sbomPackagesLock          sync.Mutex
+	sbomPackages              = make(map[hash.Hash][]sbom.Package)
+	vulnerabilityDatabaseLock sync.RWMutex
+	vulnerabilityDatabase     *vulndb.Database
+)
+
+// forgetSBOMPackages forgets the cached SBOM packages which are not in use.
+func forgetSBOMPackages(inUse map[hash.Hash]struct{}) {
+	sbomPackagesLock.Lock()
+	defer sbomPackagesLock.Unlock()
+	for hashVal := range sbomPackages {
+		if _, ok := inUse[hashVal]; !ok {
+			delete(sbomPackages, hashVal)
+		}
+	}
+}
+
+func getVulnerabilityDatabase() *vulndb.Database {
+	vulnerabilityDatabaseLock.RLock()
+	defer vulnerabilityDatabaseLock.RUnlock()
+	return vulnerabilityDatabase
+}
+
+func watchVulnerabilityDatabase(databases <-chan *vulndb.Database) {
+	htmlWriters = append(htmlWriters, vulnerabilityHtmlWriter{})
+	go func() {
+		for db := range databases {
+			vulnerabilityDatabaseLock.Lock()
+			vulnerabilityDatabase = db
+			vulnerabilityDatabaseLock.Unlock()
+		}
+	}()
+}
+
+func writeNoVulnerabilityDatabase(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintln(w, "No vulnerability feed loaded")
+}
+
+func writeVulnerabilityTableStyle(writer io.Writer) {
+	fmt.Fprintln(writer, `<style>
+                          table, th, td {
+                          border-collapse: collapse;
+                          }
+                          </style>`)
+}
+
+// getAffectedImages returns the matches for all images, keyed by advisory ID.
+func (s state) getAffectedImages(
+	db *vulndb.Database) map[string][]affectedImage {
+	imageNames := s.imageDataBase.ListImages()
+	verstr.Sort(imageNames)
+	affectedImages := make(map[string][]affectedImage)
+	sbomsInUse := make(map[hash.Hash]struct{})
+	for _, imageName := range imageNames {
+		image := s.imageDataBase.GetImage(imageName)
+		if image == nil {
+			continue
+		}
+		if image.SBOM != nil && image.SBOM.Object != nil {
+			sbomsInUse[*image.SBOM.Object] = struct{}{}
+		}
+		for _, match := range s.matchImage(db, image) {
+			affectedImages[match.AdvisoryId] = append(
+				affectedImages[match.AdvisoryId],
+				affectedImage{Image: imageName, Match: match})
+		}
+	}
+	forgetSBOMPackages(sbomsInUse)
+	return affectedImages
+}
+
+// getSBOMPackages returns the packages in the SBOM for an image, or nil if the
+// image has no SBOM or it cannot be read. The packages are cached.
+func (s state) getSBOMPackages(img *image.Image) []sbom.Package {
+	if img.SBOM == nil || img.SBOM.Object == nil {
+		return nil
+	}
+	hashVal := *img.SBOM.Object
+	sbomPackagesLock.Lock()
+	packages, ok := sbomPackages[hashVal]
+	sbomPackagesLock.Unlock()
+	if ok {
+		return packages
+	}
+	_, reader, err := s.objectServer.GetObject(hashVal)
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	bom, err := sbom.Read(reader)
+	if err != nil {
+		return nil
+	}
+	sbomPackagesLock.Lock()
+	sbomPackages[hashVal] = bom.Packages
+	sbomPackagesLock.Unlock()
+	return bom.Packages
+}
+
+// matchImage returns the matches for an image. If the image has an SBOM, the
+// packages are also matched by source package name.
+func (s state) matchImage(db *vulndb.Database,
+	img *image.Image) []vulndb.Match {
+	if packages := s.getSBOMPackages(img); len(packages) > 0 {
+		return db.MatchSBOM(&sbom.SBOM{Packages: packages})
+	}
+	return db.Match(img.Packages)
+}
+
+func (s state) listAdvisoriesHandler(w http.ResponseWriter,
+	req *http.Request) {
+	db := getVulnerabilityDatabase()
+	if db == nil {
+		writeNoVulnerabilityDatabase(w)
+		return
+	}
+	parsedQuery := url.ParseQuery(req.URL)
+	affectedImages := s.getAffectedImages(db)
+	summaries := make([]advisorySummary, 0, len(affectedImages))
+	for id, images := range affectedImages {
+		imageNames := make(map[string]struct{}, len(images))
+		for _, image := range images {
+			imageNames[image.Image] = struct{}{}
+		}
+		summaries = append(summaries, advisorySummary{
+			AdvisoryId:        id,
+			NumAffectedImages: uint(len(imageNames)),
+			Summary:           images[0].Summary,
+		})
+	}
+	sort.Slice(summaries, func(left, right int) bool {
+		return summaries[left].AdvisoryId < summaries[right].AdvisoryId
+	})
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeText:
+		for _, summary := range summaries {
+			fmt.Fprintln(writer, summary.AdvisoryId, summary.NumAffectedImages)
+		}
+		return
+	case url.OutputTypeJson:
+		if err := json.WriteWithIndent(writer, "    ", summaries); err != nil {
+			fmt.Fprintln(writer, err)
+		}
+		return
+	}
+	fmt.Fprintln(writer, "<title>imageserver advisories</title>")
+	writeVulnerabilityTableStyle(writer)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "%d of %d advisories affect images",
+		len(summaries), db.NumAdvisories())
+	fmt.Fprintln(writer, ` <a href="listAdvisories?output=text">text</a>`)
+	fmt.Fprintln(writer, ` <a href="listAdvisories?output=json">json</a>`)
+	fmt.Fprintln(writer, "</h3>")
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"Advisory", "Affected Images", "Summary")
+	for _, summary := range summaries {
+		tw.WriteRow("", "",
+			fmt.Sprintf("<a href=\"showAdvisory?%s\">%s</a>",
+				summary.AdvisoryId, summary.AdvisoryId),
+			fmt.Sprintf("%d", summary.NumAffectedImages),
+			summary.Summary,
+		)
+	}
+	fmt.Fprintln(writer, "</table>")
+	fmt.Fprintln(writer, "</body>")
+}
+
+func (s state) listVulnerabilitiesHandler(w http.ResponseWriter,
+	req *http.Request) {
+	db := getVulnerabilityDatabase()
+	if db == nil {
+		writeNoVulnerabilityDatabase(w)
+		return
+	}
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var imageName string
+	for name := range parsedQuery.Flags {
+		imageName = name
+	}
+	image := s.imageDataBase.GetImage(imageName)
+	if image == nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	matches := s.matchImage(db, image)
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeText:
+		for _, match := range matches {
+			fmt.Fprintln(writer, match.AdvisoryId, match.PackageName,
+				match.PackageVersion, match.FixedVersion)
+		}
+		return
+	case url.OutputTypeJson:
+		if err := json.WriteWithIndent(writer, "    ", matches); err != nil {
+			fmt.Fprintln(writer, err)
+		}
+		return
+	}
+	fmt.Fprintf(writer, "<title>image %s vulnerabilities</title>\n", imageName)
+	writeVulnerabilityTableStyle(writer)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "Vulnerabilities in image: %s", imageName)
+	fmt.Fprintf(writer,
+		" <a href=\"listVulnerabilities?%s&output=text\">text</a>", imageName)
+	fmt.Fprintf(writer,
+		" <a href=\"listVulnerabilities?%s&output=json\">json</a>", imageName)
+	fmt.Fprintln(writer, "</h3>")
+	if len(matches) < 1 {
+		fmt.Fprintln(writer, "No known vulnerabilities<br>")
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"Advisory", "Package", "Source", "Version", "Fixed In", "Summary")
+	for _, match := range matches {
+		tw.WriteRow("", "",
+			fmt.Sprintf("<a href=\"showAdvisory?%s\">%s</a>",
+				match.AdvisoryId, match.AdvisoryId),
+			match.PackageName,
+			match.SourcePackage,
+			match.PackageVersion,
+			match.FixedVersion,
+			match.Summary,
+		)
+	}
+	fmt.Fprintln(writer, "</table>")
+	fmt.Fprintln(writer, "</body>")
+}
+
+func (s state) showAdvisoryHandler(w http.ResponseWriter, req *http.Request) {
+	db := getVulnerabilityDatabase()
+	if db == nil {
+		writeNoVulnerabilityDatabase(w)
+		return
+	}
+	parsedQuery := url.ParseQuery(req.URL)
+	if len(parsedQuery.Flags) != 1 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var advisoryId string
+	for name := range parsedQuery.Flags {
+		advisoryId = name
+	}
+	advisory := db.GetAdvisory(advisoryId)
+	if advisory == nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	images := s.getAffectedImages(db)[advisoryId]
+	writer := bufio.NewWriter(w)
+	defer writer.Flush()
+	switch parsedQuery.OutputType() {
+	case url.OutputTypeText:
+		for _, image := range images {
+			fmt.Fprintln(writer, image.Image, image.PackageName,
+				image.PackageVersion)
+		}
+		return
+	case url.OutputTypeJson:
+		if err := json.WriteWithIndent(writer, "    ", images); err != nil {
+			fmt.Fprintln(writer, err)
+		}
+		return
+	}
+	fmt.Fprintf(writer, "<title>advisory %s</title>\n", advisoryId)
+	writeVulnerabilityTableStyle(writer)
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintln(writer, "<h3>")
+	fmt.Fprintf(writer, "Images affected by advisory: %s", advisoryId)
+	fmt.Fprintf(writer,
+		" <a href=\"showAdvisory?%s&output=text\">text</a>", advisoryId)
+	fmt.Fprintf(writer,
+		" <a href=\"showAdvisory?%s&output=json\">json</a>", advisoryId)
+	fmt.Fprintln(writer, "</h3>")
+	if advisory.Summary != "" {
+		fmt.Fprintf(writer, "Summary: %s<br>\n", advisory.Summary)
+	}
+	if len(advisory.Aliases) > 0 {
+		fmt.Fprintf(writer, "Aliases: %s<br>\n",
+			strings.Join(advisory.Aliases, ", "))
+	}
+	if len(images) < 1 {
+		fmt.Fprintln(writer, "No images are affected<br>")
+		fmt.Fprintln(writer, "</body>")
+		return
+	}
+	fmt.Fprintln(writer, `<table border="1" style="width:100%">`)
+	tw, _ := html.NewTableWriter(writer, true,
+		"Image", "Package", "Version", "Fixed In")
+	for _, image := range images {
+		tw.WriteRow("", "",
+			fmt.Sprintf("<a href=\"listVulnerabilities?%s\">%s</a>",
+				image.Image, image.Image),
+			image.PackageName,
+			image.PackageVersion,
+			image.FixedVersion,
+		)
+	}
+	fmt.Fprintln(writer, "</table>")
+	fmt.Fprintln(writer, "</body>")
+}
+
+func (vulnerabilityHtmlWriter) WriteHtml(writer io.Writer) {
+	db := getVulnerabilityDatabase()
+	if db == nil {
+		fmt.Fprintln(writer, "Vulnerability feed: not loaded<br>")
+		return
+	}
+	fmt.Fprintf(writer,
+		"Vulnerability feed: %d advisories, <a href=\"listAdvisories\">affected images</a><br>\n",
+		db.NumAdvisories())
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	iclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
@@ -39,11 +40,12 @@ func (t *srpcType) AddImageTrusted(conn *srpc.Conn,
 		return err
 	}
 	request.Image.FileSystem.RebuildInodePointers()
+	logger := tracelogger.New(conn.Context(), t.logger)
 	username := request.Image.CreatedBy
 	if username == "" {
-		t.logger.Printf("AddImage(%s)\n", request.ImageName)
+		logger.Printf("AddImage(%s)\n", request.ImageName)
 	} else {
-		t.logger.Printf("AddImage(%s) by %s\n", request.ImageName, username)
+		logger.Printf("AddImage(%s) by %s\n", request.ImageName, username)
 	}
 	return t.imageDataBase.AddImage(request.Image, request.ImageName,
 		conn.GetAuthInformation())
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os/user"
 
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
@@ -11,6 +12,7 @@ import (
 func (t *srpcType) ChownDirectory(conn *srpc.Conn,
 	request imageserver.ChangeOwnerRequest,
 	reply *imageserver.ChangeOwnerResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	username := conn.Username()
 	if username == "" {
 		return errors.New("no username: unauthenticated connection")
@@ -20,7 +22,7 @@ func (t *srpcType) ChownDirectory(conn *srpc.Conn,
 			return err
 		}
 	}
-	t.logger.Printf("ChownDirectory(%s) to: \"%s\" by %s\n",
+	logger.Printf("ChownDirectory(%s) to: \"%s\" by %s\n",
 		request.DirectoryName, request.OwnerGroup, username)
 	return t.imageDataBase.ChownDirectory(request.DirectoryName,
 		request.OwnerGroup, conn.GetAuthInformation())
@@ -3,6 +3,7 @@ package rpcd
 import (
 	"errors"
 
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
@@ -10,6 +11,7 @@ import (
 func (t *srpcType) DeleteImage(conn *srpc.Conn,
 	request imageserver.DeleteImageRequest,
 	reply *imageserver.DeleteImageResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	username := conn.Username()
 	if err := t.checkMutability(); err != nil {
 		return err
@@ -18,9 +20,9 @@ func (t *srpcType) DeleteImage(conn *srpc.Conn,
 		return errors.New("image does not exist")
 	}
 	if username == "" {
-		t.logger.Printf("DeleteImage(%s)\n", request.ImageName)
+		logger.Printf("DeleteImage(%s)\n", request.ImageName)
 	} else {
-		t.logger.Printf("DeleteImage(%s) by %s\n", request.ImageName, username)
+		logger.Printf("DeleteImage(%s) by %s\n", request.ImageName, username)
 	}
 	return t.imageDataBase.DeleteImage(request.ImageName,
 		conn.GetAuthInformation())
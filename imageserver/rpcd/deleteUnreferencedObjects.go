@@ -2,6 +2,7 @@ package rpcd
 
 import (
 	"github.com/Cloud-Foundations/Dominator/lib/format"
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
@@ -9,12 +10,13 @@ import (
 func (t *srpcType) DeleteUnreferencedObjects(conn *srpc.Conn,
 	request imageserver.DeleteUnreferencedObjectsRequest,
 	reply *imageserver.DeleteUnreferencedObjectsResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	username := conn.Username()
 	if username == "" {
-		t.logger.Printf("DeleteUnreferencedObjects(%d%%, %s)\n",
+		logger.Printf("DeleteUnreferencedObjects(%d%%, %s)\n",
 			request.Percentage, format.FormatBytes(request.Bytes))
 	} else {
-		t.logger.Printf("DeleteUnreferencedObjects(%d%%, %s) by %s\n",
+		logger.Printf("DeleteUnreferencedObjects(%d%%, %s) by %s\n",
 			request.Percentage, format.FormatBytes(request.Bytes), username)
 	}
 	return t.imageDataBase.DeleteUnreferencedObjects(request.Percentage,
@@ -1,6 +1,7 @@
 package rpcd
 
 import (
+	"github.com/Cloud-Foundations/Dominator/lib/log/tracelogger"
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
 	"github.com/Cloud-Foundations/Dominator/proto/imageserver"
 )
@@ -8,14 +9,15 @@ import (
 func (t *srpcType) MakeDirectory(conn *srpc.Conn,
 	request imageserver.MakeDirectoryRequest,
 	reply *imageserver.MakeDirectoryResponse) error {
+	logger := tracelogger.New(conn.Context(), t.logger)
 	username := conn.Username()
 	if err := t.checkMutability(); err != nil {
 		return err
 	}
 	if username == "" {
-		t.logger.Printf("MakeDirectory(%s)\n", request.DirectoryName)
+		logger.Printf("MakeDirectory(%s)\n", request.DirectoryName)
 	} else {
-		t.logger.Printf("MakeDirectory(%s) by %s\n",
+		logger.Printf("MakeDirectory(%s) by %s\n",
 			request.DirectoryName, username)
 	}
 	return t.imageDataBase.MakeDirectory(request.DirectoryName,
@@ -0,0 +1,56 @@
+/*
+Package oci converts between file-systems and OCI or Docker container
+images.
+
+Container images may be read from an OCI image layout directory, an OCI
+archive (a tarfile containing an OCI image layout) or a Docker archive
+(as produced by "docker save"). The layers are flattened into a single
+file-system, honouring whiteout files. File-systems are written as
+single-layer OCI images.
+*/
+package oci
+
+import (
+	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/untar"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+)
+
+type WriteParams struct {
+	Architecture string    // Default: runtime.GOARCH.
+	Created      time.Time // Default: now.
+	Name         string    // Used as the reference name in the index.
+}
+
+// Decode reads the container image in the OCI image layout or unpacked Docker
+// archive in dirname and returns the flattened file-system. The data for
+// regular files are passed to hasher.
+func Decode(dirname string, hasher untar.Hasher, filter *filter.Filter) (
+	*filesystem.FileSystem, error) {
+	return decode(dirname, hasher, filter)
+}
+
+// DecodeArchive reads an OCI archive or Docker archive (which may be gzip
+// compressed) and returns the flattened file-system. The archive is unpacked
+// into a temporary directory.
+func DecodeArchive(reader io.Reader, hasher untar.Hasher,
+	filter *filter.Filter) (*filesystem.FileSystem, error) {
+	return decodeArchive(reader, hasher, filter)
+}
+
+// WriteArchive writes the file-system as a single-layer OCI archive.
+func WriteArchive(writer io.Writer, fileSystem *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter, params WriteParams) error {
+	return writeArchive(writer, fileSystem, objectsGetter, params)
+}
+
+// WriteLayout writes the file-system as a single-layer OCI image layout in
+// dirname, which must not contain an existing layout.
+func WriteLayout(dirname string, fileSystem *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter, params WriteParams) error {
+	return writeLayout(dirname, fileSystem, objectsGetter, params)
+}
@@ -0,0 +1,452 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/untar"
+	"github.com/Cloud-Foundations/Dominator/lib/filter"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type entryType struct {
+	header *tar.Header
+	layer  int
+	serial uint64 // Position in the layer, to identify the entry in the layer.
+}
+
+type flattenerType struct {
+	entries map[string]*entryType // Key: normalised pathname.
+}
+
+func decode(dirname string, hasher untar.Hasher, filter *filter.Filter) (
+	*filesystem.FileSystem, error) {
+	layers, err := getLayerFilenames(dirname)
+	if err != nil {
+		return nil, err
+	}
+	flattener := &flattenerType{entries: make(map[string]*entryType)}
+	for layerIndex, filename := range layers {
+		err := forEachLayerEntry(filename,
+			func(header *tar.Header, serial uint64, reader io.Reader) error {
+				return flattener.addHeader(header, layerIndex, serial)
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(flattener.write(pipeWriter, layers))
+	}()
+	fs, err := untar.Decode(tar.NewReader(pipeReader), hasher, filter)
+	pipeReader.Close()
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func decodeArchive(reader io.Reader, hasher untar.Hasher,
+	filter *filter.Filter) (*filesystem.FileSystem, error) {
+	dirname, err := ioutil.TempDir("", "oci")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dirname)
+	if err := extractArchive(reader, dirname); err != nil {
+		return nil, err
+	}
+	return decode(dirname, hasher, filter)
+}
+
+// blobFilename returns the filename of the blob with the specified digest.
+func blobFilename(dirname, digest string) (string, error) {
+	splitDigest := strings.SplitN(digest, ":", 2)
+	if len(splitDigest) != 2 || splitDigest[0] == "" || splitDigest[1] == "" ||
+		strings.ContainsAny(digest, "/\\") || strings.Contains(digest, "..") {
+		return "", fmt.Errorf("invalid digest: %s", digest)
+	}
+	return filepath.Join(dirname, "blobs", splitDigest[0], splitDigest[1]), nil
+}
+
+// extractArchive extracts the regular files, directories and symlinks in an
+// archive. Older "docker save" archives use symlinks to share layers between
+// images. Symlinks are created after the other entries, so that no entries are
+// written through them, and only if they point to regular files in the archive.
+func extractArchive(reader io.Reader, dirname string) error {
+	reader, err := maybeDecompress(bufio.NewReader(reader))
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(reader)
+	var symlinks []*tar.Header
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return extractSymlinks(symlinks, dirname)
+			}
+			return err
+		}
+		name := normaliseName(header.Name)
+		if name == "/" {
+			continue
+		}
+		filename := filepath.Join(dirname, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(filename, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				return err
+			}
+			file, err := os.Create(filename)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			header.Name = name
+			symlinks = append(symlinks, header)
+		}
+	}
+}
+
+// extractSymlinks creates the symlinks which point to regular files in dirname.
+// Other symlinks are ignored.
+func extractSymlinks(symlinks []*tar.Header, dirname string) error {
+	for _, header := range symlinks {
+		target := header.Linkname
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(header.Name), target)
+		}
+		target = path.Clean(target)
+		if !path.IsAbs(target) || target == "/" {
+			continue // Escapes the archive.
+		}
+		fi, err := os.Lstat(filepath.Join(dirname, target))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		filename := filepath.Join(dirname, header.Name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(filepath.Join(dirname, target),
+			filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forEachLayerEntry(filename string,
+	fn func(header *tar.Header, serial uint64, reader io.Reader) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := maybeDecompress(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	tarReader := tar.NewReader(reader)
+	for serial := uint64(0); ; serial++ {
+		header, err := tarReader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+		header.Name = normaliseName(header.Name)
+		if err := fn(header, serial, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// getLayerFilenames returns the filenames of the layers of the image in an
+// OCI image layout or unpacked Docker archive, lowest layer first.
+func getLayerFilenames(dirname string) ([]string, error) {
+	var dockerManifests []dockerManifest
+	err := readJson(filepath.Join(dirname, "manifest.json"), &dockerManifests)
+	if err == nil {
+		if len(dockerManifests) != 1 {
+			return nil, fmt.Errorf("archive has %d images, need one",
+				len(dockerManifests))
+		}
+		var filenames []string
+		for _, layer := range dockerManifests[0].Layers {
+			if strings.Contains(layer, "..") {
+				return nil, fmt.Errorf("invalid layer: %s", layer)
+			}
+			filenames = append(filenames, filepath.Join(dirname, layer))
+		}
+		return filenames, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	var imageIndex index
+	if err := readJson(filepath.Join(dirname, "index.json"),
+		&imageIndex); err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(
+				"not an OCI image layout or Docker archive")
+		}
+		return nil, err
+	}
+	for {
+		desc, err := selectManifest(imageIndex.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		filename, err := blobFilename(dirname, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		switch desc.MediaType {
+		case mediaTypeImageIndex, mediaTypeDockerManifestList:
+			imageIndex = index{}
+			if err := readJson(filename, &imageIndex); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var imageManifest manifest
+		if err := readJson(filename, &imageManifest); err != nil {
+			return nil, err
+		}
+		var filenames []string
+		for _, layer := range imageManifest.Layers {
+			filename, err := blobFilename(dirname, layer.Digest)
+			if err != nil {
+				return nil, err
+			}
+			filenames = append(filenames, filename)
+		}
+		return filenames, nil
+	}
+}
+
+// maybeDecompress returns a reader which decompresses the data if they are
+// gzip compressed.
+func maybeDecompress(reader *bufio.Reader) (io.Reader, error) {
+	magic, err := reader.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.HasPrefix(magic, gzipMagic) {
+		return gzip.NewReader(reader)
+	}
+	if bytes.HasPrefix(magic, zstdMagic) {
+		return nil, errors.New("zstd compressed layers are not supported")
+	}
+	return reader, nil
+}
+
+// normaliseName converts a pathname in a tarfile to an absolute, clean
+// pathname which cannot escape the root.
+func normaliseName(name string) string {
+	return path.Clean("/" + name)
+}
+
+func readJson(filename string, value interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(value); err != nil {
+		return fmt.Errorf("error decoding: %s: %s", filename, err)
+	}
+	return nil
+}
+
+// selectManifest selects the manifest for this platform if there are several.
+func selectManifest(manifests []descriptor) (descriptor, error) {
+	if len(manifests) < 1 {
+		return descriptor{}, errors.New("no manifests in index")
+	}
+	for _, desc := range manifests {
+		if desc.Platform != nil && desc.Platform.OS == "linux" &&
+			desc.Platform.Architecture == runtime.GOARCH {
+			return desc, nil
+		}
+	}
+	return manifests[0], nil
+}
+
+func (f *flattenerType) addHeader(header *tar.Header, layer int,
+	serial uint64) error {
+	dirname, leafName := path.Split(header.Name)
+	dirname = path.Clean(dirname)
+	if leafName == whiteoutOpaque {
+		f.removeChildren(dirname, layer)
+		return nil
+	}
+	if strings.HasPrefix(leafName, whiteoutPrefix) {
+		f.removeTree(path.Join(dirname, leafName[len(whiteoutPrefix):]),
+			layer)
+		return nil
+	}
+	if oldEntry := f.entries[header.Name]; oldEntry != nil &&
+		oldEntry.header.Typeflag == tar.TypeDir &&
+		header.Typeflag != tar.TypeDir {
+		f.removeChildren(header.Name, layer)
+	}
+	if err := f.makeParents(dirname, layer); err != nil {
+		return fmt.Errorf("%s: %s", header.Name, err)
+	}
+	f.entries[header.Name] = &entryType{
+		header: header,
+		layer:  layer,
+		serial: serial,
+	}
+	return nil
+}
+
+// makeParents adds any missing parent directories, since layers are not
+// required to contain entries for them. If a parent exists and is not a
+// directory (such as a symlink), an error is returned rather than replacing it,
+// which would change the contents of the lower layer.
+func (f *flattenerType) makeParents(dirname string, layer int) error {
+	if entry := f.entries[dirname]; entry != nil {
+		if entry.header.Typeflag == tar.TypeDir {
+			return nil
+		}
+		return fmt.Errorf("parent: %s is not a directory", dirname)
+	}
+	if dirname != "/" {
+		if err := f.makeParents(path.Dir(dirname), layer); err != nil {
+			return err
+		}
+	}
+	f.entries[dirname] = &entryType{
+		header: &tar.Header{
+			Mode:     0755,
+			Name:     dirname,
+			Typeflag: tar.TypeDir,
+		},
+		layer:  layer,
+		serial: ^uint64(0),
+	}
+	return nil
+}
+
+// removeChildren removes the entries below dirname from lower layers.
+func (f *flattenerType) removeChildren(dirname string, layer int) {
+	prefix := dirname + "/"
+	if dirname == "/" {
+		prefix = "/"
+	}
+	for name, entry := range f.entries {
+		if entry.layer < layer && name != "/" &&
+			strings.HasPrefix(name, prefix) {
+			delete(f.entries, name)
+		}
+	}
+}
+
+// removeTree removes the entry and entries below it from lower layers.
+func (f *flattenerType) removeTree(pathname string, layer int) {
+	if entry := f.entries[pathname]; entry != nil && entry.layer < layer {
+		delete(f.entries, pathname)
+	}
+	f.removeChildren(pathname, layer)
+}
+
+// write writes a tarfile with the flattened file-system. Directories are
+// written first so that parents precede their children and hardlinks are
+// written last so that their targets precede them.
+func (f *flattenerType) write(writer io.Writer, layers []string) error {
+	tarWriter := tar.NewWriter(writer)
+	var directories []string
+	for name, entry := range f.entries {
+		if entry.header.Typeflag == tar.TypeDir {
+			directories = append(directories, name)
+		}
+	}
+	sort.Strings(directories)
+	for _, name := range directories {
+		if err := writeHeader(tarWriter, f.entries[name].header); err != nil {
+			return err
+		}
+	}
+	var hardlinks []*tar.Header
+	writtenFiles := make(map[string]struct{})
+	for layerIndex, filename := range layers {
+		err := forEachLayerEntry(filename,
+			func(header *tar.Header, serial uint64, reader io.Reader) error {
+				entry := f.entries[header.Name]
+				if entry == nil || entry.layer != layerIndex ||
+					entry.serial != serial ||
+					header.Typeflag == tar.TypeDir {
+					return nil
+				}
+				if header.Typeflag == tar.TypeLink {
+					hardlinks = append(hardlinks, header)
+					return nil
+				}
+				if err := writeHeader(tarWriter, header); err != nil {
+					return err
+				}
+				if _, err := io.Copy(tarWriter, reader); err != nil {
+					return err
+				}
+				writtenFiles[header.Name] = struct{}{}
+				return nil
+			})
+		if err != nil {
+			return err
+		}
+	}
+	for _, header := range hardlinks {
+		header.Linkname = normaliseName(header.Linkname)
+		if _, ok := writtenFiles[header.Linkname]; !ok {
+			continue // Target was removed or replaced.
+		}
+		if err := writeHeader(tarWriter, header); err != nil {
+			return err
+		}
+	}
+	return tarWriter.Close()
+}
+
+func writeHeader(tarWriter *tar.Writer, header *tar.Header) error {
+	newHeader := *header
+	newHeader.Name = "." + header.Name
+	if header.Name == "/" {
+		newHeader.Name = "./"
+	}
+	if header.Typeflag == tar.TypeLink {
+		newHeader.Linkname = "." + header.Linkname
+	}
+	return tarWriter.WriteHeader(&newHeader)
+}
@@ -0,0 +1,233 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver/memory"
+)
+
+type testFile struct {
+	data     string
+	linkname string
+	name     string
+	typeflag byte
+}
+
+type testHasher struct {
+	objSrv *memory.ObjectServer
+}
+
+func (h testHasher) Hash(reader io.Reader, length uint64) (hash.Hash, error) {
+	hashVal, _, err := h.objSrv.AddObject(reader, length, nil)
+	return hashVal, err
+}
+
+func makeLayer(t *testing.T, filename string, files []testFile) {
+	err := ioutil.WriteFile(filename, makeTar(t, files), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeTar(t *testing.T, files []testFile) []byte {
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+	for _, file := range files {
+		header := &tar.Header{
+			Linkname: file.linkname,
+			Mode:     0644,
+			Name:     file.name,
+			Size:     int64(len(file.data)),
+			Typeflag: file.typeflag,
+		}
+		if file.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func getFiles(fs *filesystem.FileSystem) map[string]filesystem.GenericInode {
+	files := make(map[string]filesystem.GenericInode)
+	fs.ForEachFile(
+		func(name string, inodeNumber uint64,
+			inode filesystem.GenericInode) error {
+			files[name] = inode
+			return nil
+		})
+	return files
+}
+
+func TestDecodeDockerArchive(t *testing.T) {
+	dirname := t.TempDir()
+	makeLayer(t, filepath.Join(dirname, "layer0.tar"), []testFile{
+		{name: "./", typeflag: tar.TypeDir},
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/passwd", data: "root", typeflag: tar.TypeReg},
+		{name: "etc/hosts", data: "localhost", typeflag: tar.TypeReg},
+		{name: "opt/app/lib/old.so", data: "old", typeflag: tar.TypeReg},
+		{name: "var/log/messages", data: "log", typeflag: tar.TypeReg},
+	})
+	makeLayer(t, filepath.Join(dirname, "layer1.tar"), []testFile{
+		{name: "etc/.wh.hosts", typeflag: tar.TypeReg},
+		{name: "etc/passwd", data: "root:x", typeflag: tar.TypeReg},
+		{name: "etc/passwd.link", linkname: "etc/passwd",
+			typeflag: tar.TypeLink},
+		{name: "opt/app/.wh..wh..opq", typeflag: tar.TypeReg},
+		{name: "opt/app/new.so", data: "new", typeflag: tar.TypeReg},
+		{name: "var/.wh.log", typeflag: tar.TypeReg},
+	})
+	data, _ := json.Marshal([]dockerManifest{{
+		Layers: []string{"layer0.tar", "layer1.tar"},
+	}})
+	err := ioutil.WriteFile(filepath.Join(dirname, "manifest.json"), data,
+		0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objSrv := memory.NewObjectServer()
+	fs, err := Decode(dirname, testHasher{objSrv}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := getFiles(fs)
+	for _, name := range []string{"/etc/hosts", "/opt/app/lib",
+		"/var/log", "/var/log/messages"} {
+		if _, ok := files[name]; ok {
+			t.Errorf("%s not removed", name)
+		}
+	}
+	for _, name := range []string{"/etc/passwd", "/etc/passwd.link",
+		"/opt/app/new.so", "/var"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s missing", name)
+		}
+	}
+	inode, ok := files["/etc/passwd"].(*filesystem.RegularInode)
+	if !ok {
+		t.Fatal("/etc/passwd is not a regular file")
+	}
+	inodeNumbers := fs.FilenameToInodeTable()
+	if inodeNumbers["/etc/passwd"] != inodeNumbers["/etc/passwd.link"] {
+		t.Error("/etc/passwd.link is not a hardlink to /etc/passwd")
+	}
+	_, reader, err := objSrv.GetObject(inode.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if data, _ := ioutil.ReadAll(reader); string(data) != "root:x" {
+		t.Errorf("/etc/passwd contains: %s", string(data))
+	}
+}
+
+func TestDecodeArchiveSymlinkedLayer(t *testing.T) {
+	layer := makeTar(t, []testFile{
+		{name: "etc/passwd", data: "root", typeflag: tar.TypeReg},
+	})
+	manifest, _ := json.Marshal([]dockerManifest{{
+		Layers: []string{"a/layer.tar", "b/layer.tar", "c/layer.tar"},
+	}})
+	archive := makeTar(t, []testFile{
+		{name: "a/", typeflag: tar.TypeDir},
+		{name: "a/layer.tar", data: string(layer), typeflag: tar.TypeReg},
+		{name: "b/", typeflag: tar.TypeDir},
+		{name: "b/layer.tar", linkname: "../a/layer.tar",
+			typeflag: tar.TypeSymlink},
+		{name: "c/layer.tar", linkname: "/a/layer.tar",
+			typeflag: tar.TypeSymlink},
+		{name: "escape", linkname: "../../../etc/passwd",
+			typeflag: tar.TypeSymlink},
+		{name: "manifest.json", data: string(manifest),
+			typeflag: tar.TypeReg},
+	})
+	fs, err := DecodeArchive(bytes.NewReader(archive),
+		testHasher{memory.NewObjectServer()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := getFiles(fs)["/etc/passwd"]; !ok {
+		t.Error("/etc/passwd missing")
+	}
+}
+
+func TestDecodeParentNotDirectory(t *testing.T) {
+	dirname := t.TempDir()
+	makeLayer(t, filepath.Join(dirname, "layer0.tar"), []testFile{
+		{name: "usr/bin/", typeflag: tar.TypeDir},
+		{name: "bin", linkname: "usr/bin", typeflag: tar.TypeSymlink},
+	})
+	makeLayer(t, filepath.Join(dirname, "layer1.tar"), []testFile{
+		{name: "bin/sh", data: "shell", typeflag: tar.TypeReg},
+	})
+	data, _ := json.Marshal([]dockerManifest{{
+		Layers: []string{"layer0.tar", "layer1.tar"},
+	}})
+	err := ioutil.WriteFile(filepath.Join(dirname, "manifest.json"), data,
+		0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Decode(dirname, testHasher{memory.NewObjectServer()}, nil)
+	if err == nil {
+		t.Fatal("no error when parent is a symlink")
+	}
+	if !strings.Contains(err.Error(), "/bin is not a directory") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	dirname := t.TempDir()
+	makeLayer(t, filepath.Join(dirname, "layer.tar"), []testFile{
+		{name: "bin/", typeflag: tar.TypeDir},
+		{name: "bin/sh", data: "shell", typeflag: tar.TypeReg},
+		{name: "bin/bash", linkname: "sh", typeflag: tar.TypeSymlink},
+	})
+	data, _ := json.Marshal([]dockerManifest{{Layers: []string{"layer.tar"}}})
+	err := ioutil.WriteFile(filepath.Join(dirname, "manifest.json"), data,
+		0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objSrv := memory.NewObjectServer()
+	fs, err := Decode(dirname, testHasher{objSrv}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := &bytes.Buffer{}
+	if err := WriteArchive(archive, fs, objSrv, WriteParams{}); err != nil {
+		t.Fatal(err)
+	}
+	fs, err = DecodeArchive(archive, testHasher{objSrv}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := getFiles(fs)
+	if inode, ok := files["/bin/bash"].(*filesystem.SymlinkInode); !ok {
+		t.Error("/bin/bash is not a symlink")
+	} else if inode.Symlink != "sh" {
+		t.Errorf("/bin/bash points to: %s", inode.Symlink)
+	}
+	if _, ok := files["/bin/sh"].(*filesystem.RegularInode); !ok {
+		t.Error("/bin/sh is not a regular file")
+	}
+}
@@ -0,0 +1,73 @@
+package oci
+
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeImageConfig        = "application/vnd.oci.image.config.v1+json"
+	mediaTypeImageIndex         = "application/vnd.oci.image.index.v1+json"
+	mediaTypeImageLayerGzip     = "application/vnd.oci.image.layer.v1.tar+gzip"
+	mediaTypeImageManifest      = "application/vnd.oci.image.manifest.v1+json"
+
+	annotationRefName = "org.opencontainers.image.ref.name"
+	whiteoutOpaque    = ".wh..wh..opq"
+	whiteoutPrefix    = ".wh."
+)
+
+type countingWriter struct {
+	count int64
+}
+
+type descriptor struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Digest      string            `json:"digest"`
+	MediaType   string            `json:"mediaType"`
+	Platform    *platform         `json:"platform,omitempty"`
+	Size        int64             `json:"size"`
+}
+
+type dockerManifest struct {
+	Config   string
+	Layers   []string
+	RepoTags []string
+}
+
+type imageConfig struct {
+	Architecture string         `json:"architecture"`
+	Config       struct{}       `json:"config"`
+	Created      string         `json:"created,omitempty"`
+	History      []history      `json:"history,omitempty"`
+	OS           string         `json:"os"`
+	RootFS       rootFileSystem `json:"rootfs"`
+}
+
+type history struct {
+	Created   string `json:"created,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+type index struct {
+	Manifests     []descriptor `json:"manifests"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	SchemaVersion int          `json:"schemaVersion"`
+}
+
+type layout struct {
+	ImageLayoutVersion string `json:"imageLayoutVersion"`
+}
+
+type manifest struct {
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	SchemaVersion int          `json:"schemaVersion"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+type rootFileSystem struct {
+	DiffIds []string `json:"diff_ids"`
+	Type    string   `json:"type"`
+}
@@ -0,0 +1,224 @@
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+	fstar "github.com/Cloud-Foundations/Dominator/lib/filesystem/tar"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/objectserver"
+)
+
+func formatDigest(hasher hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+}
+
+func writeArchive(writer io.Writer, fileSystem *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter, params WriteParams) error {
+	dirname, err := ioutil.TempDir("", "oci")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dirname)
+	err = writeLayout(dirname, fileSystem, objectsGetter, params)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(writer)
+	err = filepath.Walk(dirname,
+		func(pathname string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(dirname, pathname)
+			if err != nil {
+				return err
+			}
+			if name == "." {
+				return nil
+			}
+			header, err := tar.FileInfoHeader(fi, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(name)
+			if fi.IsDir() {
+				header.Name += "/"
+			}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			file, err := os.Open(pathname)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(tarWriter, file)
+			return err
+		})
+	if err != nil {
+		tarWriter.Close()
+		return err
+	}
+	return tarWriter.Close()
+}
+
+// writeBlob writes the JSON encoding of value as a blob and returns its
+// descriptor.
+func writeBlob(dirname, mediaType string, value interface{}) (
+	descriptor, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return descriptor{}, err
+	}
+	hasher := sha256.New()
+	hasher.Write(data)
+	desc := descriptor{
+		Digest:    formatDigest(hasher),
+		MediaType: mediaType,
+		Size:      int64(len(data)),
+	}
+	filename, err := blobFilename(dirname, desc.Digest)
+	if err != nil {
+		return descriptor{}, err
+	}
+	return desc, ioutil.WriteFile(filename, data, fsutil.PublicFilePerms)
+}
+
+// writeLayer writes the file-system as a gzip compressed layer blob and
+// returns its descriptor and the digest of the uncompressed layer.
+func writeLayer(dirname string, fileSystem *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter) (descriptor, string, error) {
+	tmpFilename := filepath.Join(dirname, "blobs", "sha256", "layer.tmp")
+	file, err := os.OpenFile(tmpFilename, os.O_CREATE|os.O_WRONLY|os.O_EXCL,
+		fsutil.PublicFilePerms)
+	if err != nil {
+		return descriptor{}, "", err
+	}
+	defer os.Remove(tmpFilename)
+	defer file.Close()
+	compressedHasher := sha256.New()
+	counter := &countingWriter{}
+	gzipWriter := gzip.NewWriter(io.MultiWriter(file, compressedHasher,
+		counter))
+	uncompressedHasher := sha256.New()
+	err = fstar.Write(io.MultiWriter(gzipWriter, uncompressedHasher),
+		fileSystem, objectsGetter)
+	if err != nil {
+		return descriptor{}, "", err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return descriptor{}, "", err
+	}
+	if err := file.Close(); err != nil {
+		return descriptor{}, "", err
+	}
+	desc := descriptor{
+		Digest:    formatDigest(compressedHasher),
+		MediaType: mediaTypeImageLayerGzip,
+		Size:      counter.count,
+	}
+	filename, err := blobFilename(dirname, desc.Digest)
+	if err != nil {
+		return descriptor{}, "", err
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		return descriptor{}, "", err
+	}
+	return desc, formatDigest(uncompressedHasher), nil
+}
+
+func writeLayout(dirname string, fileSystem *filesystem.FileSystem,
+	objectsGetter objectserver.ObjectsGetter, params WriteParams) error {
+	if params.Architecture == "" {
+		params.Architecture = runtime.GOARCH
+	}
+	if params.Created.IsZero() {
+		params.Created = time.Now()
+	}
+	err := os.MkdirAll(filepath.Join(dirname, "blobs", "sha256"),
+		fsutil.DirPerms)
+	if err != nil {
+		return err
+	}
+	layerDescriptor, diffId, err := writeLayer(dirname, fileSystem,
+		objectsGetter)
+	if err != nil {
+		return err
+	}
+	created := params.Created.UTC().Format(time.RFC3339)
+	configDescriptor, err := writeBlob(dirname, mediaTypeImageConfig,
+		imageConfig{
+			Architecture: params.Architecture,
+			Created:      created,
+			History: []history{{
+				Created:   created,
+				CreatedBy: "Dominator image: " + params.Name,
+			}},
+			OS: "linux",
+			RootFS: rootFileSystem{
+				DiffIds: []string{diffId},
+				Type:    "layers",
+			},
+		})
+	if err != nil {
+		return err
+	}
+	manifestDescriptor, err := writeBlob(dirname, mediaTypeImageManifest,
+		manifest{
+			Config:        configDescriptor,
+			Layers:        []descriptor{layerDescriptor},
+			MediaType:     mediaTypeImageManifest,
+			SchemaVersion: 2,
+		})
+	if err != nil {
+		return err
+	}
+	manifestDescriptor.Platform = &platform{
+		Architecture: params.Architecture,
+		OS:           "linux",
+	}
+	if params.Name != "" {
+		manifestDescriptor.Annotations = map[string]string{
+			annotationRefName: params.Name,
+		}
+	}
+	err = writeJson(filepath.Join(dirname, "index.json"), index{
+		Manifests:     []descriptor{manifestDescriptor},
+		MediaType:     mediaTypeImageIndex,
+		SchemaVersion: 2,
+	})
+	if err != nil {
+		return err
+	}
+	return writeJson(filepath.Join(dirname, "oci-layout"),
+		layout{ImageLayoutVersion: "1.0.0"})
+}
+
+func writeJson(filename string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'),
+		fsutil.PublicFilePerms)
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.count += int64(len(p))
+	return len(p), nil
+}
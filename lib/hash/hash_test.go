@@ -0,0 +1,37 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalText(t *testing.T) {
+	var h Hash
+	for index := range h {
+		h[index] = byte(index * 4)
+	}
+	text, err := h.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(text) != 128 || !strings.HasPrefix(string(text), "0004080c10") {
+		t.Fatalf("bad text: %s", text)
+	}
+	var readHash Hash
+	if err := readHash.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if readHash != h {
+		t.Errorf("expected: %x, got: %x", h, readHash)
+	}
+}
+
+func TestUnmarshalTextErrors(t *testing.T) {
+	var h Hash
+	if err := h.UnmarshalText([]byte("0011")); err == nil {
+		t.Error("no error for short text")
+	}
+	if err := h.UnmarshalText([]byte(strings.Repeat("zz", 64))); err == nil {
+		t.Error("no error for non-hexadecimal text")
+	}
+}
@@ -1,5 +1,10 @@
 package hash
 
+import (
+	"encoding/hex"
+	"errors"
+)
+
 func (h Hash) marshalText() ([]byte, error) {
 	retval := make([]byte, 0, 2*len(h))
 	for _, byteVal := range h {
@@ -15,3 +20,11 @@ func formatNibble(nibble byte) byte {
 	}
 	return 'a' + nibble - 10
 }
+
+func (h *Hash) unmarshalText(text []byte) error {
+	if len(text) != 2*len(h) {
+		return errors.New("bad hash length")
+	}
+	_, err := hex.Decode(h[:], text)
+	return err
+}
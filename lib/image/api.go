@@ -37,6 +37,10 @@ type Image struct {
 	CreatedOn     time.Time
 	ExpiresAt     time.Time
 	Packages      []Package
+	Provenance    *Annotation // Signed build provenance attestation.
+	SBOM          *Annotation // Software Bill Of Materials.
+	TestReport    *Annotation // Results of the tests run during the build.
+	TestsFailed   bool        // Uploaded despite failing tests.
 }
 
 type Package struct {
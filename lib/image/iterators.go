@@ -25,5 +25,20 @@ func (image *Image) forEachObject(objectFunc func(hash.Hash) error) error {
 			return err
 		}
 	}
+	if image.SBOM != nil && image.SBOM.Object != nil {
+		if err := objectFunc(*image.SBOM.Object); err != nil {
+			return err
+		}
+	}
+	if image.Provenance != nil && image.Provenance.Object != nil {
+		if err := objectFunc(*image.Provenance.Object); err != nil {
+			return err
+		}
+	}
+	if image.TestReport != nil && image.TestReport.Object != nil {
+		if err := objectFunc(*image.TestReport.Object); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -13,6 +13,9 @@ func (image *Image) replaceStrings(replaceFunc func(string) string) {
 	image.Triggers.ReplaceStrings(replaceFunc)
 	image.ReleaseNotes.replaceStrings(replaceFunc)
 	image.BuildLog.replaceStrings(replaceFunc)
+	image.Provenance.replaceStrings(replaceFunc)
+	image.SBOM.replaceStrings(replaceFunc)
+	image.TestReport.replaceStrings(replaceFunc)
 	for index := range image.Packages {
 		pkg := &image.Packages[index]
 		pkg.replaceStrings(replaceFunc)
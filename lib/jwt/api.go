@@ -0,0 +1,85 @@
+/*
+Package jwt verifies signed JSON Web Tokens.
+
+Package jwt implements verification of signed JSON Web Tokens (RFC 7519)
+using public keys from a JSON Web Key Set (RFC 7517). The RS256, RS384,
+RS512, ES256, ES384, ES512 and EdDSA (Ed25519) algorithms are supported.
+Unsigned tokens are never accepted.
+*/
+package jwt
+
+import (
+	"crypto"
+	"errors"
+	"time"
+)
+
+// MaxClockSkew is the tolerance permitted when checking token validity times.
+const MaxClockSkew = time.Minute
+
+var (
+	ErrorBadAudience          = errors.New("token audience not accepted")
+	ErrorBadIssuer            = errors.New("token issuer not accepted")
+	ErrorBadSignature         = errors.New("bad token signature")
+	ErrorExpired              = errors.New("token expired")
+	ErrorMalformed            = errors.New("malformed token")
+	ErrorNoKey                = errors.New("no key to verify token")
+	ErrorNotYetValid          = errors.New("token not yet valid")
+	ErrorUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+)
+
+// Audience is the "aud" claim, which may be encoded as a single string or as a
+// list of strings.
+type Audience []string
+
+func (audience *Audience) UnmarshalJSON(data []byte) error {
+	return audience.unmarshalJSON(data)
+}
+
+// Claims contains the registered claims in a token. It may be embedded in a
+// structure which also contains private claims.
+type Claims struct {
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+}
+
+// Validate checks that the claims are valid at the specified time, with a
+// tolerance of MaxClockSkew. Tokens without an expiration time are rejected.
+// If issuer is not empty, the issuer must match. If audience is not empty, it
+// must be one of the audiences of the token.
+func (claims *Claims) Validate(now time.Time, issuer, audience string) error {
+	return claims.validate(now, issuer, audience)
+}
+
+// KeySet contains public keys used to verify tokens.
+type KeySet struct {
+	keys []keyType
+}
+
+// LoadKeySet loads a JSON Web Key Set from a file.
+func LoadKeySet(filename string) (*KeySet, error) {
+	return loadKeySet(filename)
+}
+
+// ParseKeySet parses a JSON Web Key Set. Keys which are not for signatures
+// or which have unsupported types are ignored.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	return parseKeySet(data)
+}
+
+// Verify checks the signature of token with the keys in the key set and
+// decodes the claims into claims, which should be a pointer to a structure
+// (such as a structure which embeds Claims). Verify does not check the claims.
+func (keySet *KeySet) Verify(token string, claims interface{}) error {
+	return keySet.verify(token, claims)
+}
+
+type keyType struct {
+	algorithm string // May be empty.
+	id        string // May be empty.
+	publicKey crypto.PublicKey
+}
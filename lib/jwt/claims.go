@@ -0,0 +1,43 @@
+package jwt
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func (audience *Audience) unmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*audience = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*audience = list
+	return nil
+}
+
+func (claims *Claims) validate(now time.Time, issuer, audience string) error {
+	if claims.ExpiresAt == 0 ||
+		now.Add(-MaxClockSkew).After(time.Unix(claims.ExpiresAt, 0)) {
+		return ErrorExpired
+	}
+	if claims.NotBefore != 0 &&
+		now.Add(MaxClockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrorNotYetValid
+	}
+	if issuer != "" && claims.Issuer != issuer {
+		return ErrorBadIssuer
+	}
+	if audience != "" {
+		for _, tokenAudience := range claims.Audience {
+			if tokenAudience == audience {
+				return nil
+			}
+		}
+		return ErrorBadAudience
+	}
+	return nil
+}
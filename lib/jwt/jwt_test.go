@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testClaims struct {
+	Claims
+	Groups []string `json:"groups"`
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func makeToken(t *testing.T, algorithm, keyId string, signer crypto.Signer,
+	claims interface{}) string {
+	header, _ := json.Marshal(headerType{Algorithm: algorithm, KeyId: keyId})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := encode(header) + "." + encode(payload)
+	var signature []byte
+	switch key := signer.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(message))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(message))
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(message))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256,
+			digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return message + "." + encode(signature)
+}
+
+func TestVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySetData, _ := json.Marshal(jsonKeySet{Keys: []jsonKey{
+		{
+			Curve: "P-256",
+			Id:    "ec",
+			Type:  "EC",
+			X:     encode(ecKey.X.Bytes()),
+			Y:     encode(ecKey.Y.Bytes()),
+		},
+		{
+			Curve: "Ed25519",
+			Id:    "ed",
+			Type:  "OKP",
+			X:     encode(edPublicKey),
+		},
+		{
+			Algorithm: "RS256",
+			E:         encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+			Id:        "rsa",
+			N:         encode(rsaKey.N.Bytes()),
+			Type:      "RSA",
+		},
+		{Id: "encryption", Type: "RSA", Use: "enc"},
+	}})
+	keySet, err := ParseKeySet(keySetData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keySet.keys) != 3 {
+		t.Fatalf("expected 3 keys, got: %d", len(keySet.keys))
+	}
+	now := time.Now()
+	claims := testClaims{
+		Claims: Claims{
+			Audience:  Audience{"dominator"},
+			ExpiresAt: now.Add(time.Hour).Unix(),
+			Issuer:    "issuer",
+			Subject:   "alice",
+		},
+		Groups: []string{"admins"},
+	}
+	tokens := map[string]string{
+		"ES256": makeToken(t, "ES256", "ec", ecKey, claims),
+		"EdDSA": makeToken(t, "EdDSA", "", edKey, claims),
+		"RS256": makeToken(t, "RS256", "rsa", rsaKey, claims),
+	}
+	for algorithm, token := range tokens {
+		var decoded testClaims
+		if err := keySet.Verify(token, &decoded); err != nil {
+			t.Errorf("%s: %s", algorithm, err)
+			continue
+		}
+		if decoded.Subject != "alice" || len(decoded.Groups) != 1 {
+			t.Errorf("%s: bad claims: %v", algorithm, decoded)
+		}
+		if err := decoded.Validate(now, "issuer", "dominator"); err != nil {
+			t.Errorf("%s: %s", algorithm, err)
+		}
+		if err := decoded.Validate(now, "", "other"); err != ErrorBadAudience {
+			t.Errorf("%s: expected bad audience, got: %v", algorithm, err)
+		}
+		err := decoded.Validate(now.Add(2*time.Hour), "", "")
+		if err != ErrorExpired {
+			t.Errorf("%s: expected expired, got: %v", algorithm, err)
+		}
+		// Signed with the wrong key.
+		if algorithm != "EdDSA" {
+			token := makeToken(t, algorithm, "", edKey, claims)
+			if err := keySet.Verify(token, &decoded); err == nil {
+				t.Errorf("%s: signature by wrong key accepted", algorithm)
+			}
+		}
+	}
+	// Signed with the right key but declaring the wrong key.
+	token := makeToken(t, "ES256", "rsa", ecKey, claims)
+	if err := keySet.Verify(token, &testClaims{}); err != ErrorBadSignature {
+		t.Errorf("expected bad signature, got: %v", err)
+	}
+	unsigned := encode([]byte(`{"alg":"none"}`)) + "." +
+		encode([]byte(`{"sub":"mallory"}`)) + "."
+	err = keySet.Verify(unsigned, &testClaims{})
+	if err != ErrorUnsupportedAlgorithm {
+		t.Errorf("expected unsupported algorithm, got: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+)
+
+type jsonKeySet struct {
+	Keys []jsonKey `json:"keys"`
+}
+
+type jsonKey struct {
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	E         string `json:"e"`
+	Id        string `json:"kid"`
+	N         string `json:"n"`
+	Type      string `json:"kty"`
+	Use       string `json:"use"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 1 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func loadKeySet(filename string) (*KeySet, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	keySet, err := parseKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return keySet, nil
+}
+
+func parseKeySet(data []byte) (*KeySet, error) {
+	var jsonKeys jsonKeySet
+	if err := json.Unmarshal(data, &jsonKeys); err != nil {
+		return nil, err
+	}
+	keySet := &KeySet{}
+	for _, jsonKey := range jsonKeys.Keys {
+		if jsonKey.Use != "" && jsonKey.Use != "sig" {
+			continue
+		}
+		publicKey, err := jsonKey.parsePublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key: \"%s\": %s", jsonKey.Id, err)
+		}
+		if publicKey == nil {
+			continue
+		}
+		keySet.keys = append(keySet.keys, keyType{
+			algorithm: jsonKey.Algorithm,
+			id:        jsonKey.Id,
+			publicKey: publicKey,
+		})
+	}
+	return keySet, nil
+}
+
+// parsePublicKey returns the public key. If the key type is not supported,
+// nil is returned.
+func (jsonKey jsonKey) parsePublicKey() (interface{}, error) {
+	switch jsonKey.Type {
+	case "EC":
+		var curve elliptic.Curve
+		switch jsonKey.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(jsonKey.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jsonKey.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jsonKey.Curve != "Ed25519" {
+			return nil, nil
+		}
+		data, err := base64.RawURLEncoding.DecodeString(jsonKey.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != ed25519.PublicKeySize {
+			return nil, errors.New("bad Ed25519 key size")
+		}
+		return ed25519.PublicKey(data), nil
+	case "RSA":
+		n, err := decodeBigInt(jsonKey.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jsonKey.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	}
+	return nil, nil
+}
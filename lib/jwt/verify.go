@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+)
+
+var ecdsaAlgorithms = map[string]string{ // Key: curve name.
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+type headerType struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+// getHash returns the hash function for the algorithm.
+func getHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case "EdDSA":
+		return 0, nil
+	case "ES256", "RS256":
+		return crypto.SHA256, nil
+	case "ES384", "RS384":
+		return crypto.SHA384, nil
+	case "ES512", "RS512":
+		return crypto.SHA512, nil
+	}
+	return 0, ErrorUnsupportedAlgorithm
+}
+
+// verifySignature returns true if the signature of message is valid for the
+// key and algorithm.
+func verifySignature(key keyType, algorithm string, message,
+	signature []byte) bool {
+	if key.algorithm != "" && key.algorithm != algorithm {
+		return false
+	}
+	hash, err := getHash(algorithm)
+	if err != nil {
+		return false
+	}
+	var digest []byte
+	if hash != 0 {
+		hasher := hash.New()
+		hasher.Write(message)
+		digest = hasher.Sum(nil)
+	}
+	switch publicKey := key.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm != ecdsaAlgorithms[publicKey.Curve.Params().Name] {
+			return false
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != size*2 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(publicKey, digest, r, s)
+	case ed25519.PublicKey:
+		if algorithm != "EdDSA" {
+			return false
+		}
+		return ed25519.Verify(publicKey, message, signature)
+	case *rsa.PublicKey:
+		if algorithm[:2] != "RS" {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil
+	}
+	return false
+}
+
+func (keySet *KeySet) verify(token string, claims interface{}) error {
+	fields := strings.Split(token, ".")
+	if len(fields) != 3 {
+		return ErrorMalformed
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(fields[0])
+	if err != nil {
+		return ErrorMalformed
+	}
+	var header headerType
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return ErrorMalformed
+	}
+	if _, err := getHash(header.Algorithm); err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(fields[2])
+	if err != nil {
+		return ErrorMalformed
+	}
+	message := []byte(fields[0] + "." + fields[1])
+	haveKey := false
+	verified := false
+	for _, key := range keySet.keys {
+		if header.KeyId != "" && key.id != "" && key.id != header.KeyId {
+			continue
+		}
+		haveKey = true
+		if verifySignature(key, header.Algorithm, message, signature) {
+			verified = true
+			break
+		}
+	}
+	if !haveKey {
+		return ErrorNoKey
+	}
+	if !verified {
+		return ErrorBadSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(fields[1])
+	if err != nil {
+		return ErrorMalformed
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return ErrorMalformed
+	}
+	return nil
+}
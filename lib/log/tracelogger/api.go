@@ -0,0 +1,24 @@
+package tracelogger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/log/debuglogger"
+	"github.com/Cloud-Foundations/Dominator/lib/log/prefixlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
+)
+
+// New will create a logger which prefixes log lines with the trace and span
+// IDs of the current span in ctx, so that log lines may be matched with trace
+// spans. If there is no current span, logger is returned (upgraded to a
+// log.DebugLogger).
+func New(ctx context.Context, logger log.Logger) log.DebugLogger {
+	sc, ok := trace.FromContext(ctx)
+	if !ok {
+		return debuglogger.Upgrade(logger)
+	}
+	return prefixlogger.New(
+		fmt.Sprintf("[trace=%s span=%s] ", sc.TraceId, sc.SpanId), logger)
+}
@@ -0,0 +1,103 @@
+/*
+Package provenance creates and verifies signed build provenance records.
+
+A provenance record describes the inputs to an image build (in the style of
+SLSA provenance): the source image, the manifest, the build parameters and the
+builder. It is signed with the X.509 certificate and key of the builder so that
+it may be verified by anyone who trusts the CA which issued the certificate.
+*/
+package provenance
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+)
+
+const BuilderId = "imaginator"
+
+// Attestation is a signed provenance record.
+type Attestation struct {
+	Certificates [][]byte // DER encoded. Signing certificate first.
+	Payload      []byte   // JSON encoded Provenance.
+	Signature    []byte
+}
+
+type Builder struct {
+	Hostname string
+	Id       string
+}
+
+// Material describes an input to the build.
+type Material struct {
+	CommitId string `json:",omitempty"`
+	Digest   string `json:",omitempty"`
+	Name     string
+	Uri      string `json:",omitempty"`
+}
+
+type Parameters struct {
+	GitBranch       string `json:",omitempty"`
+	StreamName      string
+	VariableDigests map[string]string `json:",omitempty"` // Values hashed.
+}
+
+type Provenance struct {
+	BuildFinishedOn time.Time
+	BuildStartedOn  time.Time
+	Builder         Builder
+	Materials       []Material `json:",omitempty"`
+	Parameters      Parameters
+	Subject         Subject
+}
+
+// Subject describes the image which was built.
+type Subject struct {
+	FileSystemDigest string
+	StreamName       string
+}
+
+// DigestFileSystem returns a digest of the names, metadata (excluding
+// modification times) and contents of the files in fs.
+func DigestFileSystem(fs *filesystem.FileSystem) string {
+	return digestFileSystem(fs)
+}
+
+// DigestTree returns a digest of the names, modes and contents of the files in
+// the directory tree dirname. Git metadata directories are ignored.
+func DigestTree(dirname string) (string, error) {
+	return digestTree(dirname)
+}
+
+// DigestVariables returns a table of variable names and the digests of their
+// values, so that secret values are not disclosed.
+func DigestVariables(variables map[string]string) map[string]string {
+	return digestVariables(variables)
+}
+
+// Read will read a JSON encoded attestation from reader.
+func Read(reader io.Reader) (*Attestation, error) {
+	return read(reader)
+}
+
+// Sign will sign provenance with the private key in cert.
+func Sign(provenance *Provenance, cert *tls.Certificate) (*Attestation, error) {
+	return sign(provenance, cert)
+}
+
+// Verify will verify the signature on the attestation and, if roots is not
+// nil, that the signing certificate chains to one of roots and was valid when
+// the build finished. The provenance record and the signing certificate are
+// returned.
+func (attestation *Attestation) Verify(roots *x509.CertPool) (
+	*Provenance, *x509.Certificate, error) {
+	return attestation.verify(roots)
+}
+
+// Write will write the attestation to writer in JSON format.
+func (attestation *Attestation) Write(writer io.Writer) error {
+	return attestation.write(writer)
+}
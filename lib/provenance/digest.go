@@ -0,0 +1,98 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
+)
+
+func digestFileSystem(fs *filesystem.FileSystem) string {
+	hasher := sha256.New()
+	fs.ForEachFile(
+		func(name string, _ uint64, inode filesystem.GenericInode) error {
+			fmt.Fprintf(hasher, "%s %d %d ", name, inode.GetUid(),
+				inode.GetGid())
+			switch inode := inode.(type) {
+			case *filesystem.ComputedRegularInode:
+				fmt.Fprintf(hasher, "computed %o %s\n", inode.Mode,
+					inode.Source)
+			case *filesystem.DirectoryInode:
+				fmt.Fprintf(hasher, "directory %o\n", inode.Mode)
+			case *filesystem.RegularInode:
+				fmt.Fprintf(hasher, "file %o %d %x\n", inode.Mode, inode.Size,
+					inode.Hash)
+			case *filesystem.SpecialInode:
+				fmt.Fprintf(hasher, "special %o %d\n", inode.Mode, inode.Rdev)
+			case *filesystem.SymlinkInode:
+				fmt.Fprintf(hasher, "symlink %s\n", inode.Symlink)
+			default:
+				fmt.Fprintf(hasher, "unknown %T\n", inode)
+			}
+			return nil
+		})
+	return formatDigest(hasher)
+}
+
+func digestTree(dirname string) (string, error) {
+	hasher := sha256.New()
+	err := filepath.Walk(dirname,
+		func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() && fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			fmt.Fprintf(hasher, "%s %o\n", path[len(dirname):], fi.Mode())
+			switch {
+			case fi.Mode()&os.ModeSymlink != 0:
+				target, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(hasher, target)
+			case fi.Mode().IsRegular():
+				file, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				if _, err := io.Copy(hasher, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return "", err
+	}
+	return formatDigest(hasher), nil
+}
+
+func digestVariables(variables map[string]string) map[string]string {
+	if len(variables) < 1 {
+		return nil
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	digests := make(map[string]string, len(variables))
+	for _, name := range names {
+		hasher := sha256.New()
+		io.WriteString(hasher, variables[name])
+		digests[name] = formatDigest(hasher)
+	}
+	return digests
+}
+
+func formatDigest(hasher hash.Hash) string {
+	return fmt.Sprintf("sha256:%x", hasher.Sum(nil))
+}
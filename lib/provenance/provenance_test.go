@@ -0,0 +1,106 @@
+package provenance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func makeCertificate(t *testing.T, signer crypto.Signer) *tls.Certificate {
+	template := &x509.Certificate{
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		NotAfter:              time.Now().Add(time.Hour),
+		NotBefore:             time.Now().Add(-time.Minute),
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "builder"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		signer.Public(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: signer}
+}
+
+func testSignVerify(t *testing.T, signer crypto.Signer) {
+	cert := makeCertificate(t, signer)
+	record := &Provenance{
+		BuildFinishedOn: time.Now(),
+		Subject: Subject{
+			FileSystemDigest: "sha256:1234",
+			StreamName:       "test/stream",
+		},
+	}
+	attestation, err := Sign(record, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots.AddCert(leaf)
+	verified, _, err := attestation.Verify(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Subject != record.Subject {
+		t.Fatalf("subject: %v != %v", verified.Subject, record.Subject)
+	}
+	if _, _, err := attestation.Verify(x509.NewCertPool()); err == nil {
+		t.Fatal("untrusted certificate verified")
+	}
+	for _, finishedOn := range []time.Time{
+		{}, leaf.NotAfter.Add(time.Minute), leaf.NotBefore.Add(-time.Minute),
+	} {
+		record := *record
+		record.BuildFinishedOn = finishedOn
+		attestation, err := Sign(&record, cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := attestation.Verify(roots); err == nil {
+			t.Fatalf("verified with build finished on: %s", finishedOn)
+		}
+	}
+	attestation.Payload[len(attestation.Payload)-2] ^= 1
+	if _, _, err := attestation.Verify(nil); err == nil {
+		t.Fatal("tampered payload verified")
+	}
+}
+
+func TestSignVerifyECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSignVerify(t, key)
+}
+
+func TestSignVerifyEd25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSignVerify(t, key)
+}
+
+func TestSignVerifyRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSignVerify(t, key)
+}
@@ -0,0 +1,123 @@
+package provenance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+func getSignatureAlgorithm(
+	publicKey interface{}) (x509.SignatureAlgorithm, error) {
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256, nil
+	case ed25519.PublicKey:
+		return x509.PureEd25519, nil
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA, nil
+	}
+	return x509.UnknownSignatureAlgorithm,
+		fmt.Errorf("unsupported public key type: %T", publicKey)
+}
+
+func read(reader io.Reader) (*Attestation, error) {
+	var attestation Attestation
+	decoder := json.NewDecoder(reader)
+	if err := decoder.Decode(&attestation); err != nil {
+		return nil, fmt.Errorf("error decoding attestation: %s", err)
+	}
+	return &attestation, nil
+}
+
+func sign(provenance *Provenance, cert *tls.Certificate) (
+	*Attestation, error) {
+	if cert == nil || len(cert.Certificate) < 1 {
+		return nil, errors.New("no certificate")
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+	payload, err := json.Marshal(provenance)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		signature, err = signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(payload)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error signing: %s", err)
+	}
+	return &Attestation{
+		Certificates: cert.Certificate,
+		Payload:      payload,
+		Signature:    signature,
+	}, nil
+}
+
+func (attestation *Attestation) verify(roots *x509.CertPool) (
+	*Provenance, *x509.Certificate, error) {
+	if len(attestation.Certificates) < 1 {
+		return nil, nil, errors.New("no certificates")
+	}
+	var certs []*x509.Certificate
+	for _, der := range attestation.Certificates {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, err
+		}
+		certs = append(certs, cert)
+	}
+	signingCert := certs[0]
+	algorithm, err := getSignatureAlgorithm(signingCert.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = signingCert.CheckSignature(algorithm, attestation.Payload,
+		attestation.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad signature: %s", err)
+	}
+	var provenance Provenance
+	if err := json.Unmarshal(attestation.Payload, &provenance); err != nil {
+		return nil, nil, err
+	}
+	if roots != nil {
+		if provenance.BuildFinishedOn.IsZero() {
+			return nil, nil, errors.New("missing build finish time")
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := signingCert.Verify(x509.VerifyOptions{
+			CurrentTime:   provenance.BuildFinishedOn,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			Roots:         roots,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("untrusted certificate: %s", err)
+		}
+	}
+	return &provenance, signingCert, nil
+}
+
+func (attestation *Attestation) write(writer io.Writer) error {
+	return libjson.WriteWithIndent(writer, "    ", attestation)
+}
@@ -0,0 +1,78 @@
+package redfish
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	BootTargetBiosSetup = "BiosSetup"
+	BootTargetCd        = "Cd"
+	BootTargetHdd       = "Hdd"
+	BootTargetNone      = "None"
+	BootTargetPxe       = "Pxe"
+
+	PowerStateOff = "Off"
+	PowerStateOn  = "On"
+
+	ResetTypeForceOff         = "ForceOff"
+	ResetTypeForceRestart     = "ForceRestart"
+	ResetTypeGracefulShutdown = "GracefulShutdown"
+	ResetTypeOn               = "On"
+	ResetTypePowerCycle       = "PowerCycle"
+)
+
+// Client is a client for the Redfish API provided by a Baseboard Management
+// Controller (BMC). Only the first system and chassis are managed.
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+	password   string
+	username   string
+}
+
+type Health struct {
+	Health     string   `json:",omitempty"` // Overall health of the system.
+	PowerState string   `json:",omitempty"`
+	Sensors    []Sensor `json:",omitempty"`
+}
+
+type Params struct {
+	Address  string // Hostname, IP address or URL of the BMC.
+	Password string
+	Timeout  time.Duration // Default: 30 seconds.
+	Username string
+}
+
+type Sensor struct {
+	Health  string `json:",omitempty"`
+	Name    string
+	Reading float64 `json:",omitempty"`
+	Units   string  `json:",omitempty"`
+}
+
+// New creates a Client. BMCs commonly use self-signed certificates, so the
+// certificate presented by the BMC is not verified.
+func New(params Params) *Client {
+	return newClient(params)
+}
+
+func (c *Client) GetHealth() (*Health, error) {
+	return c.getHealth()
+}
+
+func (c *Client) GetPowerState() (string, error) {
+	return c.getPowerState()
+}
+
+// Reset performs the specified reset action (one of the ResetType* constants).
+func (c *Client) Reset(resetType string) error {
+	return c.reset(resetType)
+}
+
+// SetBootOverride sets the boot source override target (one of the BootTarget*
+// constants). If persistent is false the override applies to the next boot
+// only.
+func (c *Client) SetBootOverride(target string, persistent bool) error {
+	return c.setBootOverride(target, persistent)
+}
@@ -0,0 +1,187 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const (
+	testPassword = "secret"
+	testUsername = "admin"
+)
+
+type mockServer struct {
+	mutex      sync.Mutex
+	boot       bootType
+	powerState string
+	resets     []string
+}
+
+func (s *mockServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if user, pass, ok := req.BasicAuth(); !ok ||
+		user != testUsername || pass != testPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var response interface{}
+	switch req.Method + " " + req.URL.Path {
+	case "GET /redfish/v1/Systems":
+		response = map[string]interface{}{
+			"Members": []map[string]string{
+				{"@odata.id": "/redfish/v1/Systems/1"},
+			},
+		}
+	case "GET /redfish/v1/Systems/1":
+		response = map[string]interface{}{
+			"Actions": map[string]interface{}{
+				"#ComputerSystem.Reset": map[string]string{
+					"target": "/redfish/v1/Systems/1/Actions/Reset",
+				},
+			},
+			"PowerState": s.powerState,
+			"Status":     map[string]string{"Health": "OK"},
+		}
+	case "PATCH /redfish/v1/Systems/1":
+		var body struct{ Boot bootType }
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.boot = body.Boot
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "POST /redfish/v1/Systems/1/Actions/Reset":
+		var body struct{ ResetType string }
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.resets = append(s.resets, body.ResetType)
+		switch body.ResetType {
+		case ResetTypeOn:
+			s.powerState = PowerStateOn
+		case ResetTypeForceOff:
+			s.powerState = PowerStateOff
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "GET /redfish/v1/Chassis":
+		response = map[string]interface{}{
+			"Members": []map[string]string{
+				{"@odata.id": "/redfish/v1/Chassis/1"},
+			},
+		}
+	case "GET /redfish/v1/Chassis/1":
+		response = map[string]interface{}{
+			"Thermal": map[string]string{
+				"@odata.id": "/redfish/v1/Chassis/1/Thermal",
+			},
+		}
+	case "GET /redfish/v1/Chassis/1/Thermal":
+		response = map[string]interface{}{
+			"Fans": []map[string]interface{}{
+				{"Name": "Fan1", "Reading": 4200, "ReadingUnits": "RPM"},
+			},
+			"Temperatures": []map[string]interface{}{
+				{"Name": "CPU1", "ReadingCelsius": 45,
+					"Status": map[string]string{"Health": "OK"}},
+				{"Name": "Absent"},
+			},
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func makeTestClient(t *testing.T, password string) (*Client, *mockServer) {
+	mock := &mockServer{powerState: PowerStateOff}
+	server := httptest.NewTLSServer(mock)
+	t.Cleanup(server.Close)
+	return New(Params{
+		Address:  server.URL,
+		Password: password,
+		Username: testUsername,
+	}), mock
+}
+
+func TestAuthenticationFailure(t *testing.T) {
+	client, _ := makeTestClient(t, "wrong")
+	if _, err := client.GetPowerState(); err == nil {
+		t.Fatal("no error with wrong password")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	client, _ := makeTestClient(t, testPassword)
+	health, err := client.GetHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Health != "OK" {
+		t.Errorf("Health: %s != OK", health.Health)
+	}
+	if len(health.Sensors) != 2 {
+		t.Fatalf("number of sensors: %d != 2", len(health.Sensors))
+	}
+	if sensor := health.Sensors[0]; sensor.Name != "CPU1" ||
+		sensor.Reading != 45 || sensor.Units != "Cel" {
+		t.Errorf("bad temperature sensor: %v", sensor)
+	}
+	if sensor := health.Sensors[1]; sensor.Name != "Fan1" ||
+		sensor.Reading != 4200 || sensor.Units != "RPM" {
+		t.Errorf("bad fan sensor: %v", sensor)
+	}
+}
+
+func TestPower(t *testing.T) {
+	client, mock := makeTestClient(t, testPassword)
+	if state, err := client.GetPowerState(); err != nil {
+		t.Fatal(err)
+	} else if state != PowerStateOff {
+		t.Fatalf("power state: %s != %s", state, PowerStateOff)
+	}
+	if err := client.Reset(ResetTypeOn); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := client.GetPowerState(); err != nil {
+		t.Fatal(err)
+	} else if state != PowerStateOn {
+		t.Fatalf("power state: %s != %s", state, PowerStateOn)
+	}
+	if err := client.Reset(ResetTypePowerCycle); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.resets) != 2 || mock.resets[1] != ResetTypePowerCycle {
+		t.Errorf("unexpected resets: %v", mock.resets)
+	}
+}
+
+func TestSetBootOverride(t *testing.T) {
+	client, mock := makeTestClient(t, testPassword)
+	if err := client.SetBootOverride(BootTargetPxe, false); err != nil {
+		t.Fatal(err)
+	}
+	if mock.boot.BootSourceOverrideEnabled != "Once" ||
+		mock.boot.BootSourceOverrideTarget != BootTargetPxe {
+		t.Errorf("unexpected boot override: %v", mock.boot)
+	}
+	if err := client.SetBootOverride(BootTargetHdd, true); err != nil {
+		t.Fatal(err)
+	}
+	if mock.boot.BootSourceOverrideEnabled != "Continuous" {
+		t.Errorf("unexpected boot override: %v", mock.boot)
+	}
+	if err := client.SetBootOverride(BootTargetNone, false); err != nil {
+		t.Fatal(err)
+	}
+	if mock.boot.BootSourceOverrideEnabled != "Disabled" {
+		t.Errorf("unexpected boot override: %v", mock.boot)
+	}
+}
@@ -0,0 +1,241 @@
+package redfish
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	chassisCollectionPath = "/redfish/v1/Chassis"
+	systemCollectionPath  = "/redfish/v1/Systems"
+)
+
+type bootType struct {
+	BootSourceOverrideEnabled string
+	BootSourceOverrideTarget  string
+}
+
+type chassisType struct {
+	Thermal referenceType
+}
+
+type collectionType struct {
+	Members []referenceType
+}
+
+type fanType struct {
+	Name         string
+	Reading      *float64
+	ReadingUnits string
+	Status       statusType
+}
+
+type referenceType struct {
+	Id string `json:"@odata.id"`
+}
+
+type resetActionType struct {
+	Target string `json:"target"`
+}
+
+type statusType struct {
+	Health string
+}
+
+type systemType struct {
+	Actions struct {
+		Reset resetActionType `json:"#ComputerSystem.Reset"`
+	}
+	PowerState string
+	Status     statusType
+}
+
+type temperatureType struct {
+	Name           string
+	ReadingCelsius *float64
+	Status         statusType
+}
+
+type thermalType struct {
+	Fans         []fanType
+	Temperatures []temperatureType
+}
+
+func newClient(params Params) *Client {
+	baseUrl := strings.TrimSuffix(params.Address, "/")
+	if !strings.Contains(baseUrl, "://") {
+		baseUrl = "https://" + baseUrl
+	}
+	timeout := params.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		baseUrl: baseUrl,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		password: params.Password,
+		username: params.Username,
+	}
+}
+
+func (c *Client) doRequest(method, path string, body interface{},
+	result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseUrl+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status,
+			strings.TrimSpace(string(message)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *Client) getFirstMember(collectionPath string) (string, error) {
+	var collection collectionType
+	if err := c.doRequest("GET", collectionPath, nil, &collection); err != nil {
+		return "", err
+	}
+	if len(collection.Members) < 1 || collection.Members[0].Id == "" {
+		return "", errors.New("no members in: " + collectionPath)
+	}
+	return collection.Members[0].Id, nil
+}
+
+func (c *Client) getHealth() (*Health, error) {
+	_, system, err := c.getSystem()
+	if err != nil {
+		return nil, err
+	}
+	health := &Health{
+		Health:     system.Status.Health,
+		PowerState: system.PowerState,
+	}
+	chassisPath, err := c.getFirstMember(chassisCollectionPath)
+	if err != nil {
+		return nil, err
+	}
+	var chassis chassisType
+	if err := c.doRequest("GET", chassisPath, nil, &chassis); err != nil {
+		return nil, err
+	}
+	if chassis.Thermal.Id == "" {
+		return health, nil
+	}
+	var thermal thermalType
+	err = c.doRequest("GET", chassis.Thermal.Id, nil, &thermal)
+	if err != nil {
+		return nil, err
+	}
+	for _, temperature := range thermal.Temperatures {
+		if temperature.ReadingCelsius == nil {
+			continue
+		}
+		health.Sensors = append(health.Sensors, Sensor{
+			Health:  temperature.Status.Health,
+			Name:    temperature.Name,
+			Reading: *temperature.ReadingCelsius,
+			Units:   "Cel",
+		})
+	}
+	for _, fan := range thermal.Fans {
+		if fan.Reading == nil {
+			continue
+		}
+		health.Sensors = append(health.Sensors, Sensor{
+			Health:  fan.Status.Health,
+			Name:    fan.Name,
+			Reading: *fan.Reading,
+			Units:   fan.ReadingUnits,
+		})
+	}
+	return health, nil
+}
+
+func (c *Client) getPowerState() (string, error) {
+	_, system, err := c.getSystem()
+	if err != nil {
+		return "", err
+	}
+	return system.PowerState, nil
+}
+
+func (c *Client) getSystem() (string, *systemType, error) {
+	systemPath, err := c.getFirstMember(systemCollectionPath)
+	if err != nil {
+		return "", nil, err
+	}
+	var system systemType
+	if err := c.doRequest("GET", systemPath, nil, &system); err != nil {
+		return "", nil, err
+	}
+	return systemPath, &system, nil
+}
+
+func (c *Client) reset(resetType string) error {
+	systemPath, system, err := c.getSystem()
+	if err != nil {
+		return err
+	}
+	target := system.Actions.Reset.Target
+	if target == "" {
+		target = systemPath + "/Actions/ComputerSystem.Reset"
+	}
+	return c.doRequest("POST", target,
+		map[string]string{"ResetType": resetType}, nil)
+}
+
+func (c *Client) setBootOverride(target string, persistent bool) error {
+	systemPath, err := c.getFirstMember(systemCollectionPath)
+	if err != nil {
+		return err
+	}
+	boot := bootType{
+		BootSourceOverrideEnabled: "Once",
+		BootSourceOverrideTarget:  target,
+	}
+	if target == BootTargetNone {
+		boot.BootSourceOverrideEnabled = "Disabled"
+	} else if persistent {
+		boot.BootSourceOverrideEnabled = "Continuous"
+	}
+	return c.doRequest("PATCH", systemPath,
+		map[string]bootType{"Boot": boot}, nil)
+}
@@ -124,3 +124,11 @@ func (resource *Resource) Release() error {
 func (resource *Resource) ScheduleRelease() error {
 	return resource.scheduleRelease()
 }
+
+// TryGetAllocated attempts to get the resource without waiting, only if the
+// underlying resource is allocated and not in use. It never calls the Allocate
+// method. If it returns true, the resource is in use until a later call to Put
+// or Release.
+func (resource *Resource) TryGetAllocated() bool {
+	return resource.tryGetAllocated()
+}
@@ -173,3 +173,29 @@ func (resource *Resource) scheduleRelease() error {
 	}
 	return resource.release(true)
 }
+
+func (resource *Resource) tryGetAllocated() bool {
+	select {
+	case resource.semaphore <- struct{}{}:
+	default:
+		return false
+	}
+	pool := resource.pool
+	select {
+	case pool.semaphore <- struct{}{}:
+	default:
+		<-resource.semaphore
+		return false
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if !resource.allocated || resource.allocating {
+		<-pool.semaphore
+		<-resource.semaphore
+		return false
+	}
+	delete(pool.unused, resource)
+	pool.numUnused = uint(len(pool.unused))
+	pool.numUsed++
+	return true
+}
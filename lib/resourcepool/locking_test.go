@@ -200,6 +200,33 @@ func TestGetPutPut(t *testing.T) {
 	testResource.put()
 }
 
+func TestTryGetAllocated(t *testing.T) {
+	testPool := newTestPool(1, 1)
+	testResource := testPool.resources[0]
+	if testResource.resource.TryGetAllocated() {
+		t.Errorf("TryGetAllocated(): got unallocated resource")
+	}
+	if testResource.active {
+		t.Errorf("TryGetAllocated(): resource was allocated")
+	}
+	testResource.get(nil)
+	if testResource.resource.TryGetAllocated() {
+		t.Errorf("TryGetAllocated(): got in use resource")
+	}
+	testResource.put()
+	if !testResource.resource.TryGetAllocated() {
+		t.Errorf("TryGetAllocated(): did not get allocated resource")
+	}
+	testResource.resource.Put()
+	if tmp := testPool.getNumActive(); tmp != 1 {
+		t.Errorf("numActive = %v", tmp)
+	}
+	testResource.release()
+	if testResource.resource.TryGetAllocated() {
+		t.Errorf("TryGetAllocated(): got released resource")
+	}
+}
+
 func (testPool *testPoolType) testConcurrent(t *testing.T, numCycles int,
 	testFunc func(*testResourceType, int)) {
 	finished := make(chan struct{}, len(testPool.resources))
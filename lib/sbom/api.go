@@ -0,0 +1,87 @@
+package sbom
+
+import (
+	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+)
+
+const (
+	FormatCycloneDx = "cyclonedx"
+	FormatJson      = "json"
+	FormatSpdx      = "spdx"
+)
+
+// Difference describes the changes between two SBOMs.
+type Difference struct {
+	AddedFiles      []File          `json:",omitempty"`
+	AddedPackages   []Package       `json:",omitempty"`
+	ChangedFiles    []File          `json:",omitempty"` // New hash and size.
+	ChangedPackages []PackageChange `json:",omitempty"`
+	RemovedFiles    []File          `json:",omitempty"`
+	RemovedPackages []Package       `json:",omitempty"`
+}
+
+// File describes a regular file which is not owned by any package.
+type File struct {
+	Hash hash.Hash
+	Path string
+	Size uint64 // Bytes.
+}
+
+type Package struct {
+	Licenses string `json:",omitempty"` // As reported by the packager.
+	Name     string
+	Origin   string `json:",omitempty"` // Source package.
+	Size     uint64 `json:",omitempty"` // Bytes.
+	Version  string
+}
+
+type PackageChange struct {
+	Name       string
+	NewVersion string
+	OldVersion string
+}
+
+// SBOM is a Software Bill Of Materials for an image.
+type SBOM struct {
+	CreatedOn    time.Time
+	Packages     []Package // Sorted by name.
+	StreamName   string    `json:",omitempty"`
+	UnownedFiles []File    `json:",omitempty"` // Sorted by path.
+}
+
+// Diff returns the differences between the left and right SBOMs.
+func Diff(left, right *SBOM) *Difference {
+	return diff(left, right)
+}
+
+func Read(reader io.Reader) (*SBOM, error) {
+	return read(reader)
+}
+
+func (d *Difference) IsEmpty() bool {
+	return d.isEmpty()
+}
+
+// Write writes the differences in a human-readable format.
+func (d *Difference) Write(writer io.Writer) error {
+	return d.write(writer)
+}
+
+// Write writes the SBOM in the specified format (one of the Format*
+// constants). The name is used to identify the image in the document.
+func (s *SBOM) Write(writer io.Writer, format, name string) error {
+	return s.write(writer, format, name)
+}
+
+// WriteCycloneDx writes the SBOM as a CycloneDX 1.4 JSON document.
+func (s *SBOM) WriteCycloneDx(writer io.Writer, name string) error {
+	return s.writeCycloneDx(writer, name)
+}
+
+// WriteSpdx writes the SBOM as an SPDX 2.3 JSON document.
+func (s *SBOM) WriteSpdx(writer io.Writer, name string) error {
+	return s.writeSpdx(writer, name)
+}
@@ -0,0 +1,101 @@
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+type cycloneDxComponent struct {
+	BomRef     string              `json:"bom-ref"`
+	Hashes     []cycloneDxHash     `json:"hashes,omitempty"`
+	Licenses   []cycloneDxLicense  `json:"licenses,omitempty"`
+	Name       string              `json:"name"`
+	Properties []cycloneDxProperty `json:"properties,omitempty"`
+	Type       string              `json:"type"`
+	Version    string              `json:"version,omitempty"`
+}
+
+type cycloneDxDocument struct {
+	BomFormat   string               `json:"bomFormat"`
+	Components  []cycloneDxComponent `json:"components"`
+	Metadata    cycloneDxMetadata    `json:"metadata"`
+	SpecVersion string               `json:"specVersion"`
+	Version     uint                 `json:"version"`
+}
+
+type cycloneDxHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDxLicense struct {
+	License cycloneDxLicenseName `json:"license"`
+}
+
+type cycloneDxLicenseName struct {
+	Name string `json:"name"`
+}
+
+type cycloneDxMetadata struct {
+	Component cycloneDxComponent `json:"component"`
+	Timestamp string             `json:"timestamp"`
+}
+
+type cycloneDxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (s *SBOM) writeCycloneDx(writer io.Writer, name string) error {
+	createdOn := s.CreatedOn
+	if createdOn.IsZero() {
+		createdOn = time.Now()
+	}
+	doc := cycloneDxDocument{
+		BomFormat: "CycloneDX",
+		Components: make([]cycloneDxComponent, 0,
+			len(s.Packages)+len(s.UnownedFiles)),
+		Metadata: cycloneDxMetadata{
+			Component: cycloneDxComponent{
+				BomRef: "image",
+				Name:   name,
+				Type:   "operating-system",
+			},
+			Timestamp: createdOn.UTC().Format(time.RFC3339),
+		},
+		SpecVersion: "1.4",
+		Version:     1,
+	}
+	for index, pkg := range s.Packages {
+		component := cycloneDxComponent{
+			BomRef:  fmt.Sprintf("package-%d", index),
+			Name:    pkg.Name,
+			Type:    "library",
+			Version: pkg.Version,
+		}
+		if pkg.Licenses != "" {
+			component.Licenses = []cycloneDxLicense{
+				{cycloneDxLicenseName{pkg.Licenses}},
+			}
+		}
+		if pkg.Origin != "" {
+			component.Properties = []cycloneDxProperty{
+				{"dominator:origin", pkg.Origin},
+			}
+		}
+		doc.Components = append(doc.Components, component)
+	}
+	for index, file := range s.UnownedFiles {
+		hashText, _ := file.Hash.MarshalText()
+		doc.Components = append(doc.Components, cycloneDxComponent{
+			BomRef: fmt.Sprintf("file-%d", index),
+			Hashes: []cycloneDxHash{{"SHA-512", string(hashText)}},
+			Name:   file.Path,
+			Type:   "file",
+		})
+	}
+	return json.WriteWithIndent(writer, "  ", doc)
+}
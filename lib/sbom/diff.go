@@ -0,0 +1,101 @@
+package sbom
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/format"
+)
+
+func diff(left, right *SBOM) *Difference {
+	var d Difference
+	leftPackages := make(map[string]Package, len(left.Packages))
+	for _, pkg := range left.Packages {
+		leftPackages[pkg.Name] = pkg
+	}
+	rightPackages := make(map[string]struct{}, len(right.Packages))
+	for _, pkg := range right.Packages {
+		rightPackages[pkg.Name] = struct{}{}
+		if leftPkg, ok := leftPackages[pkg.Name]; !ok {
+			d.AddedPackages = append(d.AddedPackages, pkg)
+		} else if leftPkg.Version != pkg.Version {
+			d.ChangedPackages = append(d.ChangedPackages, PackageChange{
+				Name:       pkg.Name,
+				NewVersion: pkg.Version,
+				OldVersion: leftPkg.Version,
+			})
+		}
+	}
+	for _, pkg := range left.Packages {
+		if _, ok := rightPackages[pkg.Name]; !ok {
+			d.RemovedPackages = append(d.RemovedPackages, pkg)
+		}
+	}
+	leftFiles := make(map[string]File, len(left.UnownedFiles))
+	for _, file := range left.UnownedFiles {
+		leftFiles[file.Path] = file
+	}
+	rightFiles := make(map[string]struct{}, len(right.UnownedFiles))
+	for _, file := range right.UnownedFiles {
+		rightFiles[file.Path] = struct{}{}
+		if leftFile, ok := leftFiles[file.Path]; !ok {
+			d.AddedFiles = append(d.AddedFiles, file)
+		} else if leftFile.Hash != file.Hash {
+			d.ChangedFiles = append(d.ChangedFiles, file)
+		}
+	}
+	for _, file := range left.UnownedFiles {
+		if _, ok := rightFiles[file.Path]; !ok {
+			d.RemovedFiles = append(d.RemovedFiles, file)
+		}
+	}
+	return &d
+}
+
+func (d *Difference) isEmpty() bool {
+	return len(d.AddedFiles) < 1 &&
+		len(d.AddedPackages) < 1 &&
+		len(d.ChangedFiles) < 1 &&
+		len(d.ChangedPackages) < 1 &&
+		len(d.RemovedFiles) < 1 &&
+		len(d.RemovedPackages) < 1
+}
+
+func (d *Difference) write(writer io.Writer) error {
+	for _, pkg := range d.RemovedPackages {
+		if _, err := fmt.Fprintf(writer, "- package %s %s\n",
+			pkg.Name, pkg.Version); err != nil {
+			return err
+		}
+	}
+	for _, pkg := range d.AddedPackages {
+		if _, err := fmt.Fprintf(writer, "+ package %s %s\n",
+			pkg.Name, pkg.Version); err != nil {
+			return err
+		}
+	}
+	for _, change := range d.ChangedPackages {
+		if _, err := fmt.Fprintf(writer, "~ package %s %s -> %s\n",
+			change.Name, change.OldVersion, change.NewVersion); err != nil {
+			return err
+		}
+	}
+	for _, file := range d.RemovedFiles {
+		if _, err := fmt.Fprintf(writer, "- file %s\n", file.Path); err != nil {
+			return err
+		}
+	}
+	for _, file := range d.AddedFiles {
+		if _, err := fmt.Fprintf(writer, "+ file %s (%s)\n",
+			file.Path, format.FormatBytes(file.Size)); err != nil {
+			return err
+		}
+	}
+	for _, file := range d.ChangedFiles {
+		if _, err := fmt.Fprintf(writer, "~ file %s (%s)\n",
+			file.Path, format.FormatBytes(file.Size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
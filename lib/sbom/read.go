@@ -0,0 +1,16 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func read(reader io.Reader) (*SBOM, error) {
+	var sbom SBOM
+	decoder := json.NewDecoder(reader)
+	if err := decoder.Decode(&sbom); err != nil {
+		return nil, fmt.Errorf("error decoding SBOM: %s", err)
+	}
+	return &sbom, nil
+}
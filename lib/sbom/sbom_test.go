@@ -0,0 +1,124 @@
+package sbom
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/hash"
+)
+
+func makeTestSBOM() *SBOM {
+	var fileHash hash.Hash
+	fileHash[0] = 0x12
+	fileHash[63] = 0xef
+	return &SBOM{
+		CreatedOn: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Packages: []Package{
+			{
+				Licenses: "GPL-2+",
+				Name:     "bash",
+				Size:     1 << 20,
+				Version:  "5.1-2",
+			},
+			{
+				Name:    "libssl3",
+				Origin:  "openssl",
+				Version: "3.0.11-1",
+			},
+		},
+		StreamName:   "test/stream",
+		UnownedFiles: []File{{Hash: fileHash, Path: "/usr/bin/tool", Size: 42}},
+	}
+}
+
+func testGolden(t *testing.T, filename string,
+	writeFunc func(*bytes.Buffer) error) {
+	expected, err := os.ReadFile(filepath.Join("testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	if err := writeFunc(buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Errorf("%s: expected:\n%s\ngot:\n%s",
+			filename, expected, buffer.Bytes())
+	}
+}
+
+func TestCycloneDx(t *testing.T) {
+	testGolden(t, "cyclonedx.json", func(buffer *bytes.Buffer) error {
+		return makeTestSBOM().Write(buffer, FormatCycloneDx, "test/image")
+	})
+}
+
+func TestDiff(t *testing.T) {
+	left := makeTestSBOM()
+	if d := Diff(left, left); !d.IsEmpty() {
+		t.Fatalf("differences with self: %+v", d)
+	}
+	right := makeTestSBOM()
+	right.Packages[0].Version = "5.2-1"
+	right.Packages[1] = Package{Name: "zlib1g", Version: "1.2.13"}
+	right.UnownedFiles[0].Hash[1] = 0x34
+	right.UnownedFiles[0].Size = 2048
+	right.UnownedFiles = append(right.UnownedFiles,
+		File{Path: "/usr/bin/tool2", Size: 1024})
+	buffer := &bytes.Buffer{}
+	if err := Diff(left, right).Write(buffer); err != nil {
+		t.Fatal(err)
+	}
+	expected := `- package libssl3 3.0.11-1
++ package zlib1g 1.2.13
+~ package bash 5.1-2 -> 5.2-1
++ file /usr/bin/tool2 (1024 B)
+~ file /usr/bin/tool (2048 B)
+`
+	if buffer.String() != expected {
+		t.Errorf("expected:\n%sgot:\n%s", expected, buffer.String())
+	}
+	buffer.Reset()
+	if err := Diff(right, left).Write(buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "- file /usr/bin/tool2\n") {
+		t.Errorf("removed file not shown:\n%s", buffer.String())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	sbom := makeTestSBOM()
+	buffer := &bytes.Buffer{}
+	if err := sbom.Write(buffer, FormatJson, "test/image"); err != nil {
+		t.Fatal(err)
+	}
+	readSBOM, err := Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(readSBOM, sbom) {
+		t.Errorf("expected: %+v, got: %+v", sbom, readSBOM)
+	}
+	if _, err := Read(strings.NewReader("{")); err == nil {
+		t.Error("no error reading truncated SBOM")
+	}
+}
+
+func TestSpdx(t *testing.T) {
+	testGolden(t, "spdx.json", func(buffer *bytes.Buffer) error {
+		return makeTestSBOM().Write(buffer, FormatSpdx, "test/image")
+	})
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	err := makeTestSBOM().Write(&bytes.Buffer{}, "xml", "test/image")
+	if err == nil {
+		t.Error("no error for unsupported format")
+	}
+}
@@ -0,0 +1,119 @@
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+const spdxNoAssertion = "NOASSERTION"
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxDocument struct {
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	DataLicense       string             `json:"dataLicense"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Name              string             `json:"name"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+	SPDXID            string             `json:"SPDXID"`
+	SpdxVersion       string             `json:"spdxVersion"`
+}
+
+type spdxFile struct {
+	Checksums []spdxChecksum `json:"checksums"`
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+}
+
+type spdxPackage struct {
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	LicenseComments  string `json:"licenseComments,omitempty"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	Name             string `json:"name"`
+	SourceInfo       string `json:"sourceInfo,omitempty"`
+	SPDXID           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo"`
+}
+
+type spdxRelationship struct {
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+	RelationshipType   string `json:"relationshipType"`
+	SpdxElementId      string `json:"spdxElementId"`
+}
+
+// writeSpdx writes an SPDX document. Package licenses are free-form strings
+// from the packager which are not necessarily valid SPDX license expressions,
+// so they are recorded as license comments.
+func (s *SBOM) writeSpdx(writer io.Writer, name string) error {
+	createdOn := s.CreatedOn
+	if createdOn.IsZero() {
+		createdOn = time.Now()
+	}
+	doc := spdxDocument{
+		CreationInfo: spdxCreationInfo{
+			Created:  createdOn.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: imaginator"},
+		},
+		DataLicense: "CC0-1.0",
+		DocumentNamespace: "https://spdx.org/spdxdocs/" +
+			url.PathEscape(name),
+		Name:        name,
+		Packages:    make([]spdxPackage, 0, len(s.Packages)),
+		SPDXID:      "SPDXRef-DOCUMENT",
+		SpdxVersion: "SPDX-2.3",
+	}
+	for index, pkg := range s.Packages {
+		spdxPkg := spdxPackage{
+			DownloadLocation: spdxNoAssertion,
+			LicenseComments:  pkg.Licenses,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			Name:             pkg.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", index),
+			VersionInfo:      pkg.Version,
+		}
+		if pkg.Origin != "" {
+			spdxPkg.SourceInfo = "built from source package: " + pkg.Origin
+		}
+		doc.Packages = append(doc.Packages, spdxPkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			RelatedSpdxElement: spdxPkg.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			SpdxElementId:      doc.SPDXID,
+		})
+	}
+	for index, file := range s.UnownedFiles {
+		hashText, _ := file.Hash.MarshalText()
+		spdxFile := spdxFile{
+			Checksums: []spdxChecksum{{
+				Algorithm:     "SHA512",
+				ChecksumValue: string(hashText),
+			}},
+			FileName: "." + file.Path,
+			SPDXID:   fmt.Sprintf("SPDXRef-File-%d", index),
+		}
+		doc.Files = append(doc.Files, spdxFile)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			RelatedSpdxElement: spdxFile.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			SpdxElementId:      doc.SPDXID,
+		})
+	}
+	return json.WriteWithIndent(writer, "  ", doc)
+}
@@ -0,0 +1,20 @@
+package sbom
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+func (s *SBOM) write(writer io.Writer, format, name string) error {
+	switch format {
+	case FormatCycloneDx:
+		return s.writeCycloneDx(writer, name)
+	case FormatJson:
+		return json.WriteWithIndent(writer, "    ", s)
+	case FormatSpdx:
+		return s.writeSpdx(writer, name)
+	}
+	return fmt.Errorf("unsupported SBOM format: %s", format)
+}
@@ -0,0 +1,65 @@
+// +build linux
+
+/*
+Package namespace implements a SlaveTrader which creates build slaves on the
+local machine. Each slave runs in its own mount, PID, network and UTS
+namespaces with a fresh root file-system unpacked from an image, so isolated
+builds do not require a fleet of Hypervisors. Slaves only have access to a
+minimal set of devices and some capabilities are dropped, but they run as root
+so they are not a security boundary.
+*/
+package namespace
+
+import (
+	"net"
+	"sync"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/slavedriver"
+)
+
+type SlaveTrader struct {
+	imageLock  sync.RWMutex // Held for reading while copying the image.
+	imageMutex sync.Mutex   // Lock image updates and imageName.
+	imageName  string       // Name of the unpacked image.
+	logger     log.DebugLogger
+	options    SlaveTraderOptions
+	subnet     *net.IPNet
+	mutex      sync.Mutex            // Lock everything below.
+	slaves     map[string]*slaveType // Key: identifier (IP address).
+}
+
+type SlaveTraderOptions struct {
+	Command            []string // Command to run in the slave root.
+	ImageName          string   // Name of image or image stream.
+	ImageServerAddress string
+	MemoryInMiB        uint64 // Memory limit for each slave (cgroup v2).
+	MilliCPUs          uint   // CPU limit for each slave (cgroup v2).
+	StateDirectory     string
+	Subnet             string // Default: 10.253.0.0/16.
+}
+
+type slaveType struct {
+	exited       <-chan struct{} // Closed when process exits (if a child).
+	index        uint
+	ipAddress    net.IP
+	pid          int
+	pidNamespace string // Identifies the slave process.
+}
+
+func NewSlaveTrader(options SlaveTraderOptions,
+	logger log.DebugLogger) (*SlaveTrader, error) {
+	return newSlaveTrader(options, logger)
+}
+
+func (trader *SlaveTrader) Close() error {
+	return trader.close()
+}
+
+func (trader *SlaveTrader) CreateSlave() (slavedriver.SlaveInfo, error) {
+	return trader.createSlave()
+}
+
+func (trader *SlaveTrader) DestroySlave(identifier string) error {
+	return trader.destroySlave(identifier)
+}
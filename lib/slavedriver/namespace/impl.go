@@ -0,0 +1,689 @@
+// +build linux
+
+package namespace
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	imageclient "github.com/Cloud-Foundations/Dominator/imageserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/filesystem/util"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	objectclient "github.com/Cloud-Foundations/Dominator/lib/objectserver/client"
+	"github.com/Cloud-Foundations/Dominator/lib/slavedriver"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+const (
+	cgroupRoot    = "/sys/fs/cgroup"
+	defaultSubnet = "10.253.0.0/16"
+	oldRootName   = ".oldroot"
+	setupScript   = "mount -t proc proc /proc && " +
+		"mount -t sysfs -o ro,nosuid,nodev,noexec sysfs /sys && " +
+		"echo \"$SLAVE_HOSTNAME\" > /proc/sys/kernel/hostname && " +
+		"exec \"$@\""
+
+	capSysModule    = 16
+	capSysRawio     = 17
+	capSysBoot      = 22
+	capSysTime      = 25
+	capMknod        = 27
+	capAuditControl = 30
+	capMacOverride  = 32
+	capMacAdmin     = 33
+	capSyslog       = 34
+	capWakeAlarm    = 35
+	capBlockSuspend = 36
+	capPerfmon      = 38
+	capBpf          = 39
+)
+
+type deviceType struct {
+	name  string
+	major uint32
+	minor uint32
+}
+
+var (
+	cgroupParent = filepath.Join(cgroupRoot, "build-slaves")
+	// Slaves need CAP_SYS_ADMIN to mount file-systems for builds, so dropping
+	// capabilities does not make slaves a security boundary, but it prevents
+	// them from creating device nodes, loading modules and so on.
+	droppedCapabilities = []uintptr{
+		capSysModule, capSysRawio, capSysBoot, capSysTime, capMknod,
+		capAuditControl, capMacOverride, capMacAdmin, capSyslog,
+		capWakeAlarm, capBlockSuspend, capPerfmon, capBpf,
+	}
+	slaveDevices = []deviceType{
+		{"null", 1, 3},
+		{"random", 1, 8},
+		{"tty", 5, 0},
+		{"urandom", 1, 9},
+		{"zero", 1, 5},
+	}
+	slaveDeviceSymlinks = map[string]string{
+		"fd":     "/proc/self/fd",
+		"ptmx":   "pts/ptmx",
+		"stderr": "/proc/self/fd/2",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+	}
+)
+
+func addToUint32(ip net.IP, increment uint32) net.IP {
+	retval := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(retval,
+		binary.BigEndian.Uint32(ip.To4())+increment)
+	return retval
+}
+
+// dropCapabilities drops capabilities from the bounding set of the calling
+// thread, so that they are not available to processes it starts. Capabilities
+// not known to the kernel are ignored.
+func dropCapabilities() error {
+	for _, capability := range droppedCapabilities {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL,
+			syscall.PR_CAPBSET_DROP, capability, 0)
+		if errno != 0 && errno != syscall.EINVAL {
+			return fmt.Errorf("error dropping capability: %d: %s",
+				capability, errno)
+		}
+	}
+	return nil
+}
+
+func getPidNamespace(pid int) string {
+	if pid < 1 {
+		return ""
+	}
+	namespace, _ := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", pid))
+	return namespace
+}
+
+func isProcessInRoot(pid int, rootDir string) bool {
+	if pid < 1 {
+		return false
+	}
+	target, err := os.Readlink(fmt.Sprintf("/proc/%d/root", pid))
+	if err != nil {
+		return false
+	}
+	return target == rootDir
+}
+
+// makeDevDirectory mounts a tmpfs on devDir containing only the devices which
+// builds need, rather than exposing the devices of the host.
+func makeDevDirectory(devDir string) error {
+	err := syscall.Mount("tmpfs", devDir, "tmpfs",
+		syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=755,size=64k")
+	if err != nil {
+		return fmt.Errorf("error mounting tmpfs on: %s: %s", devDir, err)
+	}
+	for _, device := range slaveDevices {
+		filename := filepath.Join(devDir, device.name)
+		err := syscall.Mknod(filename, syscall.S_IFCHR|0666,
+			int(device.major<<8|device.minor))
+		if err != nil {
+			return fmt.Errorf("error making device: %s: %s", filename, err)
+		}
+		if err := os.Chmod(filename, 0666); err != nil { // Ignore umask.
+			return err
+		}
+	}
+	for name, target := range slaveDeviceSymlinks {
+		if err := os.Symlink(target, filepath.Join(devDir, name)); err != nil {
+			return err
+		}
+	}
+	for _, dirname := range []string{"pts", "shm"} {
+		if err := os.Mkdir(filepath.Join(devDir, dirname), 0755); err != nil {
+			return err
+		}
+	}
+	err = syscall.Mount("devpts", filepath.Join(devDir, "pts"), "devpts",
+		syscall.MS_NOSUID|syscall.MS_NOEXEC,
+		"newinstance,ptmxmode=0666,mode=0620")
+	if err != nil {
+		return fmt.Errorf("error mounting devpts: %s", err)
+	}
+	err = syscall.Mount("tmpfs", filepath.Join(devDir, "shm"), "tmpfs",
+		syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777")
+	if err != nil {
+		return fmt.Errorf("error mounting tmpfs on /dev/shm: %s", err)
+	}
+	return nil
+}
+
+// makeSlaveRoot makes rootDir the root of the mount namespace of the calling
+// thread, which must be in a private mount namespace. Unlike chroot, the old
+// root is detached so that it is not reachable from the slave.
+func makeSlaveRoot(rootDir string) error {
+	// pivot_root requires the new root to be a mount point.
+	err := syscall.Mount(rootDir, rootDir, "", syscall.MS_BIND|syscall.MS_REC,
+		"")
+	if err != nil {
+		return fmt.Errorf("error bind mounting: %s: %s", rootDir, err)
+	}
+	devDir := filepath.Join(rootDir, "dev")
+	if err := os.MkdirAll(devDir, 0755); err != nil {
+		return err
+	}
+	if err := makeDevDirectory(devDir); err != nil {
+		return err
+	}
+	oldRoot := filepath.Join(rootDir, oldRootName)
+	if err := os.Mkdir(oldRoot, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	if err := syscall.PivotRoot(rootDir, oldRoot); err != nil {
+		return fmt.Errorf("error pivoting root to: %s: %s", rootDir, err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return err
+	}
+	oldRoot = "/" + oldRootName
+	if err := syscall.Unmount(oldRoot, syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("error unmounting old root: %s", err)
+	}
+	return os.Remove(oldRoot)
+}
+
+func runCommand(args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running: %s: %s: %s",
+			strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+// runCommandInHostNamespace runs a command from a goroutine which is not
+// locked to the calling thread, so that it runs in the network namespace of
+// the host rather than a namespace the calling thread may have entered.
+func runCommandInHostNamespace(args ...string) error {
+	errorChannel := make(chan error, 1)
+	go func() {
+		errorChannel <- runCommand(args...)
+	}()
+	return <-errorChannel
+}
+
+func writeCgroupFile(cgroupDir, filename, value string) error {
+	return ioutil.WriteFile(filepath.Join(cgroupDir, filename),
+		[]byte(value+"\n"), 0644)
+}
+
+func newSlaveTrader(options SlaveTraderOptions,
+	logger log.DebugLogger) (*SlaveTrader, error) {
+	if len(options.Command) < 1 {
+		return nil, errors.New("no slave command specified")
+	}
+	if options.ImageName == "" {
+		return nil, errors.New("no slave image specified")
+	}
+	if options.ImageServerAddress == "" {
+		return nil, errors.New("no imageserver address specified")
+	}
+	if options.StateDirectory == "" {
+		return nil, errors.New("no state directory specified")
+	}
+	stateDirectory, err := filepath.Abs(options.StateDirectory)
+	if err != nil {
+		return nil, err
+	}
+	options.StateDirectory = stateDirectory
+	if options.Subnet == "" {
+		options.Subnet = defaultSubnet
+	}
+	_, subnet, err := net.ParseCIDR(options.Subnet)
+	if err != nil {
+		return nil, err
+	}
+	if subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("subnet: %s is not IPv4", options.Subnet)
+	}
+	if ones, _ := subnet.Mask.Size(); ones > 30 {
+		return nil, fmt.Errorf("subnet: %s is too small", options.Subnet)
+	}
+	trader := &SlaveTrader{
+		logger:  logger,
+		options: options,
+		subnet:  subnet,
+		slaves:  make(map[string]*slaveType),
+	}
+	slavesDir := filepath.Join(options.StateDirectory, "slaves")
+	if err := os.MkdirAll(slavesDir, fsutil.DirPerms); err != nil {
+		return nil, err
+	}
+	if options.MemoryInMiB > 0 || options.MilliCPUs > 0 {
+		if err := setupCgroupParent(); err != nil {
+			return nil, err
+		}
+	}
+	names, err := fsutil.ReadDirnames(slavesDir, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		ipAddress := net.ParseIP(name)
+		if ipAddress == nil || !subnet.Contains(ipAddress) {
+			logger.Printf("ignoring unknown slave directory: %s\n", name)
+			continue
+		}
+		slave := &slaveType{
+			index:     trader.ipAddressToIndex(ipAddress),
+			ipAddress: ipAddress.To4(),
+		}
+		data, err := ioutil.ReadFile(filepath.Join(slavesDir, name, "pid"))
+		if err == nil {
+			slave.pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		data, err = ioutil.ReadFile(filepath.Join(slavesDir, name, "pidns"))
+		if err == nil {
+			slave.pidNamespace = strings.TrimSpace(string(data))
+		}
+		trader.slaves[name] = slave
+	}
+	return trader, nil
+}
+
+func setupCgroupParent() error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot,
+		"cgroup.controllers")); err != nil {
+		return fmt.Errorf("cgroup v2 not available: %s", err)
+	}
+	err := writeCgroupFile(cgroupRoot, "cgroup.subtree_control",
+		"+cpu +memory")
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(cgroupParent, fsutil.DirPerms); err != nil {
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	return writeCgroupFile(cgroupParent, "cgroup.subtree_control",
+		"+cpu +memory")
+}
+
+func (trader *SlaveTrader) allocateSlave() (*slaveType, error) {
+	ones, bits := trader.subnet.Mask.Size()
+	maxSlaves := uint(1) << uint(bits-ones-2)
+	trader.mutex.Lock()
+	defer trader.mutex.Unlock()
+	indicesUsed := make(map[uint]struct{}, len(trader.slaves))
+	for _, slave := range trader.slaves {
+		indicesUsed[slave.index] = struct{}{}
+	}
+	for index := uint(0); index < maxSlaves; index++ {
+		if _, ok := indicesUsed[index]; ok {
+			continue
+		}
+		slave := &slaveType{
+			index:     index,
+			ipAddress: addToUint32(trader.subnet.IP, uint32(index)*4+2),
+		}
+		trader.slaves[slave.ipAddress.String()] = slave
+		return slave, nil
+	}
+	return nil, fmt.Errorf("no free addresses in subnet: %s", trader.subnet)
+}
+
+func (trader *SlaveTrader) close() error {
+	return nil
+}
+
+func (trader *SlaveTrader) createSlave() (slavedriver.SlaveInfo, error) {
+	imageDir, err := trader.getImage()
+	if err != nil {
+		return slavedriver.SlaveInfo{}, err
+	}
+	slave, err := trader.allocateSlave()
+	if err != nil {
+		return slavedriver.SlaveInfo{}, err
+	}
+	identifier := slave.ipAddress.String()
+	doCleanup := true
+	defer func() {
+		if doCleanup {
+			if err := trader.destroySlave(identifier); err != nil {
+				trader.logger.Printf("error cleaning up slave: %s: %s\n",
+					identifier, err)
+			}
+		}
+	}()
+	slaveDir := trader.getSlaveDirectory(identifier)
+	if err := os.Mkdir(slaveDir, fsutil.DirPerms); err != nil {
+		return slavedriver.SlaveInfo{}, err
+	}
+	rootDir := filepath.Join(slaveDir, "root")
+	startTime := time.Now()
+	trader.imageLock.RLock()
+	err = runCommand("cp", "-a", imageDir, rootDir)
+	trader.imageLock.RUnlock()
+	if err != nil {
+		return slavedriver.SlaveInfo{}, err
+	}
+	trader.logger.Debugf(0, "copied root for slave: %s in %s\n",
+		identifier, time.Since(startTime))
+	if err := trader.startSlave(slave, slaveDir); err != nil {
+		return slavedriver.SlaveInfo{},
+			fmt.Errorf("error starting slave: %s", err)
+	}
+	doCleanup = false
+	return slavedriver.SlaveInfo{
+		Identifier: identifier,
+		IpAddress:  slave.ipAddress,
+	}, nil
+}
+
+func (trader *SlaveTrader) destroySlave(identifier string) error {
+	trader.mutex.Lock()
+	slave := trader.slaves[identifier]
+	trader.mutex.Unlock()
+	if slave == nil {
+		return fmt.Errorf("unknown slave: %s", identifier)
+	}
+	slaveDir := trader.getSlaveDirectory(identifier)
+	rootDir := filepath.Join(slaveDir, "root")
+	if slave.isRunning(rootDir) {
+		// Killing init of the PID namespace kills all the processes in it.
+		if err := syscall.Kill(slave.pid, syscall.SIGKILL); err != nil {
+			return err
+		}
+		if err := trader.waitForExit(slave, rootDir); err != nil {
+			return err
+		}
+	}
+	hostInterface, _ := trader.getInterfaceNames(slave)
+	if _, err := net.InterfaceByName(hostInterface); err == nil {
+		if err := runCommand("ip", "link", "delete", hostInterface); err != nil {
+			trader.logger.Println(err)
+		}
+	}
+	if trader.options.MemoryInMiB > 0 || trader.options.MilliCPUs > 0 {
+		cgroupDir := filepath.Join(cgroupParent, identifier)
+		for count := 0; count < 10; count++ {
+			err := os.Remove(cgroupDir)
+			if err == nil || os.IsNotExist(err) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if err := os.RemoveAll(slaveDir); err != nil {
+		return err
+	}
+	trader.mutex.Lock()
+	delete(trader.slaves, identifier)
+	trader.mutex.Unlock()
+	return nil
+}
+
+// getImage returns the name of the directory containing the unpacked image for
+// slaves, unpacking the latest image if needed. Slaves may be allocated and
+// destroyed while the image is unpacked.
+func (trader *SlaveTrader) getImage() (string, error) {
+	imageDir := filepath.Join(trader.options.StateDirectory, "image")
+	client, err := srpc.DialHTTP("tcp", trader.options.ImageServerAddress,
+		time.Second*15)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	imageName := trader.options.ImageName
+	if isDir, err := imageclient.CheckDirectory(client, imageName); err != nil {
+		return "", err
+	} else if isDir {
+		imageName, err = imageclient.FindLatestImage(client, imageName, false)
+		if err != nil {
+			return "", err
+		}
+		if imageName == "" {
+			return "", fmt.Errorf("no images in stream: %s",
+				trader.options.ImageName)
+		}
+	}
+	trader.imageMutex.Lock()
+	defer trader.imageMutex.Unlock()
+	if imageName == trader.imageName {
+		return imageDir, nil
+	}
+	startTime := time.Now()
+	img, err := imageclient.GetImage(client, imageName)
+	if err != nil {
+		return "", err
+	}
+	if img == nil {
+		return "", fmt.Errorf("image: %s not found", imageName)
+	}
+	img.FileSystem.RebuildInodePointers()
+	tmpDir := imageDir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return "", err
+	}
+	objClient := objectclient.AttachObjectClient(client)
+	defer objClient.Close()
+	err = util.Unpack(img.FileSystem, objClient, tmpDir, trader.logger)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+	trader.imageLock.Lock()
+	defer trader.imageLock.Unlock()
+	if err := os.RemoveAll(imageDir); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpDir, imageDir); err != nil {
+		return "", err
+	}
+	trader.imageName = imageName
+	trader.logger.Printf("unpacked slave image: %s in %s\n",
+		imageName, time.Since(startTime))
+	return imageDir, nil
+}
+
+func (trader *SlaveTrader) getInterfaceNames(slave *slaveType) (
+	string, string) {
+	hostInterface := fmt.Sprintf("nsslave%d", slave.index)
+	return hostInterface, hostInterface + "p"
+}
+
+func (trader *SlaveTrader) getSlaveDirectory(identifier string) string {
+	return filepath.Join(trader.options.StateDirectory, "slaves", identifier)
+}
+
+func (trader *SlaveTrader) ipAddressToIndex(ipAddress net.IP) uint {
+	return uint(binary.BigEndian.Uint32(ipAddress.To4())-
+		binary.BigEndian.Uint32(trader.subnet.IP.To4())) / 4
+}
+
+func (trader *SlaveTrader) setupCgroup(slave *slaveType) error {
+	cgroupDir := filepath.Join(cgroupParent, slave.ipAddress.String())
+	if err := os.Mkdir(cgroupDir, fsutil.DirPerms); err != nil {
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	if trader.options.MemoryInMiB > 0 {
+		err := writeCgroupFile(cgroupDir, "memory.max",
+			strconv.FormatUint(trader.options.MemoryInMiB<<20, 10))
+		if err != nil {
+			return err
+		}
+	}
+	if trader.options.MilliCPUs > 0 {
+		err := writeCgroupFile(cgroupDir, "cpu.max",
+			fmt.Sprintf("%d 100000", trader.options.MilliCPUs*100))
+		if err != nil {
+			return err
+		}
+	}
+	return writeCgroupFile(cgroupDir, "cgroup.procs", strconv.Itoa(slave.pid))
+}
+
+// startSlave starts the slave command. The goroutine which creates the slave
+// network and mount namespaces, changes root and drops capabilities remains
+// locked to its OS thread, so that thread is destroyed when the goroutine exits
+// rather than being re-used by another goroutine.
+func (trader *SlaveTrader) startSlave(slave *slaveType,
+	slaveDir string) error {
+	errorChannel := make(chan error, 1)
+	go func() {
+		errorChannel <- trader.startSlaveInNamespace(slave, slaveDir)
+	}()
+	if err := <-errorChannel; err != nil {
+		return err
+	}
+	err := ioutil.WriteFile(filepath.Join(slaveDir, "pid"),
+		[]byte(strconv.Itoa(slave.pid)+"\n"), fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	slave.pidNamespace = getPidNamespace(slave.pid)
+	err = ioutil.WriteFile(filepath.Join(slaveDir, "pidns"),
+		[]byte(slave.pidNamespace+"\n"), fsutil.PublicFilePerms)
+	if err != nil {
+		return err
+	}
+	if trader.options.MemoryInMiB > 0 || trader.options.MilliCPUs > 0 {
+		if err := trader.setupCgroup(slave); err != nil {
+			return fmt.Errorf("error setting up cgroup: %s", err)
+		}
+	}
+	return nil
+}
+
+func (trader *SlaveTrader) startSlaveInNamespace(slave *slaveType,
+	slaveDir string) error {
+	namespaceFd, threadId, err := wsyscall.UnshareNetNamespace()
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(namespaceFd)
+	// Ensure the mounts made by the slave do not propagate to the host.
+	if err := wsyscall.UnshareMountNamespace(); err != nil {
+		return err
+	}
+	hostInterface, slaveInterface := trader.getInterfaceNames(slave)
+	hostAddress := addToUint32(slave.ipAddress, ^uint32(0)) // Subtract 1.
+	err = runCommandInHostNamespace("ip", "link", "add", hostInterface,
+		"type", "veth", "peer", "name", slaveInterface)
+	if err != nil {
+		return err
+	}
+	err = runCommandInHostNamespace("ip", "link", "set", slaveInterface,
+		"netns", strconv.Itoa(threadId))
+	if err != nil {
+		return err
+	}
+	err = runCommandInHostNamespace("ip", "addr", "add",
+		hostAddress.String()+"/30", "dev", hostInterface)
+	if err != nil {
+		return err
+	}
+	err = runCommandInHostNamespace("ip", "link", "set", hostInterface, "up")
+	if err != nil {
+		return err
+	}
+	for _, args := range [][]string{
+		{"ip", "link", "set", slaveInterface, "name", "eth0"},
+		{"ip", "addr", "add", slave.ipAddress.String() + "/30", "dev", "eth0"},
+		{"ip", "link", "set", "lo", "up"},
+		{"ip", "link", "set", "eth0", "up"},
+		{"ip", "route", "add", "default", "via", hostAddress.String()},
+	} {
+		if err := runCommand(args...); err != nil {
+			return err
+		}
+	}
+	logfile, err := os.Create(filepath.Join(slaveDir, "log"))
+	if err != nil {
+		return err
+	}
+	defer logfile.Close()
+	hostname, _ := os.Hostname()
+	// From here on, the host file-system is not accessible from this thread.
+	if err := makeSlaveRoot(filepath.Join(slaveDir, "root")); err != nil {
+		return err
+	}
+	if err := dropCapabilities(); err != nil {
+		return err
+	}
+	args := append([]string{"-c", setupScript, "slave"},
+		trader.options.Command...)
+	cmd := exec.Command("/bin/sh", args...)
+	cmd.Dir = "/"
+	cmd.Env = []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		fmt.Sprintf("SLAVE_HOSTNAME=%s-slave-%d", hostname, slave.index),
+	}
+	cmd.Stdout = logfile
+	cmd.Stderr = logfile
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWUTS,
+		Setsid: true,
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	slave.exited = exited
+	slave.pid = cmd.Process.Pid
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			trader.logger.Debugf(0, "slave: %s exited: %s\n",
+				slave.ipAddress, err)
+		}
+		close(exited)
+	}()
+	trader.logger.Printf("started slave: %s, PID: %d\n",
+		slave.ipAddress, slave.pid)
+	return nil
+}
+
+func (trader *SlaveTrader) waitForExit(slave *slaveType,
+	rootDir string) error {
+	timer := time.NewTimer(time.Second * 10)
+	defer timer.Stop()
+	if slave.exited != nil {
+		select {
+		case <-slave.exited:
+			return nil
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for slave: %s to exit",
+				slave.ipAddress)
+		}
+	}
+	for slave.isRunning(rootDir) {
+		select {
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for slave: %s to exit",
+				slave.ipAddress)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// isRunning returns true if the slave process is running. Slaves started before
+// the slave PID namespace was recorded are identified by their root directory.
+func (slave *slaveType) isRunning(rootDir string) bool {
+	if slave.pidNamespace == "" {
+		return isProcessInRoot(slave.pid, rootDir)
+	}
+	return getPidNamespace(slave.pid) == slave.pidNamespace
+}
@@ -0,0 +1,232 @@
+// +build linux
+
+package namespace
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+func makeTestTrader(t *testing.T, subnet string) *SlaveTrader {
+	trader, err := NewSlaveTrader(SlaveTraderOptions{
+		Command:            []string{"true"},
+		ImageName:          "slave/image",
+		ImageServerAddress: "localhost:1",
+		StateDirectory:     t.TempDir(),
+		Subnet:             subnet,
+	}, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trader
+}
+
+func TestAllocateSlave(t *testing.T) {
+	trader := makeTestTrader(t, "10.1.0.0/28")
+	var wg sync.WaitGroup
+	slaves := make(chan *slaveType, 4)
+	for count := 0; count < 4; count++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slave, err := trader.allocateSlave()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			slaves <- slave
+		}()
+	}
+	wg.Wait()
+	close(slaves)
+	var ipAddresses []string
+	for slave := range slaves {
+		if index := trader.ipAddressToIndex(slave.ipAddress); index !=
+			slave.index {
+			t.Errorf("%s: index: %d != %d", slave.ipAddress, index,
+				slave.index)
+		}
+		ipAddresses = append(ipAddresses, slave.ipAddress.String())
+	}
+	sort.Strings(ipAddresses)
+	expected := []string{"10.1.0.10", "10.1.0.14", "10.1.0.2", "10.1.0.6"}
+	if len(ipAddresses) != len(expected) {
+		t.Fatalf("allocated: %v", ipAddresses)
+	}
+	for index, ipAddress := range ipAddresses {
+		if ipAddress != expected[index] {
+			t.Errorf("allocated: %v, expected: %v", ipAddresses, expected)
+			break
+		}
+	}
+	if _, err := trader.allocateSlave(); err == nil {
+		t.Fatal("allocated slave in full subnet")
+	}
+	trader.mutex.Lock()
+	delete(trader.slaves, "10.1.0.6")
+	trader.mutex.Unlock()
+	if slave, err := trader.allocateSlave(); err != nil {
+		t.Fatal(err)
+	} else if slave.ipAddress.String() != "10.1.0.6" {
+		t.Errorf("re-allocated: %s", slave.ipAddress)
+	}
+}
+
+func TestIsRunning(t *testing.T) {
+	slave := &slaveType{
+		pid:          os.Getpid(),
+		pidNamespace: getPidNamespace(os.Getpid()),
+	}
+	if slave.pidNamespace == "" {
+		t.Skip("PID namespaces not available")
+	}
+	if !slave.isRunning("/nonexistent") {
+		t.Error("running slave not detected")
+	}
+	slave.pidNamespace = "pid:[0]"
+	if slave.isRunning("/") {
+		t.Error("process in other PID namespace detected as slave")
+	}
+	slave.pidNamespace = ""
+	if slave.isRunning("/nonexistent") {
+		t.Error("process in other root detected as slave")
+	}
+}
+
+func TestLoadSlaves(t *testing.T) {
+	stateDir := t.TempDir()
+	slaveDir := filepath.Join(stateDir, "slaves", "10.1.0.6")
+	if err := os.MkdirAll(slaveDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"pid": "1234\n", "pidns": "pid:[4026531836]\n"}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(slaveDir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.Mkdir(filepath.Join(stateDir, "slaves", "unknown"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trader, err := NewSlaveTrader(SlaveTraderOptions{
+		Command:            []string{"true"},
+		ImageName:          "slave/image",
+		ImageServerAddress: "localhost:1",
+		StateDirectory:     stateDir,
+		Subnet:             "10.1.0.0/24",
+	}, testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trader.slaves) != 1 {
+		t.Fatalf("loaded %d slaves", len(trader.slaves))
+	}
+	slave := trader.slaves["10.1.0.6"]
+	if slave == nil {
+		t.Fatal("slave not loaded")
+	}
+	if slave.index != 1 || slave.pid != 1234 ||
+		slave.pidNamespace != "pid:[4026531836]" ||
+		!slave.ipAddress.Equal(net.ParseIP("10.1.0.6")) {
+		t.Errorf("bad slave: %+v", slave)
+	}
+}
+
+func TestMakeSlaveRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("not running as root")
+	}
+	rootDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	errorChannel := make(chan error, 1)
+	go func() {
+		// The thread is left locked so that it is destroyed on exit.
+		runtime.LockOSThread()
+		if err := wsyscall.UnshareMountNamespace(); err != nil {
+			errorChannel <- err
+			return
+		}
+		if err := makeSlaveRoot(rootDir); err != nil {
+			errorChannel <- err
+			return
+		}
+		if err := dropCapabilities(); err != nil {
+			errorChannel <- err
+			return
+		}
+		errorChannel <- checkSlaveRoot(t, rootDir)
+	}()
+	if err := <-errorChannel; err != nil {
+		if os.IsPermission(err) || err == syscall.EPERM {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+}
+
+// checkSlaveRoot checks the root of the calling thread made by makeSlaveRoot.
+func checkSlaveRoot(t *testing.T, rootDir string) error {
+	if _, err := os.Stat("/bin"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join("/", oldRootName)); err == nil {
+		t.Error("old root not removed")
+	}
+	if _, err := os.Stat(rootDir); err == nil {
+		t.Errorf("host directory: %s accessible", rootDir)
+	}
+	file, err := os.Open("/dev")
+	if err != nil {
+		return err
+	}
+	names, err := file.Readdirnames(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	expectedNames := map[string]struct{}{"pts": {}, "shm": {}}
+	for _, device := range slaveDevices {
+		expectedNames[device.name] = struct{}{}
+		var stat syscall.Stat_t
+		err := syscall.Stat(filepath.Join("/dev", device.name), &stat)
+		if err != nil {
+			return err
+		}
+		if stat.Mode&syscall.S_IFMT != syscall.S_IFCHR ||
+			stat.Rdev != uint64(device.major<<8|device.minor) ||
+			stat.Mode&0777 != 0666 {
+			t.Errorf("/dev/%s: mode: %o, device: %d",
+				device.name, stat.Mode, stat.Rdev)
+		}
+	}
+	for name := range slaveDeviceSymlinks {
+		expectedNames[name] = struct{}{}
+	}
+	for _, name := range names {
+		if _, ok := expectedNames[name]; !ok {
+			t.Errorf("unexpected device: /dev/%s", name)
+		}
+	}
+	if len(names) != len(expectedNames) {
+		t.Errorf("devices: %v", names)
+	}
+	result, _, _ := syscall.RawSyscall(syscall.SYS_PRCTL,
+		syscall.PR_CAPBSET_READ, capMknod, 0)
+	if result != 0 {
+		t.Error("CAP_MKNOD not dropped")
+	}
+	return nil
+}
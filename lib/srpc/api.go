@@ -18,15 +18,21 @@
 	  /_go_TLS_SRPC_/         Secured (TLS, full auth), GOB coder.
 	  /_SRPC_/unsecured/JSON  Unsecured (no TLS, no auth), JSON coder.
 	  /_SRPC_/TLS/JSON        Secured (TLS, full auth), JSON coder.
-	Thus, a web server may also support SRPC on the same port.
+	Thus, a web server may also support SRPC on the same port. The
+	/_goSRPC_/listMethods path lists the registered methods and the
+	/_goSRPC_/describeMethods path returns a JSON array describing them,
+	including JSON Schemas for the request and response types of request/reply
+	methods. Since JSON is language neutral, generic clients may use these
+	schemas to call request/reply methods using the JSON coder.
 
 	A client issues a HTTP CONNECT request to a server and (for secured
 	connections) performs a TLS handshake. If the server requires the TLS
 	handshake prior to the HTTP CONNECT, the client will retry with that mode.
 
 	Once connected, a client may issue a sequence of RPC calls, one at a time
-	per connection. The client sends the name of the RPC method to call,
-	followed by a newline character (a carriage return+newline is permitted).
+	per connection (unless the connection is multiplexed, see below). The
+	client sends the name of the RPC method to call, followed by a newline
+	character (a carriage return+newline is permitted).
 	For a secured connection, the server will verify if the client X509
 	certificate is signed by a trusted CA and if the method is listed in the
 	list of permitted methods in the certificate.
@@ -39,21 +45,77 @@
 	available as a fallback). Most method handlers wait for client messages and
 	then respond. Once the method handler exits (without an error code), the
 	server waits for another method call.
+
+	A client may request a multiplexed connection by sending the
+	"X-Srpc-Multiplex: 1" header with the HTTP CONNECT request. A server which
+	supports multiplexing sends the same header in its response. Old servers
+	ignore the header, in which case the connection is not multiplexed. On a
+	multiplexed connection, data are sent in frames. Each frame has a 9 byte
+	header: the frame type (1 byte), the stream ID (4 bytes, big-endian) and
+	the payload length (4 bytes, big-endian, at most 32 KiB), followed by the
+	payload. The frame types are:
+	  0: data
+	  1: close (the sender will send no more data on the stream)
+	  2: reset (the sender has abandoned the stream)
+	  3: window update (payload is the 4 byte big-endian increment)
+	The client opens a stream by sending a (possibly empty) data frame with a
+	new stream ID, which must be greater than any previous stream ID. Each
+	stream carries a single method call, using the same protocol as described
+	above, and is finished once both sides have sent a close or reset frame.
+	Each side may send up to 256 KiB of data on a stream before it must wait
+	for a window update from the receiver. Thus, many calls may be in progress
+	at the same time on a single connection.
+
+	The method name line may be followed by parameters (separated by spaces)
+	of the form "key=value". Unknown parameters are ignored. The "timeout"
+	parameter (a Go duration string) is the time remaining before the client
+	will abandon the call. Servers which accept it send the
+	"X-Srpc-Deadlines: 1" header in the HTTP CONNECT response, and clients only
+	send it to such servers. The server cancels the context of the call when
+	the timeout expires. The "traceparent" parameter (in the W3C traceparent
+	format) identifies the client span of a traced call. Servers which accept
+	it send the "X-Srpc-Trace: 1" header in the HTTP CONNECT response. The
+	server records a span for each call, which is a child of the client span
+	if one was sent.
+
+	A server may also listen on a Unix socket (see StartUnixServer). The
+	protocol is the same, except that a client which does not use TLS is
+	authenticated using the credentials (user ID) of the peer process, even if
+	the server requires TLS for network connections. The root user and the user
+	the server runs as may call all methods, other users may only call public
+	methods or methods granted by the receiver.
+
+	As an alternative to client certificates, a client may authenticate with a
+	bearer token (such as a signed JWT) on the TLS endpoints, if the server has
+	a registered TokenVerifier. The client sends the "X-Srpc-Token-Auth: 1"
+	header with the HTTP CONNECT request and the server sends the same header
+	in its response if it accepts tokens. After the TLS handshake, the client
+	sends the token followed by a newline character. The server responds with a
+	newline character if the token is valid, else it responds with an error
+	message followed by a newline character and closes the connection. Since
+	the token is only sent over the encrypted connection, it is not exposed to
+	eavesdroppers. The identity in the token replaces the identity in any client
+	certificate.
 */
 package srpc
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"flag"
+	"io"
+	"math/big"
 	"net"
+	"os"
 	"sync"
 	"time"
 
 	libnet "github.com/Cloud-Foundations/Dominator/lib/net"
 	"github.com/Cloud-Foundations/Dominator/lib/resourcepool"
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
 )
 
 var (
@@ -63,16 +125,30 @@ var (
 	ErrorBadCertificate       = errors.New("bad certificate")
 	ErrorNoSrpcEndpoint       = errors.New("no SRPC endpoint")
 	ErrorAccessToMethodDenied = errors.New("access to method denied")
+	ErrorCertificateRevoked   = errors.New("certificate revoked")
+	ErrorRateLimitExceeded    = errors.New("rate limit exceeded")
+	ErrorCircuitBreakerOpen   = errors.New("circuit breaker open")
 
 	ErrorCloseClient = errors.New("close client")
 )
 
 var (
+	tlsConfigLock      sync.RWMutex // Protects the following.
 	clientTlsConfig    *tls.Config
+	clientTokenGetter  func() (string, error)
 	fullAuthCaCertPool *x509.CertPool
 	serverTlsConfig    *tls.Config
+	revocationList     *RevocationList
 	tlsRequired        bool
+	tokenVerifier      TokenVerifier
+
+	policyLock          sync.RWMutex // Protects the following.
+	auditMethodPatterns []string
+	callAuditor         func(record *CallRecord)
+	rateLimits          *RateLimits
 
+	srpcMultiplex = flag.Bool("srpcMultiplex", false,
+		"If true, request multiplexed connections to servers")
 	srpcProxy = flag.String("srpcProxy", "",
 		"Proxy to use (only works for some operations)")
 )
@@ -81,7 +157,8 @@ var (
 // trusted certificates. It returns false if unencrypted or unauthenticated
 // connections are permitted (i.e. insecure mode).
 func CheckTlsRequired() bool {
-	return tlsRequired
+	_, _, requireTls := getServerTlsConfig()
+	return requireTls
 }
 
 // GetEarliestClientCertExpiration returns the earliest expiration time of any
@@ -91,6 +168,27 @@ func GetEarliestClientCertExpiration() time.Time {
 	return getEarliestClientCertExpiration()
 }
 
+// GetClientCertificate returns the first certificate (including the private
+// key) registered with RegisterClientTlsConfig. It returns nil if there are no
+// certificates. This may be used to sign data with the identity of the process.
+func GetClientCertificate() *tls.Certificate {
+	return getClientCertificate()
+}
+
+// GetMethodDescriptions fetches the descriptions of the methods registered
+// with the HTTP SRPC server at the specified address. If timeout is zero or
+// less, no timeout is applied.
+func GetMethodDescriptions(address string, timeout time.Duration) (
+	[]MethodDescription, error) {
+	return getMethodDescriptions(address, timeout)
+}
+
+// JoinHostPort returns the address for the host and port. If host is a Unix
+// socket address (with the "unix://" prefix), it is returned unchanged.
+func JoinHostPort(host string, port uint) string {
+	return joinHostPort(host, port)
+}
+
 // LoadCertificates loads zero or more X509 certificates from directory. Each
 // certificate must be stored in a pair of PEM-encoded files, with the private
 // key in a file with extension '.key' and the corresponding public key
@@ -106,6 +204,24 @@ type AuthInformation struct {
 	Username         string
 }
 
+// CallRecord contains information about a completed method call, for
+// auditing.
+type CallRecord struct {
+	Duration      time.Duration
+	Error         error // nil: the call succeeded.
+	GroupList     map[string]struct{}
+	RemoteAddr    string
+	ServiceMethod string
+	StartTime     time.Time
+	Username      string // Empty string for unauthenticated.
+}
+
+// String returns a single line description of the call, suitable for an audit
+// log.
+func (record *CallRecord) String() string {
+	return record.string()
+}
+
 // Dialer implements a dialer that can be use to create connections.
 type Dialer interface {
 	Dial(network, address string) (net.Conn, error)
@@ -131,6 +247,17 @@ type MethodBlocker interface {
 	BlockMethod(methodName string, authInfo *AuthInformation) (func(), error)
 }
 
+// MethodDescription describes a registered method. The request and response
+// schemas are only provided for request/reply methods.
+type MethodDescription struct {
+	Name           string      // Service.Method
+	Public         bool        `json:",omitempty"`
+	RequestSchema  *TypeSchema `json:",omitempty"`
+	ResponseSchema *TypeSchema `json:",omitempty"`
+	Signature      string      // Go function signature.
+	Type           string      // One of: "coder", "raw", "request-reply".
+}
+
 // MethodGranter defines an interface to grant method calls (if access is not
 // granted by the built-in authorisation mechanism) for a receiver (passed to
 // RegisterName).
@@ -162,15 +289,46 @@ func RegisterNameWithOptions(name string, rcvr interface{},
 // RegisterServerTlsConfig registers the configuration for TLS server
 // connections.
 // If requireTls is true, any non-TLS connection will be rejected.
+// RegisterServerTlsConfig may be called again to replace the configuration
+// (such as when certificates are renewed). The new configuration is used for
+// new connections and existing connections are not affected.
 func RegisterServerTlsConfig(config *tls.Config, requireTls bool) {
-	serverTlsConfig = config
-	tlsRequired = requireTls
+	registerServerTlsConfig(config, requireTls)
+}
+
+// RegisterServerTlsConfigWithFullAuthCA is similar to RegisterServerTlsConfig
+// except that the CA certificate pool used for full authentication (see
+// RegisterFullAuthCA) is registered at the same time. A nil certPool means
+// that the CA certificate pool in config is used for full auth checks. This
+// ensures that new connections never see a mix of old and new configuration.
+func RegisterServerTlsConfigWithFullAuthCA(config *tls.Config,
+	certPool *x509.CertPool, requireTls bool) {
+	registerServerTlsConfigWithFullAuthCA(config, certPool, requireTls)
+}
+
+// RegisterCallAuditor registers a function which is called after each call to
+// a method which matches one of the methodPatterns (see filepath.Match), such
+// as "Hypervisor.DestroyVm" or "ImageServer.*". The auditor is called
+// synchronously, so it should not block. If auditor is nil, calls are not
+// audited. RegisterCallAuditor may be called again to replace the auditor.
+func RegisterCallAuditor(auditor func(record *CallRecord),
+	methodPatterns []string) {
+	registerCallAuditor(auditor, methodPatterns)
 }
 
 // RegisterClientTlsConfig registers the configuration for TLS client
-// connections.
+// connections. It may be called again to replace the configuration. The new
+// configuration is used for new connections.
 func RegisterClientTlsConfig(config *tls.Config) {
-	clientTlsConfig = config
+	registerClientTlsConfig(config)
+}
+
+// RegisterClientTokenGetter registers a function which returns a bearer token
+// to authenticate with when connecting to servers. It is called for each new
+// connection to a TLS endpoint of a server which accepts tokens, so that
+// short-lived tokens may be refreshed.
+func RegisterClientTokenGetter(getter func() (string, error)) {
+	registerClientTokenGetter(getter)
 }
 
 // RegisterFullAuthCA registers the CA certificate pool used for full
@@ -179,7 +337,133 @@ func RegisterClientTlsConfig(config *tls.Config) {
 // used for full auth checks. This allows for distinguishing between CAs trusted
 // for everything versus CAs trusted only for identity (username and groups).
 func RegisterFullAuthCA(certPool *x509.CertPool) {
-	fullAuthCaCertPool = certPool
+	registerFullAuthCA(certPool)
+}
+
+// RegisterRateLimits registers per-user method rate limits. Calls which exceed
+// a limit are rejected with ErrorRateLimitExceeded. RegisterRateLimits may be
+// called again to replace the limits (the state of the limits is reset). If
+// limits is nil, calls are not rate limited. The limits must not be modified
+// after they are registered.
+func RegisterRateLimits(limits *RateLimits) {
+	registerRateLimits(limits)
+}
+
+// RegisterRevocationList registers the list of revoked certificates and users.
+// Method calls made over connections authenticated with a revoked certificate
+// or by a revoked user are rejected with an error wrapping
+// ErrorCertificateRevoked. RegisterRevocationList may be called again to
+// replace the list, which is then checked for all subsequent method calls,
+// including those on existing connections. The list must not be modified after
+// it is registered.
+func RegisterRevocationList(list *RevocationList) {
+	registerRevocationList(list)
+}
+
+// RegisterTokenVerifier registers the verifier for bearer tokens. If no
+// verifier is registered, tokens are not accepted. When accepting tokens, the
+// server TLS configuration should permit clients without certificates
+// (tls.VerifyClientCertIfGiven). Clients without a certificate or a valid
+// token are rejected if TLS is required.
+func RegisterTokenVerifier(verifier TokenVerifier) {
+	registerTokenVerifier(verifier)
+}
+
+// RateLimits contains token bucket rate limits for method calls. Each limit
+// applies to the users and methods matching the specified patterns (see
+// filepath.Match) and each user has a separate bucket for each method. The
+// first matching limit applies. The zero value contains no limits.
+type RateLimits struct {
+	limits []*rateLimitType
+}
+
+// AddLimit adds a limit of rate calls per second, with bursts of up to burst
+// calls, for users matching usernamePattern calling methods matching
+// methodPattern. Unauthenticated users have an empty username.
+func (limits *RateLimits) AddLimit(usernamePattern, methodPattern string,
+	rate float64, burst uint) error {
+	return limits.addLimit(usernamePattern, methodPattern, rate, burst)
+}
+
+// Check consumes a token from the bucket for the user and method and returns
+// ErrorRateLimitExceeded if the bucket is empty.
+func (limits *RateLimits) Check(username, serviceMethod string) error {
+	return limits.check(username, serviceMethod, time.Now())
+}
+
+// RevocationList contains revoked certificates and users. The zero value is an
+// empty list.
+type RevocationList struct {
+	issuerSerials map[string]map[string]struct{} // Key: raw issuer, serial.
+	serials       map[string]struct{}            // Leaves, for all issuers.
+	usernames     map[string]struct{}
+}
+
+// AddCRL adds the certificates listed in crl to the list. The signature of crl
+// should be checked before calling AddCRL.
+func (list *RevocationList) AddCRL(crl *x509.RevocationList) {
+	list.addCRL(crl)
+}
+
+// AddSerialNumber adds a certificate serial number to the list. Leaf
+// certificates with this serial number are revoked, irrespective of the
+// issuer. CA certificates are only revoked by CRLs from their issuer.
+func (list *RevocationList) AddSerialNumber(serialNumber *big.Int) {
+	list.addSerialNumber(serialNumber)
+}
+
+// AddUsername adds a username to the list. Certificates identifying the user
+// are revoked.
+func (list *RevocationList) AddUsername(username string) {
+	list.addUsername(username)
+}
+
+// Check returns an error wrapping ErrorCertificateRevoked if username is
+// revoked or if every chain in verifiedChains contains a revoked certificate.
+func (list *RevocationList) Check(verifiedChains [][]*x509.Certificate,
+	username string) error {
+	return list.check(verifiedChains, username)
+}
+
+// TokenIdentity contains the identity and permissions from a verified bearer
+// token. The permitted methods have the same semantics as those in client
+// certificates.
+type TokenIdentity struct {
+	GroupList        map[string]struct{}
+	PermittedMethods map[string]struct{} // Empty: none permitted.
+	Username         string
+}
+
+// TokenVerifier defines an interface to verify bearer tokens. The VerifyToken
+// method returns the identity in the token if it is valid.
+type TokenVerifier interface {
+	VerifyToken(token string) (*TokenIdentity, error)
+}
+
+// TypeSchema is a JSON Schema describing the JSON encoding of a Go type. The
+// Title is the name of the Go type and Values describes the values of maps.
+// Recursive references are not expanded.
+type TypeSchema struct {
+	Description string                 `json:"description,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Items       *TypeSchema            `json:"items,omitempty"`
+	Properties  map[string]*TypeSchema `json:"properties,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Values      *TypeSchema            `json:"additionalProperties,omitempty"`
+}
+
+// CircuitBreakerState is the state of the circuit breaker of a ClientResource.
+type CircuitBreakerState uint
+
+const (
+	CircuitBreakerClosed   CircuitBreakerState = iota // Connecting permitted.
+	CircuitBreakerOpen                                // Connecting fails fast.
+	CircuitBreakerHalfOpen                            // One trial permitted.
+)
+
+func (state CircuitBreakerState) String() string {
+	return state.string()
 }
 
 type privateClientResource struct {
@@ -196,6 +480,42 @@ type ClientResource struct {
 	client                *Client
 	inUse                 bool
 	closeError            error
+	options               ClientResourceOptions
+	lock                  sync.Mutex // Protect the following fields.
+	consecutiveFailures   uint
+	healthTimer           *time.Timer
+	lastError             error
+	retryTime             time.Time
+	trialInProgress       bool
+}
+
+// ClientResourceOptions specifies how a ClientResource checks the health of
+// its connection and backs off from a failing server.
+type ClientResourceOptions struct {
+	// FailureThreshold is the number of consecutive connection failures which
+	// open the circuit breaker, after which Get* methods fail fast until a
+	// backoff interval has elapsed. A single trial connection is then
+	// permitted, while other Get* calls continue to fail fast until it
+	// completes. If zero, the circuit breaker is disabled.
+	FailureThreshold uint
+	// HealthCheckInterval is how long a connection may be idle before it is
+	// checked with a Ping. If the Ping fails the connection is closed. If
+	// zero, health checks are disabled.
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration // Default: 10 seconds.
+	// The backoff interval doubles from MinimumBackoff (default: 1 second)
+	// for each consecutive failure, up to MaximumBackoff (default: 5
+	// minutes). Random jitter of up to half the interval is subtracted.
+	MaximumBackoff time.Duration
+	MinimumBackoff time.Duration
+}
+
+// ClientResourceStatus contains the connection health of a ClientResource.
+type ClientResourceStatus struct {
+	CircuitBreakerState CircuitBreakerState
+	ConsecutiveFailures uint
+	LastError           error     // The last connection error.
+	RetryTime           time.Time // When the circuit breaker permits a trial.
 }
 
 // SetDefaultGrantMethod registers the grantMethod function which will be
@@ -209,6 +529,18 @@ func SetDefaultGrantMethod(grantMethod func(serviceMethod string,
 	defaultGrantMethod = grantMethod
 }
 
+// StartUnixServer starts serving SRPC (but not other handlers registered with
+// the default HTTP mux) on the Unix socket at path, in the background. If path
+// starts with '@', an abstract socket is used, otherwise any existing socket
+// file is replaced and given the specified permissions. Clients which do not
+// use TLS are authenticated with their peer credentials (SO_PEERCRED) and the
+// user ID is mapped to a username and groups. Receivers should be registered
+// before the server is started. The returned io.Closer may be used to stop the
+// server and close the socket.
+func StartUnixServer(path string, perm os.FileMode) (io.Closer, error) {
+	return startUnixServer(path, perm)
+}
+
 // NewClientResource returns a ClientResource which may be later used to Get*
 // a Client which is part of a managed pool of connection slots (to limit
 // consumption of resources such as file descriptors). Clients can be released
@@ -226,7 +558,17 @@ func SetDefaultGrantMethod(grantMethod func(serviceMethod string,
 // communications error, Close shuts down the client so that a subsequent Get*
 // creates a new connection.
 func NewClientResource(network, address string) *ClientResource {
-	return newClientResource(network, address)
+	return newClientResource(network, address, ClientResourceOptions{})
+}
+
+// NewClientResourceWithOptions is similar to NewClientResource except that
+// options may be specified to check the health of idle connections and to
+// back off from a failing server. A ClientResource should be created once for
+// each address, so that its connection failures are counted together. If the
+// circuit breaker is open, Get* methods return ErrorCircuitBreakerOpen.
+func NewClientResourceWithOptions(network, address string,
+	options ClientResourceOptions) *ClientResource {
+	return newClientResource(network, address, options)
 }
 
 // GetHTTP is similar to DialHTTP except that the returned Client is part of a
@@ -240,7 +582,7 @@ func NewClientResource(network, address string) *ClientResource {
 // (typically 3 minutes for TCP).
 func (cr *ClientResource) GetHTTP(cancelChannel <-chan struct{},
 	timeout time.Duration) (*Client, error) {
-	return cr.getHTTP(clientTlsConfig, cancelChannel,
+	return cr.getHTTP(getClientTlsConfig(), cancelChannel,
 		&net.Dialer{Timeout: timeout})
 }
 
@@ -248,7 +590,12 @@ func (cr *ClientResource) GetHTTP(cancelChannel <-chan struct{},
 // create the underlying connection.
 func (cr *ClientResource) GetHTTPWithDialer(cancelChannel <-chan struct{},
 	dialer Dialer) (*Client, error) {
-	return cr.getHTTP(clientTlsConfig, cancelChannel, dialer)
+	return cr.getHTTP(getClientTlsConfig(), cancelChannel, dialer)
+}
+
+// GetStatus returns the connection health of the ClientResource.
+func (cr *ClientResource) GetStatus() ClientResourceStatus {
+	return cr.getStatus()
 }
 
 // GetTlsHTTP is similar to DialTlsHTTP but returns a Client that is part of a
@@ -264,7 +611,7 @@ func (cr *ClientResource) GetTlsHTTP(tlsConfig *tls.Config,
 func (cr *ClientResource) GetTlsHTTPWithDialer(tlsConfig *tls.Config,
 	cancelChannel <-chan struct{}, dialer Dialer) (*Client, error) {
 	if tlsConfig == nil {
-		tlsConfig = clientTlsConfig
+		tlsConfig = getClientTlsConfig()
 	}
 	return cr.getHTTP(tlsConfig, cancelChannel, dialer)
 }
@@ -277,17 +624,23 @@ type Client struct {
 	bufrw       *bufio.ReadWriter
 	callLock    sync.Mutex
 	conn        net.Conn
+	deadlines   bool // Server accepts call timeouts.
 	isEncrypted bool
 	makeCoder   coderMaker
+	mux         *muxConnType // nil: not multiplexed.
 	resource    *ClientResource
 	tcpConn     libnet.TCPConn // The underlying raw connection.
+	tracing     bool           // Server accepts trace parents.
 }
 
 // DialHTTP connects to an HTTP SRPC server at the specified network address
 // listening on the HTTP SRPC path. If timeout is zero or less, the underlying
-// OS timeout is used (typically 3 minutes for TCP).
+// OS timeout is used (typically 3 minutes for TCP). If the address has the
+// "unix://" prefix, the remainder is the path of a Unix socket (or the name of
+// an abstract socket if it starts with '@') and network is ignored. This
+// applies to all the Dial functions.
 func DialHTTP(network, address string, timeout time.Duration) (*Client, error) {
-	return dialHTTP(network, address, clientTlsConfig,
+	return dialHTTP(network, address, getClientTlsConfig(),
 		&net.Dialer{Timeout: timeout})
 }
 
@@ -295,7 +648,20 @@ func DialHTTP(network, address string, timeout time.Duration) (*Client, error) {
 // create the underlying connection.
 func DialHTTPWithDialer(network, address string, dialer Dialer) (
 	*Client, error) {
-	return dialHTTP(network, address, clientTlsConfig, dialer)
+	return dialHTTP(network, address, getClientTlsConfig(), dialer)
+}
+
+// DialJsonHTTP is similar to DialTlsHTTP, except that only the JSON coder is
+// used, so that a client may encode requests and decode responses without the
+// Go types for them. If tlsConfig is nil, the registered client TLS
+// configuration (if any) is used.
+func DialJsonHTTP(network, address string, tlsConfig *tls.Config,
+	timeout time.Duration) (*Client, error) {
+	if tlsConfig == nil {
+		tlsConfig = getClientTlsConfig()
+	}
+	return dialJsonHTTP(network, address, tlsConfig,
+		&net.Dialer{Timeout: timeout})
 }
 
 // DialTlsHTTP connects to an HTTP SRPC TLS server at the specified network
@@ -314,7 +680,7 @@ func DialTlsHTTPWithDialer(network, address string, tlsConfig *tls.Config,
 	dialer Dialer) (
 	*Client, error) {
 	if tlsConfig == nil {
-		tlsConfig = clientTlsConfig
+		tlsConfig = getClientTlsConfig()
 	}
 	return dialHTTP(network, address, tlsConfig, dialer)
 }
@@ -326,18 +692,38 @@ func (client *Client) Close() error {
 
 // Call opens a buffered connection to the named Service.Method function, and
 // returns a connection handle and an error status. The connection handle wraps
-// a *bufio.ReadWriter. Only one connection can be made per Client. The Call
-// method will block if another Call is in progress. The Close method must be
-// called prior to attempting another Call.
+// a *bufio.ReadWriter. Unless the Client is multiplexed, only one connection
+// can be made per Client, the Call method will block if another Call is in
+// progress and the Close method must be called prior to attempting another
+// Call. A multiplexed Client permits concurrent calls. The Close method must
+// always be called when the call is complete.
 func (client *Client) Call(serviceMethod string) (*Conn, error) {
 	return client.call(serviceMethod)
 }
 
+// CallContext is similar to Call except that the call is abandoned if ctx is
+// cancelled or its deadline expires before the returned Conn is closed. The
+// deadline is sent to the server, which may use it to cancel the work (see
+// the Conn.Context method). When a call is abandoned, a multiplexed stream is
+// reset, otherwise the underlying connection is closed and the Client must be
+// closed (or released with Put, which will then close it).
+func (client *Client) CallContext(ctx context.Context, serviceMethod string) (
+	*Conn, error) {
+	return client.callContext(ctx, serviceMethod)
+}
+
 // IsEncrypted will return true if the underlying connection is TLS-encrypted.
 func (client *Client) IsEncrypted() bool {
 	return client.isEncrypted
 }
 
+// IsMultiplexed will return true if the connection permits concurrent calls.
+// Multiplexing is requested with the -srpcMultiplex command-line flag and must
+// be supported by the server.
+func (client *Client) IsMultiplexed() bool {
+	return client.mux != nil
+}
+
 // Ping sends a short "are you alive?" request and waits for a response. No
 // method permissions are required for this operation. The Ping method is a
 // wrapper around the Call method and hence will block if a Call is already in
@@ -358,11 +744,17 @@ func (client *Client) Put() {
 // SetKeepAlive sets whether the operating system should send keepalive messages
 // on the connection.
 func (client *Client) SetKeepAlive(keepalive bool) error {
+	if client.tcpConn == nil { // Unix socket.
+		return nil
+	}
 	return client.tcpConn.SetKeepAlive(keepalive)
 }
 
 // SetKeepAlivePeriod sets the period between keepalive messages.
 func (client *Client) SetKeepAlivePeriod(d time.Duration) error {
+	if client.tcpConn == nil { // Unix socket.
+		return nil
+	}
 	return client.tcpConn.SetKeepAlivePeriod(d)
 }
 
@@ -375,6 +767,13 @@ func (client *Client) RequestReply(serviceMethod string, request interface{},
 	return client.requestReply(serviceMethod, request, reply)
 }
 
+// RequestReplyContext is similar to RequestReply except that the call is
+// abandoned if ctx is cancelled or its deadline expires (see CallContext).
+func (client *Client) RequestReplyContext(ctx context.Context,
+	serviceMethod string, request interface{}, reply interface{}) error {
+	return client.requestReplyContext(ctx, serviceMethod, request, reply)
+}
+
 type Conn struct {
 	Decoder
 	Encoder
@@ -387,6 +786,13 @@ type Conn struct {
 	username         string              // Empty string for unauthenticated.
 	permittedMethods map[string]struct{} // nil: all, empty: none permitted.
 	releaseNotifier  func()
+	stream           *muxStreamType // Client-side multiplexed call.
+	verifiedChains   [][]*x509.Certificate
+	callError        error // Error returned by handler or from RequestReply.
+	ctx              context.Context
+	watcherDone      chan struct{} // Client-side: closed when watcher exits.
+	watcherStop      chan struct{} // Client-side: closed to stop watcher.
+	span             *trace.Span    // Client-side traced call.
 }
 
 // Close will close the connection to the Sevice.Method function, releasing the
@@ -395,6 +801,14 @@ func (conn *Conn) Close() error {
 	return conn.close()
 }
 
+// Context returns the context for the call. On the server side, it is
+// cancelled when the timeout sent by the client expires, when the client
+// abandons a multiplexed call or when the method handler returns. On the client
+// side, it is the context passed to CallContext.
+func (conn *Conn) Context() context.Context {
+	return conn.context()
+}
+
 // GetAuthInformation will return authentication information for the client who
 // holds the certificate used to authenticate the connection to the server. If
 // the connection was not authenticated nil is returned. If the connection is a
@@ -2,6 +2,7 @@ package srpc
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"errors"
 	"io"
@@ -23,10 +24,14 @@ type endpointType struct {
 }
 
 var (
-	clientMetricsDir          *tricorder.DirectorySpec
-	clientMetricsMutex        sync.Mutex
-	numInUseClientConnections uint64
-	numOpenClientConnections  uint64
+	clientMetricsDir             *tricorder.DirectorySpec
+	clientMetricsMutex           sync.Mutex
+	numClientCircuitBreakerTrips uint64
+	numClientHealthChecks        uint64
+	numFailFastClientConnections uint64
+	numFailedClientHealthChecks  uint64
+	numInUseClientConnections    uint64
+	numOpenClientConnections     uint64
 )
 
 func init() {
@@ -39,6 +44,36 @@ func registerClientMetrics() {
 	if err != nil {
 		panic(err)
 	}
+	err = clientMetricsDir.RegisterMetric("earliest-certificate-expiration",
+		getEarliestClientCertExpiration, units.None,
+		"earliest expiration time of client certificates")
+	if err != nil {
+		panic(err)
+	}
+	err = clientMetricsDir.RegisterMetric("num-circuit-breaker-trips",
+		&numClientCircuitBreakerTrips, units.None,
+		"number of times circuit breakers opened")
+	if err != nil {
+		panic(err)
+	}
+	err = clientMetricsDir.RegisterMetric("num-fail-fast-connections",
+		&numFailFastClientConnections, units.None,
+		"number of connections failed fast by open circuit breakers")
+	if err != nil {
+		panic(err)
+	}
+	err = clientMetricsDir.RegisterMetric("num-failed-health-checks",
+		&numFailedClientHealthChecks, units.None,
+		"number of failed health checks of idle connections")
+	if err != nil {
+		panic(err)
+	}
+	err = clientMetricsDir.RegisterMetric("num-health-checks",
+		&numClientHealthChecks, units.None,
+		"number of health checks of idle connections")
+	if err != nil {
+		panic(err)
+	}
 	err = clientMetricsDir.RegisterMetric("num-in-use-connections",
 		&numInUseClientConnections, units.None,
 		"number of connections in use")
@@ -53,8 +88,16 @@ func registerClientMetrics() {
 }
 
 func dial(network, address string, dialer Dialer) (net.Conn, error) {
-	hostPort := strings.SplitN(address, ":", 2)
-	address = strings.SplitN(hostPort[0], "*", 2)[0] + ":" + hostPort[1]
+	if isUnixAddress(address) {
+		network = "unix"
+		address = address[len(unixAddressPrefix):]
+		if _, ok := dialer.(*net.Dialer); !ok {
+			dialer = &net.Dialer{} // Other dialers may not support Unix.
+		}
+	} else {
+		hostPort := strings.SplitN(address, ":", 2)
+		address = strings.SplitN(hostPort[0], "*", 2)[0] + ":" + hostPort[1]
+	}
 	conn, err := dialer.Dial(network, address)
 	if err != nil {
 		if strings.Contains(err.Error(), ErrorConnectionRefused.Error()) {
@@ -80,23 +123,11 @@ func dial(network, address string, dialer Dialer) (net.Conn, error) {
 
 func dialHTTP(network, address string, tlsConfig *tls.Config,
 	dialer Dialer) (*Client, error) {
-	if *srpcProxy == "" {
-		return dialHTTPDirect(network, address, tlsConfig, dialer)
-	}
-	var err error
-	if d, ok := dialer.(*net.Dialer); ok {
-		dialer, err = newProxyDialer(*srpcProxy, d)
-	} else {
-		dialer, err = newProxyDialer(*srpcProxy, &net.Dialer{})
-	}
-	if err != nil {
-		return nil, err
-	}
-	return dialHTTPDirect(network, address, tlsConfig, dialer)
+	return dialHTTPWithProxy(network, address, tlsConfig, dialer, false)
 }
 
 func dialHTTPDirect(network, address string, tlsConfig *tls.Config,
-	dialer Dialer) (*Client, error) {
+	dialer Dialer, jsonOnly bool) (*Client, error) {
 	insecureEndpoints := []endpointType{
 		{&gobCoder{}, rpcPath, false},
 		{&jsonCoder{}, jsonRpcPath, false},
@@ -105,6 +136,10 @@ func dialHTTPDirect(network, address string, tlsConfig *tls.Config,
 		{&gobCoder{}, tlsRpcPath, true},
 		{&jsonCoder{}, jsonTlsRpcPath, true},
 	}
+	if jsonOnly {
+		insecureEndpoints = selectJsonEndpoints(insecureEndpoints)
+		secureEndpoints = selectJsonEndpoints(secureEndpoints)
+	}
 	if tlsConfig == nil {
 		return dialHTTPEndpoints(network, address, nil, false, dialer,
 			insecureEndpoints)
@@ -149,7 +184,16 @@ func dialHTTPEndpoint(network, address string, tlsConfig *tls.Config,
 		}
 		dataConn = tlsConn
 	}
-	if err := doHTTPConnect(dataConn, endpoint.path); err != nil {
+	header := make(http.Header)
+	if *srpcMultiplex {
+		header.Set(multiplexHeader, multiplexVersion)
+	}
+	tokenGetter := getClientTokenGetter()
+	if endpoint.tls && tokenGetter != nil {
+		header.Set(tokenAuthHeader, tokenAuthVersion)
+	}
+	responseHeader, err := doHTTPConnect(dataConn, endpoint.path, header)
+	if err != nil {
 		return nil, err
 	}
 	if endpoint.tls && !fullTLS {
@@ -162,9 +206,21 @@ func dialHTTPEndpoint(network, address string, tlsConfig *tls.Config,
 		}
 		dataConn = tlsConn
 	}
+	if endpoint.tls && tokenGetter != nil &&
+		responseHeader.Get(tokenAuthHeader) == tokenAuthVersion {
+		if err := sendToken(dataConn, tokenGetter); err != nil {
+			return nil, err
+		}
+	}
 	doClose = false
-	return newClient(unsecuredConn, dataConn, endpoint.tls,
-		endpoint.coderMaker), nil
+	client := newClient(unsecuredConn, dataConn, endpoint.tls,
+		endpoint.coderMaker)
+	client.deadlines = responseHeader.Get(deadlinesHeader) == deadlinesVersion
+	client.tracing = responseHeader.Get(traceHeader) == traceVersion
+	if responseHeader.Get(multiplexHeader) == multiplexVersion {
+		client.mux = newMuxConn(client.bufrw, dataConn, nil)
+	}
+	return client, nil
 }
 
 func dialHTTPEndpoints(network, address string, tlsConfig *tls.Config,
@@ -182,44 +238,74 @@ func dialHTTPEndpoints(network, address string, tlsConfig *tls.Config,
 	return nil, ErrorNoSrpcEndpoint
 }
 
-func doHTTPConnect(conn net.Conn, path string) error {
-	io.WriteString(conn, "CONNECT "+path+" HTTP/1.0\n\n")
+// dialHTTPWithProxy connects to the SRPC server at address, through the HTTP
+// proxy specified by -srpcProxy (if any). Unix sockets are always dialed
+// directly. If jsonOnly is true, only the JSON endpoint is tried.
+func dialHTTPWithProxy(network, address string, tlsConfig *tls.Config,
+	dialer Dialer, jsonOnly bool) (*Client, error) {
+	if *srpcProxy == "" || isUnixAddress(address) {
+		return dialHTTPDirect(network, address, tlsConfig, dialer, jsonOnly)
+	}
+	var err error
+	if d, ok := dialer.(*net.Dialer); ok {
+		dialer, err = newProxyDialer(*srpcProxy, d)
+	} else {
+		dialer, err = newProxyDialer(*srpcProxy, &net.Dialer{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dialHTTPDirect(network, address, tlsConfig, dialer, jsonOnly)
+}
+
+// dialJsonHTTP connects to the JSON endpoint of the SRPC server at address.
+func dialJsonHTTP(network, address string, tlsConfig *tls.Config,
+	dialer Dialer) (*Client, error) {
+	return dialHTTPWithProxy(network, address, tlsConfig, dialer, true)
+}
+
+// doHTTPConnect sends the HTTP CONNECT request with the specified header
+// fields (used to request optional features) and returns the response header.
+func doHTTPConnect(conn net.Conn, path string, header http.Header) (
+	http.Header, error) {
+	request := "CONNECT " + path + " HTTP/1.0\n"
+	for key, values := range header {
+		for _, value := range values {
+			request += key + ": " + value + "\n"
+		}
+	}
+	io.WriteString(conn, request+"\n")
 	// Require successful HTTP response before switching to SRPC protocol.
 	resp, err := http.ReadResponse(bufio.NewReader(conn),
 		&http.Request{Method: "CONNECT"})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.StatusCode == http.StatusNotFound {
-		return ErrorNoSrpcEndpoint
+		return nil, ErrorNoSrpcEndpoint
 	}
 	if resp.StatusCode == http.StatusUnauthorized {
-		return ErrorBadCertificate
+		return nil, ErrorBadCertificate
 	}
 	if resp.StatusCode == http.StatusMethodNotAllowed {
-		return ErrorMissingCertificate
+		return nil, ErrorMissingCertificate
 	}
 	if resp.StatusCode != http.StatusOK || resp.Status != connectString {
-		return errors.New("unexpected HTTP response: " + resp.Status)
+		return nil, errors.New("unexpected HTTP response: " + resp.Status)
 	}
-	return nil
+	return resp.Header, nil
 }
 
-func getEarliestClientCertExpiration() time.Time {
-	var earliest time.Time
-	if clientTlsConfig == nil {
-		return earliest
-	}
-	for _, cert := range clientTlsConfig.Certificates {
-		if cert.Leaf != nil && !cert.Leaf.NotAfter.IsZero() {
-			if earliest.IsZero() {
-				earliest = cert.Leaf.NotAfter
-			} else if cert.Leaf.NotAfter.Before(earliest) {
-				earliest = cert.Leaf.NotAfter
-			}
-		}
+func getClientCertificate() *tls.Certificate {
+	config := getClientTlsConfig()
+	if config == nil || len(config.Certificates) < 1 {
+		return nil
 	}
-	return earliest
+	return &config.Certificates[0]
+}
+
+func getEarliestClientCertExpiration() time.Time {
+	return getEarliestCertExpiration(getClientTlsConfig())
 }
 
 func newClient(rawConn, dataConn net.Conn, isEncrypted bool,
@@ -240,49 +326,136 @@ func newClient(rawConn, dataConn net.Conn, isEncrypted bool,
 	return client
 }
 
-func (client *Client) call(serviceMethod string) (*Conn, error) {
-	if client.conn == nil {
-		panic("cannot call Client after Put()")
+// callMethod sends the method name and waits for the server to accept the
+// call.
+func callMethod(bufrw *bufio.ReadWriter, serviceMethod string) error {
+	_, err := bufrw.WriteString(serviceMethod + "\n")
+	if err != nil {
+		return err
 	}
-	client.callLock.Lock()
-	conn, err := client.callWithLock(serviceMethod)
+	if err = bufrw.Flush(); err != nil {
+		return err
+	}
+	resp, err := bufrw.ReadString('\n')
 	if err != nil {
-		client.callLock.Unlock()
+		return err
+	}
+	if resp != "\n" {
+		resp := resp[:len(resp)-1]
+		if resp == ErrorAccessToMethodDenied.Error() {
+			return ErrorAccessToMethodDenied
+		}
+		if resp == ErrorRateLimitExceeded.Error() {
+			return ErrorRateLimitExceeded
+		}
+		return errors.New(resp)
 	}
-	return conn, err
+	return nil
 }
 
-func (client *Client) callWithLock(serviceMethod string) (*Conn, error) {
-	_, err := client.bufrw.WriteString(serviceMethod + "\n")
-	if err != nil {
+func selectJsonEndpoints(endpoints []endpointType) []endpointType {
+	var jsonEndpoints []endpointType
+	for _, endpoint := range endpoints {
+		if _, ok := endpoint.coderMaker.(*jsonCoder); ok {
+			jsonEndpoints = append(jsonEndpoints, endpoint)
+		}
+	}
+	return jsonEndpoints
+}
+
+func (client *Client) call(serviceMethod string) (*Conn, error) {
+	return client.callContext(context.Background(), serviceMethod)
+}
+
+func (client *Client) callContext(ctx context.Context, serviceMethod string) (
+	*Conn, error) {
+	if client.conn == nil {
+		panic("cannot call Client after Put()")
+	}
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if err = client.bufrw.Flush(); err != nil {
+	callLine := serviceMethod
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		if client.deadlines && serviceMethod != "" {
+			callLine += " " + timeoutParameter + "=" + timeout.String()
+		}
+	}
+	ctx, span := startClientSpan(ctx, serviceMethod)
+	if span != nil && client.tracing {
+		callLine += " " + traceParentParameter + "=" + span.TraceParent()
+	}
+	var conn *Conn
+	var err error
+	if client.mux != nil {
+		conn, err = client.callMultiplexed(ctx, callLine)
+	} else {
+		client.callLock.Lock()
+		conn, err = client.callWithLock(ctx, callLine)
+		if err != nil {
+			client.callLock.Unlock()
+		}
+	}
+	if err != nil {
+		span.End(err)
 		return nil, err
 	}
-	resp, err := client.bufrw.ReadString('\n')
+	conn.span = span
+	return conn, nil
+}
+
+func (client *Client) callMultiplexed(ctx context.Context, callLine string) (
+	*Conn, error) {
+	stream, err := client.mux.openStream()
 	if err != nil {
 		return nil, err
 	}
-	if resp != "\n" {
-		resp := resp[:len(resp)-1]
-		if resp == ErrorAccessToMethodDenied.Error() {
-			return nil, ErrorAccessToMethodDenied
-		}
-		return nil, errors.New(resp)
+	bufrw := bufio.NewReadWriter(bufio.NewReader(stream),
+		bufio.NewWriter(stream))
+	conn := &Conn{
+		Decoder:     client.makeCoder.MakeDecoder(bufrw),
+		Encoder:     client.makeCoder.MakeEncoder(bufrw),
+		parent:      client,
+		isEncrypted: client.isEncrypted,
+		ReadWriter:  bufrw,
+		stream:      stream,
+		ctx:         ctx,
+	}
+	conn.startWatcher()
+	if err := callMethod(bufrw, callLine); err != nil {
+		conn.stopWatcher()
+		stream.closeWrite()
+		return nil, conn.mapError(err)
 	}
+	return conn, nil
+}
+
+func (client *Client) callWithLock(ctx context.Context, callLine string) (
+	*Conn, error) {
 	conn := &Conn{
 		Decoder:     client.makeCoder.MakeDecoder(client.bufrw),
 		Encoder:     client.makeCoder.MakeEncoder(client.bufrw),
 		parent:      client,
 		isEncrypted: client.isEncrypted,
 		ReadWriter:  client.bufrw,
+		ctx:         ctx,
+	}
+	conn.startWatcher()
+	if err := callMethod(client.bufrw, callLine); err != nil {
+		conn.stopWatcher()
+		return nil, conn.mapError(err)
 	}
 	return conn, nil
 }
 
 func (client *Client) close() error {
-	client.bufrw.Flush()
+	if client.mux == nil {
+		client.bufrw.Flush()
+	}
 	if client.resource == nil {
 		clientMetricsMutex.Lock()
 		numOpenClientConnections--
@@ -301,7 +474,11 @@ func (client *Client) close() error {
 }
 
 func (client *Client) ping() error {
-	conn, err := client.call("")
+	return client.pingContext(context.Background())
+}
+
+func (client *Client) pingContext(ctx context.Context) error {
+	conn, err := client.callContext(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -311,12 +488,20 @@ func (client *Client) ping() error {
 
 func (client *Client) requestReply(serviceMethod string, request interface{},
 	reply interface{}) error {
-	conn, err := client.Call(serviceMethod)
+	return client.requestReplyContext(context.Background(), serviceMethod,
+		request, reply)
+}
+
+func (client *Client) requestReplyContext(ctx context.Context,
+	serviceMethod string, request interface{}, reply interface{}) error {
+	conn, err := client.callContext(ctx, serviceMethod)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	return conn.requestReply(request, reply)
+	err = conn.mapError(conn.requestReply(request, reply))
+	conn.callError = err
+	conn.Close()
+	return err
 }
 
 func (conn *Conn) requestReply(request interface{}, reply interface{}) error {
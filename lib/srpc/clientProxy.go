@@ -65,7 +65,8 @@ func (d *proxyDialer) Dial(network, address string) (net.Conn, error) {
 }
 
 func (d *proxyDialer) dialTCP(address string) (net.Conn, error) {
-	client, err := dialHTTP("tcp", d.proxyAddress, clientTlsConfig, d.dialer)
+	client, err := dialHTTP("tcp", d.proxyAddress, getClientTlsConfig(),
+		d.dialer)
 	if err != nil {
 		return nil, err
 	}
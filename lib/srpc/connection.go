@@ -3,8 +3,14 @@ package srpc
 import "io"
 
 func (conn *Conn) close() error {
+	conn.stopWatcher()
+	conn.span.End(conn.callError)
 	err := conn.Flush()
-	if conn.parent != nil {
+	if conn.stream != nil {
+		if e := conn.stream.closeWrite(); err == nil {
+			err = e
+		}
+	} else if conn.parent != nil {
 		conn.parent.callLock.Unlock()
 	}
 	return err
@@ -0,0 +1,126 @@
+package srpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
+)
+
+// Call parameters are described in the package documentation.
+
+const (
+	deadlinesHeader  = "X-Srpc-Deadlines"
+	deadlinesVersion = "1"
+
+	timeoutParameter     = "timeout"
+	traceParentParameter = "traceparent"
+)
+
+type callParametersType struct {
+	timeout     time.Duration     // Zero: no timeout.
+	traceParent trace.SpanContext // Zero value: no parent span.
+}
+
+// makeCallContext returns the context for a call on the server side.
+func makeCallContext(parent context.Context, timeout time.Duration) (
+	context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// parseCallLine splits the line sent by the client into the Service.Method
+// name and the call parameters. Unknown parameters are ignored so that they may
+// be added later, as are malformed trace parents.
+func parseCallLine(line string) (string, callParametersType, error) {
+	var params callParametersType
+	fields := strings.Fields(line)
+	if len(fields) < 1 {
+		return "", params, nil
+	}
+	for _, field := range fields[1:] {
+		splitField := strings.SplitN(field, "=", 2)
+		if len(splitField) != 2 {
+			continue
+		}
+		switch splitField[0] {
+		case timeoutParameter:
+			timeout, err := time.ParseDuration(splitField[1])
+			if err != nil {
+				return "", params, errors.New("bad timeout: " + splitField[1])
+			}
+			if timeout <= 0 {
+				return "", params, context.DeadlineExceeded
+			}
+			params.timeout = timeout
+		case traceParentParameter:
+			params.traceParent, _ = trace.ParseTraceParent(splitField[1])
+		}
+	}
+	return fields[0], params, nil
+}
+
+// abandon abandons a client-side call. A multiplexed stream is reset, otherwise
+// the connection is closed since it is in an unknown state.
+func (conn *Conn) abandon(err error) {
+	if conn.stream != nil {
+		conn.stream.reset(err)
+		return
+	}
+	conn.parent.conn.Close()
+	if conn.parent.resource != nil {
+		conn.parent.resource.ScheduleClose()
+	}
+}
+
+func (conn *Conn) context() context.Context {
+	if conn.ctx == nil {
+		return context.Background()
+	}
+	return conn.ctx
+}
+
+// mapError returns the context error if the context has expired, since err is
+// likely a consequence of the call being abandoned.
+func (conn *Conn) mapError(err error) error {
+	if err != nil && conn.ctx != nil {
+		if ctxErr := conn.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
+
+// startWatcher starts a goroutine which abandons the call if the context is
+// cancelled or expires before stopWatcher is called.
+func (conn *Conn) startWatcher() {
+	done := conn.ctx.Done()
+	if done == nil {
+		return
+	}
+	conn.watcherDone = make(chan struct{})
+	conn.watcherStop = make(chan struct{})
+	go func() {
+		defer close(conn.watcherDone)
+		select {
+		case <-conn.watcherStop:
+		case <-done:
+			conn.abandon(conn.ctx.Err())
+		}
+	}()
+}
+
+// stopWatcher stops the watcher goroutine (if running) and waits for it to
+// exit.
+func (conn *Conn) stopWatcher() {
+	if conn.watcherStop == nil {
+		return
+	}
+	close(conn.watcherStop)
+	<-conn.watcherDone
+	conn.watcherStop = nil
+}
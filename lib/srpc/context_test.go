@@ -0,0 +1,88 @@
+package srpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+var waitResults = make(chan error, 1)
+
+func (t *serverType) Wait(conn *Conn, request test.EchoRequest,
+	response *test.EchoResponse) error {
+	select {
+	case <-conn.Context().Done():
+		waitResults <- conn.Context().Err()
+		return conn.Context().Err()
+	case <-time.After(10 * time.Second):
+		waitResults <- nil
+		return nil
+	}
+}
+
+func testCallContext(t *testing.T, client *Client, deadlines bool,
+	expectedServerErrs ...error) {
+	client.deadlines = deadlines
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+	var response test.EchoResponse
+	err := client.RequestReplyContext(ctx, "Test.Wait", test.EchoRequest{},
+		&response)
+	// The server may respond with the error before the client gives up.
+	if err == nil || err.Error() != context.DeadlineExceeded.Error() {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+	select {
+	case err := <-waitResults:
+		for _, expectedErr := range expectedServerErrs {
+			if err == expectedErr {
+				return
+			}
+		}
+		t.Errorf("expected server error: %v, got: %v", expectedServerErrs, err)
+	case <-time.After(5 * time.Second):
+		t.Error("server call not cancelled")
+	}
+}
+
+func TestCallContextMultiplexed(t *testing.T) {
+	client := makeMultiplexedClientServer(&gobCoder{})
+	defer client.Close()
+	// The stream reset may arrive before the server deadline expires.
+	testCallContext(t, client, true, context.DeadlineExceeded,
+		context.Canceled)
+	// Without the deadline, the server is informed by the stream reset.
+	testCallContext(t, client, false, context.Canceled)
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallContextPlain(t *testing.T) {
+	client := makeClientServer(&gobCoder{})
+	defer client.Close()
+	testCallContext(t, client, true, context.DeadlineExceeded)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.CallContext(ctx, "Test.Wait"); err != context.Canceled {
+		t.Errorf("expected cancelled, got: %v", err)
+	}
+}
+
+func TestParseCallLine(t *testing.T) {
+	serviceMethod, params, err := parseCallLine(
+		"Test.Wait unknown=1 timeout=1.5s\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceMethod != "Test.Wait" ||
+		params.timeout != 1500*time.Millisecond {
+		t.Errorf("bad parse: %s %s", serviceMethod, params.timeout)
+	}
+	if _, _, err := parseCallLine("Test.Wait timeout=bad"); err == nil {
+		t.Error("bad timeout accepted")
+	}
+}
@@ -1,15 +1,32 @@
 package srpc
 
 import (
+	"context"
 	"crypto/tls"
+	"math/rand"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/connpool"
 )
 
-func newClientResource(network, address string) *ClientResource {
+func newClientResource(network, address string,
+	options ClientResourceOptions) *ClientResource {
+	if options.HealthCheckTimeout <= 0 {
+		options.HealthCheckTimeout = 10 * time.Second
+	}
+	if options.MinimumBackoff <= 0 {
+		options.MinimumBackoff = time.Second
+	}
+	if options.MaximumBackoff <= 0 {
+		options.MaximumBackoff = 5 * time.Minute
+	}
+	if options.MaximumBackoff < options.MinimumBackoff {
+		options.MaximumBackoff = options.MinimumBackoff
+	}
 	clientResource := &ClientResource{
 		network: network,
 		address: address,
+		options: options,
 	}
 	clientResource.privateClientResource.clientResource = clientResource
 	rp := connpool.GetResourcePool()
@@ -17,11 +34,114 @@ func newClientResource(network, address string) *ClientResource {
 	return clientResource
 }
 
+func (state CircuitBreakerState) string() string {
+	switch state {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// checkCircuitBreaker returns ErrorCircuitBreakerOpen if the circuit breaker
+// is open, or half-open with a trial in progress, and connecting should fail
+// fast. If the caller may make the trial connection, true is returned and
+// finishCircuitBreakerTrial must be called afterwards.
+func (cr *ClientResource) checkCircuitBreaker() (bool, error) {
+	cr.lock.Lock()
+	state := cr.getCircuitBreakerState(time.Now())
+	isTrial := state == CircuitBreakerHalfOpen && !cr.trialInProgress
+	if isTrial {
+		cr.trialInProgress = true
+	}
+	cr.lock.Unlock()
+	if state == CircuitBreakerClosed || isTrial {
+		return isTrial, nil
+	}
+	clientMetricsMutex.Lock()
+	numFailFastClientConnections++
+	clientMetricsMutex.Unlock()
+	return false, ErrorCircuitBreakerOpen
+}
+
+// checkHealth pings the connection if it is idle. If the Ping fails, the
+// connection is closed so that a subsequent Get* creates a new connection.
+func (cr *ClientResource) checkHealth() {
+	if !cr.resource.TryGetAllocated() {
+		return // In use (Put will reschedule), released or no free slot.
+	}
+	client := cr.client
+	ctx, cancel := context.WithTimeout(context.Background(),
+		cr.options.HealthCheckTimeout)
+	err := client.pingContext(ctx)
+	cancel()
+	clientMetricsMutex.Lock()
+	numClientHealthChecks++
+	if err != nil {
+		numFailedClientHealthChecks++
+	}
+	clientMetricsMutex.Unlock()
+	if err != nil {
+		client.close()
+		return
+	}
+	cr.scheduleHealthCheck()
+	cr.resource.Put()
+}
+
+// computeBackoff returns the backoff interval for the current number of
+// consecutive failures, with jitter so that retries are spread out.
+func (cr *ClientResource) computeBackoff() time.Duration {
+	backoff := cr.options.MaximumBackoff
+	shift := cr.consecutiveFailures - cr.options.FailureThreshold
+	if shift < 32 {
+		interval := cr.options.MinimumBackoff << shift
+		if interval > 0 && interval < backoff {
+			backoff = interval
+		}
+	}
+	return backoff - time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// finishCircuitBreakerTrial permits another trial connection, once the result
+// of the trial connection (if it was attempted) has been recorded.
+func (cr *ClientResource) finishCircuitBreakerTrial() {
+	cr.lock.Lock()
+	cr.trialInProgress = false
+	cr.lock.Unlock()
+}
+
+// getCircuitBreakerState returns the state of the circuit breaker. The lock
+// must be held.
+func (cr *ClientResource) getCircuitBreakerState(
+	now time.Time) CircuitBreakerState {
+	if cr.options.FailureThreshold < 1 ||
+		cr.consecutiveFailures < cr.options.FailureThreshold {
+		return CircuitBreakerClosed
+	}
+	if now.Before(cr.retryTime) {
+		return CircuitBreakerOpen
+	}
+	return CircuitBreakerHalfOpen
+}
+
 func (cr *ClientResource) getHTTP(tlsConfig *tls.Config,
 	cancelChannel <-chan struct{}, dialer connpool.Dialer) (*Client, error) {
+	isTrial, err := cr.checkCircuitBreaker()
+	if err != nil {
+		return nil, err
+	}
 	cr.privateClientResource.tlsConfig = tlsConfig
 	cr.privateClientResource.dialer = dialer
-	if err := cr.resource.Get(cancelChannel); err != nil {
+	err = cr.resource.Get(cancelChannel)
+	if isTrial {
+		cr.finishCircuitBreakerTrial()
+	}
+	if err != nil {
 		return nil, err
 	}
 	cr.inUse = true
@@ -31,7 +151,67 @@ func (cr *ClientResource) getHTTP(tlsConfig *tls.Config,
 	return cr.client, nil
 }
 
+func (cr *ClientResource) getStatus() ClientResourceStatus {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	return ClientResourceStatus{
+		CircuitBreakerState: cr.getCircuitBreakerState(time.Now()),
+		ConsecutiveFailures: cr.consecutiveFailures,
+		LastError:           cr.lastError,
+		RetryTime:           cr.retryTime,
+	}
+}
+
+// recordConnectResult updates the circuit breaker with the result of
+// connecting.
+func (cr *ClientResource) recordConnectResult(err error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	if err == nil {
+		cr.consecutiveFailures = 0
+		cr.lastError = nil
+		cr.retryTime = time.Time{}
+		return
+	}
+	cr.consecutiveFailures++
+	cr.lastError = err
+	if cr.options.FailureThreshold < 1 ||
+		cr.consecutiveFailures < cr.options.FailureThreshold {
+		return
+	}
+	if cr.consecutiveFailures == cr.options.FailureThreshold {
+		clientMetricsMutex.Lock()
+		numClientCircuitBreakerTrips++
+		clientMetricsMutex.Unlock()
+	}
+	cr.retryTime = time.Now().Add(cr.computeBackoff())
+}
+
+func (cr *ClientResource) scheduleHealthCheck() {
+	if cr.options.HealthCheckInterval <= 0 {
+		return
+	}
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	if cr.healthTimer == nil {
+		cr.healthTimer = time.AfterFunc(cr.options.HealthCheckInterval,
+			cr.checkHealth)
+	} else {
+		cr.healthTimer.Reset(cr.options.HealthCheckInterval)
+	}
+}
+
+func (cr *ClientResource) stopHealthCheck() {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	if cr.healthTimer != nil {
+		cr.healthTimer.Stop()
+	}
+}
+
 func (client *Client) put() {
+	// Schedule before Put, which may release the resource and stop the timer.
+	client.resource.scheduleHealthCheck()
 	client.resource.resource.Put()
 	if client.resource.inUse {
 		clientMetricsMutex.Lock()
@@ -44,6 +224,7 @@ func (client *Client) put() {
 func (pcr *privateClientResource) Allocate() error {
 	cr := pcr.clientResource
 	client, err := dialHTTP(cr.network, cr.address, pcr.tlsConfig, pcr.dialer)
+	cr.recordConnectResult(err)
 	if err != nil {
 		return err
 	}
@@ -54,6 +235,7 @@ func (pcr *privateClientResource) Allocate() error {
 
 func (pcr *privateClientResource) Release() error {
 	cr := pcr.clientResource
+	cr.stopHealthCheck()
 	err := cr.client.conn.Close()
 	cr.client = nil
 	return err
@@ -0,0 +1,160 @@
+package srpc
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func getHealthCheckCounts() (uint64, uint64) {
+	clientMetricsMutex.Lock()
+	defer clientMetricsMutex.Unlock()
+	return numClientHealthChecks, numFailedClientHealthChecks
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	cr := NewClientResourceWithOptions("tcp", address,
+		ClientResourceOptions{
+			FailureThreshold: 2,
+			MaximumBackoff:   time.Hour,
+			MinimumBackoff:   time.Hour,
+		})
+	for count := 0; count < 2; count++ {
+		if _, err := cr.GetHTTP(nil, time.Second); err == nil {
+			t.Fatal("no error connecting to closed port")
+		} else if err == ErrorCircuitBreakerOpen {
+			t.Fatalf("circuit breaker opened after %d failures", count)
+		}
+	}
+	status := cr.GetStatus()
+	if status.CircuitBreakerState != CircuitBreakerOpen {
+		t.Fatalf("circuit breaker: %s", status.CircuitBreakerState)
+	}
+	if status.ConsecutiveFailures != 2 || status.LastError == nil {
+		t.Errorf("bad status: %+v", status)
+	}
+	if _, err := cr.GetHTTP(nil, time.Second); err != ErrorCircuitBreakerOpen {
+		t.Fatalf("did not fail fast: %v", err)
+	}
+	cr.lock.Lock()
+	cr.retryTime = time.Now()
+	cr.lock.Unlock()
+	if state := cr.GetStatus().CircuitBreakerState; state !=
+		CircuitBreakerHalfOpen {
+		t.Fatalf("circuit breaker: %s", state)
+	}
+	// Only one trial may be in progress.
+	if isTrial, err := cr.checkCircuitBreaker(); err != nil || !isTrial {
+		t.Fatalf("trial not permitted: %v", err)
+	}
+	if _, err := cr.GetHTTP(nil, time.Second); err != ErrorCircuitBreakerOpen {
+		t.Fatalf("second trial did not fail fast: %v", err)
+	}
+	cr.finishCircuitBreakerTrial()
+	if _, err := cr.GetHTTP(nil, time.Second); err == ErrorCircuitBreakerOpen {
+		t.Fatal("trial connection not permitted")
+	}
+	status = cr.GetStatus()
+	if status.CircuitBreakerState != CircuitBreakerOpen ||
+		status.ConsecutiveFailures != 3 {
+		t.Fatalf("bad status after failed trial: %+v", status)
+	}
+	addr, err := makeListener(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr.lock.Lock()
+	cr.address = addr.String()
+	cr.retryTime = time.Now()
+	cr.lock.Unlock()
+	client, err := cr.GetHTTP(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Put()
+	cr.ScheduleClose()
+	status = cr.GetStatus()
+	if status.CircuitBreakerState != CircuitBreakerClosed ||
+		status.ConsecutiveFailures != 0 || status.LastError != nil {
+		t.Errorf("bad status after successful trial: %+v", status)
+	}
+}
+
+func TestComputeBackoff(t *testing.T) {
+	cr := NewClientResourceWithOptions("tcp", "localhost:1",
+		ClientResourceOptions{
+			FailureThreshold: 1,
+			MaximumBackoff:   8 * time.Second,
+			MinimumBackoff:   time.Second,
+		})
+	tests := []struct {
+		failures uint
+		maximum  time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 8 * time.Second},
+		{100, 8 * time.Second},
+	}
+	for _, test := range tests {
+		cr.consecutiveFailures = test.failures
+		for count := 0; count < 100; count++ {
+			backoff := cr.computeBackoff()
+			if backoff < test.maximum/2 || backoff > test.maximum {
+				t.Fatalf("failures: %d, backoff: %s not in [%s, %s]",
+					test.failures, backoff, test.maximum/2, test.maximum)
+			}
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	addr, err := makeListener(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := NewClientResourceWithOptions(addr.Network(), addr.String(),
+		ClientResourceOptions{
+			HealthCheckInterval: 10 * time.Millisecond,
+			HealthCheckTimeout:  time.Second,
+		})
+	defer cr.ScheduleClose()
+	numChecks, numFailed := getHealthCheckCounts()
+	client, err := cr.GetHTTP(nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Put()
+	for count := 0; ; count++ {
+		if count >= 100 {
+			t.Fatal("no health checks")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if checks, _ := getHealthCheckCounts(); checks > numChecks+1 {
+			break
+		}
+	}
+	if _, failed := getHealthCheckCounts(); failed != numFailed {
+		t.Fatal("health check failed")
+	}
+	client.conn.Close()
+	for count := 0; ; count++ {
+		if count >= 100 {
+			t.Fatal("health check did not fail")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if _, failed := getHealthCheckCounts(); failed > numFailed {
+			break
+		}
+	}
+	if cr.resource.TryGetAllocated() {
+		t.Error("connection not closed after failed health check")
+	}
+}
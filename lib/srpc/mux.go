@@ -0,0 +1,428 @@
+package srpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+// The multiplexing protocol is described in the package documentation.
+
+const (
+	multiplexHeader  = "X-Srpc-Multiplex"
+	multiplexVersion = "1"
+
+	muxFrameHeaderSize   = 9
+	muxInitialWindowSize = 256 << 10
+	muxMaxFrameSize      = 32 << 10
+)
+
+const (
+	muxFrameData         = iota // Payload: data.
+	muxFrameClose               // No more data will be sent on the stream.
+	muxFrameReset               // Abandon the stream.
+	muxFrameWindowUpdate        // Payload: window increment (4 bytes).
+)
+
+var (
+	errorMuxProtocol    = errors.New("multiplex protocol error")
+	errorStreamClosed   = errors.New("stream closed")
+	errorStreamReset    = errors.New("stream reset by peer")
+	errorStreamsTooMany = errors.New("too many streams")
+)
+
+type muxConnType struct {
+	closer    io.Closer
+	done      chan struct{}               // Closed when the connection fails.
+	newStream func(stream *muxStreamType) // nil: client side.
+	reader    *bufio.Reader
+	writeLock sync.Mutex
+	writer    *bufio.Writer
+	// The following are protected by mutex.
+	mutex        sync.Mutex
+	err          error
+	lastStreamId uint32
+	streams      map[uint32]*muxStreamType // Key: stream ID.
+}
+
+type muxStreamType struct {
+	failed chan struct{} // Closed when err is set.
+	id     uint32
+	mux    *muxConnType
+	// The following are protected by mutex.
+	mutex         sync.Mutex
+	cond          *sync.Cond // Signalled when any of the following changes.
+	err           error      // Reset or connection error.
+	localClosed   bool
+	readBuffer    bytes.Buffer
+	receiveWindow uint32
+	remoteClosed  bool
+	resetSent     bool
+	sendWindow    uint32
+	unacked       uint32 // Bytes read but not yet granted to the sender.
+}
+
+func newMuxConn(rw *bufio.ReadWriter, closer io.Closer,
+	newStream func(stream *muxStreamType)) *muxConnType {
+	mux := &muxConnType{
+		closer:    closer,
+		done:      make(chan struct{}),
+		newStream: newStream,
+		reader:    rw.Reader,
+		writer:    rw.Writer,
+		streams:   make(map[uint32]*muxStreamType),
+	}
+	go mux.readLoop()
+	return mux
+}
+
+func (mux *muxConnType) close() error {
+	mux.fail(io.EOF)
+	return nil
+}
+
+// fail marks the connection and all its streams as failed and closes the
+// underlying connection. Only the first call has any effect.
+func (mux *muxConnType) fail(err error) {
+	mux.mutex.Lock()
+	if mux.err != nil {
+		mux.mutex.Unlock()
+		return
+	}
+	mux.err = err
+	streams := mux.streams
+	mux.streams = make(map[uint32]*muxStreamType)
+	mux.mutex.Unlock()
+	mux.closer.Close()
+	close(mux.done)
+	for _, stream := range streams {
+		stream.mutex.Lock()
+		stream.setError(err)
+		stream.cond.Broadcast()
+		stream.mutex.Unlock()
+	}
+}
+
+// getStream returns the stream with the specified ID. On the server side, a
+// new stream is created if the ID has not been seen before.
+func (mux *muxConnType) getStream(id uint32) (*muxStreamType, bool) {
+	mux.mutex.Lock()
+	defer mux.mutex.Unlock()
+	if stream := mux.streams[id]; stream != nil {
+		return stream, false
+	}
+	if mux.newStream == nil || id <= mux.lastStreamId {
+		return nil, false
+	}
+	mux.lastStreamId = id
+	stream := mux.makeStream(id)
+	mux.streams[id] = stream
+	return stream, true
+}
+
+func (mux *muxConnType) makeStream(id uint32) *muxStreamType {
+	stream := &muxStreamType{
+		failed:        make(chan struct{}),
+		id:            id,
+		mux:           mux,
+		receiveWindow: muxInitialWindowSize,
+		sendWindow:    muxInitialWindowSize,
+	}
+	stream.cond = sync.NewCond(&stream.mutex)
+	return stream
+}
+
+// openStream opens a new stream from the client side by sending an empty data
+// frame. The write lock is held while allocating the stream ID so that stream
+// IDs are sent in increasing order.
+func (mux *muxConnType) openStream() (*muxStreamType, error) {
+	mux.writeLock.Lock()
+	mux.mutex.Lock()
+	if mux.err != nil {
+		err := mux.err
+		mux.mutex.Unlock()
+		mux.writeLock.Unlock()
+		if err == io.EOF {
+			return nil, errorStreamClosed
+		}
+		return nil, err
+	}
+	if mux.lastStreamId == ^uint32(0) {
+		mux.mutex.Unlock()
+		mux.writeLock.Unlock()
+		return nil, errorStreamsTooMany
+	}
+	mux.lastStreamId++
+	stream := mux.makeStream(mux.lastStreamId)
+	mux.streams[stream.id] = stream
+	mux.mutex.Unlock()
+	err := mux.writeFrameWithLock(muxFrameData, stream.id, nil)
+	mux.writeLock.Unlock()
+	if err != nil {
+		mux.fail(err)
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (mux *muxConnType) processFrame(frameType byte, id uint32,
+	payload []byte) error {
+	stream, isNew := mux.getStream(id)
+	if stream == nil {
+		return nil // Stream already finished: ignore.
+	}
+	if isNew {
+		go mux.newStream(stream)
+	}
+	switch frameType {
+	case muxFrameData:
+		return stream.receive(payload)
+	case muxFrameClose:
+		stream.remoteClose(nil)
+	case muxFrameReset:
+		stream.remoteClose(errorStreamReset)
+	case muxFrameWindowUpdate:
+		if len(payload) != 4 {
+			return errorMuxProtocol
+		}
+		stream.grant(binary.BigEndian.Uint32(payload))
+	default:
+		return errorMuxProtocol
+	}
+	return nil
+}
+
+func (mux *muxConnType) readLoop() {
+	header := make([]byte, muxFrameHeaderSize)
+	for {
+		if _, err := io.ReadFull(mux.reader, header); err != nil {
+			mux.fail(err)
+			return
+		}
+		length := binary.BigEndian.Uint32(header[5:])
+		if length > muxMaxFrameSize {
+			mux.fail(errorMuxProtocol)
+			return
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(mux.reader, payload); err != nil {
+			mux.fail(err)
+			return
+		}
+		err := mux.processFrame(header[0], binary.BigEndian.Uint32(header[1:]),
+			payload)
+		if err != nil {
+			mux.fail(err)
+			return
+		}
+	}
+}
+
+func (mux *muxConnType) removeStream(id uint32) {
+	mux.mutex.Lock()
+	delete(mux.streams, id)
+	mux.mutex.Unlock()
+}
+
+func (mux *muxConnType) writeFrame(frameType byte, id uint32,
+	payload []byte) error {
+	mux.writeLock.Lock()
+	err := mux.writeFrameWithLock(frameType, id, payload)
+	mux.writeLock.Unlock()
+	if err != nil {
+		mux.fail(err)
+	}
+	return err
+}
+
+// writeFrameWithLock writes a frame. The write lock must be held.
+func (mux *muxConnType) writeFrameWithLock(frameType byte, id uint32,
+	payload []byte) error {
+	var header [muxFrameHeaderSize]byte
+	header[0] = frameType
+	binary.BigEndian.PutUint32(header[1:], id)
+	binary.BigEndian.PutUint32(header[5:], uint32(len(payload)))
+	if _, err := mux.writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := mux.writer.Write(payload); err != nil {
+		return err
+	}
+	return mux.writer.Flush()
+}
+
+// closeWrite closes the local side of the stream. If data which have not been
+// read are pending, the stream is reset so that the peer stops sending.
+func (stream *muxStreamType) closeWrite() error {
+	stream.mutex.Lock()
+	if stream.localClosed {
+		stream.mutex.Unlock()
+		return nil
+	}
+	stream.localClosed = true
+	frameType := byte(muxFrameClose)
+	if !stream.remoteClosed && stream.readBuffer.Len() > 0 {
+		frameType = muxFrameReset
+		stream.resetSent = true
+	}
+	stream.readBuffer.Reset()
+	remoteClosed := stream.remoteClosed
+	err := stream.err
+	stream.cond.Broadcast()
+	stream.mutex.Unlock()
+	if remoteClosed {
+		stream.mux.removeStream(stream.id)
+	}
+	if err != nil && err != errorStreamReset {
+		return nil // The connection has failed.
+	}
+	return stream.mux.writeFrame(frameType, stream.id, nil)
+}
+
+// grant increases the send window.
+func (stream *muxStreamType) grant(increment uint32) {
+	stream.mutex.Lock()
+	stream.sendWindow += increment
+	stream.cond.Broadcast()
+	stream.mutex.Unlock()
+}
+
+func (stream *muxStreamType) Read(p []byte) (int, error) {
+	stream.mutex.Lock()
+	for stream.readBuffer.Len() < 1 {
+		if stream.remoteClosed {
+			stream.mutex.Unlock()
+			return 0, io.EOF
+		}
+		if stream.err != nil {
+			err := stream.err
+			stream.mutex.Unlock()
+			return 0, err
+		}
+		if stream.localClosed {
+			stream.mutex.Unlock()
+			return 0, errorStreamClosed
+		}
+		stream.cond.Wait()
+	}
+	nRead, _ := stream.readBuffer.Read(p)
+	stream.unacked += uint32(nRead)
+	var increment uint32
+	if stream.unacked >= muxInitialWindowSize/4 && !stream.remoteClosed {
+		increment = stream.unacked
+		stream.unacked = 0
+		stream.receiveWindow += increment
+	}
+	stream.mutex.Unlock()
+	if increment > 0 {
+		var payload [4]byte
+		binary.BigEndian.PutUint32(payload[:], increment)
+		stream.mux.writeFrame(muxFrameWindowUpdate, stream.id, payload[:])
+	}
+	return nRead, nil
+}
+
+// receive appends data from the peer to the read buffer.
+func (stream *muxStreamType) receive(data []byte) error {
+	stream.mutex.Lock()
+	if stream.localClosed {
+		// Nobody is reading: tell the peer to stop sending.
+		sendReset := !stream.resetSent && !stream.remoteClosed
+		stream.resetSent = true
+		stream.mutex.Unlock()
+		if sendReset {
+			// Do not block the read loop.
+			go stream.mux.writeFrame(muxFrameReset, stream.id, nil)
+		}
+		return nil
+	}
+	if uint32(len(data)) > stream.receiveWindow || stream.remoteClosed {
+		stream.mutex.Unlock()
+		return errorMuxProtocol
+	}
+	stream.receiveWindow -= uint32(len(data))
+	stream.readBuffer.Write(data)
+	stream.cond.Broadcast()
+	stream.mutex.Unlock()
+	return nil
+}
+
+// remoteClose marks the remote side of the stream as closed. If err is not
+// nil, subsequent writes will fail with err.
+func (stream *muxStreamType) remoteClose(err error) {
+	stream.mutex.Lock()
+	stream.remoteClosed = true
+	if err != nil {
+		stream.setError(err)
+	}
+	localClosed := stream.localClosed
+	stream.cond.Broadcast()
+	stream.mutex.Unlock()
+	if localClosed {
+		stream.mux.removeStream(stream.id)
+	}
+}
+
+// reset abandons the stream: the local side is closed, subsequent reads and
+// writes fail with err and the peer is sent a reset frame (unless the stream
+// has already finished).
+func (stream *muxStreamType) reset(err error) {
+	stream.mutex.Lock()
+	stream.setError(err)
+	stream.localClosed = true
+	stream.readBuffer.Reset()
+	sendReset := !stream.resetSent && !stream.remoteClosed
+	stream.resetSent = true
+	stream.cond.Broadcast()
+	stream.mutex.Unlock()
+	stream.mux.removeStream(stream.id)
+	if sendReset {
+		stream.mux.writeFrame(muxFrameReset, stream.id, nil)
+	}
+}
+
+// setError records the first error for the stream. The mutex must be held.
+func (stream *muxStreamType) setError(err error) {
+	if stream.err == nil {
+		stream.err = err
+		close(stream.failed)
+	}
+}
+
+func (stream *muxStreamType) Write(p []byte) (int, error) {
+	var nWritten int
+	for len(p) > 0 {
+		stream.mutex.Lock()
+		for stream.sendWindow < 1 && stream.err == nil &&
+			!stream.localClosed {
+			stream.cond.Wait()
+		}
+		if err := stream.err; err != nil {
+			stream.mutex.Unlock()
+			return nWritten, err
+		}
+		if stream.localClosed {
+			stream.mutex.Unlock()
+			return nWritten, errorStreamClosed
+		}
+		length := uint32(len(p))
+		if length > stream.sendWindow {
+			length = stream.sendWindow
+		}
+		if length > muxMaxFrameSize {
+			length = muxMaxFrameSize
+		}
+		stream.sendWindow -= length
+		stream.mutex.Unlock()
+		err := stream.mux.writeFrame(muxFrameData, stream.id, p[:length])
+		if err != nil {
+			return nWritten, err
+		}
+		nWritten += int(length)
+		p = p[length:]
+	}
+	return nWritten, nil
+}
@@ -0,0 +1,136 @@
+package srpc
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+func makeMultiplexedClientServer(makeCoder coderMaker) *Client {
+	serverPipe, clientPipe := net.Pipe()
+	go handleMultiplexedConnection(&Conn{
+		ReadWriter: bufio.NewReadWriter(bufio.NewReader(serverPipe),
+			bufio.NewWriter(serverPipe)),
+	},
+		serverPipe, makeCoder)
+	client := newClient(clientPipe, clientPipe, false, makeCoder)
+	client.mux = newMuxConn(client.bufrw, clientPipe, nil)
+	return client
+}
+
+func testMultiplexedConcurrentCalls(t *testing.T, makeCoder coderMaker) {
+	client := makeMultiplexedClientServer(makeCoder)
+	defer client.Close()
+	// Hold a call open while making others.
+	blockedConn, err := client.Call("Test.Plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errors := make(chan error, 20)
+	for index := 0; index < cap(errors); index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			request := fmt.Sprintf("test%d", index)
+			var response test.EchoResponse
+			err := client.RequestReply("Test.RequestReply",
+				test.EchoRequest{Request: request}, &response)
+			if err == nil && response.Response != request {
+				err = fmt.Errorf("Response: %s != %s",
+					response.Response, request)
+			}
+			errors <- err
+		}(index)
+	}
+	wg.Wait()
+	close(errors)
+	for err := range errors {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = blockedConn.Encode(test.EchoRequest{Request: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blockedConn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var response test.EchoResponse
+	if err := blockedConn.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "plain" {
+		t.Errorf("Response: %s != plain\n", response.Response)
+	}
+	if err := blockedConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call("Test.None"); err == nil {
+		t.Fatal("no failure when calling unknown method")
+	} else if !strings.Contains(err.Error(), "unknown method") {
+		t.Fatal(err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testMultiplexedLargeMessage(t *testing.T, makeCoder coderMaker) {
+	client := makeMultiplexedClientServer(makeCoder)
+	defer client.Close()
+	request := strings.Repeat("x", muxInitialWindowSize*3)
+	var response test.EchoResponse
+	err := client.RequestReply("Test.RequestReply",
+		test.EchoRequest{Request: request}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != request {
+		t.Fatal("large response does not match request")
+	}
+}
+
+func TestGobMultiplexedConcurrentCalls(t *testing.T) {
+	testMultiplexedConcurrentCalls(t, &gobCoder{})
+}
+
+func TestJsonMultiplexedConcurrentCalls(t *testing.T) {
+	testMultiplexedConcurrentCalls(t, &jsonCoder{})
+}
+
+func TestGobMultiplexedLargeMessage(t *testing.T) {
+	testMultiplexedLargeMessage(t, &gobCoder{})
+}
+
+func TestJsonMultiplexedLargeMessage(t *testing.T) {
+	testMultiplexedLargeMessage(t, &jsonCoder{})
+}
+
+func TestMultiplexedListener(t *testing.T) {
+	*srpcMultiplex = true
+	defer func() { *srpcMultiplex = false }()
+	client, err := makeListenerAndConnect(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if !client.IsMultiplexed() {
+		t.Fatal("connection not multiplexed")
+	}
+	var response test.EchoResponse
+	err = client.RequestReply("Test.RequestReply",
+		test.EchoRequest{Request: "listener"}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "listener" {
+		t.Errorf("Response: %s != listener\n", response.Response)
+	}
+}
@@ -0,0 +1,175 @@
+package srpc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type rateLimitType struct {
+	burst           float64
+	methodPattern   string
+	rate            float64 // Tokens per second.
+	usernamePattern string
+	mutex           sync.Mutex                  // Protects the following.
+	buckets         map[string]*tokenBucketType // Key: username, method.
+}
+
+type tokenBucketType struct {
+	lastUpdate time.Time
+	tokens     float64
+}
+
+func getCallAuditor(serviceMethod string) func(record *CallRecord) {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+	if callAuditor == nil {
+		return nil
+	}
+	for _, pattern := range auditMethodPatterns {
+		if matched, _ := filepath.Match(pattern, serviceMethod); matched {
+			return callAuditor
+		}
+	}
+	return nil
+}
+
+func getRateLimits() *RateLimits {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+	return rateLimits
+}
+
+func registerCallAuditor(auditor func(record *CallRecord),
+	methodPatterns []string) {
+	policyLock.Lock()
+	defer policyLock.Unlock()
+	callAuditor = auditor
+	auditMethodPatterns = methodPatterns
+}
+
+func registerRateLimits(limits *RateLimits) {
+	policyLock.Lock()
+	defer policyLock.Unlock()
+	rateLimits = limits
+}
+
+func (record *CallRecord) string() string {
+	groups := make([]string, 0, len(record.GroupList))
+	for group := range record.GroupList {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	username := record.Username
+	if username == "" {
+		username = "-"
+	}
+	result := fmt.Sprintf("method=%s user=%s groups=%s remote=%s duration=%s",
+		record.ServiceMethod, username, strings.Join(groups, ","),
+		record.RemoteAddr, record.Duration)
+	if record.Error != nil {
+		result += fmt.Sprintf(" error=%q", record.Error.Error())
+	}
+	return result
+}
+
+func (limits *RateLimits) addLimit(usernamePattern, methodPattern string,
+	rate float64, burst uint) error {
+	if _, err := filepath.Match(usernamePattern, ""); err != nil {
+		return fmt.Errorf("bad username pattern: %s: %s", usernamePattern, err)
+	}
+	if _, err := filepath.Match(methodPattern, ""); err != nil {
+		return fmt.Errorf("bad method pattern: %s: %s", methodPattern, err)
+	}
+	if rate < 0 {
+		return errors.New("negative rate")
+	}
+	if burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	limits.limits = append(limits.limits, &rateLimitType{
+		burst:           float64(burst),
+		methodPattern:   methodPattern,
+		rate:            rate,
+		usernamePattern: usernamePattern,
+		buckets:         make(map[string]*tokenBucketType),
+	})
+	return nil
+}
+
+func (limits *RateLimits) check(username, serviceMethod string,
+	now time.Time) error {
+	if limits == nil {
+		return nil
+	}
+	for _, limit := range limits.limits {
+		if limit.matches(username, serviceMethod) {
+			return limit.take(username+"\x00"+serviceMethod, now)
+		}
+	}
+	return nil
+}
+
+func (limit *rateLimitType) matches(username, serviceMethod string) bool {
+	if matched, _ := filepath.Match(limit.usernamePattern, username); !matched {
+		return false
+	}
+	matched, _ := filepath.Match(limit.methodPattern, serviceMethod)
+	return matched
+}
+
+// take removes a token from the bucket, after refilling it for the time elapsed
+// since the last update.
+func (limit *rateLimitType) take(key string, now time.Time) error {
+	limit.mutex.Lock()
+	defer limit.mutex.Unlock()
+	bucket := limit.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucketType{lastUpdate: now, tokens: limit.burst}
+		limit.buckets[key] = bucket
+	} else if elapsed := now.Sub(bucket.lastUpdate); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * limit.rate
+		if bucket.tokens > limit.burst {
+			bucket.tokens = limit.burst
+		}
+		bucket.lastUpdate = now
+	}
+	if bucket.tokens < 1 {
+		return ErrorRateLimitExceeded
+	}
+	bucket.tokens--
+	return nil
+}
+
+// auditCall calls the registered auditor (if any) for the method.
+func (conn *Conn) auditCall(serviceMethod string, startTime time.Time,
+	err error) {
+	auditor := getCallAuditor(serviceMethod)
+	if auditor == nil {
+		return
+	}
+	auditor(&CallRecord{
+		Duration:      time.Since(startTime),
+		Error:         err,
+		GroupList:     conn.groupList,
+		RemoteAddr:    conn.remoteAddr,
+		ServiceMethod: serviceMethod,
+		StartTime:     startTime,
+		Username:      conn.username,
+	})
+}
+
+// checkRateLimit returns ErrorRateLimitExceeded if the user has exceeded the
+// rate limit for the method.
+func (conn *Conn) checkRateLimit(serviceMethod string) error {
+	err := getRateLimits().check(conn.username, serviceMethod, time.Now())
+	if err != nil {
+		atomic.AddUint64(&numRateLimitedCalls, 1)
+	}
+	return err
+}
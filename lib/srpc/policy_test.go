@@ -0,0 +1,127 @@
+package srpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+func TestCallPolicies(t *testing.T) {
+	records := make(chan *CallRecord, 10)
+	registerCallAuditor(func(record *CallRecord) { records <- record },
+		[]string{"Test.Request*"})
+	limits := &RateLimits{}
+	if err := limits.AddLimit("*", "Test.RequestReply", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	registerRateLimits(limits)
+	defer func() {
+		registerCallAuditor(nil, nil)
+		registerRateLimits(nil)
+	}()
+	client := makeClientServer(&gobCoder{})
+	defer client.Close()
+	var response test.EchoResponse
+	err := client.RequestReply("Test.RequestReply",
+		test.EchoRequest{Request: "test"}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RequestReply("Test.RequestReply",
+		test.EchoRequest{Request: "test"}, &response)
+	if err != ErrorRateLimitExceeded {
+		t.Errorf("expected rate limit exceeded, got: %v", err)
+	}
+	if err := client.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expectedErr := range []error{nil, ErrorRateLimitExceeded} {
+		select {
+		case record := <-records:
+			if record.ServiceMethod != "Test.RequestReply" {
+				t.Errorf("unexpected method: %s", record.ServiceMethod)
+			}
+			if record.Error != expectedErr {
+				t.Errorf("expected error: %v, got: %v",
+					expectedErr, record.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("call not audited")
+		}
+	}
+	select {
+	case record := <-records:
+		t.Errorf("unexpected record: %s", record)
+	default:
+	}
+}
+
+func (t *serverType) RequestFailure(conn *Conn, request test.EchoRequest,
+	response *test.EchoResponse) error {
+	return errors.New(request.Request)
+}
+
+func TestCallAuditFailure(t *testing.T) {
+	records := make(chan *CallRecord, 10)
+	registerCallAuditor(func(record *CallRecord) { records <- record },
+		[]string{"Test.RequestFailure"})
+	defer registerCallAuditor(nil, nil)
+	client := makeClientServer(&gobCoder{})
+	defer client.Close()
+	var response test.EchoResponse
+	err := client.RequestReply("Test.RequestFailure",
+		test.EchoRequest{Request: "handler failed"}, &response)
+	if err == nil || err.Error() != "handler failed" {
+		t.Fatalf("expected handler error, got: %v", err)
+	}
+	select {
+	case record := <-records:
+		if record.Error == nil {
+			t.Fatal("failed call audited as success")
+		}
+		if record.Error.Error() != "handler failed" {
+			t.Errorf("unexpected error: %s", record.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call not audited")
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	limits := &RateLimits{}
+	if err := limits.AddLimit("alice", "Test.*", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := limits.AddLimit("*", "Test.Plain", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := limits.AddLimit("[", "Test.Plain", 0, 1); err == nil {
+		t.Error("bad pattern accepted")
+	}
+	now := time.Now()
+	for index, expectedErr := range []error{nil, nil, ErrorRateLimitExceeded} {
+		err := limits.check("alice", "Test.Plain", now)
+		if err != expectedErr {
+			t.Errorf("call %d: expected: %v, got: %v", index, expectedErr, err)
+		}
+	}
+	// Buckets are separate for each method.
+	if err := limits.check("alice", "Test.Other", now); err != nil {
+		t.Error(err)
+	}
+	err := limits.check("alice", "Test.Plain", now.Add(time.Second))
+	if err != nil {
+		t.Errorf("bucket not refilled: %s", err)
+	}
+	if err := limits.check("bob", "Test.Plain", now); err != nil {
+		t.Error(err)
+	}
+	if limits.check("bob", "Test.Plain", now.Add(time.Hour)) == nil {
+		t.Error("zero rate refilled bucket")
+	}
+	if err := limits.check("bob", "Test.Other", now); err != nil {
+		t.Errorf("unlimited method limited: %s", err)
+	}
+}
@@ -0,0 +1,204 @@
+package srpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+var (
+	typeOfJsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeOfTime          = reflect.TypeOf(time.Time{})
+)
+
+// addProperties adds the JSON properties for the fields of the struct type.
+// Fields of embedded structs are added after the other fields, so that
+// shallower fields take precedence, as with the encoding/json package.
+func addProperties(properties map[string]*TypeSchema, typ reflect.Type,
+	parents map[reflect.Type]struct{}) {
+	var embeddedTypes []reflect.Type
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		splitTag := strings.Split(tag, ",")
+		name := splitTag[0]
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embeddedTypes = append(embeddedTypes, fieldType)
+				continue
+			}
+		}
+		if field.PkgPath != "" { // Field must be exported.
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := makeTypeSchema(fieldType, parents)
+		if schema == nil {
+			continue
+		}
+		for _, option := range splitTag[1:] {
+			if option == "string" {
+				schema = &TypeSchema{Type: "string"}
+			}
+		}
+		if _, ok := properties[name]; !ok {
+			properties[name] = schema
+		}
+	}
+	for _, embeddedType := range embeddedTypes {
+		if _, ok := parents[embeddedType]; ok {
+			continue
+		}
+		parents[embeddedType] = struct{}{}
+		addProperties(properties, embeddedType, parents)
+		delete(parents, embeddedType)
+	}
+}
+
+func describeMethods() []MethodDescription {
+	var descriptions []MethodDescription
+	for receiverName, receiver := range receivers {
+		for methodName, method := range receiver.methods {
+			descriptions = append(descriptions,
+				method.describe(receiverName+"."+methodName))
+		}
+	}
+	sort.Slice(descriptions, func(left, right int) bool {
+		return descriptions[left].Name < descriptions[right].Name
+	})
+	return descriptions
+}
+
+func describeMethodsHttpHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	libjson.WriteWithIndent(w, "    ", describeMethods())
+}
+
+func getMethodDescriptions(address string, timeout time.Duration) (
+	[]MethodDescription, error) {
+	httpClient := &http.Client{Timeout: timeout}
+	resp, err := httpClient.Get("http://" + address + describeMethodsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	var descriptions []MethodDescription
+	if err := json.NewDecoder(resp.Body).Decode(&descriptions); err != nil {
+		return nil, err
+	}
+	return descriptions, nil
+}
+
+func implements(typ, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PtrTo(typ).Implements(iface)
+}
+
+// makeTypeSchema returns the schema for the JSON encoding of typ, or nil if
+// the type cannot be encoded. The struct types being described are in parents,
+// to detect recursive references.
+func makeTypeSchema(typ reflect.Type,
+	parents map[reflect.Type]struct{}) *TypeSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	schema := &TypeSchema{}
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		schema.Title = typ.String()
+	}
+	switch {
+	case typ == typeOfTime:
+		schema.Type = "string"
+		schema.Format = "date-time"
+		return schema
+	case typ.Kind() == reflect.Interface:
+		return schema // Any value.
+	case implements(typ, typeOfJsonMarshaler):
+		schema.Description = "custom JSON encoding"
+		return schema
+	case implements(typ, typeOfTextMarshaler):
+		schema.Type = "string"
+		return schema
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		schema.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Array:
+		schema.Type = "array"
+		schema.Items = makeTypeSchema(typ.Elem(), parents)
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 &&
+			!implements(typ.Elem(), typeOfJsonMarshaler) &&
+			!implements(typ.Elem(), typeOfTextMarshaler) {
+			schema.Type = "string"
+			schema.Format = "byte" // Base64 encoded.
+		} else {
+			schema.Type = "array"
+			schema.Items = makeTypeSchema(typ.Elem(), parents)
+		}
+	case reflect.Map:
+		schema.Type = "object"
+		schema.Values = makeTypeSchema(typ.Elem(), parents)
+	case reflect.Struct:
+		schema.Type = "object"
+		if _, ok := parents[typ]; ok {
+			schema.Description = "recursive reference"
+			return schema
+		}
+		parents[typ] = struct{}{}
+		schema.Properties = make(map[string]*TypeSchema)
+		addProperties(schema.Properties, typ, parents)
+		delete(parents, typ)
+	default: // Channels, complex numbers and functions cannot be encoded.
+		return nil
+	}
+	return schema
+}
+
+func (m *methodWrapper) describe(serviceMethod string) MethodDescription {
+	description := MethodDescription{
+		Name:      serviceMethod,
+		Public:    m.public,
+		Signature: m.fn.Type().String(),
+	}
+	switch m.methodType {
+	case methodTypeRaw:
+		description.Type = "raw"
+	case methodTypeCoder:
+		description.Type = "coder"
+	case methodTypeRequestReply:
+		description.Type = "request-reply"
+		description.RequestSchema = makeTypeSchema(m.requestType,
+			make(map[reflect.Type]struct{}))
+		description.ResponseSchema = makeTypeSchema(m.responseType,
+			make(map[reflect.Type]struct{}))
+	}
+	return description
+}
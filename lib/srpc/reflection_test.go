@@ -0,0 +1,128 @@
+package srpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+type embeddedType struct {
+	Name   string
+	Shared string
+}
+
+type schemaTestType struct {
+	*embeddedType
+	Children []*schemaTestType
+	Count    uint64 `json:",string"`
+	Data     []byte
+	Hidden   string `json:"-"`
+	Labels   map[string]string
+	Renamed  bool `json:"renamed,omitempty"`
+	Shared   int
+	Time     time.Time
+	private  int
+}
+
+func TestDescribeMethods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(describeMethodsHttpHandler))
+	defer server.Close()
+	methodDescriptions, err := GetMethodDescriptions(
+		strings.TrimPrefix(server.URL, "http://"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := make(map[string]MethodDescription)
+	for _, description := range methodDescriptions {
+		descriptions[description.Name] = description
+	}
+	if description := descriptions["Test.Plain"]; description.Type != "raw" {
+		t.Errorf("Test.Plain: bad type: %s", description.Type)
+	}
+	description := descriptions["Test.RequestReply"]
+	if description.Type != "request-reply" {
+		t.Fatalf("Test.RequestReply: bad type: %s", description.Type)
+	}
+	if !strings.Contains(description.Signature, "test.EchoRequest") {
+		t.Errorf("bad signature: %s", description.Signature)
+	}
+	requestSchema := description.RequestSchema
+	if requestSchema.Title != "test.EchoRequest" ||
+		requestSchema.Properties["Request"].Type != "string" {
+		t.Errorf("bad request schema: %+v", requestSchema)
+	}
+	if description.ResponseSchema.Title != "test.EchoResponse" {
+		t.Errorf("bad response schema: %+v", description.ResponseSchema)
+	}
+}
+
+func TestDialJsonHTTP(t *testing.T) {
+	addr, err := makeListener(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialJsonHTTP(addr.Network(), addr.String(), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, ok := client.makeCoder.(*jsonCoder); !ok {
+		t.Fatal("JSON coder not used")
+	}
+	var response json.RawMessage
+	err = client.RequestReply("Test.RequestReply",
+		json.RawMessage(`{"Request":"test"}`), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var echoResponse test.EchoResponse
+	if err := json.Unmarshal(response, &echoResponse); err != nil {
+		t.Fatal(err)
+	}
+	if echoResponse.Response != "test" {
+		t.Errorf("bad response: %s", response)
+	}
+}
+
+func TestMakeTypeSchema(t *testing.T) {
+	schema := makeTypeSchema(reflect.TypeOf(schemaTestType{}),
+		make(map[reflect.Type]struct{}))
+	expectedTypes := map[string]string{
+		"Children": "array",
+		"Count":    "string",
+		"Data":     "string",
+		"Labels":   "object",
+		"Name":     "string",
+		"renamed":  "boolean",
+		"Shared":   "integer",
+		"Time":     "string",
+	}
+	if len(schema.Properties) != len(expectedTypes) {
+		t.Errorf("expected %d properties, got: %d",
+			len(expectedTypes), len(schema.Properties))
+	}
+	for name, expectedType := range expectedTypes {
+		if property := schema.Properties[name]; property == nil {
+			t.Errorf("missing property: %s", name)
+		} else if property.Type != expectedType {
+			t.Errorf("%s: %s != %s", name, property.Type, expectedType)
+		}
+	}
+	if schema.Properties["Data"].Format != "byte" ||
+		schema.Properties["Time"].Format != "date-time" {
+		t.Error("bad formats")
+	}
+	if schema.Properties["Labels"].Values.Type != "string" {
+		t.Error("bad map values")
+	}
+	items := schema.Properties["Children"].Items
+	if items.Description != "recursive reference" || items.Properties != nil {
+		t.Errorf("recursive reference expanded: %+v", items)
+	}
+}
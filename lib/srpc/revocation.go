@@ -0,0 +1,91 @@
+package srpc
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+)
+
+func (list *RevocationList) addCRL(crl *x509.RevocationList) {
+	if list.issuerSerials == nil {
+		list.issuerSerials = make(map[string]map[string]struct{})
+	}
+	issuer := string(crl.RawIssuer)
+	serials := list.issuerSerials[issuer]
+	if serials == nil {
+		serials = make(map[string]struct{}, len(crl.RevokedCertificateEntries))
+		list.issuerSerials[issuer] = serials
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		serials[entry.SerialNumber.String()] = struct{}{}
+	}
+}
+
+func (list *RevocationList) addSerialNumber(serialNumber *big.Int) {
+	if list.serials == nil {
+		list.serials = make(map[string]struct{})
+	}
+	list.serials[serialNumber.String()] = struct{}{}
+}
+
+func (list *RevocationList) addUsername(username string) {
+	if list.usernames == nil {
+		list.usernames = make(map[string]struct{})
+	}
+	list.usernames[username] = struct{}{}
+}
+
+func (list *RevocationList) check(verifiedChains [][]*x509.Certificate,
+	username string) error {
+	if list == nil {
+		return nil
+	}
+	if _, ok := list.usernames[username]; ok && username != "" {
+		return fmt.Errorf("%w: user: %s", ErrorCertificateRevoked, username)
+	}
+	var err error
+	for _, chain := range verifiedChains {
+		if err = list.checkChain(chain); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// checkCertificate returns an error if cert has been revoked. Serial numbers
+// revoked for all issuers only apply to leaf certificates, since serial numbers
+// are only unique per issuer and would otherwise match unrelated CAs.
+func (list *RevocationList) checkCertificate(cert *x509.Certificate,
+	isLeaf bool) error {
+	serial := cert.SerialNumber.String()
+	_, ok := list.issuerSerials[string(cert.RawIssuer)][serial]
+	if !ok && isLeaf {
+		_, ok = list.serials[serial]
+	}
+	if ok {
+		return fmt.Errorf("%w: serial: %s, subject: %s, issuer: %s",
+			ErrorCertificateRevoked, cert.SerialNumber.Text(16),
+			cert.Subject, cert.Issuer)
+	}
+	return nil
+}
+
+func (list *RevocationList) checkChain(chain []*x509.Certificate) error {
+	for index, cert := range chain {
+		if err := list.checkCertificate(cert, index == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRevocation returns an error if the certificate used to authenticate the
+// connection or the user has been revoked.
+func (conn *Conn) checkRevocation() error {
+	err := getRevocationList().check(conn.verifiedChains, conn.username)
+	if err != nil {
+		atomic.AddUint64(&numRevokedCalls, 1)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package srpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func makeTestCertificate(t *testing.T, commonName string, serial int64,
+	parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (
+	*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		KeyUsage:              x509.KeyUsageCRLSign | x509.KeyUsageCertSign,
+		NotAfter:              time.Now().Add(time.Hour),
+		NotBefore:             time.Now().Add(-time.Minute),
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: commonName},
+	}
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, parent,
+		key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestRevocationList(t *testing.T) {
+	caCert, caKey := makeTestCertificate(t, "CA", 1, nil, nil)
+	goodCert, _ := makeTestCertificate(t, "good", 2, caCert, caKey)
+	revokedCert, _ := makeTestCertificate(t, "revoked", 3, caCert, caKey)
+	otherCaCert, otherCaKey := makeTestCertificate(t, "other CA", 4, nil, nil)
+	otherCert, _ := makeTestCertificate(t, "other", 3, otherCaCert,
+		otherCaKey)
+	crlData, err := x509.CreateRevocationList(rand.Reader,
+		&x509.RevocationList{
+			Number: big.NewInt(1),
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{
+					RevocationTime: time.Now(),
+					SerialNumber:   revokedCert.SerialNumber,
+				},
+			},
+		},
+		caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(crlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := &RevocationList{}
+	list.AddCRL(crl)
+	list.AddUsername("mallory")
+	chain := func(cert, ca *x509.Certificate) [][]*x509.Certificate {
+		return [][]*x509.Certificate{{cert, ca}}
+	}
+	if err := list.Check(chain(goodCert, caCert), "alice"); err != nil {
+		t.Errorf("good certificate rejected: %s", err)
+	}
+	err = list.Check(chain(revokedCert, caCert), "bob")
+	if !errors.Is(err, ErrorCertificateRevoked) {
+		t.Errorf("revoked certificate accepted: %v", err)
+	}
+	// Same serial number, different issuer.
+	if err := list.Check(chain(otherCert, otherCaCert), "bob"); err != nil {
+		t.Errorf("certificate from other CA rejected: %s", err)
+	}
+	err = list.Check(chain(goodCert, caCert), "mallory")
+	if !errors.Is(err, ErrorCertificateRevoked) {
+		t.Errorf("revoked user accepted: %v", err)
+	}
+	list.AddSerialNumber(big.NewInt(3))
+	err = list.Check(chain(otherCert, otherCaCert), "bob")
+	if !errors.Is(err, ErrorCertificateRevoked) {
+		t.Errorf("certificate with revoked serial number accepted: %v", err)
+	}
+	// Serial numbers without an issuer do not revoke CA certificates.
+	list.AddSerialNumber(caCert.SerialNumber)
+	if err := list.Check(chain(goodCert, caCert), "alice"); err != nil {
+		t.Errorf("CA certificate with revoked serial number rejected: %s",
+			err)
+	}
+	err = list.Check(chain(caCert, caCert), "alice")
+	if !errors.Is(err, ErrorCertificateRevoked) {
+		t.Errorf("leaf certificate with revoked serial number accepted: %v",
+			err)
+	}
+	var nilList *RevocationList
+	if err := nilList.Check(chain(revokedCert, caCert), "mallory"); err != nil {
+		t.Errorf("nil list rejected certificate: %s", err)
+	}
+}
@@ -3,6 +3,7 @@ package srpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/net"
@@ -23,12 +25,13 @@ import (
 )
 
 const (
-	connectString   = "200 Connected to Go SRPC"
-	rpcPath         = "/_goSRPC_/"      // Legacy endpoint. GOB coder.
-	tlsRpcPath      = "/_go_TLS_SRPC_/" // Legacy endpoint. GOB coder.
-	jsonRpcPath     = "/_SRPC_/unsecured/JSON"
-	jsonTlsRpcPath  = "/_SRPC_/TLS/JSON"
-	listMethodsPath = rpcPath + "listMethods"
+	connectString       = "200 Connected to Go SRPC"
+	rpcPath             = "/_goSRPC_/"      // Legacy endpoint. GOB coder.
+	tlsRpcPath          = "/_go_TLS_SRPC_/" // Legacy endpoint. GOB coder.
+	jsonRpcPath         = "/_SRPC_/unsecured/JSON"
+	jsonTlsRpcPath      = "/_SRPC_/TLS/JSON"
+	describeMethodsPath = rpcPath + "describeMethods"
+	listMethodsPath     = rpcPath + "listMethods"
 
 	methodTypeRaw = iota
 	methodTypeCoder
@@ -67,7 +70,9 @@ var (
 	serverMetricsMutex           sync.Mutex
 	numServerConnections         uint64
 	numOpenServerConnections     uint64
+	numRateLimitedCalls          uint64 // Updated with sync/atomic.
 	numRejectedServerConnections uint64
+	numRevokedCalls              uint64 // Updated with sync/atomic.
 )
 
 // Precompute some reflect types. Can't use the types directly because Typeof
@@ -78,20 +83,32 @@ var typeOfEncoder = reflect.TypeOf((*Encoder)(nil)).Elem()
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
 func init() {
-	http.HandleFunc(rpcPath, gobUnsecuredHttpHandler)
-	http.HandleFunc(tlsRpcPath, gobTlsHttpHandler)
-	http.HandleFunc(jsonRpcPath, jsonUnsecuredHttpHandler)
-	http.HandleFunc(jsonTlsRpcPath, jsonTlsHttpHandler)
-	http.HandleFunc(listMethodsPath, listMethodsHttpHandler)
+	registerHttpHandlers(http.DefaultServeMux)
 	registerServerMetrics()
 }
 
+// registerHttpHandlers registers the SRPC handlers with serveMux.
+func registerHttpHandlers(serveMux *http.ServeMux) {
+	serveMux.HandleFunc(rpcPath, gobUnsecuredHttpHandler)
+	serveMux.HandleFunc(tlsRpcPath, gobTlsHttpHandler)
+	serveMux.HandleFunc(jsonRpcPath, jsonUnsecuredHttpHandler)
+	serveMux.HandleFunc(jsonTlsRpcPath, jsonTlsHttpHandler)
+	serveMux.HandleFunc(describeMethodsPath, describeMethodsHttpHandler)
+	serveMux.HandleFunc(listMethodsPath, listMethodsHttpHandler)
+}
+
 func registerServerMetrics() {
 	var err error
 	serverMetricsDir, err = tricorder.RegisterDirectory("srpc/server")
 	if err != nil {
 		panic(err)
 	}
+	err = serverMetricsDir.RegisterMetric("certificate-expiration",
+		getServerCertExpiration, units.None,
+		"earliest expiration time of server certificates")
+	if err != nil {
+		panic(err)
+	}
 	err = serverMetricsDir.RegisterMetric("num-connections",
 		&numServerConnections, units.None, "number of connection attempts")
 	if err != nil {
@@ -108,6 +125,18 @@ func registerServerMetrics() {
 	if err != nil {
 		panic(err)
 	}
+	err = serverMetricsDir.RegisterMetric("num-rate-limited-calls",
+		&numRateLimitedCalls, units.None,
+		"number of calls rejected due to rate limits")
+	if err != nil {
+		panic(err)
+	}
+	err = serverMetricsDir.RegisterMetric("num-revoked-calls",
+		&numRevokedCalls, units.None,
+		"number of calls rejected due to revoked certificates or users")
+	if err != nil {
+		panic(err)
+	}
 	bucketer = tricorder.NewGeometricBucketer(0.1, 1e5)
 }
 
@@ -275,12 +304,14 @@ func httpHandler(w http.ResponseWriter, req *http.Request, doTls bool,
 	serverMetricsMutex.Lock()
 	numServerConnections++
 	serverMetricsMutex.Unlock()
-	if doTls && serverTlsConfig == nil {
+	serverConfig, fullAuthCaPool, requireTls := getServerTlsConfig()
+	if doTls && serverConfig == nil {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	if (tlsRequired && !doTls) || req.Method != "CONNECT" {
+	unixPeer := getUnixPeer(req)
+	if (requireTls && !doTls && unixPeer == nil) || req.Method != "CONNECT" {
 		serverMetricsMutex.Lock()
 		numRejectedServerConnections++
 		serverMetricsMutex.Unlock()
@@ -288,10 +319,13 @@ func httpHandler(w http.ResponseWriter, req *http.Request, doTls bool,
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	if tlsRequired && req.TLS != nil {
-		if serverTlsConfig == nil ||
+	verifier := getTokenVerifier()
+	tokenAuth := doTls && verifier != nil &&
+		req.Header.Get(tokenAuthHeader) == tokenAuthVersion
+	if requireTls && req.TLS != nil && !tokenAuth {
+		if serverConfig == nil ||
 			!checkVerifiedChains(req.TLS.VerifiedChains,
-				serverTlsConfig.ClientCAs) {
+				serverConfig.ClientCAs) {
 			serverMetricsMutex.Lock()
 			numRejectedServerConnections++
 			serverMetricsMutex.Unlock()
@@ -327,22 +361,32 @@ func httpHandler(w http.ResponseWriter, req *http.Request, doTls bool,
 			log.Println("error setting keepalive period: ", err.Error())
 			return
 		}
-	} else {
+	} else if unixPeer == nil {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusNotAcceptable)
 		log.Println("non-TCP connection")
 		return
 	}
-	_, err = io.WriteString(unsecuredConn, "HTTP/1.0 "+connectString+"\n\n")
-	if err != nil {
+	response := "HTTP/1.0 " + connectString + "\n" +
+		deadlinesHeader + ": " + deadlinesVersion + "\n" +
+		traceHeader + ": " + traceVersion + "\n"
+	multiplex := req.Header.Get(multiplexHeader) == multiplexVersion
+	if multiplex {
+		response += multiplexHeader + ": " + multiplexVersion + "\n"
+	}
+	if tokenAuth {
+		response += tokenAuthHeader + ": " + tokenAuthVersion + "\n"
+	}
+	if _, err := io.WriteString(unsecuredConn, response+"\n"); err != nil {
 		log.Println("error writing connect message: ", err.Error())
 		return
 	}
 	myConn := &Conn{remoteAddr: req.RemoteAddr}
+	var dataConn io.Closer = unsecuredConn
 	if doTls {
 		var tlsConn *tls.Conn
 		if req.TLS == nil {
-			tlsConn = tls.Server(unsecuredConn, serverTlsConfig)
+			tlsConn = tls.Server(unsecuredConn, serverConfig)
 			connToClose = tlsConn
 			if err := tlsConn.Handshake(); err != nil {
 				serverMetricsMutex.Lock()
@@ -358,21 +402,58 @@ func httpHandler(w http.ResponseWriter, req *http.Request, doTls bool,
 			}
 		}
 		myConn.isEncrypted = true
+		tlsState := tlsConn.ConnectionState()
 		myConn.username, myConn.permittedMethods, myConn.groupList, err =
-			getAuth(tlsConn.ConnectionState())
+			getAuth(tlsState, fullAuthCaPool)
 		if err != nil {
 			log.Println(err)
 			return
 		}
+		myConn.verifiedChains = tlsState.VerifiedChains
 		myConn.ReadWriter = bufio.NewReadWriter(bufio.NewReader(tlsConn),
 			bufio.NewWriter(tlsConn))
+		dataConn = tlsConn
+		if tokenAuth {
+			identity, err := receiveToken(myConn.ReadWriter, verifier)
+			if err != nil {
+				serverMetricsMutex.Lock()
+				numRejectedServerConnections++
+				serverMetricsMutex.Unlock()
+				log.Printf("%s: token rejected: %s\n", req.RemoteAddr, err)
+				return
+			}
+			myConn.groupList = identity.GroupList
+			myConn.permittedMethods = identity.PermittedMethods
+			if myConn.permittedMethods == nil {
+				myConn.permittedMethods = make(map[string]struct{})
+			}
+			myConn.username = identity.Username
+		} else if requireTls && len(tlsState.VerifiedChains) < 1 {
+			// The TLS configuration may permit clients without certificates
+			// so that they may authenticate with a token.
+			serverMetricsMutex.Lock()
+			numRejectedServerConnections++
+			serverMetricsMutex.Unlock()
+			log.Printf("%s: no certificate or token\n", req.RemoteAddr)
+			return
+		}
 	} else {
 		myConn.ReadWriter = bufrw
+		if unixPeer != nil {
+			myConn.groupList = unixPeer.groupList
+			myConn.permittedMethods = unixPeer.permittedMethods
+			myConn.remoteAddr = unixPeer.remoteAddr()
+			myConn.username = unixPeer.username
+		}
 	}
 	serverMetricsMutex.Lock()
 	numOpenServerConnections++
 	serverMetricsMutex.Unlock()
-	handleConnection(myConn, makeCoder)
+	if multiplex {
+		handleMultiplexedConnection(myConn, dataConn, makeCoder)
+	} else {
+		handleConnection(myConn, makeCoder)
+	}
 	serverMetricsMutex.Lock()
 	numOpenServerConnections--
 	serverMetricsMutex.Unlock()
@@ -391,13 +472,13 @@ func checkVerifiedChains(verifiedChains [][]*x509.Certificate,
 	return false
 }
 
-func getAuth(state tls.ConnectionState) (string, map[string]struct{},
-	map[string]struct{}, error) {
+func getAuth(state tls.ConnectionState, fullAuthCaPool *x509.CertPool) (
+	string, map[string]struct{}, map[string]struct{}, error) {
 	var username string
 	permittedMethods := make(map[string]struct{})
 	trustCertMethods := false
-	if fullAuthCaCertPool == nil ||
-		checkVerifiedChains(state.VerifiedChains, fullAuthCaCertPool) {
+	if fullAuthCaPool == nil ||
+		checkVerifiedChains(state.VerifiedChains, fullAuthCaPool) {
 		trustCertMethods = true
 	}
 	var groupList map[string]struct{}
@@ -433,9 +514,10 @@ func getAuth(state tls.ConnectionState) (string, map[string]struct{},
 func handleConnection(conn *Conn, makeCoder coderMaker) {
 	defer conn.callReleaseNotifier()
 	defer conn.Flush()
+	parentCtx := conn.context()
 	for ; ; conn.Flush() {
 		conn.callReleaseNotifier()
-		serviceMethod, err := conn.ReadString('\n')
+		callLine, err := conn.ReadString('\n')
 		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			return
 		}
@@ -447,7 +529,14 @@ func handleConnection(conn *Conn, makeCoder coderMaker) {
 			}
 			continue
 		}
-		serviceMethod = strings.TrimSpace(serviceMethod)
+		serviceMethod, params, err := parseCallLine(callLine)
+		if err != nil {
+			if _, err := conn.WriteString(err.Error() + "\n"); err != nil {
+				log.Println(err)
+				return
+			}
+			continue
+		}
 		if serviceMethod == "" {
 			// Received a "ping" request, send response.
 			if _, err := conn.WriteString("\n"); err != nil {
@@ -458,6 +547,9 @@ func handleConnection(conn *Conn, makeCoder coderMaker) {
 		}
 		method, err := conn.findMethod(serviceMethod)
 		if err != nil {
+			_, span := conn.startServerSpan(parentCtx, serviceMethod, params)
+			span.End(err)
+			conn.auditCall(serviceMethod, span.StartTime, err)
 			if _, err := conn.WriteString(err.Error() + "\n"); err != nil {
 				log.Println(err)
 				return
@@ -473,7 +565,23 @@ func handleConnection(conn *Conn, makeCoder coderMaker) {
 			log.Println(err)
 			return
 		}
-		if err := method.call(conn, makeCoder); err != nil {
+		ctx, span := conn.startServerSpan(parentCtx, serviceMethod, params)
+		ctx, cancel := makeCallContext(ctx, params.timeout)
+		conn.callError = nil
+		conn.ctx = ctx
+		err = method.call(conn, makeCoder)
+		cancel()
+		conn.ctx = parentCtx
+		// The error from a RequestReply handler is sent to the client, so
+		// method.call only returns the write error. The handler error is
+		// recorded in conn.callError so that failed calls are audited.
+		callError := err
+		if callError == nil {
+			callError = conn.callError
+		}
+		span.End(callError)
+		conn.auditCall(serviceMethod, span.StartTime, callError)
+		if err != nil {
 			if err != ErrorCloseClient {
 				log.Println(err)
 			}
@@ -482,6 +590,39 @@ func handleConnection(conn *Conn, makeCoder coderMaker) {
 	}
 }
 
+// handleMultiplexedConnection serves the streams on a multiplexed connection,
+// each in a separate goroutine. It returns when the connection is closed.
+func handleMultiplexedConnection(conn *Conn, closer io.Closer,
+	makeCoder coderMaker) {
+	mux := newMuxConn(conn.ReadWriter, closer,
+		func(stream *muxStreamType) {
+			// Cancel calls when the stream is reset or the connection fails.
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-stream.failed:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			streamConn := &Conn{
+				groupList:        conn.groupList,
+				isEncrypted:      conn.isEncrypted,
+				permittedMethods: conn.permittedMethods,
+				ReadWriter: bufio.NewReadWriter(bufio.NewReader(stream),
+					bufio.NewWriter(stream)),
+				remoteAddr:     conn.remoteAddr,
+				username:       conn.username,
+				verifiedChains: conn.verifiedChains,
+				ctx:            ctx,
+			}
+			handleConnection(streamConn, makeCoder)
+			stream.closeWrite()
+			cancel()
+		})
+	<-mux.done
+}
+
 func (conn *Conn) callReleaseNotifier() {
 	if releaseNotifier := conn.releaseNotifier; releaseNotifier != nil {
 		releaseNotifier()
@@ -490,6 +631,9 @@ func (conn *Conn) callReleaseNotifier() {
 }
 
 func (conn *Conn) findMethod(serviceMethod string) (*methodWrapper, error) {
+	if err := conn.checkRevocation(); err != nil {
+		return nil, err
+	}
 	splitServiceMethod := strings.Split(serviceMethod, ".")
 	if len(splitServiceMethod) != 2 {
 		return nil, errors.New("malformed Service.Method: " + serviceMethod)
@@ -511,10 +655,13 @@ func (conn *Conn) findMethod(serviceMethod string) (*methodWrapper, error) {
 	} else {
 		conn.haveMethodAccess = false
 		if !method.public {
-			method.numDeniedCalls++
+			atomic.AddUint64(&method.numDeniedCalls, 1)
 			return nil, ErrorAccessToMethodDenied
 		}
 	}
+	if err := conn.checkRateLimit(serviceMethod); err != nil {
+		return nil, err
+	}
 	authInfo := conn.GetAuthInformation()
 	if rn, err := receiver.blockMethod(methodName, authInfo); err != nil {
 		return nil, err
@@ -553,7 +700,7 @@ func listMethodsHttpHandler(w http.ResponseWriter, req *http.Request) {
 }
 
 func (m *methodWrapper) call(conn *Conn, makeCoder coderMaker) error {
-	m.numPermittedCalls++
+	atomic.AddUint64(&m.numPermittedCalls, 1)
 	startTime := time.Now()
 	err := m._call(conn, makeCoder)
 	timeTaken := time.Since(startTime)
@@ -603,6 +750,7 @@ func (m *methodWrapper) _call(conn *Conn, makeCoder coderMaker) error {
 		if errInter != nil {
 			m.failedRRCallsDistribution.Add(timeTaken)
 			err := errInter.(error)
+			conn.callError = err
 			_, err = conn.WriteString(err.Error() + "\n")
 			return err
 		}
@@ -13,9 +13,13 @@ import (
 )
 
 var (
+	bearerTokenFile = flag.String("bearerTokenFile", "",
+		"Name of file containing bearer token to authenticate with")
 	certDirectory = flag.String("certDirectory",
 		path.Join(os.Getenv("HOME"), ".ssl"),
 		"Name of directory containing user SSL certificates")
+	certReloadInterval = flag.Duration("certReloadInterval", 0,
+		"Interval between checks for changed certificates (0: never reload)")
 )
 
 // GetCertDirectory returns the directory containing the client certificates.
@@ -26,7 +30,19 @@ func GetCertDirectory() string {
 // SetupTls loads zero or more client certificates from files and registers them
 // with the lib/srpc package. The following command-line flags are registered
 // with the standard flag package:
-//   -certDirectory: Name of directory containing user SSL certificates
+//   -bearerTokenFile:    Name of file containing bearer token
+//   -certDirectory:      Name of directory containing user SSL certificates
+//   -certReloadInterval: Interval between checks for changed certificates
+//   -traceExportFile:    Name of file to append trace spans to
+//   -traceExportUrl:     URL of OTLP/HTTP collector to export spans to
+// If -certReloadInterval is non-zero, the certificates are reloaded and
+// registered again when they change, so that renewed certificates are used for
+// new connections. This is useful for long-running clients.
+// If -bearerTokenFile is specified, the token in the file is sent to servers
+// which accept bearer tokens. The file is read for each new connection, so
+// that the token may be refreshed. Client certificates are not required.
+// If -traceExportFile or -traceExportUrl are specified, trace spans for method
+// calls are exported in OTLP JSON format.
 func SetupTls(ignoreMissingCerts bool) error {
 	return setupTls(ignoreMissingCerts)
 }
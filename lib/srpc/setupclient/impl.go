@@ -1,31 +1,122 @@
 package setupclient
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/trace/setuptrace"
 )
 
+// getChecksum returns a checksum of the names and contents of the certificate
+// and key files in the certificate directory.
+func getChecksum() [sha256.Size]byte {
+	hasher := sha256.New()
+	fileInfos, _ := ioutil.ReadDir(*certDirectory)
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if !strings.HasSuffix(name, ".cert") &&
+			!strings.HasSuffix(name, ".key") {
+			continue
+		}
+		data, _ := ioutil.ReadFile(filepath.Join(*certDirectory, name))
+		fmt.Fprintf(hasher, "%s %d\n", name, len(data))
+		hasher.Write(data)
+	}
+	var checksum [sha256.Size]byte
+	copy(checksum[:], hasher.Sum(nil))
+	return checksum
+}
+
+func loadTls() ([]tls.Certificate, error) {
+	certs, err := srpc.LoadCertificates(*certDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, nil
+	}
+	registerClientTlsConfig(certs)
+	return certs, nil
+}
+
+func readBearerToken() (string, error) {
+	data, err := ioutil.ReadFile(*bearerTokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// registerClientTlsConfig registers a client TLS configuration with the
+// specified certificates. If there are no certificates, a configuration is
+// only registered if a bearer token is used for authentication.
+func registerClientTlsConfig(certs []tls.Certificate) {
+	if len(certs) < 1 && *bearerTokenFile == "" {
+		return
+	}
+	clientConfig := new(tls.Config)
+	clientConfig.InsecureSkipVerify = true
+	clientConfig.MinVersion = tls.VersionTLS12
+	clientConfig.Certificates = certs
+	srpc.RegisterClientTlsConfig(clientConfig)
+}
+
 func setupTls(ignoreMissingCerts bool) error {
+	if err := setuptrace.Setup(); err != nil {
+		return err
+	}
+	if *bearerTokenFile != "" {
+		if _, err := readBearerToken(); err != nil {
+			return err
+		}
+		srpc.RegisterClientTokenGetter(readBearerToken)
+		ignoreMissingCerts = true
+	}
 	if *certDirectory == "" {
+		registerClientTlsConfig(nil)
 		return nil
 	}
 	// Load certificates.
-	certs, err := srpc.LoadCertificates(*certDirectory)
+	checksum := getChecksum()
+	certs, err := loadTls()
 	if err != nil {
 		return err
 	}
+	if *certReloadInterval > 0 {
+		go watchTls(checksum, *certReloadInterval)
+	}
 	if certs == nil {
+		registerClientTlsConfig(nil)
 		if ignoreMissingCerts {
 			return nil
 		}
 		return srpc.ErrorMissingCertificate
 	}
-	// Setup client.
-	clientConfig := new(tls.Config)
-	clientConfig.InsecureSkipVerify = true
-	clientConfig.MinVersion = tls.VersionTLS12
-	clientConfig.Certificates = certs
-	srpc.RegisterClientTlsConfig(clientConfig)
 	return nil
 }
+
+// watchTls periodically checks if the certificates have changed and if so,
+// reloads them. Existing connections are not affected.
+func watchTls(checksum [sha256.Size]byte, interval time.Duration) {
+	for range time.Tick(interval) {
+		newChecksum := getChecksum()
+		if newChecksum == checksum {
+			continue
+		}
+		checksum = newChecksum
+		if certs, err := loadTls(); err != nil {
+			log.Printf("error reloading certificates: %s\n", err)
+		} else if certs == nil {
+			log.Println("no certificates to reload")
+		} else {
+			log.Println("reloaded certificates")
+		}
+	}
+}
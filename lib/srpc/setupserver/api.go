@@ -6,12 +6,13 @@
 */
 package setupserver
 
-// SetupTls loads client and server certificates from files and registers them
-// with the lib/srpc package. The following command-line flags are registered
-// with the standard flag package:
-//   -caFile:   Name of file containing the root of trust
-//   -certFile: Name of file containing the SSL certificate
-//   -keyFile:  Name of file containing the SSL key
+import (
+	"io"
+)
+
+// SetupTls loads client and server certificates from files (specified by
+// command-line flags such as -CAfile, -certFile and -keyFile) and registers
+// them with the lib/srpc package. The files are reloaded when they change.
 func SetupTls() error {
 	return setupTls(true)
 }
@@ -21,3 +22,13 @@ func SetupTls() error {
 func SetupTlsClientOnly() error {
 	return setupTls(false)
 }
+
+// StartUnixServer starts serving SRPC on the Unix socket specified by the
+// -unixSocket command-line flag (if specified). Local clients may connect
+// without TLS and are authenticated using their user ID. The socket file may
+// only be accessed by its owner and group. This should be called after the
+// receivers are registered. The returned io.Closer (nil if no socket was
+// specified) may be used to stop the server.
+func StartUnixServer() (io.Closer, error) {
+	return startUnixServer()
+}
@@ -1,15 +1,19 @@
 package setupserver
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+	"github.com/Cloud-Foundations/Dominator/lib/trace/setuptrace"
 )
 
 var (
@@ -23,13 +27,44 @@ var (
 	keyFile = flag.String("keyFile",
 		path.Join("/etc/ssl", getDirname(), "key.pem"),
 		"Name of file containing the SSL key")
+	tlsReloadInterval = flag.Duration("tlsReloadInterval", time.Minute,
+		"Interval between checks for changed certificate, key and CA files "+
+			"(0: never reload)")
 )
 
+// getChecksum returns a checksum of the contents of the specified files.
+// Missing or unreadable files are treated as empty.
+func getChecksum(filenames []string) [sha256.Size]byte {
+	hasher := sha256.New()
+	for _, filename := range filenames {
+		data, _ := ioutil.ReadFile(filename)
+		fmt.Fprintf(hasher, "%s %d\n", filename, len(data))
+		hasher.Write(data)
+	}
+	var checksum [sha256.Size]byte
+	copy(checksum[:], hasher.Sum(nil))
+	return checksum
+}
+
 func getDirname() string {
 	return path.Base(os.Args[0])
 }
 
-func setupTls(setupServer bool) error {
+func getFilenames(setupServer bool) []string {
+	filenames := []string{*certFile, *keyFile}
+	if setupServer {
+		filenames = append(filenames, *caFile)
+		if *identityCaFile != "" {
+			filenames = append(filenames, *identityCaFile)
+		}
+		if *jwksFile != "" {
+			filenames = append(filenames, *jwksFile)
+		}
+	}
+	return filenames
+}
+
+func loadTls(setupServer bool) error {
 	// Load certificates and key.
 	cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
 	if err != nil {
@@ -50,6 +85,15 @@ func setupTls(setupServer bool) error {
 		serverConfig.MinVersion = tls.VersionTLS12
 		serverConfig.ClientCAs = caCertPool
 		serverConfig.Certificates = append(serverConfig.Certificates, cert)
+		tokenVerifier, err := loadTokenVerifier()
+		if err != nil {
+			return err
+		}
+		if tokenVerifier != nil {
+			// Clients may authenticate with a token instead of a certificate.
+			serverConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		var fullAuthCaCertPool *x509.CertPool
 		if *identityCaFile != "" {
 			identityCaData, err := ioutil.ReadFile(*identityCaFile)
 			if err != nil {
@@ -58,7 +102,7 @@ func setupTls(setupServer bool) error {
 						*caFile, err)
 				}
 			} else {
-				srpc.RegisterFullAuthCA(caCertPool)
+				fullAuthCaCertPool = caCertPool
 				caCertPool := x509.NewCertPool()
 				if !caCertPool.AppendCertsFromPEM(caData) {
 					return fmt.Errorf("unable to parse CA file")
@@ -69,7 +113,9 @@ func setupTls(setupServer bool) error {
 				serverConfig.ClientCAs = caCertPool
 			}
 		}
-		srpc.RegisterServerTlsConfig(serverConfig, true)
+		srpc.RegisterTokenVerifier(tokenVerifier)
+		srpc.RegisterServerTlsConfigWithFullAuthCA(serverConfig,
+			fullAuthCaCertPool, true)
 	}
 	// Setup client.
 	clientConfig := new(tls.Config)
@@ -79,3 +125,52 @@ func setupTls(setupServer bool) error {
 	srpc.RegisterClientTlsConfig(clientConfig)
 	return nil
 }
+
+// reloadTls reloads the certificate, key and CA files if their checksum differs
+// from checksum. If they cannot be loaded, the previously registered
+// configuration is kept. The new checksum is returned.
+func reloadTls(setupServer bool, filenames []string,
+	checksum [sha256.Size]byte) [sha256.Size]byte {
+	newChecksum := getChecksum(filenames)
+	if newChecksum == checksum {
+		return checksum
+	}
+	if err := loadTls(setupServer); err != nil {
+		log.Printf("error reloading TLS credentials: %s\n", err)
+	} else {
+		log.Println("reloaded TLS credentials")
+	}
+	return newChecksum
+}
+
+func setupTls(setupServer bool) error {
+	filenames := getFilenames(setupServer)
+	checksum := getChecksum(filenames)
+	if err := loadTls(setupServer); err != nil {
+		return err
+	}
+	if err := setuptrace.Setup(); err != nil {
+		return err
+	}
+	if setupServer {
+		if err := setupRevocation(); err != nil {
+			return err
+		}
+		if err := setupPolicies(); err != nil {
+			return err
+		}
+	}
+	if *tlsReloadInterval > 0 {
+		go watchTls(setupServer, filenames, checksum, *tlsReloadInterval)
+	}
+	return nil
+}
+
+// watchTls periodically checks if the certificate, key or CA files have
+// changed and if so, reloads them. Existing connections are not affected.
+func watchTls(setupServer bool, filenames []string,
+	checksum [sha256.Size]byte, interval time.Duration) {
+	for range time.Tick(interval) {
+		checksum = reloadTls(setupServer, filenames, checksum)
+	}
+}
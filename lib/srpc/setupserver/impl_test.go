@@ -0,0 +1,214 @@
+package setupserver
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+type testCredentials struct {
+	caPem   []byte
+	certPem []byte
+	keyPem  []byte
+	serial  *big.Int
+	tlsCert tls.Certificate
+}
+
+func makeTestCredentials(t *testing.T, serial int64) testCredentials {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		NotAfter:              time.Now().Add(time.Hour),
+		NotBefore:             time.Now().Add(-time.Minute),
+		SerialNumber:          big.NewInt(serial * 10),
+		Subject:               pkix.Name{CommonName: "test CA"},
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate,
+		caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth,
+			x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		NotAfter:     time.Now().Add(time.Hour),
+		NotBefore:    time.Now().Add(-time.Minute),
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, caTemplate,
+		key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := testCredentials{
+		caPem: pem.EncodeToMemory(
+			&pem.Block{Type: "CERTIFICATE", Bytes: caDer}),
+		certPem: pem.EncodeToMemory(
+			&pem.Block{Type: "CERTIFICATE", Bytes: certDer}),
+		keyPem: pem.EncodeToMemory(
+			&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		serial: template.SerialNumber,
+	}
+	credentials.tlsCert, err = tls.X509KeyPair(credentials.certPem,
+		credentials.keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credentials
+}
+
+// testHandshake connects to the TLS SRPC endpoint with the client certificate
+// and returns the certificate presented by the server. TLS 1.2 is used so that
+// a rejected client certificate fails the handshake.
+func testHandshake(address string,
+	clientCert tls.Certificate) (*x509.Certificate, error) {
+	conn, err := net.DialTimeout("tcp", address, time.Second*5)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+	io.WriteString(conn, "CONNECT /_go_TLS_SRPC_/ HTTP/1.0\n\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn),
+		&http.Request{Method: "CONNECT"})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	tlsConn := tls.Client(conn, &tls.Config{
+		Certificates:       []tls.Certificate{clientCert},
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	return tlsConn.ConnectionState().PeerCertificates[0], nil
+}
+
+func writeTestCredentials(t *testing.T, credentials testCredentials) {
+	for filename, data := range map[string][]byte{
+		*caFile:   credentials.caPem,
+		*certFile: credentials.certPem,
+		*keyFile:  credentials.keyPem,
+	} {
+		if err := os.WriteFile(filename, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReloadTls(t *testing.T) {
+	dirname := t.TempDir()
+	savedFlags := []*string{caFile, certFile, identityCaFile, jwksFile, keyFile}
+	savedValues := make([]string, 0, len(savedFlags))
+	for _, flagValue := range savedFlags {
+		savedValues = append(savedValues, *flagValue)
+	}
+	defer func() {
+		for index, flagValue := range savedFlags {
+			*flagValue = savedValues[index]
+		}
+		srpc.RegisterServerTlsConfig(nil, false)
+		srpc.RegisterClientTlsConfig(nil)
+	}()
+	*caFile = filepath.Join(dirname, "CA.pem")
+	*certFile = filepath.Join(dirname, "cert.pem")
+	*identityCaFile = ""
+	*jwksFile = ""
+	*keyFile = filepath.Join(dirname, "key.pem")
+	oldCredentials := makeTestCredentials(t, 1)
+	newCredentials := makeTestCredentials(t, 2)
+	writeTestCredentials(t, oldCredentials)
+	filenames := getFilenames(true)
+	checksum := getChecksum(filenames)
+	if err := loadTls(true); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.DefaultServeMux)
+	defer server.Close()
+	address := server.Listener.Addr().String()
+	check := func(name string, expected, rejected testCredentials) {
+		t.Helper()
+		peerCert, err := testHandshake(address, expected.tlsCert)
+		if err != nil {
+			t.Fatalf("%s: handshake failed: %s", name, err)
+		}
+		if peerCert.SerialNumber.Cmp(expected.serial) != 0 {
+			t.Errorf("%s: server certificate serial: %s != %s",
+				name, peerCert.SerialNumber, expected.serial)
+		}
+		if _, err := testHandshake(address, rejected.tlsCert); err == nil {
+			t.Errorf("%s: client certificate from other CA accepted", name)
+		}
+	}
+	check("initial", oldCredentials, newCredentials)
+	if reloadTls(true, filenames, checksum) != checksum {
+		t.Error("checksum changed for unchanged files")
+	}
+	// Replaced files are used for new handshakes, by servers and clients.
+	writeTestCredentials(t, newCredentials)
+	newChecksum := reloadTls(true, filenames, checksum)
+	if newChecksum == checksum {
+		t.Fatal("checksum unchanged for changed files")
+	}
+	checksum = newChecksum
+	check("reloaded", newCredentials, oldCredentials)
+	client, err := srpc.DialHTTP("tcp", address, time.Second*5)
+	if err != nil {
+		t.Errorf("client with reloaded certificate: %s", err)
+	} else {
+		client.Close()
+	}
+	// Bad files are not loaded and the previous configuration is kept.
+	for filename, goodData := range map[string][]byte{
+		*caFile:   newCredentials.caPem,
+		*certFile: newCredentials.certPem,
+	} {
+		err := os.WriteFile(filename, []byte("bad data\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previousChecksum := checksum
+		checksum = reloadTls(true, filenames, checksum)
+		if checksum == previousChecksum {
+			t.Errorf("%s: checksum unchanged", filename)
+		}
+		check("bad "+filepath.Base(filename), newCredentials, oldCredentials)
+		if err := os.WriteFile(filename, goodData, 0600); err != nil {
+			t.Fatal(err)
+		}
+		checksum = reloadTls(true, filenames, checksum)
+	}
+}
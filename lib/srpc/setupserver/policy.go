@@ -0,0 +1,137 @@
+package setupserver
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/logbuf"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+var (
+	auditLogDirectory = flag.String("auditLogDirectory", "",
+		"Directory to write the SRPC call audit log to. If empty, no audit log")
+	auditLogFileMaxSize = flagutil.Size(10 << 20)
+	auditLogQuota       = flagutil.Size(100 << 20)
+	auditMethods        = flagutil.StringList{
+		"*.Delete*", "*.Destroy*", "*.Remove*",
+	}
+	rateLimitsFile = flag.String("rateLimitsFile", "",
+		"Name of file containing per-user SRPC method rate limits")
+)
+
+func init() {
+	flag.Var(&auditLogFileMaxSize, "auditLogFileMaxSize",
+		"Maximum size for an audit log file. If exceeded, new file is created")
+	flag.Var(&auditLogQuota, "auditLogQuota",
+		"Audit log quota. If exceeded, old audit logs are deleted")
+	flag.Var(&auditMethods, "auditMethods",
+		"Comma separated list of Service.Method patterns to audit")
+}
+
+// loadRateLimits loads a rate limits file. Each line contains a username
+// pattern, a Service.Method pattern, a rate (calls per second) and a burst
+// size. Empty lines and lines starting with '#' are ignored.
+func loadRateLimits(reader io.Reader) (*srpc.RateLimits, error) {
+	limits := &srpc.RateLimits{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("bad line: %s", line)
+		}
+		rate, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad rate: %s", fields[2])
+		}
+		burst, err := strconv.ParseUint(fields[3], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad burst: %s", fields[3])
+		}
+		err = limits.AddLimit(fields[0], fields[1], rate, uint(burst))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// setupAuditLog registers an auditor which writes to a rotating log in the
+// audit log directory (if configured).
+func setupAuditLog() error {
+	if *auditLogDirectory == "" {
+		return nil
+	}
+	if err := os.MkdirAll(*auditLogDirectory, 0755); err != nil {
+		return err
+	}
+	logBuffer := logbuf.NewWithOptions(logbuf.Options{
+		Directory:   *auditLogDirectory,
+		MaxFileSize: auditLogFileMaxSize,
+		Quota:       auditLogQuota,
+	})
+	logger := log.New(logBuffer, "", log.LstdFlags)
+	srpc.RegisterCallAuditor(func(record *srpc.CallRecord) {
+		logger.Println(record)
+	},
+		auditMethods)
+	return nil
+}
+
+// setupPolicies sets up the audit log and rate limits.
+func setupPolicies() error {
+	if err := setupAuditLog(); err != nil {
+		return err
+	}
+	return setupRateLimits()
+}
+
+// setupRateLimits loads the rate limits file (if configured), registers the
+// limits and starts a goroutine which reloads them when the file is replaced.
+func setupRateLimits() error {
+	if *rateLimitsFile == "" {
+		return nil
+	}
+	file, err := os.Open(*rateLimitsFile)
+	if err != nil {
+		return err
+	}
+	limits, err := loadRateLimits(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %s", *rateLimitsFile, err)
+	}
+	srpc.RegisterRateLimits(limits)
+	go watchRateLimits(*rateLimitsFile)
+	return nil
+}
+
+func watchRateLimits(filename string) {
+	channel := fsutil.WatchFile(filename, nil)
+	(<-channel).Close() // Drain the first event: already loaded.
+	for readCloser := range channel {
+		limits, err := loadRateLimits(readCloser)
+		readCloser.Close()
+		if err != nil {
+			log.Printf("error reloading rate limits: %s: %s\n", filename, err)
+			continue
+		}
+		srpc.RegisterRateLimits(limits)
+		log.Println("reloaded rate limits")
+	}
+}
@@ -0,0 +1,247 @@
+package setupserver
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/flagutil"
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+type denyListType struct {
+	serialNumbers []*big.Int
+	usernames     []string
+}
+
+type revocationLoaderType struct {
+	crls     map[string]*x509.RevocationList // Key: location.
+	denyList *denyListType
+}
+
+var (
+	crlLocations flagutil.StringList
+	denyListFile = flag.String("denyListFile", "",
+		"Name of file containing revoked certificate serial numbers and users")
+	revocationRefreshInterval = flag.Duration("revocationRefreshInterval",
+		5*time.Minute,
+		"Interval between refreshes of CRLs and the deny-list (0: never)")
+
+	crlHttpClient = &http.Client{Timeout: time.Minute}
+)
+
+func init() {
+	flag.Var(&crlLocations, "crlLocations",
+		"Comma separated list of CRL files or URLs")
+}
+
+// findIssuer returns the CA certificate which signed crl.
+func findIssuer(crl *x509.RevocationList,
+	caCerts []*x509.Certificate) (*x509.Certificate, error) {
+	for _, caCert := range caCerts {
+		if string(caCert.RawSubject) != string(crl.RawIssuer) {
+			continue
+		}
+		if err := crl.CheckSignatureFrom(caCert); err == nil {
+			return caCert, nil
+		}
+	}
+	return nil, errors.New("CRL not signed by a trusted CA")
+}
+
+// loadCaCertificates loads the certificates in the CA files.
+func loadCaCertificates() ([]*x509.Certificate, error) {
+	var caCerts []*x509.Certificate
+	for _, filename := range []string{*caFile, *identityCaFile} {
+		if filename == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			if os.IsNotExist(err) && filename == *identityCaFile {
+				continue
+			}
+			return nil, err
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", filename, err)
+			}
+			caCerts = append(caCerts, cert)
+		}
+	}
+	return caCerts, nil
+}
+
+// loadCrl loads a PEM or DER encoded CRL from a file or URL and checks that it
+// was signed by one of the CA certificates.
+func loadCrl(location string,
+	caCerts []*x509.Certificate) (*x509.RevocationList, error) {
+	data, err := readLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block type: %s", block.Type)
+		}
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := findIssuer(crl, caCerts); err != nil {
+		return nil, err
+	}
+	if !crl.NextUpdate.IsZero() && time.Since(crl.NextUpdate) > 0 {
+		log.Printf("CRL: %s is stale: next update was due at: %s\n",
+			location, crl.NextUpdate.Format(time.RFC3339))
+	}
+	return crl, nil
+}
+
+// loadDenyList loads a deny-list file. Each line contains either
+// "serial <number>" or "user <username>". Serial numbers are decimal unless
+// they have a "0x" prefix and only revoke leaf certificates. Empty lines and
+// lines starting with '#' are ignored.
+func loadDenyList(filename string) (*denyListType, error) {
+	lines, err := fsutil.LoadLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	denyList := &denyListType{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 1 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: bad line: %s", filename, line)
+		}
+		switch fields[0] {
+		case "serial":
+			serialNumber, ok := new(big.Int).SetString(fields[1], 0)
+			if !ok {
+				return nil, fmt.Errorf("%s: bad serial number: %s",
+					filename, fields[1])
+			}
+			denyList.serialNumbers = append(denyList.serialNumbers,
+				serialNumber)
+		case "user":
+			denyList.usernames = append(denyList.usernames, fields[1])
+		default:
+			return nil, fmt.Errorf("%s: unknown type: %s", filename, fields[0])
+		}
+	}
+	return denyList, nil
+}
+
+func readLocation(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") &&
+		!strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(location)
+	}
+	resp, err := crlHttpClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", location, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// setupRevocation loads the CRLs and deny-list (if configured), registers them
+// with the lib/srpc package and starts a goroutine to periodically refresh
+// them.
+func setupRevocation() error {
+	if len(crlLocations) < 1 && *denyListFile == "" {
+		return nil
+	}
+	loader := &revocationLoaderType{
+		crls: make(map[string]*x509.RevocationList, len(crlLocations)),
+	}
+	if err := loader.load(); err != nil {
+		return err
+	}
+	if *revocationRefreshInterval > 0 {
+		go loader.refresh(*revocationRefreshInterval)
+	}
+	return nil
+}
+
+// load loads the CRLs and deny-list and registers the revocation list. If a
+// CRL or the deny-list cannot be loaded, the previously loaded version is used
+// and the first error is returned.
+func (loader *revocationLoaderType) load() error {
+	var firstError error
+	caCerts, err := loadCaCertificates()
+	if err != nil {
+		firstError = fmt.Errorf("unable to load CA certificates: %s", err)
+	}
+	for _, location := range crlLocations {
+		if caCerts == nil {
+			break
+		}
+		if crl, err := loadCrl(location, caCerts); err != nil {
+			if firstError == nil {
+				firstError = fmt.Errorf("unable to load CRL: %s: %s",
+					location, err)
+			}
+		} else {
+			loader.crls[location] = crl
+		}
+	}
+	if *denyListFile != "" {
+		if denyList, err := loadDenyList(*denyListFile); err != nil {
+			if firstError == nil {
+				firstError = fmt.Errorf("unable to load deny-list: %s", err)
+			}
+		} else {
+			loader.denyList = denyList
+		}
+	}
+	list := &srpc.RevocationList{}
+	for _, crl := range loader.crls {
+		list.AddCRL(crl)
+	}
+	if loader.denyList != nil {
+		for _, serialNumber := range loader.denyList.serialNumbers {
+			list.AddSerialNumber(serialNumber)
+		}
+		for _, username := range loader.denyList.usernames {
+			list.AddUsername(username)
+		}
+	}
+	srpc.RegisterRevocationList(list)
+	return firstError
+}
+
+func (loader *revocationLoaderType) refresh(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := loader.load(); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package setupserver
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/jwt"
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+type tokenClaimsType struct {
+	jwt.Claims
+	Groups           []string `json:"groups,omitempty"`
+	PermittedMethods []string `json:"permitted_methods,omitempty"`
+	Username         string   `json:"username,omitempty"`
+}
+
+type tokenVerifierType struct {
+	audience string
+	issuer   string
+	keySet   *jwt.KeySet
+}
+
+var (
+	jwksFile = flag.String("jwksFile", "",
+		"Name of file containing JSON Web Key Set to verify bearer tokens")
+	jwtAudience = flag.String("jwtAudience", "",
+		"Required audience of bearer tokens (default: any)")
+	jwtIssuer = flag.String("jwtIssuer", "",
+		"Required issuer of bearer tokens (default: any)")
+)
+
+// loadTokenVerifier loads the JSON Web Key Set and returns a verifier. If no
+// key set is configured, nil is returned.
+func loadTokenVerifier() (srpc.TokenVerifier, error) {
+	if *jwksFile == "" {
+		return nil, nil
+	}
+	keySet, err := jwt.LoadKeySet(*jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load JWKS file: %s", err)
+	}
+	return &tokenVerifierType{
+		audience: *jwtAudience,
+		issuer:   *jwtIssuer,
+		keySet:   keySet,
+	}, nil
+}
+
+// VerifyToken verifies a JWT. The username is taken from the "username" claim,
+// or from the "sub" claim if absent. The "groups" and "permitted_methods"
+// claims are lists of group names and Service.Method patterns, respectively.
+func (v *tokenVerifierType) VerifyToken(token string) (
+	*srpc.TokenIdentity, error) {
+	var claims tokenClaimsType
+	if err := v.keySet.Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	if err := claims.Validate(time.Now(), v.issuer, v.audience); err != nil {
+		return nil, err
+	}
+	identity := &srpc.TokenIdentity{
+		GroupList:        make(map[string]struct{}, len(claims.Groups)),
+		PermittedMethods: make(map[string]struct{}),
+		Username:         claims.Username,
+	}
+	if identity.Username == "" {
+		identity.Username = claims.Subject
+	}
+	if identity.Username == "" {
+		return nil, errors.New("no username in token")
+	}
+	for _, group := range claims.Groups {
+		identity.GroupList[group] = struct{}{}
+	}
+	for _, method := range claims.PermittedMethods {
+		if strings.Count(method, ".") != 1 {
+			return nil, fmt.Errorf("bad permitted method: \"%s\"", method)
+		}
+		identity.PermittedMethods[method] = struct{}{}
+	}
+	return identity, nil
+}
@@ -0,0 +1,22 @@
+package setupserver
+
+import (
+	"flag"
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/srpc"
+)
+
+var (
+	unixSocket = flag.String("unixSocket", "",
+		"Name of Unix socket to listen on for local clients (@name: abstract)")
+)
+
+func startUnixServer() (io.Closer, error) {
+	if *unixSocket == "" {
+		return nil, nil
+	}
+	// Access to methods is checked using the peer credentials, but limit
+	// connections to the owner and group of the socket for defence in depth.
+	return srpc.StartUnixServer(*unixSocket, 0660)
+}
@@ -0,0 +1,114 @@
+package srpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+)
+
+func getClientTlsConfig() *tls.Config {
+	tlsConfigLock.RLock()
+	defer tlsConfigLock.RUnlock()
+	return clientTlsConfig
+}
+
+// getEarliestCertExpiration returns the earliest expiration time of any
+// certificate in config. The zero value is returned if there are no
+// certificates with an expiration time.
+func getEarliestCertExpiration(config *tls.Config) time.Time {
+	var earliest time.Time
+	if config == nil {
+		return earliest
+	}
+	for _, cert := range config.Certificates {
+		leaf := cert.Leaf
+		if leaf == nil && len(cert.Certificate) > 0 {
+			leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+		}
+		if leaf == nil || leaf.NotAfter.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || leaf.NotAfter.Before(earliest) {
+			earliest = leaf.NotAfter
+		}
+	}
+	return earliest
+}
+
+func getClientTokenGetter() func() (string, error) {
+	tlsConfigLock.RLock()
+	defer tlsConfigLock.RUnlock()
+	return clientTokenGetter
+}
+
+func getRevocationList() *RevocationList {
+	tlsConfigLock.RLock()
+	defer tlsConfigLock.RUnlock()
+	return revocationList
+}
+
+func getServerCertExpiration() time.Time {
+	config, _, _ := getServerTlsConfig()
+	return getEarliestCertExpiration(config)
+}
+
+// getServerTlsConfig returns the server TLS configuration, the full auth CA
+// certificate pool and whether TLS is required. These are consistent with
+// each other.
+func getServerTlsConfig() (*tls.Config, *x509.CertPool, bool) {
+	tlsConfigLock.RLock()
+	defer tlsConfigLock.RUnlock()
+	return serverTlsConfig, fullAuthCaCertPool, tlsRequired
+}
+
+func getTokenVerifier() TokenVerifier {
+	tlsConfigLock.RLock()
+	defer tlsConfigLock.RUnlock()
+	return tokenVerifier
+}
+
+func registerClientTlsConfig(config *tls.Config) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	clientTlsConfig = config
+}
+
+func registerClientTokenGetter(getter func() (string, error)) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	clientTokenGetter = getter
+}
+
+func registerFullAuthCA(certPool *x509.CertPool) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	fullAuthCaCertPool = certPool
+}
+
+func registerRevocationList(list *RevocationList) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	revocationList = list
+}
+
+func registerServerTlsConfig(config *tls.Config, requireTls bool) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	serverTlsConfig = config
+	tlsRequired = requireTls
+}
+
+func registerServerTlsConfigWithFullAuthCA(config *tls.Config,
+	certPool *x509.CertPool, requireTls bool) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	serverTlsConfig = config
+	fullAuthCaCertPool = certPool
+	tlsRequired = requireTls
+}
+
+func registerTokenVerifier(verifier TokenVerifier) {
+	tlsConfigLock.Lock()
+	defer tlsConfigLock.Unlock()
+	tokenVerifier = verifier
+}
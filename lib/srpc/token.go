@@ -0,0 +1,80 @@
+package srpc
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	maxTokenLength   = 64 << 10
+	tokenAuthHeader  = "X-Srpc-Token-Auth"
+	tokenAuthVersion = "1"
+)
+
+var errorLineTooLong = errors.New("line too long")
+
+// readLine reads a line, excluding the trailing newline character. Data are
+// read one byte at a time so that no data after the line are consumed.
+func readLine(reader io.Reader, maxLength int) (string, error) {
+	var builder strings.Builder
+	buffer := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(reader, buffer); err != nil {
+			return "", err
+		}
+		if buffer[0] == '\n' {
+			return builder.String(), nil
+		}
+		if builder.Len() >= maxLength {
+			return "", errorLineTooLong
+		}
+		builder.WriteByte(buffer[0])
+	}
+}
+
+// receiveToken reads a bearer token from the client, verifies it and sends the
+// response. The identity in the token is returned.
+func receiveToken(rw *bufio.ReadWriter,
+	verifier TokenVerifier) (*TokenIdentity, error) {
+	token, err := readLine(rw, maxTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := verifier.VerifyToken(strings.TrimSpace(token))
+	if err == nil && identity.Username == "" {
+		err = errors.New("no username in token")
+	}
+	if err != nil {
+		rw.WriteString(strings.Replace(err.Error(), "\n", " ", -1) + "\n")
+		rw.Flush()
+		return nil, err
+	}
+	if _, err := rw.WriteString("\n"); err != nil {
+		return nil, err
+	}
+	return identity, rw.Flush()
+}
+
+// sendToken sends a bearer token to the server and waits for the response.
+func sendToken(conn io.ReadWriter, getter func() (string, error)) error {
+	token, err := getter()
+	if err != nil {
+		return errors.New("error getting token: " + err.Error())
+	}
+	if token == "" || strings.ContainsAny(token, "\r\n") {
+		return errors.New("bad token")
+	}
+	if _, err := io.WriteString(conn, token+"\n"); err != nil {
+		return err
+	}
+	response, err := readLine(conn, maxTokenLength)
+	if err != nil {
+		return err
+	}
+	if response != "" {
+		return errors.New("token rejected: " + response)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package srpc
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+type testTokenVerifier struct{}
+
+func (testTokenVerifier) VerifyToken(token string) (*TokenIdentity, error) {
+	if token != "good" {
+		return nil, errors.New("bad token")
+	}
+	return &TokenIdentity{
+		PermittedMethods: map[string]struct{}{"Test.Username": {}},
+		Username:         "alice",
+	}, nil
+}
+
+func (t *serverType) Username(conn *Conn, request test.EchoRequest,
+	response *test.EchoResponse) error {
+	*response = test.EchoResponse{Response: conn.GetAuthInformation().Username}
+	return nil
+}
+
+func makeTlsListener(t *testing.T) net.Addr {
+	caCert, caKey := makeTestCertificate(t, "CA", 1, nil, nil)
+	cert, key := makeTestCertificate(t, "server", 2, caCert, caKey)
+	registerServerTlsConfig(&tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{cert.Raw},
+			Leaf:        cert,
+			PrivateKey:  key,
+		}},
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}, true)
+	listener, err := net.Listen("tcp", "localhost:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(tlsRpcPath, gobTlsHttpHandler)
+	go http.Serve(listener, serveMux)
+	return listener.Addr()
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	addr := makeTlsListener(t)
+	registerTokenVerifier(testTokenVerifier{})
+	defer func() {
+		registerClientTokenGetter(nil)
+		registerServerTlsConfig(nil, false)
+		registerTokenVerifier(nil)
+	}()
+	clientConfig := &tls.Config{InsecureSkipVerify: true}
+	// No certificate and no token.
+	client, err := DialTlsHTTP(addr.Network(), addr.String(), clientConfig, 0)
+	if err == nil {
+		if err := client.Ping(); err == nil {
+			t.Error("connection without certificate or token accepted")
+		}
+		client.Close()
+	}
+	// Bad token.
+	registerClientTokenGetter(func() (string, error) { return "bad", nil })
+	_, err = DialTlsHTTP(addr.Network(), addr.String(), clientConfig, 0)
+	if err == nil || !strings.Contains(err.Error(), "token rejected") {
+		t.Errorf("bad token not rejected: %v", err)
+	}
+	// Good token.
+	registerClientTokenGetter(func() (string, error) { return "good", nil })
+	client, err = DialTlsHTTP(addr.Network(), addr.String(), clientConfig, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var response test.EchoResponse
+	err = client.RequestReply("Test.Username", test.EchoRequest{}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "alice" {
+		t.Errorf("Username: %s != alice", response.Response)
+	}
+	err = client.RequestReply("Test.RequestReply", test.EchoRequest{},
+		&response)
+	if err == nil || err.Error() != ErrorAccessToMethodDenied.Error() {
+		t.Errorf("method not in token permitted: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package srpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
+)
+
+// Tracing is described in the package documentation.
+
+const (
+	traceHeader  = "X-Srpc-Trace"
+	traceVersion = "1"
+)
+
+// setSpanMethod sets the standard attributes for the method called.
+func setSpanMethod(span *trace.Span, serviceMethod string) {
+	span.SetAttribute("rpc.system", "srpc")
+	splitServiceMethod := strings.SplitN(serviceMethod, ".", 2)
+	span.SetAttribute("rpc.service", splitServiceMethod[0])
+	if len(splitServiceMethod) > 1 {
+		span.SetAttribute("rpc.method", splitServiceMethod[1])
+	}
+}
+
+// startClientSpan starts a span for a call if ctx contains a current span (so
+// that untraced programs do not start new traces).
+func startClientSpan(ctx context.Context, serviceMethod string) (
+	context.Context, *trace.Span) {
+	if serviceMethod == "" {
+		return ctx, nil
+	}
+	if _, ok := trace.FromContext(ctx); !ok {
+		return ctx, nil
+	}
+	ctx, span := trace.StartSpan(ctx, serviceMethod, trace.SpanKindClient)
+	setSpanMethod(span, serviceMethod)
+	return ctx, span
+}
+
+// startServerSpan starts a span for a call. If the client sent a trace parent,
+// the span is a child of the client span.
+func (conn *Conn) startServerSpan(ctx context.Context, serviceMethod string,
+	params callParametersType) (context.Context, *trace.Span) {
+	if params.traceParent.IsValid() {
+		ctx = trace.ContextWithRemoteParent(ctx, params.traceParent)
+	}
+	ctx, span := trace.StartSpan(ctx, serviceMethod, trace.SpanKindServer)
+	setSpanMethod(span, serviceMethod)
+	span.SetAttribute("client.address", conn.remoteAddr)
+	if conn.username != "" {
+		span.SetAttribute("enduser.id", conn.username)
+	}
+	return ctx, span
+}
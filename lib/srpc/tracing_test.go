@@ -0,0 +1,113 @@
+package srpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+type testExporter struct {
+	mutex sync.Mutex
+	spans []*trace.Span
+}
+
+var (
+	registerTestExporter sync.Once
+	spanExporter         = &testExporter{}
+)
+
+func (exporter *testExporter) ExportSpan(span *trace.Span) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	exporter.spans = append(exporter.spans, span)
+}
+
+// getSpans waits for the expected number of spans in the trace.
+func (exporter *testExporter) getSpans(traceId trace.TraceId,
+	expected int) []*trace.Span {
+	for timeout := time.After(time.Second); ; {
+		var spans []*trace.Span
+		exporter.mutex.Lock()
+		for _, span := range exporter.spans {
+			if span.TraceId == traceId {
+				spans = append(spans, span)
+			}
+		}
+		exporter.mutex.Unlock()
+		if len(spans) >= expected {
+			return spans
+		}
+		select {
+		case <-timeout:
+			return spans
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func testTracing(t *testing.T, client *Client) {
+	registerTestExporter.Do(func() { trace.RegisterExporter(spanExporter) })
+	client.tracing = true
+	ctx, root := trace.StartSpan(context.Background(), "test",
+		trace.SpanKindInternal)
+	var response test.EchoResponse
+	err := client.RequestReplyContext(ctx, "Test.RequestReply",
+		test.EchoRequest{Request: "test"}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RequestReplyContext(ctx, "Test.Unknown", test.EchoRequest{},
+		&response)
+	if err == nil {
+		t.Fatal("no failure when calling unknown method")
+	}
+	spans := spanExporter.getSpans(root.TraceId, 4)
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got: %d", len(spans))
+	}
+	clientSpans := make(map[trace.SpanId]*trace.Span)
+	for _, span := range spans {
+		if span.Kind == trace.SpanKindClient {
+			if span.ParentId != root.SpanId {
+				t.Errorf("client span: %s has wrong parent", span.Name)
+			}
+			clientSpans[span.SpanId] = span
+		}
+	}
+	for _, span := range spans {
+		if span.Kind != trace.SpanKindServer {
+			continue
+		}
+		clientSpan := clientSpans[span.ParentId]
+		if clientSpan == nil {
+			t.Errorf("server span: %s has no client parent", span.Name)
+			continue
+		}
+		if span.Name != clientSpan.Name {
+			t.Errorf("server span: %s != %s", span.Name, clientSpan.Name)
+		}
+		if (span.Error == nil) != (span.Name == "Test.RequestReply") {
+			t.Errorf("server span: %s has error: %v", span.Name, span.Error)
+		}
+		if (clientSpan.Error == nil) != (span.Error == nil) {
+			t.Errorf("client span: %s has error: %v",
+				clientSpan.Name, clientSpan.Error)
+		}
+	}
+}
+
+func TestTracingMultiplexed(t *testing.T) {
+	client := makeMultiplexedClientServer(&gobCoder{})
+	defer client.Close()
+	testTracing(t, client)
+}
+
+func TestTracingPlain(t *testing.T) {
+	client := makeClientServer(&gobCoder{})
+	defer client.Close()
+	testTracing(t, client)
+}
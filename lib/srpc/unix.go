@@ -0,0 +1,137 @@
+package srpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/wsyscall"
+)
+
+const unixAddressPrefix = "unix://"
+
+type unixPeerKeyType struct{}
+
+type unixPeerType struct {
+	groupList        map[string]struct{}
+	permittedMethods map[string]struct{} // nil: all permitted.
+	pid              int32
+	username         string
+}
+
+var unixPeerKey unixPeerKeyType
+
+// getUnixPeer returns the peer for a request received on a Unix socket, else
+// nil.
+func getUnixPeer(req *http.Request) *unixPeerType {
+	peer, _ := req.Context().Value(unixPeerKey).(*unixPeerType)
+	return peer
+}
+
+func isUnixAddress(address string) bool {
+	return strings.HasPrefix(address, unixAddressPrefix)
+}
+
+func joinHostPort(host string, port uint) string {
+	if isUnixAddress(host) {
+		return host
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// lookupUnixPeer looks up the credentials of the peer connected to conn. The
+// root user and the user the server is running as may call all methods, other
+// users may only call public methods or methods granted by the receiver.
+func lookupUnixPeer(conn *net.UnixConn) (*unixPeerType, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var ucred wsyscall.Ucred
+	err = rawConn.Control(func(fd uintptr) {
+		err = wsyscall.GetPeerCredentials(int(fd), &ucred)
+	})
+	if err != nil {
+		return nil, err
+	}
+	userInfo, err := user.LookupId(strconv.FormatUint(uint64(ucred.Uid), 10))
+	if err != nil {
+		return nil, err
+	}
+	groupIds, err := userInfo.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+	peer := &unixPeerType{
+		groupList: make(map[string]struct{}, len(groupIds)),
+		pid:       ucred.Pid,
+		username:  userInfo.Username,
+	}
+	for _, groupId := range groupIds {
+		if group, err := user.LookupGroupId(groupId); err == nil {
+			peer.groupList[group.Name] = struct{}{}
+		}
+	}
+	if ucred.Uid != 0 && int(ucred.Uid) != os.Getuid() {
+		peer.permittedMethods = make(map[string]struct{})
+	}
+	return peer, nil
+}
+
+func startUnixServer(path string, perm os.FileMode) (io.Closer, error) {
+	if !strings.HasPrefix(path, "@") {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(path, "@") {
+		if err := os.Chmod(path, perm); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	// Only the SRPC handlers are served, since other handlers registered with
+	// the default mux do not check the peer credentials.
+	serveMux := http.NewServeMux()
+	registerHttpHandlers(serveMux)
+	server := &http.Server{ConnContext: unixConnContext, Handler: serveMux}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("error serving on: %s: %s\n", path, err)
+		}
+	}()
+	return server, nil
+}
+
+// unixConnContext adds the peer credentials to the context for the
+// connection. If they cannot be determined, the connection is closed.
+func unixConnContext(ctx context.Context, conn net.Conn) context.Context {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		conn.Close()
+		return ctx
+	}
+	peer, err := lookupUnixPeer(unixConn)
+	if err != nil {
+		log.Printf("error getting Unix socket peer: %s\n", err)
+		conn.Close()
+		return ctx
+	}
+	return context.WithValue(ctx, unixPeerKey, peer)
+}
+
+func (peer *unixPeerType) remoteAddr() string {
+	return fmt.Sprintf("unix:pid=%d", peer.pid)
+}
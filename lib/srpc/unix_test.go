@@ -0,0 +1,121 @@
+package srpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/proto/test"
+)
+
+func testUnixServer(t *testing.T, path string) {
+	client, err := dialHTTP("tcp", JoinHostPort(unixAddressPrefix+path, 1),
+		nil, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.SetKeepAlive(true); err != nil {
+		t.Fatal(err)
+	}
+	userInfo, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response test.EchoResponse
+	err = client.RequestReply("Test.Username", test.EchoRequest{}, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != userInfo.Username {
+		t.Errorf("username: %s != %s", response.Response, userInfo.Username)
+	}
+}
+
+func TestJoinHostPort(t *testing.T) {
+	if address := JoinHostPort("localhost", 6969); address != "localhost:6969" {
+		t.Errorf("bad address: %s", address)
+	}
+	address := JoinHostPort("unix:///run/subd.sock", 6969)
+	if address != "unix:///run/subd.sock" {
+		t.Errorf("bad Unix address: %s", address)
+	}
+}
+
+func TestUnixServerAbstract(t *testing.T) {
+	// The name is unique so that the test may be repeated (-count) and run
+	// concurrently with other test binaries.
+	path := fmt.Sprintf("@srpc-test-%d-%d", os.Getpid(), time.Now().UnixNano())
+	closer, err := StartUnixServer(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	testUnixServer(t, path)
+}
+
+func TestUnixServerPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "srpc.sock")
+	closer, err := StartUnixServer(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	testUnixServer(t, path)
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("bad permissions: %s", fi.Mode())
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("socket not removed after close")
+	}
+}
+
+func TestUnixServerOnlySrpcHandlers(t *testing.T) {
+	const otherPath = "/_srpc_test_unix_other_"
+	http.HandleFunc(otherPath, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	path := filepath.Join(t.TempDir(), "srpc.sock")
+	closer, err := StartUnixServer(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	testUnixServer(t, path)
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network,
+				address string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	defer httpClient.CloseIdleConnections()
+	resp, err := httpClient.Get("http://localhost" + otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("handler from default mux served: %s", resp.Status)
+	}
+	resp, err = httpClient.Get("http://localhost" + listMethodsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("SRPC handler not served: %s", resp.Status)
+	}
+}
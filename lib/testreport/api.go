@@ -0,0 +1,54 @@
+package testreport
+
+import (
+	"io"
+	"time"
+)
+
+// Report contains the results of the tests run for an image build.
+type Report struct {
+	CreatedOn  time.Time
+	Failed     bool // True if any test failed which is not allowed to fail.
+	StreamName string
+	Tests      []Result
+}
+
+// Result contains the result of a single test.
+type Result struct {
+	Duration   time.Duration
+	Error      string `json:",omitempty"`
+	ExitStatus int
+	Flaky      bool `json:",omitempty"` // Failure allowed by policy.
+	Name       string
+	Output     string `json:",omitempty"`
+	Passed     bool
+	TimedOut   bool `json:",omitempty"`
+}
+
+// Summary contains the number of tests with each outcome.
+type Summary struct {
+	NumFailed   uint
+	NumFlaky    uint // Failed, but allowed to fail.
+	NumPassed   uint
+	NumTimedOut uint // Also counted in NumFailed or NumFlaky.
+}
+
+// Read will read a JSON encoded test report from reader.
+func Read(reader io.Reader) (*Report, error) {
+	return read(reader)
+}
+
+// Summarise returns the number of tests with each outcome.
+func (report *Report) Summarise() Summary {
+	return report.summarise()
+}
+
+// Write will write the report to writer in JSON format.
+func (report *Report) Write(writer io.Writer) error {
+	return report.write(writer)
+}
+
+// String returns a short description of the summary.
+func (summary Summary) String() string {
+	return summary.string()
+}
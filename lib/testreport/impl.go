@@ -0,0 +1,51 @@
+package testreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	libjson "github.com/Cloud-Foundations/Dominator/lib/json"
+)
+
+func read(reader io.Reader) (*Report, error) {
+	var report Report
+	decoder := json.NewDecoder(reader)
+	if err := decoder.Decode(&report); err != nil {
+		return nil, fmt.Errorf("error decoding test report: %s", err)
+	}
+	return &report, nil
+}
+
+func (report *Report) summarise() Summary {
+	var summary Summary
+	for _, result := range report.Tests {
+		if result.Passed {
+			summary.NumPassed++
+		} else if result.Flaky {
+			summary.NumFlaky++
+		} else {
+			summary.NumFailed++
+		}
+		if result.TimedOut {
+			summary.NumTimedOut++
+		}
+	}
+	return summary
+}
+
+func (report *Report) write(writer io.Writer) error {
+	return libjson.WriteWithIndent(writer, "    ", report)
+}
+
+func (summary Summary) string() string {
+	retval := fmt.Sprintf("%d passed, %d failed", summary.NumPassed,
+		summary.NumFailed)
+	if summary.NumFlaky > 0 {
+		retval += fmt.Sprintf(", %d flaky", summary.NumFlaky)
+	}
+	if summary.NumTimedOut > 0 {
+		retval += fmt.Sprintf(" (%d timed out)", summary.NumTimedOut)
+	}
+	return retval
+}
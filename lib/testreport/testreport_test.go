@@ -0,0 +1,66 @@
+package testreport
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testReport = &Report{
+	CreatedOn:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	Failed:     true,
+	StreamName: "test/stream",
+	Tests: []Result{
+		{Duration: time.Second, Name: "boot", Passed: true},
+		{
+			Duration:   10 * time.Second,
+			Error:      "timed out",
+			ExitStatus: -1,
+			Flaky:      true,
+			Name:       "network",
+			TimedOut:   true,
+		},
+		{
+			Duration:   2 * time.Second,
+			Error:      "exit status 1",
+			ExitStatus: 1,
+			Name:       "services",
+			Output:     "sshd not running\n",
+		},
+	},
+}
+
+func TestReadWrite(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	if err := testReport.Write(buffer); err != nil {
+		t.Fatal(err)
+	}
+	report, err := Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, testReport) {
+		t.Errorf("expected: %+v, got: %+v", testReport, report)
+	}
+	if _, err := Read(strings.NewReader("[]")); err == nil {
+		t.Error("no error reading bad report")
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	summary := testReport.Summarise()
+	expected := Summary{NumFailed: 1, NumFlaky: 1, NumPassed: 1,
+		NumTimedOut: 1}
+	if summary != expected {
+		t.Errorf("expected: %+v, got: %+v", expected, summary)
+	}
+	if str := summary.String(); str !=
+		"1 passed, 1 failed, 1 flaky (1 timed out)" {
+		t.Errorf("summary: %s", str)
+	}
+	if str := (Summary{NumPassed: 2}).String(); str != "2 passed, 0 failed" {
+		t.Errorf("summary: %s", str)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Package trace provides distributed tracing.
+
+Package trace records spans (timed operations) which together form a trace
+of an operation across processes. The current span is carried in a
+context.Context and is propagated between processes using the W3C
+traceparent format ("00-<trace ID>-<span ID>-<flags>"). Completed spans are
+passed to the registered exporters, which may write them in the OpenTelemetry
+(OTLP) JSON format to a file or send them to an OTLP/HTTP collector. The
+lib/log/tracelogger package creates loggers which add the trace and span IDs to
+log lines.
+*/
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+var (
+	exportersLock sync.RWMutex // Protects the following.
+	exporters     []Exporter
+)
+
+// Exporter is the interface for exporting completed spans. ExportSpan must
+// not block and must not modify the span.
+type Exporter interface {
+	ExportSpan(span *Span)
+}
+
+// Flusher is an optional interface for exporters which batch spans. Flush
+// must wait until the spans which have been passed to ExportSpan are exported.
+type Flusher interface {
+	Flush()
+}
+
+// Span records a timed operation. Spans are created with StartSpan and must be
+// ended with the End method.
+type Span struct {
+	Attributes map[string]string
+	EndTime    time.Time
+	Error      error // nil: the operation succeeded.
+	Kind       SpanKind
+	Name       string
+	ParentId   SpanId // Zero value: the span is the root of the trace.
+	SpanContext
+	StartTime time.Time
+	mutex     sync.Mutex // Protects Attributes and ended.
+	ended     bool
+}
+
+// End records the end time and error (nil if the operation succeeded) of the
+// span and exports it. Only the first call has any effect. It is safe to call
+// End for a nil span.
+func (span *Span) End(err error) {
+	span.end(err)
+}
+
+// SetAttribute sets an attribute of the span. It is safe to call
+// SetAttribute for a nil span.
+func (span *Span) SetAttribute(key, value string) {
+	span.setAttribute(key, value)
+}
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	SpanId  SpanId
+	TraceId TraceId
+}
+
+// ParseTraceParent parses a W3C traceparent value.
+func ParseTraceParent(value string) (SpanContext, error) {
+	return parseTraceParent(value)
+}
+
+// IsValid returns true if the trace and span IDs are not zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.SpanId != SpanId{} && sc.TraceId != TraceId{}
+}
+
+// TraceParent returns the span context in the W3C traceparent format.
+func (sc SpanContext) TraceParent() string {
+	return sc.traceParent()
+}
+
+// SpanId identifies a span.
+type SpanId [8]byte
+
+// String returns the span ID in hexadecimal.
+func (id SpanId) String() string {
+	return encodeHex(id[:])
+}
+
+// SpanKind describes the relationship of a span to its parent and children.
+type SpanKind uint
+
+// TraceId identifies a trace.
+type TraceId [16]byte
+
+// String returns the trace ID in hexadecimal.
+func (id TraceId) String() string {
+	return encodeHex(id[:])
+}
+
+// ContextWithRemoteParent returns a copy of ctx in which parent (typically
+// received from another process) is the current span.
+func ContextWithRemoteParent(ctx context.Context,
+	parent SpanContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, parent)
+}
+
+// Flush waits until the spans which have been ended are exported by the
+// registered exporters which support flushing. It should be called before a
+// short-lived program exits.
+func Flush() {
+	flush()
+}
+
+// FromContext returns the current span context in ctx. If there is none, false
+// is returned.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	return fromContext(ctx)
+}
+
+// NewFileExporter returns an Exporter which appends batches of spans to the
+// specified file in the OTLP JSON format, one batch per line (as read by the
+// OpenTelemetry Collector file receiver). The serviceName identifies the
+// process in the exported spans. Spans which cannot be written are logged to
+// the logger and dropped.
+func NewFileExporter(filename string, serviceName string,
+	logger log.Logger) (Exporter, error) {
+	return newFileExporter(filename, serviceName, logger)
+}
+
+// NewHttpExporter returns an Exporter which sends batches of spans in the OTLP
+// JSON format to an OTLP/HTTP collector at url (typically
+// "http://localhost:4318/v1/traces"). Spans which cannot be sent are logged to
+// the logger and dropped.
+func NewHttpExporter(url string, serviceName string,
+	logger log.Logger) Exporter {
+	return newHttpExporter(url, serviceName, logger)
+}
+
+// RegisterExporter registers an exporter for completed spans. Multiple
+// exporters may be registered.
+func RegisterExporter(exporter Exporter) {
+	registerExporter(exporter)
+}
+
+// StartSpan starts a new span with the specified name and kind. If ctx
+// contains a current span, the new span is its child, otherwise the new span
+// is the root of a new trace. A copy of ctx in which the new span is the
+// current span is returned.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (
+	context.Context, *Span) {
+	return startSpan(ctx, name, kind)
+}
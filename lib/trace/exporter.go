@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+const (
+	exportInterval = 5 * time.Second
+	maxBatchSize   = 512
+	queueLength    = 4096
+)
+
+type batchingExporter struct {
+	flushQueue  chan chan<- struct{}
+	logger      log.Logger
+	queue       chan *Span
+	serviceName string
+	write       func(data []byte) error
+}
+
+func newBatchingExporter(serviceName string, logger log.Logger,
+	write func(data []byte) error) *batchingExporter {
+	exporter := &batchingExporter{
+		flushQueue:  make(chan chan<- struct{}),
+		logger:      logger,
+		queue:       make(chan *Span, queueLength),
+		serviceName: serviceName,
+		write:       write,
+	}
+	go exporter.loop()
+	return exporter
+}
+
+func newFileExporter(filename string, serviceName string,
+	logger log.Logger) (Exporter, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0644)
+	if err != nil {
+		return nil, err
+	}
+	return newBatchingExporter(serviceName, logger,
+		func(data []byte) error {
+			_, err := file.Write(append(data, '\n'))
+			return err
+		}), nil
+}
+
+func newHttpExporter(url string, serviceName string,
+	logger log.Logger) Exporter {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return newBatchingExporter(serviceName, logger,
+		func(data []byte) error {
+			resp, err := client.Post(url, "application/json",
+				bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("%s: %s", url, resp.Status)
+			}
+			return nil
+		})
+}
+
+// ExportSpan queues the span for export. If the queue is full, the span is
+// dropped.
+func (exporter *batchingExporter) ExportSpan(span *Span) {
+	select {
+	case exporter.queue <- span:
+	default:
+	}
+}
+
+// Flush waits until the queued spans are exported.
+func (exporter *batchingExporter) Flush() {
+	done := make(chan struct{})
+	exporter.flushQueue <- done
+	<-done
+}
+
+func (exporter *batchingExporter) export(batch []*Span) {
+	data, err := encodeSpans(batch, exporter.serviceName)
+	if err == nil {
+		err = exporter.write(data)
+	}
+	if err != nil {
+		exporter.logger.Printf("error exporting %d spans: %s\n", len(batch),
+			err)
+	}
+}
+
+func (exporter *batchingExporter) loop() {
+	ticker := time.NewTicker(exportInterval)
+	var batch []*Span
+	for {
+		var done chan<- struct{}
+		select {
+		case span := <-exporter.queue:
+			batch = append(batch, span)
+			if len(batch) < maxBatchSize {
+				continue
+			}
+		case done = <-exporter.flushQueue:
+			for len(exporter.queue) > 0 {
+				batch = append(batch, <-exporter.queue)
+			}
+		case <-ticker.C:
+		}
+		if len(batch) > 0 {
+			exporter.export(batch)
+			batch = nil
+		}
+		if done != nil {
+			close(done)
+		}
+	}
+}
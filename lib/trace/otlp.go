@@ -0,0 +1,133 @@
+package trace
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The following types implement the OTLP JSON encoding of an
+// ExportTraceServiceRequest. IDs are hexadecimal and times are decimal strings
+// of nanoseconds since the Unix epoch.
+
+const (
+	otlpScopeName = "github.com/Cloud-Foundations/Dominator/lib/trace"
+
+	otlpStatusCodeOk    = 1
+	otlpStatusCodeError = 2
+)
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Kind              int             `json:"kind"`
+	Name              string          `json:"name"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	SpanId            string          `json:"spanId"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	Status            otlpStatus      `json:"status"`
+	TraceId           string          `json:"traceId"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func encodeAttributes(attributes map[string]string) []otlpAttribute {
+	if len(attributes) < 1 {
+		return nil
+	}
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		encoded = append(encoded, otlpAttribute{
+			Key:   key,
+			Value: otlpAnyValue{StringValue: attributes[key]},
+		})
+	}
+	return encoded
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	encoded := otlpSpan{
+		Attributes:        encodeAttributes(span.Attributes),
+		EndTimeUnixNano:   formatTime(span.EndTime),
+		Kind:              int(span.Kind) + 1, // OTLP 0 is unspecified.
+		Name:              span.Name,
+		SpanId:            span.SpanId.String(),
+		StartTimeUnixNano: formatTime(span.StartTime),
+		Status:            otlpStatus{Code: otlpStatusCodeOk},
+		TraceId:           span.TraceId.String(),
+	}
+	if span.ParentId != (SpanId{}) {
+		encoded.ParentSpanId = span.ParentId.String()
+	}
+	if span.Error != nil {
+		encoded.Status = otlpStatus{
+			Code:    otlpStatusCodeError,
+			Message: span.Error.Error(),
+		}
+	}
+	return encoded
+}
+
+// encodeSpans encodes spans as an OTLP JSON ExportTraceServiceRequest.
+func encodeSpans(spans []*Span, serviceName string) ([]byte, error) {
+	encodedSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		encodedSpans = append(encodedSpans, encodeSpan(span))
+	}
+	return json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: encodeAttributes(
+					map[string]string{"service.name": serviceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Spans: encodedSpans,
+			}},
+		}},
+	})
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
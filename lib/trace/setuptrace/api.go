@@ -0,0 +1,16 @@
+/*
+Package setuptrace registers the command-line flags which configure the export
+of trace spans and sets up the trace exporters.
+
+Both the SRPC client and server setup packages use this package, so a program
+may link either or both of them.
+*/
+package setuptrace
+
+// Setup registers the trace exporters configured by the -traceExportFile and
+// -traceExportUrl command-line flags (if any). It must be called after the
+// flags are parsed. Only the first call has any effect; later calls return the
+// error (if any) from the first call.
+func Setup() error {
+	return setup()
+}
@@ -0,0 +1,44 @@
+package setuptrace
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Cloud-Foundations/Dominator/lib/trace"
+)
+
+var (
+	traceExportFile = flag.String("traceExportFile", "",
+		"Name of file to append trace spans to (OTLP JSON)")
+	traceExportUrl = flag.String("traceExportUrl", "",
+		"URL of OTLP/HTTP collector to export trace spans to")
+
+	setupOnce  sync.Once
+	setupError error
+)
+
+func setup() error {
+	setupOnce.Do(func() { setupError = setupExporters() })
+	return setupError
+}
+
+func setupExporters() error {
+	serviceName := filepath.Base(os.Args[0])
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	if *traceExportFile != "" {
+		exporter, err := trace.NewFileExporter(*traceExportFile, serviceName,
+			logger)
+		if err != nil {
+			return err
+		}
+		trace.RegisterExporter(exporter)
+	}
+	if *traceExportUrl != "" {
+		trace.RegisterExporter(trace.NewHttpExporter(*traceExportUrl,
+			serviceName, logger))
+	}
+	return nil
+}
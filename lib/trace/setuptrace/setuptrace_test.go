@@ -0,0 +1,26 @@
+package setuptrace_test
+
+import (
+	"flag"
+	"testing"
+
+	_ "github.com/Cloud-Foundations/Dominator/lib/srpc/setupclient"
+	_ "github.com/Cloud-Foundations/Dominator/lib/srpc/setupserver"
+	"github.com/Cloud-Foundations/Dominator/lib/trace/setuptrace"
+)
+
+// Linking both the SRPC client and server setup packages must not register
+// the trace flags twice (which panics during initialisation).
+func TestSetup(t *testing.T) {
+	for _, name := range []string{"traceExportFile", "traceExportUrl"} {
+		if flag.Lookup(name) == nil {
+			t.Errorf("-%s not registered", name)
+		}
+	}
+	if err := setuptrace.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	if err := setuptrace.Setup(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+type contextKey struct{}
+
+func encodeHex(data []byte) string {
+	return hex.EncodeToString(data)
+}
+
+func flush() {
+	for _, exporter := range getExporters() {
+		if flusher, ok := exporter.(Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+func fromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(contextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+func getExporters() []Exporter {
+	exportersLock.RLock()
+	defer exportersLock.RUnlock()
+	return exporters
+}
+
+// parseHex decodes value (which must be lower case hexadecimal) into id and
+// returns an error if it is the wrong length or is zero.
+func parseHex(id []byte, value string) error {
+	if len(value) != hex.EncodedLen(len(id)) ||
+		strings.ToLower(value) != value {
+		return errors.New("bad ID: " + value)
+	}
+	if _, err := hex.Decode(id, []byte(value)); err != nil {
+		return err
+	}
+	for _, b := range id {
+		if b != 0 {
+			return nil
+		}
+	}
+	return errors.New("zero ID")
+}
+
+func parseTraceParent(value string) (SpanContext, error) {
+	var sc SpanContext
+	fields := strings.Split(value, "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" {
+		return sc, errors.New("bad traceparent: " + value)
+	}
+	if fields[0] == "00" && len(fields) != 4 {
+		return sc, errors.New("bad traceparent: " + value)
+	}
+	if err := parseHex(sc.TraceId[:], fields[1]); err != nil {
+		return sc, err
+	}
+	if err := parseHex(sc.SpanId[:], fields[2]); err != nil {
+		return sc, err
+	}
+	return sc, nil
+}
+
+func registerExporter(exporter Exporter) {
+	exportersLock.Lock()
+	defer exportersLock.Unlock()
+	exporters = append(exporters, exporter)
+}
+
+func startSpan(ctx context.Context, name string, kind SpanKind) (
+	context.Context, *Span) {
+	span := &Span{
+		Kind:      kind,
+		Name:      name,
+		StartTime: time.Now(),
+	}
+	if parent, ok := fromContext(ctx); ok {
+		span.ParentId = parent.SpanId
+		span.TraceId = parent.TraceId
+	} else {
+		rand.Read(span.TraceId[:])
+	}
+	rand.Read(span.SpanId[:])
+	return context.WithValue(ctx, contextKey{}, span.SpanContext), span
+}
+
+func (span *Span) end(err error) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.EndTime = time.Now()
+	span.Error = err
+	span.mutex.Unlock()
+	for _, exporter := range getExporters() {
+		exporter.ExportSpan(span)
+	}
+}
+
+func (span *Span) setAttribute(key, value string) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	if span.ended {
+		return
+	}
+	if span.Attributes == nil {
+		span.Attributes = make(map[string]string)
+	}
+	span.Attributes[key] = value
+}
+
+func (sc SpanContext) traceParent() string {
+	return "00-" + sc.TraceId.String() + "-" + sc.SpanId.String() + "-01"
+}
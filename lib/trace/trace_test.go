@@ -0,0 +1,128 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/log/testlogger"
+)
+
+func TestStartSpan(t *testing.T) {
+	ctx, root := StartSpan(context.Background(), "root", SpanKindInternal)
+	if root.ParentId != (SpanId{}) || !root.IsValid() {
+		t.Fatalf("bad root span: %+v", root.SpanContext)
+	}
+	if sc, ok := FromContext(ctx); !ok || sc != root.SpanContext {
+		t.Fatal("root span not in context")
+	}
+	_, child := StartSpan(ctx, "child", SpanKindClient)
+	if child.TraceId != root.TraceId || child.ParentId != root.SpanId {
+		t.Errorf("bad child span: %+v", child)
+	}
+	if child.SpanId == root.SpanId {
+		t.Error("child has same span ID as parent")
+	}
+	child.SetAttribute("key", "value")
+	child.End(errors.New("failed"))
+	child.End(nil)
+	child.SetAttribute("key", "changed")
+	if child.Error == nil || child.Attributes["key"] != "value" {
+		t.Error("span modified after End")
+	}
+	var nilSpan *Span
+	nilSpan.SetAttribute("key", "value")
+	nilSpan.End(nil)
+}
+
+func TestTraceParent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceId.String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		sc.SpanId.String() != "00f067aa0ba902b7" {
+		t.Errorf("bad parse: %+v", sc)
+	}
+	if sc.TraceParent() != value {
+		t.Errorf("TraceParent: %s != %s", sc.TraceParent(), value)
+	}
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceParent(value); err == nil {
+			t.Errorf("bad traceparent accepted: %s", value)
+		}
+	}
+}
+
+func TestEncodeSpans(t *testing.T) {
+	ctx, parent := StartSpan(context.Background(), "parent", SpanKindServer)
+	_, child := StartSpan(ctx, "child", SpanKindClient)
+	child.SetAttribute("rpc.method", "Poll")
+	child.End(errors.New("failed"))
+	parent.End(nil)
+	data, err := encodeSpans([]*Span{child, parent}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request otlpRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	resourceSpans := request.ResourceSpans[0]
+	if resourceSpans.Resource.Attributes[0].Value.StringValue != "test" {
+		t.Errorf("bad resource: %+v", resourceSpans.Resource)
+	}
+	spans := resourceSpans.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got: %d", len(spans))
+	}
+	if spans[0].ParentSpanId != parent.SpanId.String() ||
+		spans[0].TraceId != parent.TraceId.String() {
+		t.Errorf("bad child span: %+v", spans[0])
+	}
+	if spans[0].Status.Code != otlpStatusCodeError ||
+		spans[0].Status.Message != "failed" {
+		t.Errorf("bad child status: %+v", spans[0].Status)
+	}
+	if spans[0].Kind != 3 || spans[1].Kind != 2 {
+		t.Errorf("bad kinds: %d, %d", spans[0].Kind, spans[1].Kind)
+	}
+	if spans[1].ParentSpanId != "" || spans[1].Status.Code != otlpStatusCodeOk {
+		t.Errorf("bad parent span: %+v", spans[1])
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "spans")
+	exporter, err := NewFileExporter(filename, "test", testlogger.New(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := StartSpan(context.Background(), "span", SpanKindInternal)
+	span.End(nil)
+	exporter.ExportSpan(span)
+	exporter.(Flusher).Flush()
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request otlpRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].SpanId != span.SpanId.String() {
+		t.Errorf("bad exported spans: %+v", spans)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Package vulndb matches package lists against an offline vulnerability feed.
+
+The feed contains advisories in the Open Source Vulnerability (OSV) format.
+It may be a single JSON advisory, a JSON array of advisories, a stream of
+JSON advisories, a ZIP archive of JSON advisories (such as the all.zip
+files published by OSV) or a directory of JSON advisory files.
+Advisories are matched against packages by package name and, if known (such
+as from an SBOM), by source package name, since Debian and Ubuntu advisories
+name source packages. Versions are compared using the Debian version
+comparison rules, which also work for RPM package versions.
+*/
+package vulndb
+
+import (
+	"io"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+)
+
+type Advisory struct {
+	Affected  []AffectedPackage `json:"affected,omitempty"`
+	Aliases   []string          `json:"aliases,omitempty"`
+	Id        string            `json:"id"`
+	Summary   string            `json:"summary,omitempty"`
+	Withdrawn string            `json:"withdrawn,omitempty"`
+}
+
+type AffectedPackage struct {
+	Package  PackageId `json:"package"`
+	Ranges   []Range   `json:"ranges,omitempty"`
+	Versions []string  `json:"versions,omitempty"`
+}
+
+type Database struct {
+	advisories map[string]*Advisory         // Key: advisory ID.
+	packages   map[string][]packageAdvisory // Key: package name.
+}
+
+type Event struct {
+	Fixed        string `json:"fixed,omitempty"`
+	Introduced   string `json:"introduced,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+type loadingDatabase struct {
+	Database
+	ecosystems map[string]struct{}
+}
+
+// Match describes a package which is affected by an advisory.
+type Match struct {
+	AdvisoryId     string
+	FixedVersion   string `json:",omitempty"` // Empty if no fix is known.
+	PackageName    string
+	PackageVersion string
+	SourcePackage  string `json:",omitempty"` // If matched by source package.
+	Summary        string `json:",omitempty"`
+}
+
+type PackageId struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+type Range struct {
+	Events []Event `json:"events"`
+	Type   string  `json:"type"`
+}
+
+type packageAdvisory struct {
+	advisory *Advisory
+	affected *AffectedPackage
+}
+
+// Load loads a vulnerability feed from a file or directory. Only advisories
+// for packages in the specified ecosystems (such as "Debian" or "Ubuntu") are
+// loaded. At least one ecosystem must be specified, since package names in
+// different ecosystems (such as PyPI and Debian) are unrelated.
+func Load(pathname string, ecosystems []string) (*Database, error) {
+	return load(pathname, ecosystems)
+}
+
+// Read reads a vulnerability feed. Only advisories for packages in the
+// specified ecosystems are loaded.
+func Read(reader io.Reader, ecosystems []string) (*Database, error) {
+	return read(reader, ecosystems)
+}
+
+// WatchFeed watches the feed file given by pathname and yields a new Database
+// each time the file is replaced. Any errors are logged.
+func WatchFeed(pathname string, ecosystems []string,
+	logger log.DebugLogger) <-chan *Database {
+	return watchFeed(pathname, ecosystems, logger)
+}
+
+// CompareVersions compares two package versions and returns a negative
+// number, zero or a positive number if left is lesser than, equal to or
+// greater than right.
+func CompareVersions(left, right string) int {
+	return compareVersions(left, right)
+}
+
+func (db *Database) GetAdvisory(id string) *Advisory {
+	return db.advisories[id]
+}
+
+// Match returns the matches for the packages, sorted by advisory ID and
+// package name. Since the source packages are not known, advisories which name
+// source packages may be missed: use MatchSBOM if an SBOM is available.
+func (db *Database) Match(packages []image.Package) []Match {
+	return db.match(packages)
+}
+
+// MatchSBOM returns the matches for the packages in the SBOM, sorted by
+// advisory ID and package name. Packages are matched by name and by source
+// package name.
+func (db *Database) MatchSBOM(bom *sbom.SBOM) []Match {
+	return db.matchSBOM(bom)
+}
+
+func (db *Database) NumAdvisories() uint {
+	return uint(len(db.advisories))
+}
@@ -0,0 +1,185 @@
+package vulndb
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+)
+
+var (
+	errNoEcosystems = errors.New("no ecosystems specified")
+	zipMagic        = []byte("PK\x03\x04")
+)
+
+func load(pathname string, ecosystems []string) (*Database, error) {
+	if len(ecosystems) < 1 {
+		return nil, errNoEcosystems
+	}
+	fi, err := os.Stat(pathname)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		file, err := os.Open(pathname)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return read(file, ecosystems)
+	}
+	names, err := fsutil.ReadDirnames(pathname, false)
+	if err != nil {
+		return nil, err
+	}
+	db := newDatabase(ecosystems)
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(pathname, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := db.decodeStream(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("error decoding: %s: %s", name, err)
+		}
+	}
+	return db.finish(), nil
+}
+
+func newDatabase(ecosystems []string) *loadingDatabase {
+	db := &loadingDatabase{
+		Database: Database{
+			advisories: make(map[string]*Advisory),
+			packages:   make(map[string][]packageAdvisory),
+		},
+		ecosystems: make(map[string]struct{}, len(ecosystems)),
+	}
+	for _, ecosystem := range ecosystems {
+		db.ecosystems[ecosystem] = struct{}{}
+	}
+	return db
+}
+
+func read(reader io.Reader, ecosystems []string) (*Database, error) {
+	if len(ecosystems) < 1 {
+		return nil, errNoEcosystems
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	db := newDatabase(ecosystems)
+	if !bytes.HasPrefix(data, zipMagic) {
+		if err := db.decodeStream(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		return db.finish(), nil
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range zipReader.File {
+		if !strings.HasSuffix(file.Name, ".json") {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = db.decodeStream(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding: %s: %s", file.Name, err)
+		}
+	}
+	return db.finish(), nil
+}
+
+func (db *loadingDatabase) add(advisory *Advisory) {
+	if advisory.Id == "" || advisory.Withdrawn != "" {
+		return
+	}
+	var affected []AffectedPackage
+	for _, pkg := range advisory.Affected {
+		if db.wantEcosystem(pkg.Package.Ecosystem) {
+			affected = append(affected, pkg)
+		}
+	}
+	if len(affected) < 1 {
+		return
+	}
+	if _, ok := db.advisories[advisory.Id]; ok {
+		return
+	}
+	advisory.Affected = affected
+	db.advisories[advisory.Id] = advisory
+	for index := range advisory.Affected {
+		pkg := &advisory.Affected[index]
+		db.packages[pkg.Package.Name] = append(db.packages[pkg.Package.Name],
+			packageAdvisory{advisory: advisory, affected: pkg})
+	}
+}
+
+// decodeStream decodes a single advisory, an array of advisories or a sequence
+// of advisories.
+func (db *loadingDatabase) decodeStream(reader io.Reader) error {
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		ch, _, err := bufferedReader.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' {
+			continue
+		}
+		bufferedReader.UnreadRune()
+		break
+	}
+	decoder := json.NewDecoder(bufferedReader)
+	if ch, err := bufferedReader.Peek(1); err == nil && ch[0] == '[' {
+		var advisories []*Advisory
+		if err := decoder.Decode(&advisories); err != nil {
+			return err
+		}
+		for _, advisory := range advisories {
+			db.add(advisory)
+		}
+		return nil
+	}
+	for {
+		var advisory Advisory
+		if err := decoder.Decode(&advisory); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		db.add(&advisory)
+	}
+}
+
+func (db *loadingDatabase) finish() *Database {
+	return &db.Database
+}
+
+// wantEcosystem returns true if the ecosystem is wanted. Ecosystems may have
+// a release suffix (such as "Debian:11") which is ignored.
+func (db *loadingDatabase) wantEcosystem(ecosystem string) bool {
+	_, ok := db.ecosystems[strings.SplitN(ecosystem, ":", 2)[0]]
+	return ok
+}
@@ -0,0 +1,135 @@
+package vulndb
+
+import (
+	"sort"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+)
+
+// isAffected returns true if the version is affected, along with the lowest
+// fixed version greater than the version, if known.
+func (pkg *AffectedPackage) isAffected(version string) (bool, string) {
+	for _, affectedVersion := range pkg.Versions {
+		if compareVersions(version, affectedVersion) == 0 {
+			return true, ""
+		}
+	}
+	for _, versionRange := range pkg.Ranges {
+		if versionRange.Type == "GIT" {
+			continue
+		}
+		if affected, fixed := versionRange.isAffected(version); affected {
+			return true, fixed
+		}
+	}
+	return false, ""
+}
+
+// isAffected evaluates the events in version order. The version is affected
+// if the last event at or below it is an introduction.
+func (versionRange *Range) isAffected(version string) (bool, string) {
+	affected := false
+	var fixedVersion string
+	events := make([]Event, len(versionRange.Events))
+	copy(events, versionRange.Events)
+	sort.SliceStable(events, func(left, right int) bool {
+		return compareVersions(events[left].version(),
+			events[right].version()) < 0
+	})
+	for _, event := range events {
+		switch {
+		case event.Introduced != "":
+			if event.Introduced == "0" ||
+				compareVersions(version, event.Introduced) >= 0 {
+				affected = true
+				fixedVersion = ""
+			}
+		case event.Fixed != "":
+			if compareVersions(version, event.Fixed) >= 0 {
+				affected = false
+			} else if affected && fixedVersion == "" {
+				fixedVersion = event.Fixed
+			}
+		case event.LastAffected != "":
+			if compareVersions(version, event.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected, fixedVersion
+}
+
+func (event Event) version() string {
+	switch {
+	case event.Introduced != "":
+		return event.Introduced
+	case event.Fixed != "":
+		return event.Fixed
+	}
+	return event.LastAffected
+}
+
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(left, right int) bool {
+		if matches[left].AdvisoryId != matches[right].AdvisoryId {
+			return matches[left].AdvisoryId < matches[right].AdvisoryId
+		}
+		return matches[left].PackageName < matches[right].PackageName
+	})
+}
+
+func (db *Database) match(packages []image.Package) []Match {
+	var matches []Match
+	for _, pkg := range packages {
+		matches = db.matchPackage(matches, pkg.Name, "", pkg.Version)
+	}
+	sortMatches(matches)
+	return matches
+}
+
+// matchPackage appends the matches for a package to matches. Advisories for
+// the package name and for the source package name (origin) are matched. The
+// binary package version is used, which is the same as the source package
+// version (apart from binary-only rebuilds).
+func (db *Database) matchPackage(matches []Match,
+	name, origin, version string) []Match {
+	names := []string{name}
+	if origin != "" && origin != name {
+		names = append(names, origin)
+	}
+	matched := make(map[string]struct{})
+	for _, matchName := range names {
+		for _, pkgAdvisory := range db.packages[matchName] {
+			if _, ok := matched[pkgAdvisory.advisory.Id]; ok {
+				continue
+			}
+			affected, fixed := pkgAdvisory.affected.isAffected(version)
+			if !affected {
+				continue
+			}
+			matched[pkgAdvisory.advisory.Id] = struct{}{}
+			match := Match{
+				AdvisoryId:     pkgAdvisory.advisory.Id,
+				FixedVersion:   fixed,
+				PackageName:    name,
+				PackageVersion: version,
+				Summary:        pkgAdvisory.advisory.Summary,
+			}
+			if matchName != name {
+				match.SourcePackage = matchName
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+func (db *Database) matchSBOM(bom *sbom.SBOM) []Match {
+	var matches []Match
+	for _, pkg := range bom.Packages {
+		matches = db.matchPackage(matches, pkg.Name, pkg.Origin, pkg.Version)
+	}
+	sortMatches(matches)
+	return matches
+}
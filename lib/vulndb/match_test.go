@@ -0,0 +1,148 @@
+package vulndb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Cloud-Foundations/Dominator/lib/image"
+	"github.com/Cloud-Foundations/Dominator/lib/sbom"
+)
+
+const testFeed = `[
+{
+  "id": "DSA-1",
+  "summary": "openssl vulnerability",
+  "affected": [{
+    "package": {"ecosystem": "Debian:11", "name": "openssl"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [
+      {"introduced": "0"}, {"fixed": "1.1.1n-0+deb11u4"}]}]
+  }]
+},
+{
+  "id": "DSA-2",
+  "affected": [{
+    "package": {"ecosystem": "Debian:11", "name": "curl"},
+    "versions": ["7.74.0-1.3+deb11u1"]
+  }]
+},
+{
+  "id": "PYSEC-1",
+  "affected": [{
+    "package": {"ecosystem": "PyPI", "name": "openssl"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+  }]
+},
+{
+  "id": "RHSA-1",
+  "affected": [{
+    "package": {"ecosystem": "Red Hat", "name": "bash"},
+    "ranges": [{"type": "ECOSYSTEM", "events": [
+      {"introduced": "4.0-1"}, {"last_affected": "4.2-5"}]}]
+  }]
+}
+]`
+
+func TestCompareVersions(t *testing.T) {
+	var tests = []struct {
+		left, right string
+		want        int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0a", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u4", -1},
+		{"4.2_5", "4.2-5", 0},
+		{"007", "7", 0},
+	}
+	for _, test := range tests {
+		got := CompareVersions(test.left, test.right)
+		if got > 0 {
+			got = 1
+		} else if got < 0 {
+			got = -1
+		}
+		if got != test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d", test.left, test.right,
+				got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	db, err := Read(strings.NewReader(testFeed), []string{"Debian", "Red Hat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num := db.NumAdvisories(); num != 3 {
+		t.Fatalf("number of advisories: %d != 3", num)
+	}
+	matches := db.Match([]image.Package{
+		{Name: "bash", Version: "4.2_5"},
+		{Name: "curl", Version: "7.74.0-1.3+deb11u1"},
+		{Name: "openssl", Version: "1.1.1n-0+deb11u3"},
+	})
+	if len(matches) != 3 {
+		t.Fatalf("number of matches: %d != 3: %v", len(matches), matches)
+	}
+	if matches[0].AdvisoryId != "DSA-1" ||
+		matches[0].FixedVersion != "1.1.1n-0+deb11u4" {
+		t.Errorf("bad match: %v", matches[0])
+	}
+	if matches[2].AdvisoryId != "RHSA-1" {
+		t.Errorf("bad match: %v", matches[2])
+	}
+	matches = db.Match([]image.Package{
+		{Name: "bash", Version: "4.2_6"},
+		{Name: "curl", Version: "7.74.0-1.3+deb11u2"},
+		{Name: "openssl", Version: "1.1.1n-0+deb11u4"},
+	})
+	if len(matches) != 0 {
+		t.Errorf("unexpected matches: %v", matches)
+	}
+}
+
+func TestMatchSBOM(t *testing.T) {
+	db, err := Read(strings.NewReader(testFeed), []string{"Debian"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := db.MatchSBOM(&sbom.SBOM{
+		Packages: []sbom.Package{
+			{Name: "curl", Origin: "curl", Version: "7.74.0-1.3+deb11u1"},
+			{Name: "libssl1.1", Origin: "openssl",
+				Version: "1.1.1n-0+deb11u3"},
+			{Name: "openssl", Origin: "openssl", Version: "1.1.1n-0+deb11u4"},
+		},
+	})
+	if len(matches) != 2 {
+		t.Fatalf("number of matches: %d != 2: %v", len(matches), matches)
+	}
+	if matches[0].AdvisoryId != "DSA-1" ||
+		matches[0].PackageName != "libssl1.1" ||
+		matches[0].SourcePackage != "openssl" {
+		t.Errorf("bad match: %v", matches[0])
+	}
+	if matches[1].AdvisoryId != "DSA-2" || matches[1].SourcePackage != "" {
+		t.Errorf("bad match: %v", matches[1])
+	}
+}
+
+func TestNoEcosystems(t *testing.T) {
+	if _, err := Read(strings.NewReader(testFeed), nil); err == nil {
+		t.Error("no error reading feed without ecosystems")
+	}
+	db, err := Read(strings.NewReader(testFeed), []string{"Debian"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := db.Match([]image.Package{{Name: "openssl", Version: "1.0"}})
+	for _, match := range matches {
+		if match.AdvisoryId == "PYSEC-1" {
+			t.Errorf("matched advisory from unwanted ecosystem: %v", match)
+		}
+	}
+}
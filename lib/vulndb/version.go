@@ -0,0 +1,102 @@
+package vulndb
+
+import (
+	"strings"
+)
+
+// compareVersions compares versions using the Debian rules. Underscores are
+// treated as hyphens, since RPM package versions may be listed as
+// VERSION_RELEASE.
+func compareVersions(left, right string) int {
+	left = strings.Replace(left, "_", "-", -1)
+	right = strings.Replace(right, "_", "-", -1)
+	leftEpoch, left := splitEpoch(left)
+	rightEpoch, right := splitEpoch(right)
+	if result := compareNumbers(leftEpoch, rightEpoch); result != 0 {
+		return result
+	}
+	leftUpstream, leftRevision := splitRevision(left)
+	rightUpstream, rightRevision := splitRevision(right)
+	if result := compareFragments(leftUpstream, rightUpstream); result != 0 {
+		return result
+	}
+	return compareFragments(leftRevision, rightRevision)
+}
+
+// compareFragments compares alternating non-digit and digit substrings.
+func compareFragments(left, right string) int {
+	for left != "" || right != "" {
+		for (left != "" && !isDigit(left[0])) ||
+			(right != "" && !isDigit(right[0])) {
+			leftOrder := characterOrder(left)
+			rightOrder := characterOrder(right)
+			if leftOrder != rightOrder {
+				return leftOrder - rightOrder
+			}
+			left = left[1:]
+			right = right[1:]
+		}
+		var leftNumber, rightNumber string
+		leftNumber, left = splitDigits(left)
+		rightNumber, right = splitDigits(right)
+		if result := compareNumbers(leftNumber, rightNumber); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// compareNumbers compares strings of digits of arbitrary length.
+func compareNumbers(left, right string) int {
+	left = strings.TrimLeft(left, "0")
+	right = strings.TrimLeft(right, "0")
+	if len(left) != len(right) {
+		return len(left) - len(right)
+	}
+	return strings.Compare(left, right)
+}
+
+// characterOrder returns the sort order of the first character of a string.
+// The end of the string and digits sort after a tilde but before anything
+// else and letters sort before other characters.
+func characterOrder(str string) int {
+	if str == "" {
+		return 0
+	}
+	ch := str[0]
+	switch {
+	case isDigit(ch):
+		return 0
+	case ch == '~':
+		return -1
+	case (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+		return int(ch)
+	}
+	return int(ch) + 256
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func splitDigits(str string) (string, string) {
+	index := 0
+	for index < len(str) && isDigit(str[index]) {
+		index++
+	}
+	return str[:index], str[index:]
+}
+
+func splitEpoch(version string) (string, string) {
+	if index := strings.IndexByte(version, ':'); index >= 0 {
+		return version[:index], version[index+1:]
+	}
+	return "", version
+}
+
+func splitRevision(version string) (string, string) {
+	if index := strings.LastIndexByte(version, '-'); index >= 0 {
+		return version[:index], version[index+1:]
+	}
+	return version, ""
+}
@@ -0,0 +1,27 @@
+package vulndb
+
+import (
+	"github.com/Cloud-Foundations/Dominator/lib/fsutil"
+	"github.com/Cloud-Foundations/Dominator/lib/log"
+)
+
+func watchFeed(pathname string, ecosystems []string,
+	logger log.DebugLogger) <-chan *Database {
+	channel := make(chan *Database, 1)
+	go func() {
+		for readCloser := range fsutil.WatchFile(pathname, logger) {
+			db, err := read(readCloser, ecosystems)
+			readCloser.Close()
+			if err != nil {
+				logger.Printf("Error loading vulnerability feed: %s: %s\n",
+					pathname, err)
+				continue
+			}
+			logger.Debugf(0, "Loaded %d advisories from: %s\n",
+				db.NumAdvisories(), pathname)
+			channel <- db
+		}
+		close(channel)
+	}()
+	return channel
+}
@@ -46,6 +46,12 @@ type Stat_t struct {
 	Ctim    syscall.Timespec
 }
 
+type Ucred struct {
+	Pid int32
+	Uid uint32
+	Gid uint32
+}
+
 type Timeval struct {
 	Sec  int64
 	Usec int64
@@ -72,6 +78,12 @@ func Getrusage(who int, rusage *Rusage) error {
 	return getrusage(who, rusage)
 }
 
+// GetPeerCredentials gets the credentials of the peer process connected to the
+// Unix socket fd.
+func GetPeerCredentials(fd int, ucred *Ucred) error {
+	return getPeerCredentials(fd, ucred)
+}
+
 func SetAllGid(gid int) error {
 	return setAllGid(gid)
 }
@@ -21,6 +21,10 @@ func convertStat(dest *Stat_t, source *syscall.Stat_t) {
 	dest.Ctim = source.Ctimespec
 }
 
+func getPeerCredentials(fd int, ucred *Ucred) error {
+	return syscall.ENOTSUP
+}
+
 func getrusage(who int, rusage *Rusage) error {
 	switch who {
 	case RUSAGE_CHILDREN:
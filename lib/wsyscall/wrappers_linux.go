@@ -30,6 +30,18 @@ func fallocate(fd int, mode uint32, off int64, len int64) error {
 	return syscall.Fallocate(fd, mode, off, len)
 }
 
+func getPeerCredentials(fd int, ucred *Ucred) error {
+	rawUcred, err := syscall.GetsockoptUcred(fd, syscall.SOL_SOCKET,
+		syscall.SO_PEERCRED)
+	if err != nil {
+		return err
+	}
+	ucred.Pid = rawUcred.Pid
+	ucred.Uid = rawUcred.Uid
+	ucred.Gid = rawUcred.Gid
+	return nil
+}
+
 func getrusage(who int, rusage *Rusage) error {
 	switch who {
 	case RUSAGE_CHILDREN:
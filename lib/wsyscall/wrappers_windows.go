@@ -6,6 +6,10 @@ func fallocate(fd int, mode uint32, off int64, len int64) error {
 	return syscall.ENOTSUP
 }
 
+func getPeerCredentials(fd int, ucred *Ucred) error {
+	return syscall.ENOTSUP
+}
+
 func getrusage(who int, rusage *Rusage) error {
 	return syscall.ENOTSUP
 }
@@ -2,11 +2,31 @@ package fleetmanager
 
 import (
 	"net"
+	"time"
 
 	"github.com/Cloud-Foundations/Dominator/lib/tags"
 	proto "github.com/Cloud-Foundations/Dominator/proto/hypervisor"
 )
 
+const (
+	BootDeviceNone      = 0 // Clear any boot device override.
+	BootDevicePxe       = 1
+	BootDeviceDisk      = 2
+	BootDeviceCdrom     = 3
+	BootDeviceBiosSetup = 4
+
+	EventTypeVmCreated          = 0
+	EventTypeVmDestroyed        = 1
+	EventTypeVmMigrated         = 2
+	EventTypeVmStateChanged     = 3
+	EventTypeMachineTagsChanged = 4
+
+	ManagementTypeIpmi    = 0
+	ManagementTypeRedfish = 1
+)
+
+type BootDevice uint
+
 type ChangeMachineTagsRequest struct {
 	Hostname string
 	Tags     tags.Tags
@@ -16,6 +36,32 @@ type ChangeMachineTagsResponse struct {
 	Error string
 }
 
+type CheckVmAllocationRequest struct {
+	IpAddress   net.IP    `json:",omitempty"` // If set, resources are reserved.
+	OwnerGroups []string  `json:",omitempty"`
+	OwnerUsers  []string  `json:",omitempty"` // First entry is primary owner.
+	Resources   Resources // Resources requested. NumVMs=1 for new VMs.
+}
+
+type CheckVmAllocationResponse struct {
+	Error string // Non-empty if the allocation would exceed a quota.
+}
+
+type Event struct {
+	Hypervisor         string      `json:",omitempty"` // Hostname.
+	IpAddress          string      `json:",omitempty"` // VM IP address.
+	OwnerGroups        []string    `json:",omitempty"`
+	OwnerUsers         []string    `json:",omitempty"`
+	PreviousHypervisor string      `json:",omitempty"` // For migrations.
+	State              proto.State `json:",omitempty"`
+	Tags               tags.Tags   `json:",omitempty"`
+	Time               time.Time
+	Type               EventType
+	Username           string `json:",omitempty"` // Acting user, if known.
+}
+
+type EventType uint
+
 type GetHypervisorForVMRequest struct {
 	IpAddress net.IP
 }
@@ -30,10 +76,32 @@ type GetMachineInfoRequest struct {
 }
 
 type GetMachineInfoResponse struct {
-	Error    string          `json:",omitempty"`
-	Location string          `json:",omitempty"`
-	Machine  Machine         `json:",omitempty"`
-	Subnets  []*proto.Subnet `json:",omitempty"`
+	Error             string                   `json:",omitempty"`
+	HardwareInventory *proto.HardwareInventory `json:",omitempty"`
+	Location          string                   `json:",omitempty"`
+	Machine           Machine                  `json:",omitempty"`
+	Subnets           []*proto.Subnet          `json:",omitempty"`
+}
+
+type GetOwnerUsageRequest struct {
+	OwnerGroup string `json:",omitempty"` // If both are empty, list all.
+	OwnerUser  string `json:",omitempty"`
+}
+
+type GetOwnerUsageResponse struct {
+	Error  string       `json:",omitempty"`
+	Owners []OwnerUsage `json:",omitempty"`
+}
+
+// The GetTopology() RPC is streamed.
+// The client sends a single GetTopologyRequest message.
+// The server sends a GetTopologyResponse message. If the Error field is empty,
+// the server then sends the topology (a fleetmanager/topology.Topology).
+
+type GetTopologyRequest struct{}
+
+type GetTopologyResponse struct {
+	Error string
 }
 
 // The GetUpdates() RPC is fully streamed.
@@ -75,6 +143,21 @@ type ListHypervisorsInLocationResponse struct {
 	Error               string
 }
 
+type ListEventsRequest struct {
+	EndTime    time.Time `json:",omitempty"` // Zero means now.
+	Hypervisor string    `json:",omitempty"`
+	IpAddress  net.IP    `json:",omitempty"` // VM IP address.
+	MaxEvents  uint      `json:",omitempty"` // Zero means no limit.
+	OwnerGroup string    `json:",omitempty"`
+	OwnerUser  string    `json:",omitempty"`
+	StartTime  time.Time `json:",omitempty"`
+}
+
+type ListEventsResponse struct {
+	Error  string  `json:",omitempty"`
+	Events []Event `json:",omitempty"` // Oldest first.
+}
+
 type ListVMsInLocationRequest struct {
 	Location string
 }
@@ -90,12 +173,15 @@ type Machine struct {
 	GatewaySubnetId         string `json:",omitempty"`
 	NetworkEntry            `json:",omitempty"`
 	IPMI                    NetworkEntry   `json:",omitempty"`
+	ManagementType          ManagementType `json:",omitempty"` // For the BMC.
 	OwnerGroups             []string       `json:",omitempty"`
 	OwnerUsers              []string       `json:",omitempty"`
 	SecondaryNetworkEntries []NetworkEntry `json:",omitempty"`
 	Tags                    tags.Tags      `json:",omitempty"`
 }
 
+type ManagementType uint
+
 type MoveIpAddressesRequest struct {
 	HypervisorHostname string
 	IpAddresses        []net.IP
@@ -112,6 +198,29 @@ type NetworkEntry struct {
 	SubnetId       string       `json:",omitempty"`
 }
 
+type OwnerUsage struct {
+	OwnerGroup string     `json:",omitempty"`
+	OwnerUser  string     `json:",omitempty"`
+	Quota      *Resources `json:",omitempty"` // nil: no quota.
+	Usage      Resources
+}
+
+type PowerCycleMachineRequest struct {
+	Hostname string
+}
+
+type PowerCycleMachineResponse struct {
+	Error string
+}
+
+type PowerOffMachineRequest struct {
+	Hostname string
+}
+
+type PowerOffMachineResponse struct {
+	Error string
+}
+
 type PowerOnMachineRequest struct {
 	Hostname string
 }
@@ -119,3 +228,30 @@ type PowerOnMachineRequest struct {
 type PowerOnMachineResponse struct {
 	Error string
 }
+
+// A Quota limits the resources which may be consumed by the VMs owned by an
+// OwnerGroup or a primary OwnerUser. Exactly one of those must be specified.
+type Quota struct {
+	OwnerGroup string `json:",omitempty"`
+	OwnerUser  string `json:",omitempty"`
+	Limits     Resources
+}
+
+type SetMachineBootDeviceRequest struct {
+	BootDevice BootDevice
+	Hostname   string
+	Persistent bool // If false, the override applies to the next boot only.
+}
+
+type SetMachineBootDeviceResponse struct {
+	Error string
+}
+
+// Zero values in Resources used as quota limits mean no limit.
+type Resources struct {
+	IpAddresses uint   `json:",omitempty"`
+	MemoryInMiB uint64 `json:",omitempty"`
+	MilliCPUs   uint   `json:",omitempty"`
+	NumVMs      uint   `json:",omitempty"`
+	VolumeBytes uint64 `json:",omitempty"`
+}
@@ -6,6 +6,132 @@ import (
 	"net"
 )
 
+const (
+	bootDeviceUnknown     = "UNKNOWN BootDevice"
+	eventTypeUnknown      = "UNKNOWN EventType"
+	managementTypeUnknown = "UNKNOWN ManagementType"
+)
+
+var (
+	bootDeviceToText = map[BootDevice]string{
+		BootDeviceNone:      "none",
+		BootDevicePxe:       "pxe",
+		BootDeviceDisk:      "disk",
+		BootDeviceCdrom:     "cdrom",
+		BootDeviceBiosSetup: "bios-setup",
+	}
+	textToBootDevice map[string]BootDevice
+
+	eventTypeToText = map[EventType]string{
+		EventTypeVmCreated:          "VM created",
+		EventTypeVmDestroyed:        "VM destroyed",
+		EventTypeVmMigrated:         "VM migrated",
+		EventTypeVmStateChanged:     "VM state changed",
+		EventTypeMachineTagsChanged: "machine tags changed",
+	}
+	textToEventType map[string]EventType
+
+	managementTypeToText = map[ManagementType]string{
+		ManagementTypeIpmi:    "ipmi",
+		ManagementTypeRedfish: "redfish",
+	}
+	textToManagementType map[string]ManagementType
+)
+
+func init() {
+	textToBootDevice = make(map[string]BootDevice, len(bootDeviceToText))
+	for bootDevice, text := range bootDeviceToText {
+		textToBootDevice[text] = bootDevice
+	}
+	textToEventType = make(map[string]EventType, len(eventTypeToText))
+	for eventType, text := range eventTypeToText {
+		textToEventType[text] = eventType
+	}
+	textToManagementType = make(map[string]ManagementType,
+		len(managementTypeToText))
+	for managementType, text := range managementTypeToText {
+		textToManagementType[text] = managementType
+	}
+}
+
+func (bootDevice BootDevice) MarshalText() ([]byte, error) {
+	if text := bootDevice.String(); text == bootDeviceUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (bootDevice BootDevice) String() string {
+	if text, ok := bootDeviceToText[bootDevice]; ok {
+		return text
+	} else {
+		return bootDeviceUnknown
+	}
+}
+
+func (bootDevice *BootDevice) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToBootDevice[txt]; ok {
+		*bootDevice = val
+		return nil
+	} else {
+		return errors.New("unknown BootDevice: " + txt)
+	}
+}
+
+func (eventType EventType) MarshalText() ([]byte, error) {
+	if text := eventType.String(); text == eventTypeUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (eventType EventType) String() string {
+	if text, ok := eventTypeToText[eventType]; ok {
+		return text
+	} else {
+		return eventTypeUnknown
+	}
+}
+
+func (eventType *EventType) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToEventType[txt]; ok {
+		*eventType = val
+		return nil
+	} else {
+		return errors.New("unknown EventType: " + txt)
+	}
+}
+
+func (managementType ManagementType) MarshalText() ([]byte, error) {
+	if text := managementType.String(); text == managementTypeUnknown {
+		return nil, errors.New(text)
+	} else {
+		return []byte(text), nil
+	}
+}
+
+func (managementType ManagementType) String() string {
+	if text, ok := managementTypeToText[managementType]; ok {
+		return text
+	} else {
+		return managementTypeUnknown
+	}
+}
+
+func (managementType *ManagementType) UnmarshalText(text []byte) error {
+	txt := string(text)
+	if val, ok := textToManagementType[txt]; ok {
+		*managementType = val
+		return nil
+	} else {
+		return errors.New("unknown ManagementType: " + txt)
+	}
+}
+
 func listsEqual(left, right []string) bool {
 	if len(left) != len(right) {
 		return false
@@ -51,6 +177,9 @@ func (left *Machine) Equal(right *Machine) bool {
 	if !left.IPMI.Equal(&right.IPMI) {
 		return false
 	}
+	if left.ManagementType != right.ManagementType {
+		return false
+	}
 	if !left.NetworkEntry.Equal(&right.NetworkEntry) {
 		return false
 	}
@@ -248,6 +248,13 @@ type DiscardVmSnapshotResponse struct {
 	Error string
 }
 
+type Disk struct {
+	Model      string `json:",omitempty"`
+	Name       string
+	Rotational bool   `json:",omitempty"`
+	Size       uint64 // Bytes.
+}
+
 type ExportLocalVmInfo struct {
 	Bridges []string
 	LocalVmInfo
@@ -279,16 +286,17 @@ type GetUpdatesRequest struct {
 }
 
 type Update struct {
-	HaveAddressPool  bool               `json:",omitempty"`
-	AddressPool      []Address          `json:",omitempty"` // Used & free.
-	NumFreeAddresses map[string]uint    `json:",omitempty"` // Key: subnet ID.
-	HealthStatus     string             `json:",omitempty"`
-	HaveSerialNumber bool               `json:",omitempty"`
-	SerialNumber     string             `json:",omitempty"`
-	HaveSubnets      bool               `json:",omitempty"`
-	Subnets          []Subnet           `json:",omitempty"`
-	HaveVMs          bool               `json:",omitempty"`
-	VMs              map[string]*VmInfo `json:",omitempty"` // Key: IP address.
+	HaveAddressPool   bool               `json:",omitempty"`
+	AddressPool       []Address          `json:",omitempty"` // Used & free.
+	NumFreeAddresses  map[string]uint    `json:",omitempty"` // Key: subnet ID.
+	HealthStatus      string             `json:",omitempty"`
+	HardwareInventory *HardwareInventory `json:",omitempty"`
+	HaveSerialNumber  bool               `json:",omitempty"`
+	SerialNumber      string             `json:",omitempty"`
+	HaveSubnets       bool               `json:",omitempty"`
+	Subnets           []Subnet           `json:",omitempty"`
+	HaveVMs           bool               `json:",omitempty"`
+	VMs               map[string]*VmInfo `json:",omitempty"` // Key: IP address.
 }
 
 type GetVmAccessTokenRequest struct {
@@ -341,6 +349,21 @@ type ListSubnetsResponse struct {
 	Subnets []Subnet `json:",omitempty"`
 }
 
+type HardwareInventory struct {
+	BiosDate          string             `json:",omitempty"`
+	BiosVendor        string             `json:",omitempty"`
+	BiosVersion       string             `json:",omitempty"`
+	CpuModel          string             `json:",omitempty"`
+	Disks             []Disk             `json:",omitempty"`
+	MemoryInMiB       uint64             `json:",omitempty"`
+	NetworkInterfaces []NetworkInterface `json:",omitempty"`
+	NumCores          uint               `json:",omitempty"` // Physical.
+	NumCPUs           uint               `json:",omitempty"` // Logical.
+	NumSockets        uint               `json:",omitempty"`
+	ProductName       string             `json:",omitempty"`
+	SystemVendor      string             `json:",omitempty"`
+}
+
 type ImportLocalVmRequest struct {
 	VerificationCookie []byte `json:",omitempty"`
 	VmInfo
@@ -410,6 +433,14 @@ type NetbootMachineResponse struct {
 	Error string
 }
 
+type NetworkInterface struct {
+	Driver          string `json:",omitempty"`
+	FirmwareVersion string `json:",omitempty"`
+	MacAddress      string `json:",omitempty"`
+	Name            string
+	SpeedInMbps     uint `json:",omitempty"` // Zero if link is down.
+}
+
 type PatchVmImageRequest struct {
 	ImageName    string
 	ImageTimeout time.Duration
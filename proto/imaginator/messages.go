@@ -10,6 +10,7 @@ type BuildImageRequest struct {
 	DisableRecursiveBuild bool
 	ExpiresIn             time.Duration
 	GitBranch             string
+	GitCommitId           string // Optional: build this commit of GitBranch.
 	MaxSourceAge          time.Duration
 	ReturnImage           bool
 	StreamBuildLog        bool
@@ -23,3 +24,31 @@ type BuildImageResponse struct {
 	BuildLog    []byte
 	ErrorString string
 }
+
+type CancelBuildRequest struct {
+	Id uint64
+}
+
+type CancelBuildResponse struct {
+	Error string
+}
+
+type ListBuildQueueRequest struct{}
+
+type ListBuildQueueResponse struct {
+	Builds []QueuedBuild
+	Error  string
+}
+
+// QueuedBuild describes a build which is queued or running.
+type QueuedBuild struct {
+	GitBranch   string `json:",omitempty"`
+	Id          uint64
+	Interactive bool `json:",omitempty"` // Requested rather than automatic.
+	NumWaiters  uint `json:",omitempty"` // Number of duplicate requests.
+	QueuedAt    time.Time
+	Running     bool      `json:",omitempty"`
+	StartedAt   time.Time `json:",omitempty"`
+	StreamName  string
+	Username    string `json:",omitempty"`
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"io"
 
 	"github.com/Cloud-Foundations/Dominator/lib/hash"
@@ -27,7 +28,12 @@ func GetConfiguration(client *srpc.Client) (sub.Configuration, error) {
 
 func CallPoll(client *srpc.Client, request sub.PollRequest,
 	reply *sub.PollResponse) error {
-	return callPoll(client, request, reply)
+	return callPoll(context.Background(), client, request, reply)
+}
+
+func CallPollContext(ctx context.Context, client *srpc.Client,
+	request sub.PollRequest, reply *sub.PollResponse) error {
+	return callPoll(ctx, client, request, reply)
 }
 
 func SetConfiguration(client *srpc.Client, config sub.Configuration) error {
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"errors"
 
 	"github.com/Cloud-Foundations/Dominator/lib/filesystem"
@@ -9,13 +10,24 @@ import (
 	"github.com/Cloud-Foundations/Dominator/proto/sub"
 )
 
-func callPoll(client *srpc.Client, request sub.PollRequest,
-	reply *sub.PollResponse) error {
-	conn, err := client.Call("Subd.Poll")
+func callPoll(ctx context.Context, client *srpc.Client,
+	request sub.PollRequest, reply *sub.PollResponse) error {
+	conn, err := client.CallContext(ctx, "Subd.Poll")
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
+	if err := poll(conn, request, reply); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr // The call was abandoned.
+		}
+		return err
+	}
+	return nil
+}
+
+func poll(conn *srpc.Conn, request sub.PollRequest,
+	reply *sub.PollResponse) error {
 	if err := conn.Encode(request); err != nil {
 		return err
 	}